@@ -3,14 +3,19 @@ package main
 import (
 	"context"
 	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -23,6 +28,7 @@ import (
 	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"github.com/guillermoBallester/isthmus/internal/core/port"
 	"github.com/guillermoBallester/isthmus/internal/core/service"
+	"github.com/guillermoBallester/isthmus/internal/snapshot"
 	"github.com/guillermoBallester/isthmus/internal/telemetry"
 	"github.com/jackc/pgx/v5/pgxpool"
 	mcpserver "github.com/mark3labs/mcp-go/server"
@@ -59,6 +65,9 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("loading config: %w", err)
 	}
+	if cfg.DumpConfig {
+		return dumpConfig(os.Stdout, cfg)
+	}
 
 	logger := newLogger(cfg)
 	logger.Info("starting isthmus",
@@ -73,25 +82,67 @@ func run() error {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 	defer stop()
 
-	pool, err := connectDB(ctx, cfg)
+	pool, err := connectDB(ctx, cfg, cfg.DatabaseURL)
 	if err != nil {
 		return err
 	}
 	defer pool.Close()
 	logger.Info("database pool connected", slog.String("db.system", "postgresql"))
 
+	var replica *pgxpool.Pool
+	if cfg.ReplicaDatabaseURL != "" {
+		replica, err = connectDB(ctx, cfg, cfg.ReplicaDatabaseURL)
+		if err != nil {
+			return fmt.Errorf("replica: %w", err)
+		}
+		defer replica.Close()
+		logger.Info("replica pool connected", slog.String("db.system", "postgresql"))
+	}
+
 	if cfg.DryRun {
 		printResolvedConfig(cfg)
 		return nil
 	}
 
-	explorer, masks, err := buildExplorer(pool, cfg, logger)
+	explorer, pol, masks, err := buildExplorer(ctx, pool, cfg, logger)
+	if err != nil {
+		return err
+	}
+	var patterns []domain.MaskPattern
+	var jsonMasks map[string][]domain.JSONPathMask
+	var toolDescriptions map[string]string
+	var customTools []mcp.CustomTool
+	var toolMasking map[string]domain.ToolMasking
+	var rowLimits map[domain.TableRef]int
+	var tableTimeouts map[domain.TableRef]time.Duration
+	var tableDefaults map[domain.TableRef]domain.MaskType
+	var schemaDefaults map[string]domain.MaskType
+	if pol != nil {
+		patterns = policy.MaskPatterns(pol.ColumnPatterns)
+		jsonMasks = policy.JSONMaskSpec(pol.Context)
+		rowLimits = policy.RowLimitSpec(pol.Context)
+		tableTimeouts = policy.TimeoutSpec(pol.Context)
+		tableDefaults, schemaDefaults = policy.DefaultMaskSpec(pol.Context)
+		if err := mcp.ValidateToolDescriptions(pol.ToolDescriptions); err != nil {
+			return fmt.Errorf("loading policy: %w", err)
+		}
+		toolDescriptions = pol.ToolDescriptions
+		customTools = customToolsFromPolicy(pol)
+		if err := mcp.ValidateCustomTools(customTools); err != nil {
+			return fmt.Errorf("loading policy: %w", err)
+		}
+		toolMasking = pol.ToolMaskSpec()
+		if err := mcp.ValidateToolMasking(toolMasking, customTools); err != nil {
+			return fmt.Errorf("loading policy: %w", err)
+		}
+	}
+	tableResources, err := buildTableResources(ctx, cfg, explorer, pol, logger)
 	if err != nil {
 		return err
 	}
-	executor := buildExecutor(pool, cfg, logger)
+	executor := buildExecutor(ctx, pool, replica, cfg, logger)
 
-	auditor, closeAuditor, err := buildAuditor(cfg, logger)
+	auditor, closeAuditor, err := buildAuditor(ctx, cfg, logger)
 	if err != nil {
 		return err
 	}
@@ -99,7 +150,10 @@ func run() error {
 
 	var otelProvider *telemetry.Provider
 	if cfg.OTelEnabled {
-		otelProvider, err = telemetry.Init(ctx, "isthmus", version)
+		otelProvider, err = telemetry.Init(ctx, "isthmus", version, telemetry.ExporterTLS{
+			CAFile:   cfg.OTelCAFile,
+			Insecure: cfg.OTelInsecure,
+		})
 		if err != nil {
 			return fmt.Errorf("initializing otel: %w", err)
 		}
@@ -110,10 +164,13 @@ func run() error {
 				logger.Error("shutting down otel", slog.String("error", err.Error()))
 			}
 		}()
+		if err := telemetry.RegisterPoolMetrics(poolStats(pool)); err != nil {
+			return fmt.Errorf("registering pool metrics: %w", err)
+		}
 		logger.Info("opentelemetry enabled")
 	}
 
-	return serve(ctx, cfg, version, pool, explorer, executor, masks, auditor, logger)
+	return serve(ctx, cfg, version, pool, explorer, executor, masks, tableDefaults, schemaDefaults, patterns, jsonMasks, rowLimits, tableTimeouts, tableResources, toolDescriptions, customTools, toolMasking, auditor, logger)
 }
 
 func newLogger(cfg *config.Config) *slog.Logger {
@@ -123,11 +180,14 @@ func newLogger(cfg *config.Config) *slog.Logger {
 	}))
 }
 
-func connectDB(ctx context.Context, cfg *config.Config) (*pgxpool.Pool, error) {
-	pool, err := postgres.NewPool(ctx, cfg.DatabaseURL, postgres.PoolOptions{
-		MaxConns:        cfg.PoolMaxConns,
-		MinConns:        cfg.PoolMinConns,
-		MaxConnLifetime: cfg.PoolMaxConnLifetime,
+func connectDB(ctx context.Context, cfg *config.Config, dsn string) (*pgxpool.Pool, error) {
+	pool, err := postgres.NewPool(ctx, dsn, postgres.PoolOptions{
+		MaxConns:          cfg.PoolMaxConns,
+		MinConns:          cfg.PoolMinConns,
+		MaxConnLifetime:   cfg.PoolMaxConnLifetime,
+		HealthCheckPeriod: cfg.PoolHealthCheckPeriod,
+		DialerProxy:       cfg.DialerProxy,
+		SetupSQL:          cfg.ConnectSetupSQL,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("connecting to database: %w", err)
@@ -135,38 +195,237 @@ func connectDB(ctx context.Context, cfg *config.Config) (*pgxpool.Pool, error) {
 	return pool, nil
 }
 
-func buildExplorer(pool *pgxpool.Pool, cfg *config.Config, logger *slog.Logger) (port.SchemaExplorer, map[string]domain.MaskType, error) {
-	var explorer port.SchemaExplorer = postgres.NewExplorer(pool, cfg.Schemas)
-	var masks map[string]domain.MaskType
+// poolStats adapts pgxpool statistics for the isthmus.pool.* metrics.
+func poolStats(pool *pgxpool.Pool) func() telemetry.PoolStats {
+	return func() telemetry.PoolStats {
+		s := pool.Stat()
+		return telemetry.PoolStats{
+			AcquiredConns:     s.AcquiredConns(),
+			IdleConns:         s.IdleConns(),
+			TotalConns:        s.TotalConns(),
+			MaxConns:          s.MaxConns(),
+			EmptyAcquireCount: s.EmptyAcquireCount(),
+			AcquireDuration:   s.AcquireDuration(),
+		}
+	}
+}
 
-	if cfg.PolicyFile != "" {
-		pol, err := policy.LoadFromFile(cfg.PolicyFile)
+// customToolsFromPolicy converts the policy's tool definitions, sorted by
+// name so tools are registered in a stable order.
+func customToolsFromPolicy(pol *policy.Policy) []mcp.CustomTool {
+	var tools []mcp.CustomTool
+	for _, name := range slices.Sorted(maps.Keys(pol.Tools)) {
+		def := pol.Tools[name]
+		tool := mcp.CustomTool{Name: name, Description: def.Description, SQL: def.SQL}
+		for _, p := range def.Params {
+			tool.Params = append(tool.Params, mcp.CustomToolParam{Name: p.Name, Type: p.Type, Description: p.Description})
+		}
+		tools = append(tools, tool)
+	}
+	return tools
+}
+
+// checkPolicyColumns reports policy masks on columns that do not exist, as a
+// warning or a startup error depending on mode (POLICY_STRICT).
+func checkPolicyColumns(ctx context.Context, lister port.ColumnLister, pol *policy.Policy, mode string, logger *slog.Logger) error {
+	if mode == "off" {
+		return nil
+	}
+	missing, err := policy.MissingMaskedColumns(ctx, lister, pol.Context)
+	if err != nil {
+		return fmt.Errorf("checking policy columns: %w", err)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if mode == "error" {
+		return fmt.Errorf("policy masks columns that do not exist: %s", strings.Join(missing, ", "))
+	}
+	logger.Warn("policy masks columns that do not exist", slog.Any("columns", missing))
+	return nil
+}
+
+// buildExplorer returns the schema explorer, wrapped with the policy when one
+// is configured. The loaded policy and its explicit column masks are
+// returned too (nil without a policy).
+func buildExplorer(ctx context.Context, pool *pgxpool.Pool, cfg *config.Config, logger *slog.Logger) (port.SchemaExplorer, *policy.Policy, map[string]domain.MaskType, error) {
+	var explorer port.SchemaExplorer = postgres.NewExplorer(pool, cfg.Schemas,
+		postgres.WithSchemaCacheTTL(cfg.SchemaCacheTTL),
+		postgres.WithPKIndexTTL(cfg.FKInferenceCacheTTL),
+		postgres.WithFKInferenceScope(postgres.FKInferenceScope(cfg.FKInferenceScope)),
+		postgres.WithFKInferenceHighConfidenceOnly(cfg.FKInferenceHighConfidenceOnly),
+		postgres.WithFKInferenceMax(cfg.FKInferenceMax),
+		postgres.WithSampleRows(cfg.DescribeIncludeSamples),
+		postgres.WithIndexUsage(cfg.DescribeIncludeIndexUsage),
+		postgres.WithRowEstimateSampling(cfg.RowEstimateSampling),
+		postgres.WithResolveAmbiguous(cfg.ResolveAmbiguous),
+		postgres.WithHiddenSchemas(cfg.HiddenSchemas),
+	)
+
+	paths, err := policyPaths(cfg)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("loading policy: %w", err)
+	}
+	if len(paths) == 0 && cfg.ContextFile == "" {
+		return explorer, nil, nil, nil
+	}
+
+	pol := &policy.Policy{}
+	if len(paths) > 0 {
+		pol, err = policy.LoadFromFiles(paths)
 		if err != nil {
-			return nil, nil, fmt.Errorf("loading policy: %w", err)
+			return nil, nil, nil, fmt.Errorf("loading policy: %w", err)
 		}
-		masks = policy.MaskSpec(pol.Context)
-		explorer = policy.NewPolicyExplorer(explorer, pol, masks)
-		logger.Info("policy loaded", slog.String("file", cfg.PolicyFile))
-		if len(masks) > 0 {
-			logger.Info("column masking enabled", slog.Int("masked_columns", len(masks)))
+	}
+	if cfg.ContextFile != "" {
+		descriptions, err := policy.LoadContextFile(cfg.ContextFile)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("loading context file: %w", err)
 		}
+		policy.MergeDescriptions(&pol.Context, descriptions)
+		logger.Info("context file loaded",
+			slog.String("file", cfg.ContextFile),
+			slog.Int("tables", len(descriptions.Tables)),
+		)
 	}
+	lister := postgres.NewColumnLister(pool)
+	if err := checkPolicyColumns(ctx, lister, pol, cfg.PolicyStrict, logger); err != nil {
+		return nil, nil, nil, err
+	}
+	masks := policy.MaskSpec(pol.Context)
+	patterns := policy.MaskPatterns(pol.ColumnPatterns)
+	explorer = policy.NewPolicyExplorer(explorer, pol, masks, policy.WithColumnLister(lister))
+	if len(paths) > 0 {
+		logger.Info("policy loaded", slog.Any("files", paths))
+	}
+	if len(masks) > 0 || len(patterns) > 0 {
+		logger.Info("column masking enabled",
+			slog.Int("masked_columns", len(masks)),
+			slog.Int("column_patterns", len(patterns)),
+		)
+	}
+
+	return explorer, pol, masks, nil
+}
 
-	return explorer, masks, nil
+// buildTableResources lists the tables to expose as MCP resources: none unless
+// enabled, only the policy's tables with RESOURCES_FROM_POLICY_ONLY, otherwise
+// every table in scope.
+func buildTableResources(ctx context.Context, cfg *config.Config, explorer port.SchemaExplorer, pol *policy.Policy, logger *slog.Logger) ([]port.TableInfo, error) {
+	if !cfg.TableResources && !cfg.ResourcesFromPolicyOnly {
+		return nil, nil
+	}
+
+	tables, err := explorer.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing tables for resources: %w", err)
+	}
+	if cfg.ResourcesFromPolicyOnly && pol != nil {
+		tables = policy.FilterTables(tables, pol.Context)
+	}
+
+	logger.Info("table resources enabled",
+		slog.Int("tables", len(tables)),
+		slog.Bool("from_policy_only", cfg.ResourcesFromPolicyOnly),
+	)
+	return tables, nil
+}
+
+// policyPaths resolves the policy files to merge: the comma-separated
+// POLICY_FILE entries first, then the YAML files in POLICY_DIR by name.
+func policyPaths(cfg *config.Config) ([]string, error) {
+	var paths []string
+	for _, p := range strings.Split(cfg.PolicyFile, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if cfg.PolicyDir != "" {
+		dirPaths, err := policy.FilesInDir(cfg.PolicyDir)
+		if err != nil {
+			return nil, err
+		}
+		if len(dirPaths) == 0 {
+			return nil, fmt.Errorf("no policy files found in %s", cfg.PolicyDir)
+		}
+		paths = append(paths, dirPaths...)
+	}
+	return paths, nil
 }
 
-func buildExecutor(pool *pgxpool.Pool, cfg *config.Config, logger *slog.Logger) port.QueryExecutor {
-	var executor port.QueryExecutor = postgres.NewExecutor(pool, cfg.ReadOnly, cfg.MaxRows, cfg.QueryTimeout)
+// buildExecutor returns the query executor. When replica is not nil, calls
+// with prefer_replica run on it and all others on pool.
+func buildExecutor(ctx context.Context, pool, replica *pgxpool.Pool, cfg *config.Config, logger *slog.Logger) port.QueryExecutor {
+	newExecutor := func(pool *pgxpool.Pool) *postgres.Executor {
+		planHints := cfg.PlanHints && planHintsLoaded(ctx, pool, logger)
+		return postgres.NewExecutor(pool, cfg.ReadOnly, cfg.MaxRows, cfg.QueryTimeout,
+			postgres.WithMaxRowsCeiling(cfg.MaxRowsCeiling),
+			postgres.WithMaxResultMemory(cfg.MaxResultMemory),
+			postgres.WithQueryTimeoutBounds(cfg.QueryTimeoutMin, cfg.QueryTimeoutMax),
+			postgres.WithPlanLiteralRedaction(cfg.ExplainRedactLiterals),
+			postgres.WithPgErrorDetail(cfg.LogPgErrorDetail),
+			postgres.WithMultiStatement(cfg.AllowMultiStatement),
+			postgres.WithRollbackAlways(cfg.RollbackAlways),
+			postgres.WithLimitInjection(cfg.LimitInjection),
+			postgres.WithStrictExplain(cfg.StrictExplain),
+			postgres.WithDuplicateColumns(postgres.DuplicateColumns(cfg.DuplicateColumns)),
+			postgres.WithPlanHints(planHints),
+			postgres.WithSearchPath(enforcedSchemas(cfg)),
+		)
+	}
+
+	var executor port.QueryExecutor = newExecutor(pool)
+	if replica != nil {
+		executor = postgres.NewReplicaRoutingExecutor(executor, newExecutor(replica))
+		logger.Info("prefer_replica routing enabled")
+	}
 
 	if cfg.ExplainOnly {
 		executor = postgres.NewExplainOnlyExecutor(executor)
 		logger.Info("explain-only mode enabled")
+	} else {
+		// Still honor callers that opt in per call with explain_only.
+		executor = postgres.NewExplainOnRequestExecutor(executor)
 	}
 
 	return executor
 }
 
-func buildAuditor(cfg *config.Config, logger *slog.Logger) (port.QueryAuditor, func(), error) {
+// enforcedSchemas returns the schemas queries are confined to with
+// ENFORCE_SCHEMAS: the configured schemas less the hidden ones. It is nil
+// when schemas are not enforced.
+func enforcedSchemas(cfg *config.Config) []string {
+	if !cfg.EnforceSchemas {
+		return nil
+	}
+	return slices.DeleteFunc(slices.Clone(cfg.Schemas), func(s string) bool {
+		return slices.Contains(cfg.HiddenSchemas, s)
+	})
+}
+
+// planHintsLoaded reports whether pg_hint_plan is loaded on pool's server.
+// PLAN_HINTS has no effect without it, so a missing module is logged and the
+// passthrough stays off.
+func planHintsLoaded(ctx context.Context, pool *pgxpool.Pool, logger *slog.Logger) bool {
+	loaded, err := postgres.PlanHintsLoaded(ctx, pool)
+	if err != nil {
+		logger.Warn("plan_hints disabled: could not check for pg_hint_plan", slog.String("error", err.Error()))
+		return false
+	}
+	if !loaded {
+		logger.Warn("plan_hints disabled: pg_hint_plan is not loaded (add it to shared_preload_libraries)")
+		return false
+	}
+	logger.Info("plan_hints enabled")
+	return true
+}
+
+func buildAuditor(ctx context.Context, cfg *config.Config, logger *slog.Logger) (port.QueryAuditor, func(), error) {
+	if cfg.AuditSink == "stderr" {
+		// Never stdout: the stdio transport owns it.
+		logger.Info("audit logging enabled", slog.String("sink", "stderr"))
+		return audit.NewWriterAuditor(os.Stderr), func() {}, nil
+	}
 	if cfg.AuditLog == "" {
 		return port.NoopAuditor{}, func() {}, nil
 	}
@@ -177,7 +436,10 @@ func buildAuditor(cfg *config.Config, logger *slog.Logger) (port.QueryAuditor, f
 	}
 	logger.Info("audit logging enabled", slog.String("file", cfg.AuditLog))
 
+	stopReopen := reopenOnSIGHUP(ctx, fa, logger)
+
 	closeFn := func() {
+		stopReopen()
 		if err := fa.Close(); err != nil {
 			logger.Error("closing audit log", slog.String("error", err.Error()))
 		}
@@ -186,7 +448,38 @@ func buildAuditor(cfg *config.Config, logger *slog.Logger) (port.QueryAuditor, f
 	return fa, closeFn, nil
 }
 
-func serve(ctx context.Context, cfg *config.Config, ver string, pool *pgxpool.Pool, explorer port.SchemaExplorer, executor port.QueryExecutor, masks map[string]domain.MaskType, auditor port.QueryAuditor, logger *slog.Logger) error {
+// reopenOnSIGHUP reopens the audit log whenever the process receives SIGHUP,
+// so logrotate can move the file aside without isthmus writing to the old
+// inode. The returned function stops listening.
+func reopenOnSIGHUP(ctx context.Context, fa *audit.FileAuditor, logger *slog.Logger) func() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-hup:
+				if err := fa.Reopen(); err != nil {
+					logger.Error("reopening audit log", slog.String("error", err.Error()))
+					continue
+				}
+				logger.Info("audit log reopened")
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(hup)
+		close(done)
+	}
+}
+
+func serve(ctx context.Context, cfg *config.Config, ver string, pool *pgxpool.Pool, explorer port.SchemaExplorer, executor port.QueryExecutor, masks map[string]domain.MaskType, tableDefaults map[domain.TableRef]domain.MaskType, schemaDefaults map[string]domain.MaskType, patterns []domain.MaskPattern, jsonMasks map[string][]domain.JSONPathMask, rowLimits map[domain.TableRef]int, tableTimeouts map[domain.TableRef]time.Duration, tableResources []port.TableInfo, toolDescriptions map[string]string, customTools []mcp.CustomTool, toolMasking map[string]domain.ToolMasking, auditor port.QueryAuditor, logger *slog.Logger) error {
 	var tracer = telemetry.NoopTracer()
 	var inst port.Instrumentation = port.NoopInstrumentation{}
 	if cfg.OTelEnabled {
@@ -194,10 +487,74 @@ func serve(ctx context.Context, cfg *config.Config, ver string, pool *pgxpool.Po
 		inst = telemetry.NewInstruments()
 	}
 
-	validator := domain.NewPgQueryValidator()
-	querySvc := service.NewQueryService(validator, executor, auditor, logger, masks, tracer, inst)
+	validatorOpts := []domain.ValidatorOption{
+		domain.WithMultiStatement(cfg.AllowMultiStatement),
+		domain.WithFunctionCheck(cfg.FunctionCheck),
+		domain.WithBlockedFunctions(cfg.BlockedFunctions),
+	}
+	if allowed := enforcedSchemas(cfg); allowed != nil {
+		validatorOpts = append(validatorOpts, domain.WithAllowedSchemas(allowed))
+	}
+	validator := domain.NewPgQueryValidator(validatorOpts...)
+	querySvc := service.NewQueryService(validator, executor, auditor, logger, masks, tracer, inst,
+		service.WithMaskPatterns(patterns),
+		service.WithDefaultMasks(tableDefaults, schemaDefaults, postgres.NewColumnLister(pool)),
+		service.WithJSONMasks(jsonMasks),
+		service.WithTableRowLimits(rowLimits),
+		service.WithTableTimeouts(tableTimeouts),
+		service.WithKeyCase(domain.KeyCase(cfg.ResultKeyCase)),
+		service.WithNumberedDuplicates(cfg.DuplicateColumns == "suffix"),
+		service.WithToolMasking(toolMasking),
+	)
 
-	mcpServer := mcp.NewServer(ver, explorer, querySvc, logger, tracer, inst)
+	toolOpts := []mcp.ToolOption{
+		mcp.WithServerInfo(mcp.ServerInfo{
+			Version:         ver,
+			StartedAt:       time.Now(),
+			Transport:       cfg.Transport,
+			ReadOnly:        cfg.ReadOnly,
+			ExplainOnly:     cfg.ExplainOnly,
+			MaxRows:         cfg.MaxRows,
+			MaxRowsCeiling:  cfg.MaxRowsCeiling,
+			QueryTimeout:    cfg.QueryTimeout.String(),
+			QueryTimeoutMin: cfg.QueryTimeoutMin.String(),
+			QueryTimeoutMax: cfg.QueryTimeoutMax.String(),
+			AnalyzeMaxCost:  cfg.AnalyzeMaxCost,
+			MultiStatement:  cfg.AllowMultiStatement,
+			Schemas:         cfg.Schemas,
+			PolicyActive:    cfg.PolicyFile != "" || cfg.PolicyDir != "",
+			MaskingActive:   len(masks) > 0 || len(tableDefaults) > 0 || len(schemaDefaults) > 0 || len(patterns) > 0 || len(jsonMasks) > 0,
+		}),
+		mcp.WithReplicationStatus(postgres.NewReplicationReporter(pool)),
+		mcp.WithTableResources(tableResources),
+		mcp.WithAnalyzeMaxCost(cfg.AnalyzeMaxCost),
+		mcp.WithToolDescriptions(toolDescriptions),
+		mcp.WithToolCallTimeout(cfg.ToolCallTimeout),
+		mcp.WithMaxArgBytes(cfg.MaxArgBytes),
+		mcp.WithIncludeTiming(cfg.IncludeTiming),
+		mcp.WithExecutedSQL(cfg.IncludeSQL),
+		mcp.WithDescribeConcurrency(cfg.DescribeMaxConcurrency),
+		mcp.WithCustomTools(customTools),
+		mcp.WithReplica(cfg.ReplicaDatabaseURL != ""),
+		mcp.WithPageTokenKey([]byte(cfg.PageTokenKey)),
+	}
+	if cfg.ServerInfoTablespaces {
+		toolOpts = append(toolOpts, mcp.WithTablespaces(postgres.NewTablespaceLister(pool)))
+	}
+	if cfg.TableGrowthSnapshot != "" {
+		toolOpts = append(toolOpts, mcp.WithTableGrowth(snapshot.NewFileStore(cfg.TableGrowthSnapshot)))
+	}
+
+	if cfg.Transport != "http" && cfg.IdleTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		idle := mcp.NewIdleWatchdog(cfg.IdleTimeout, logger)
+		toolOpts = append(toolOpts, mcp.WithIdleWatchdog(idle))
+		go idle.Run(ctx, cancel)
+	}
+
+	mcpServer := mcp.NewServer(ver, explorer, querySvc, logger, tracer, inst, toolOpts...)
 
 	switch cfg.Transport {
 	case "http":
@@ -211,7 +568,8 @@ func serveStdio(ctx context.Context, mcpServer *mcpserver.MCPServer, logger *slo
 	stdioServer := mcpserver.NewStdioServer(mcpServer)
 
 	logger.Info("serving MCP over stdio")
-	if err := stdioServer.Listen(ctx, os.Stdin, os.Stdout); err != nil {
+	// A canceled context is a requested shutdown: a signal or the idle timeout.
+	if err := stdioServer.Listen(ctx, os.Stdin, os.Stdout); err != nil && !errors.Is(err, context.Canceled) {
 		return fmt.Errorf("stdio server: %w", err)
 	}
 
@@ -307,6 +665,7 @@ func parseFlags(args []string) (config.Overrides, error) {
 
 	showVersion := fs.Bool("version", false, "Print version and exit")
 	dryRun := fs.Bool("dry-run", false, "Validate config, connect to DB, ping, then exit")
+	dumpConfig := fs.Bool("dump-config", false, "Print the resolved config as JSON to stdout, with secrets redacted, then exit")
 	explainOnly := fs.Bool("explain-only", false, "Force all query calls to return EXPLAIN plans")
 	auditLog := fs.String("audit-log", "", "Path to NDJSON file for query audit logging")
 	databaseURL := fs.String("database-url", "", "PostgreSQL connection string (overrides DATABASE_URL env)")
@@ -333,6 +692,7 @@ func parseFlags(args []string) (config.Overrides, error) {
 
 	var overrides config.Overrides
 	overrides.DryRun = *dryRun
+	overrides.DumpConfig = *dumpConfig
 	overrides.ExplainOnly = *explainOnly
 	overrides.AuditLog = *auditLog
 
@@ -380,27 +740,323 @@ func parseFlags(args []string) (config.Overrides, error) {
 func printResolvedConfig(cfg *config.Config) {
 	fmt.Fprintf(os.Stderr, "dry-run: config OK, database reachable\n")
 	fmt.Fprintf(os.Stderr, "  database_url:  %s\n", redactDSN(cfg.DatabaseURL))
+	if cfg.ReplicaDatabaseURL != "" {
+		fmt.Fprintf(os.Stderr, "  replica_database_url: %s\n", redactDSN(cfg.ReplicaDatabaseURL))
+	}
 	fmt.Fprintf(os.Stderr, "  read_only:     %t\n", cfg.ReadOnly)
 	fmt.Fprintf(os.Stderr, "  max_rows:      %d\n", cfg.MaxRows)
-	fmt.Fprintf(os.Stderr, "  query_timeout: %s\n", cfg.QueryTimeout)
+	fmt.Fprintf(os.Stderr, "  max_rows_ceiling: %d\n", cfg.MaxRowsCeiling)
+	if cfg.MaxResultMemory > 0 {
+		fmt.Fprintf(os.Stderr, "  max_result_memory: %d\n", cfg.MaxResultMemory)
+	}
+	fmt.Fprintf(os.Stderr, "  query_timeout: %s (min %s, max %s)\n", cfg.QueryTimeout, cfg.QueryTimeoutMin, cfg.QueryTimeoutMax)
+	if cfg.AnalyzeMaxCost > 0 {
+		fmt.Fprintf(os.Stderr, "  analyze_max_cost: %g\n", cfg.AnalyzeMaxCost)
+	}
+	if cfg.ExplainRedactLiterals {
+		fmt.Fprintf(os.Stderr, "  explain_redact_literals: true\n")
+	}
+	if cfg.LogPgErrorDetail {
+		fmt.Fprintf(os.Stderr, "  log_pg_error_detail: true\n")
+	}
+	if cfg.AllowMultiStatement {
+		fmt.Fprintf(os.Stderr, "  allow_multi_statement: true\n")
+	}
+	if cfg.FunctionCheck {
+		fmt.Fprintf(os.Stderr, "  function_check: true\n")
+	}
+	if len(cfg.BlockedFunctions) > 0 {
+		fmt.Fprintf(os.Stderr, "  blocked_functions: %v\n", cfg.BlockedFunctions)
+	}
+	if cfg.RollbackAlways {
+		fmt.Fprintf(os.Stderr, "  rollback_always: true\n")
+	}
+	if cfg.LimitInjection {
+		fmt.Fprintf(os.Stderr, "  limit_injection: true\n")
+	}
+	if cfg.StrictExplain {
+		fmt.Fprintf(os.Stderr, "  strict_explain: true\n")
+	}
+	if cfg.PlanHints {
+		fmt.Fprintf(os.Stderr, "  plan_hints: true\n")
+	}
+	if cfg.ToolCallTimeout > 0 {
+		fmt.Fprintf(os.Stderr, "  tool_call_timeout: %s\n", cfg.ToolCallTimeout)
+	}
+	if cfg.MaxArgBytes > 0 {
+		fmt.Fprintf(os.Stderr, "  max_arg_bytes: %d\n", cfg.MaxArgBytes)
+	}
+	if cfg.IncludeTiming {
+		fmt.Fprintf(os.Stderr, "  include_timing: true\n")
+	}
+	if cfg.IncludeSQL {
+		fmt.Fprintf(os.Stderr, "  include_executed_sql: true\n")
+	}
 	fmt.Fprintf(os.Stderr, "  log_level:     %s\n", cfg.LogLevel)
+	fmt.Fprintf(os.Stderr, "  result_key_case: %s\n", cfg.ResultKeyCase)
+	if cfg.DuplicateColumns != "error" {
+		fmt.Fprintf(os.Stderr, "  duplicate_columns: %s\n", cfg.DuplicateColumns)
+	}
+	if cfg.SchemaCacheTTL > 0 {
+		fmt.Fprintf(os.Stderr, "  schema_cache_ttl: %s\n", cfg.SchemaCacheTTL)
+	}
+	fmt.Fprintf(os.Stderr, "  fk_inference_cache_ttl: %s\n", cfg.FKInferenceCacheTTL)
+	fmt.Fprintf(os.Stderr, "  fk_inference_scope: %s\n", cfg.FKInferenceScope)
+	if cfg.FKInferenceHighConfidenceOnly {
+		fmt.Fprintf(os.Stderr, "  fk_inference_high_confidence_only: true\n")
+	}
+	if cfg.FKInferenceMax > 0 {
+		fmt.Fprintf(os.Stderr, "  fk_inference_max: %d\n", cfg.FKInferenceMax)
+	}
+	if !cfg.DescribeIncludeSamples {
+		fmt.Fprintf(os.Stderr, "  describe_include_samples: false\n")
+	}
+	if cfg.DescribeMaxConcurrency > 0 {
+		fmt.Fprintf(os.Stderr, "  describe_max_concurrency: %d\n", cfg.DescribeMaxConcurrency)
+	}
+	if !cfg.DescribeIncludeIndexUsage {
+		fmt.Fprintf(os.Stderr, "  describe_include_index_usage: false\n")
+	}
+	if cfg.RowEstimateSampling {
+		fmt.Fprintf(os.Stderr, "  row_estimate_sampling: true\n")
+	}
+	if cfg.ResolveAmbiguous {
+		fmt.Fprintf(os.Stderr, "  resolve_ambiguous: true\n")
+	}
+	if cfg.ServerInfoTablespaces {
+		fmt.Fprintf(os.Stderr, "  server_info_tablespaces: true\n")
+	}
 	fmt.Fprintf(os.Stderr, "  transport:     %s\n", cfg.Transport)
 	if cfg.Transport == "http" {
 		fmt.Fprintf(os.Stderr, "  http_addr:     %s\n", cfg.HTTPAddr)
 		fmt.Fprintf(os.Stderr, "  http_bearer_token: ***\n")
 	}
+	if cfg.IdleTimeout > 0 {
+		fmt.Fprintf(os.Stderr, "  idle_timeout:  %s\n", cfg.IdleTimeout)
+	}
+	if cfg.DialerProxy != "" {
+		fmt.Fprintf(os.Stderr, "  dialer_proxy:  %s\n", redactDSN(cfg.DialerProxy))
+	}
 	if cfg.PolicyFile != "" {
 		fmt.Fprintf(os.Stderr, "  policy_file:   %s\n", cfg.PolicyFile)
 	}
+	if cfg.PolicyDir != "" {
+		fmt.Fprintf(os.Stderr, "  policy_dir:    %s\n", cfg.PolicyDir)
+	}
+	if cfg.ContextFile != "" {
+		fmt.Fprintf(os.Stderr, "  context_file:  %s\n", cfg.ContextFile)
+	}
+	if cfg.TableGrowthSnapshot != "" {
+		fmt.Fprintf(os.Stderr, "  table_growth_snapshot: %s\n", cfg.TableGrowthSnapshot)
+	}
+	if cfg.PolicyStrict != "off" {
+		fmt.Fprintf(os.Stderr, "  policy_strict: %s\n", cfg.PolicyStrict)
+	}
+	if cfg.TableResources || cfg.ResourcesFromPolicyOnly {
+		fmt.Fprintf(os.Stderr, "  table_resources: true (from_policy_only: %v)\n", cfg.ResourcesFromPolicyOnly)
+	}
 	if len(cfg.Schemas) > 0 {
 		fmt.Fprintf(os.Stderr, "  schemas:       %v\n", cfg.Schemas)
 	}
+	if len(cfg.HiddenSchemas) > 0 {
+		fmt.Fprintf(os.Stderr, "  hidden_schemas: %v\n", cfg.HiddenSchemas)
+	}
+	if cfg.EnforceSchemas {
+		fmt.Fprintf(os.Stderr, "  enforce_schemas: true\n")
+	}
 	fmt.Fprintf(os.Stderr, "  pool_max_conns:        %d\n", cfg.PoolMaxConns)
 	fmt.Fprintf(os.Stderr, "  pool_min_conns:        %d\n", cfg.PoolMinConns)
 	fmt.Fprintf(os.Stderr, "  pool_max_conn_lifetime: %s\n", cfg.PoolMaxConnLifetime)
+	fmt.Fprintf(os.Stderr, "  pool_health_check_period: %s\n", cfg.PoolHealthCheckPeriod)
+	if cfg.ConnectSetupSQL != "" {
+		fmt.Fprintf(os.Stderr, "  connect_setup_sql: %s\n", cfg.ConnectSetupSQL)
+	}
 	if cfg.OTelEnabled {
 		fmt.Fprintf(os.Stderr, "  otel:          enabled\n")
+		if cfg.OTelCAFile != "" {
+			fmt.Fprintf(os.Stderr, "  otel_ca_file:  %s\n", cfg.OTelCAFile)
+		}
+		if cfg.OTelInsecure {
+			fmt.Fprintf(os.Stderr, "  otel_insecure: true\n")
+		}
+	}
+	switch {
+	case cfg.AuditSink == "stderr":
+		fmt.Fprintf(os.Stderr, "  audit_sink:    stderr\n")
+	case cfg.AuditLog != "":
+		fmt.Fprintf(os.Stderr, "  audit_log:     %s\n", cfg.AuditLog)
+	}
+}
+
+// resolvedConfig is the JSON form of config.Config written by --dump-config.
+// Durations are strings such as "30s"; connection strings are redacted with
+// redactDSN and the bearer token is never included, only whether one is set.
+type resolvedConfig struct {
+	DatabaseURL        string `json:"database_url"`
+	ReplicaDatabaseURL string `json:"replica_database_url,omitempty"`
+	DialerProxy        string `json:"dialer_proxy,omitempty"`
+	ReadOnly           bool   `json:"read_only"`
+	MaxRows            int    `json:"max_rows"`
+	MaxRowsCeiling     int    `json:"max_rows_ceiling"`
+	MaxResultMemory    int64  `json:"max_result_memory"`
+	QueryTimeout       string `json:"query_timeout"`
+	QueryTimeoutMin    string `json:"query_timeout_min"`
+	QueryTimeoutMax    string `json:"query_timeout_max"`
+
+	AnalyzeMaxCost        float64  `json:"analyze_max_cost"`
+	ExplainRedactLiterals bool     `json:"explain_redact_literals"`
+	LogPgErrorDetail      bool     `json:"log_pg_error_detail"`
+	AllowMultiStatement   bool     `json:"allow_multi_statement"`
+	FunctionCheck         bool     `json:"function_check"`
+	BlockedFunctions      []string `json:"blocked_functions"`
+	RollbackAlways        bool     `json:"rollback_always"`
+	LimitInjection        bool     `json:"limit_injection"`
+	StrictExplain         bool     `json:"strict_explain"`
+	PlanHints             bool     `json:"plan_hints"`
+	ToolCallTimeout       string   `json:"tool_call_timeout"`
+	MaxArgBytes           int      `json:"max_arg_bytes"`
+	ExplainOnly           bool     `json:"explain_only"`
+
+	ResultKeyCase    string `json:"result_key_case"`
+	DuplicateColumns string `json:"duplicate_columns"`
+	IncludeTiming    bool   `json:"include_timing"`
+	IncludeSQL       bool   `json:"include_executed_sql"`
+
+	Schemas        []string `json:"schemas"`
+	HiddenSchemas  []string `json:"hidden_schemas"`
+	EnforceSchemas bool     `json:"enforce_schemas"`
+	PolicyFile     string   `json:"policy_file,omitempty"`
+	PolicyDir      string   `json:"policy_dir,omitempty"`
+	ContextFile    string   `json:"context_file,omitempty"`
+	PolicyStrict   string   `json:"policy_strict"`
+
+	SchemaCacheTTL                string `json:"schema_cache_ttl"`
+	FKInferenceCacheTTL           string `json:"fk_inference_cache_ttl"`
+	FKInferenceScope              string `json:"fk_inference_scope"`
+	FKInferenceHighConfidenceOnly bool   `json:"fk_inference_high_confidence_only"`
+	FKInferenceMax                int    `json:"fk_inference_max"`
+	TableGrowthSnapshot           string `json:"table_growth_snapshot,omitempty"`
+	DescribeIncludeSamples        bool   `json:"describe_include_samples"`
+	DescribeIncludeIndexUsage     bool   `json:"describe_include_index_usage"`
+	DescribeMaxConcurrency        int    `json:"describe_max_concurrency"`
+	RowEstimateSampling           bool   `json:"row_estimate_sampling"`
+	ResolveAmbiguous              bool   `json:"resolve_ambiguous"`
+	ServerInfoTablespaces         bool   `json:"server_info_tablespaces"`
+
+	TableResources          bool `json:"table_resources"`
+	ResourcesFromPolicyOnly bool `json:"resources_from_policy_only"`
+
+	LogLevel string `json:"log_level"`
+
+	Transport          string `json:"transport"`
+	HTTPAddr           string `json:"http_addr"`
+	HTTPBearerTokenSet bool   `json:"http_bearer_token_set"`
+	PageTokenKeySet    bool   `json:"page_token_key_set"`
+	IdleTimeout        string `json:"idle_timeout"`
+
+	PoolMaxConns          int32  `json:"pool_max_conns"`
+	PoolMinConns          int32  `json:"pool_min_conns"`
+	PoolMaxConnLifetime   string `json:"pool_max_conn_lifetime"`
+	PoolHealthCheckPeriod string `json:"pool_health_check_period"`
+	ConnectSetupSQL       string `json:"connect_setup_sql,omitempty"`
+
+	OTelEnabled  bool   `json:"otel_enabled"`
+	OTelCAFile   string `json:"otel_ca_file,omitempty"`
+	OTelInsecure bool   `json:"otel_insecure"`
+	AuditSink    string `json:"audit_sink"`
+	AuditLog     string `json:"audit_log,omitempty"`
+}
+
+// newResolvedConfig builds the --dump-config view of cfg.
+func newResolvedConfig(cfg *config.Config) resolvedConfig {
+	rc := resolvedConfig{
+		DatabaseURL:                   redactDSN(cfg.DatabaseURL),
+		ReadOnly:                      cfg.ReadOnly,
+		MaxRows:                       cfg.MaxRows,
+		MaxRowsCeiling:                cfg.MaxRowsCeiling,
+		MaxResultMemory:               cfg.MaxResultMemory,
+		QueryTimeout:                  cfg.QueryTimeout.String(),
+		QueryTimeoutMin:               cfg.QueryTimeoutMin.String(),
+		QueryTimeoutMax:               cfg.QueryTimeoutMax.String(),
+		AnalyzeMaxCost:                cfg.AnalyzeMaxCost,
+		ExplainRedactLiterals:         cfg.ExplainRedactLiterals,
+		LogPgErrorDetail:              cfg.LogPgErrorDetail,
+		AllowMultiStatement:           cfg.AllowMultiStatement,
+		FunctionCheck:                 cfg.FunctionCheck,
+		BlockedFunctions:              cfg.BlockedFunctions,
+		RollbackAlways:                cfg.RollbackAlways,
+		LimitInjection:                cfg.LimitInjection,
+		StrictExplain:                 cfg.StrictExplain,
+		PlanHints:                     cfg.PlanHints,
+		ToolCallTimeout:               cfg.ToolCallTimeout.String(),
+		MaxArgBytes:                   cfg.MaxArgBytes,
+		ExplainOnly:                   cfg.ExplainOnly,
+		ResultKeyCase:                 cfg.ResultKeyCase,
+		DuplicateColumns:              cfg.DuplicateColumns,
+		IncludeTiming:                 cfg.IncludeTiming,
+		IncludeSQL:                    cfg.IncludeSQL,
+		Schemas:                       cfg.Schemas,
+		HiddenSchemas:                 cfg.HiddenSchemas,
+		EnforceSchemas:                cfg.EnforceSchemas,
+		PolicyFile:                    cfg.PolicyFile,
+		PolicyDir:                     cfg.PolicyDir,
+		ContextFile:                   cfg.ContextFile,
+		PolicyStrict:                  cfg.PolicyStrict,
+		SchemaCacheTTL:                cfg.SchemaCacheTTL.String(),
+		FKInferenceCacheTTL:           cfg.FKInferenceCacheTTL.String(),
+		FKInferenceScope:              cfg.FKInferenceScope,
+		FKInferenceHighConfidenceOnly: cfg.FKInferenceHighConfidenceOnly,
+		FKInferenceMax:                cfg.FKInferenceMax,
+		TableGrowthSnapshot:           cfg.TableGrowthSnapshot,
+		DescribeIncludeSamples:        cfg.DescribeIncludeSamples,
+		DescribeIncludeIndexUsage:     cfg.DescribeIncludeIndexUsage,
+		DescribeMaxConcurrency:        cfg.DescribeMaxConcurrency,
+		RowEstimateSampling:           cfg.RowEstimateSampling,
+		ResolveAmbiguous:              cfg.ResolveAmbiguous,
+		ServerInfoTablespaces:         cfg.ServerInfoTablespaces,
+		TableResources:                cfg.TableResources,
+		ResourcesFromPolicyOnly:       cfg.ResourcesFromPolicyOnly,
+		LogLevel:                      cfg.LogLevel.String(),
+		Transport:                     cfg.Transport,
+		IdleTimeout:                   cfg.IdleTimeout.String(),
+		HTTPAddr:                      cfg.HTTPAddr,
+		HTTPBearerTokenSet:            cfg.HTTPBearerToken != "",
+		PageTokenKeySet:               cfg.PageTokenKey != "",
+		PoolMaxConns:                  cfg.PoolMaxConns,
+		PoolMinConns:                  cfg.PoolMinConns,
+		PoolMaxConnLifetime:           cfg.PoolMaxConnLifetime.String(),
+		PoolHealthCheckPeriod:         cfg.PoolHealthCheckPeriod.String(),
+		ConnectSetupSQL:               cfg.ConnectSetupSQL,
+		OTelEnabled:                   cfg.OTelEnabled,
+		OTelCAFile:                    cfg.OTelCAFile,
+		OTelInsecure:                  cfg.OTelInsecure,
+		AuditSink:                     cfg.AuditSink,
+		AuditLog:                      cfg.AuditLog,
+	}
+	if rc.Schemas == nil {
+		rc.Schemas = []string{}
+	}
+	if rc.HiddenSchemas == nil {
+		rc.HiddenSchemas = []string{}
+	}
+	if rc.BlockedFunctions == nil {
+		rc.BlockedFunctions = []string{}
 	}
+	if cfg.ReplicaDatabaseURL != "" {
+		rc.ReplicaDatabaseURL = redactDSN(cfg.ReplicaDatabaseURL)
+	}
+	if cfg.DialerProxy != "" {
+		rc.DialerProxy = redactDSN(cfg.DialerProxy)
+	}
+	return rc
+}
+
+// dumpConfig writes the resolved configuration to w as indented JSON, with
+// secrets redacted. It does not connect to the database.
+func dumpConfig(w io.Writer, cfg *config.Config) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newResolvedConfig(cfg))
 }
 
 // redactDSN replaces the password in a PostgreSQL DSN with "***".
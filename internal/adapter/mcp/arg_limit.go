@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// WithMaxArgBytes rejects tool calls with a string argument longer than n
+// bytes, before the tool runs. Strings nested in array or object arguments
+// count too. Zero or negative disables the limit.
+func WithMaxArgBytes(n int) ToolOption {
+	return func(o *toolOptions) {
+		o.maxArgBytes = n
+	}
+}
+
+// withArgLimit wraps handler so calls with an oversized string argument get
+// an error result instead of reaching it.
+func withArgLimit(name string, n int, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if n <= 0 {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		for arg, v := range request.GetArguments() {
+			if size := longestString(v); size > n {
+				return mcp.NewToolResultError(fmt.Sprintf(
+					"%s: argument %q is %d bytes, over the %d-byte limit", name, arg, size, n)), nil
+			}
+		}
+		return handler(ctx, request)
+	}
+}
+
+// longestString returns the length of the longest string in v, a decoded
+// JSON value.
+func longestString(v any) int {
+	longest := 0
+	switch t := v.(type) {
+	case string:
+		longest = len(t)
+	case []any:
+		for _, e := range t {
+			longest = max(longest, longestString(e))
+		}
+	case map[string]any:
+		for k, e := range t {
+			longest = max(longest, len(k), longestString(e))
+		}
+	}
+	return longest
+}
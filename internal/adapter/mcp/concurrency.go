@@ -0,0 +1,48 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// WithDescribeConcurrency caps how many describe_table and lint_schema calls
+// run at once. A full describe runs a dozen catalog queries, sampling and FK
+// inference, several of them in parallel, so a burst of them can take every
+// pooled connection and starve query. Further calls wait for a free slot.
+// schema_graph, which describes many tables in one call, describes no more
+// than n of them at once. Zero or negative disables the limit.
+func WithDescribeConcurrency(n int) ToolOption {
+	return func(o *toolOptions) {
+		o.describeLimit = n
+	}
+}
+
+// newSemaphore returns a semaphore with n slots, or nil when n is not
+// positive.
+func newSemaphore(n int) chan struct{} {
+	if n <= 0 {
+		return nil
+	}
+	return make(chan struct{}, n)
+}
+
+// withConcurrencyLimit wraps handler so it holds a slot of sem while it runs.
+// A call that cannot get a slot before its context ends returns an error
+// without running. A nil sem leaves handler unchanged.
+func withConcurrencyLimit(name string, sem chan struct{}, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if sem == nil {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return mcp.NewToolResultError(fmt.Sprintf("%s: canceled while waiting for a free slot (at most %d such calls run at once)", name, cap(sem))), nil
+		}
+		defer func() { <-sem }()
+		return handler(ctx, request)
+	}
+}
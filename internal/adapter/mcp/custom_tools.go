@@ -0,0 +1,137 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/guillermoBallester/isthmus/internal/core/service"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// CustomTool is an operator-defined, parameterized read-only query exposed
+// as its own tool. Params type the $1, $2, ... placeholders of SQL, in
+// order, and are all required.
+type CustomTool struct {
+	Name        string
+	Description string
+	SQL         string
+	Params      []CustomToolParam
+}
+
+// CustomToolParam is one argument of a CustomTool.
+type CustomToolParam struct {
+	Name        string
+	Type        domain.ParamType
+	Description string
+}
+
+// WithCustomTools registers each tool as an MCP tool that runs its query
+// through the query service. Check them with ValidateCustomTools first.
+func WithCustomTools(tools []CustomTool) ToolOption {
+	return func(o *toolOptions) {
+		o.customTools = tools
+	}
+}
+
+// ValidateCustomTools reports custom tools whose names clash with a built-in
+// tool or with each other.
+func ValidateCustomTools(tools []CustomTool) error {
+	seen := make(map[string]bool, len(tools))
+	for _, t := range tools {
+		if slices.Contains(toolNames, t.Name) {
+			return fmt.Errorf("tools: %q is a built-in tool", t.Name)
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("tools: %q is defined twice", t.Name)
+		}
+		seen[t.Name] = true
+	}
+	return nil
+}
+
+// ValidateToolMasking reports masking rules for tools that are neither
+// built-in nor among custom.
+func ValidateToolMasking(masking map[string]domain.ToolMasking, custom []CustomTool) error {
+	for name := range masking {
+		if slices.Contains(toolNames, name) || slices.ContainsFunc(custom, func(t CustomTool) bool { return t.Name == name }) {
+			continue
+		}
+		return fmt.Errorf("tool_masking: unknown tool %q", name)
+	}
+	return nil
+}
+
+// newCustomTool builds the MCP tool definition for t, mapping each parameter
+// type to the closest JSON schema type.
+func newCustomTool(t CustomTool) mcp.Tool {
+	opts := []mcp.ToolOption{mcp.WithDescription(t.Description)}
+	for i, p := range t.Params {
+		desc := p.Description
+		if desc == "" {
+			desc = fmt.Sprintf("%s value for $%d", p.Type, i+1)
+		}
+		popts := []mcp.PropertyOption{mcp.Required(), mcp.Description(desc)}
+		switch p.Type {
+		case domain.ParamInteger, domain.ParamNumeric:
+			opts = append(opts, mcp.WithNumber(p.Name, popts...))
+		case domain.ParamBoolean:
+			opts = append(opts, mcp.WithBoolean(p.Name, popts...))
+		default:
+			opts = append(opts, mcp.WithString(p.Name, popts...))
+		}
+	}
+	return mcp.NewTool(t.Name, opts...)
+}
+
+// customToolHandler binds the call's arguments into t's query and runs it
+// like the query tool, so validation, row limits, masking and auditing all
+// apply. With includeTiming, the rows are wrapped together with the phase timings.
+func customToolHandler(t CustomTool, query *service.QueryService, logger *slog.Logger, includeTiming bool) server.ToolHandlerFunc {
+	types := make([]domain.ParamType, len(t.Params))
+	for i, p := range t.Params {
+		types[i] = p.Type
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.GetArguments()
+		values := make([]any, len(t.Params))
+		for i, p := range t.Params {
+			v, ok := args[p.Name]
+			if !ok || v == nil {
+				return mcp.NewToolResultError(fmt.Sprintf("%s is required", p.Name)), nil
+			}
+			values[i] = v
+		}
+
+		sql, err := domain.BindParams(t.SQL, types, values)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, t.Name)), nil
+		}
+
+		ctx = service.WithToolName(ctx, t.Name)
+		var phases *service.Timing
+		if includeTiming {
+			phases = &service.Timing{}
+			ctx = service.WithTiming(ctx, phases)
+		}
+		results, err := query.Execute(ctx, sql)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, t.Name)), nil
+		}
+
+		var payload any = rowsOrEmpty(results)
+		if includeTiming {
+			payload = queryRows{Rows: rowsOrEmpty(results), Timing: phases}
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, t.Name)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
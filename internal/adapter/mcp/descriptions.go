@@ -0,0 +1,41 @@
+package mcp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// toolNames lists every tool RegisterTools can register, including optional
+// ones, so description overrides can be checked against it.
+var toolNames = []string{"server_info", "discover", "describe_table", "query", "ping", "filter_selectivity", "table_growth", "recent_activity", "generate_select", "lint_schema", "schema_graph"}
+
+// WithToolDescriptions replaces the built-in description of each named tool.
+// Keys must be tool names; check them with ValidateToolDescriptions first.
+func WithToolDescriptions(descriptions map[string]string) ToolOption {
+	return func(o *toolOptions) {
+		o.descriptions = descriptions
+	}
+}
+
+// ValidateToolDescriptions reports overrides that target unknown tools or
+// are empty.
+func ValidateToolDescriptions(descriptions map[string]string) error {
+	for name, desc := range descriptions {
+		if !slices.Contains(toolNames, name) {
+			return fmt.Errorf("tool_descriptions: unknown tool %q (known: %s)", name, strings.Join(toolNames, ", "))
+		}
+		if strings.TrimSpace(desc) == "" {
+			return fmt.Errorf("tool_descriptions[%q] is empty", name)
+		}
+	}
+	return nil
+}
+
+// description returns the operator's override for tool, or def.
+func (o *toolOptions) description(tool, def string) string {
+	if d, ok := o.descriptions[tool]; ok {
+		return d
+	}
+	return def
+}
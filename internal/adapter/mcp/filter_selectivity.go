@@ -0,0 +1,107 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/guillermoBallester/isthmus/internal/core/service"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const descFilterSelectivity = "Estimate how many rows of a table match a WHERE predicate, without running the query. " +
+	"Uses the planner's row estimate from EXPLAIN, so it is cheap even on large tables but only as accurate as the table statistics. " +
+	"Use this to gauge how selective a filter is before writing the real query."
+
+const descWhereParam = "Boolean filter expression, as it would appear after WHERE (e.g. \"status = 'active' AND price > 10\")"
+
+// filterSelectivityResponse reports planner estimates for a filtered and an
+// unfiltered scan of the same table.
+type filterSelectivityResponse struct {
+	Table              string  `json:"table"`
+	Where              string  `json:"where"`
+	EstimatedRows      int64   `json:"estimated_rows"`
+	EstimatedTotalRows int64   `json:"estimated_total_rows"`
+	Selectivity        float64 `json:"selectivity"` // estimated_rows / estimated_total_rows
+}
+
+func filterSelectivityHandler(query *service.QueryService, logger *slog.Logger) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tableName, ok := request.GetArguments()["table_name"].(string)
+		if !ok || tableName == "" {
+			return mcp.NewToolResultError("table_name is required"), nil
+		}
+		where, ok := request.GetArguments()["where"].(string)
+		if !ok || where == "" {
+			return mcp.NewToolResultError("where is required"), nil
+		}
+		if err := domain.ValidatePredicate(where); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid where: %v", err)), nil
+		}
+
+		schema, _ := request.GetArguments()["schema"].(string)
+		tableName, schema, err := resolveTableArgs(tableName, schema)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		table := domain.QualifiedName(schema, tableName)
+
+		ctx = service.WithToolName(ctx, "filter_selectivity")
+		filteredPlan, err := explainPlan(ctx, query, fmt.Sprintf("SELECT 1 FROM %s WHERE %s", table, where))
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "filter selectivity")), nil
+		}
+		totalPlan, err := explainPlan(ctx, query, fmt.Sprintf("SELECT 1 FROM %s", table))
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "filter selectivity")), nil
+		}
+		filtered, total := filteredPlan.PlanRows, totalPlan.PlanRows
+
+		resp := filterSelectivityResponse{
+			Table:              table,
+			Where:              where,
+			EstimatedRows:      int64(filtered),
+			EstimatedTotalRows: int64(total),
+		}
+		if total > 0 {
+			resp.Selectivity = min(filtered/total, 1)
+		}
+
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "filter selectivity")), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+// explainPlan runs EXPLAIN (FORMAT JSON) for sql through the query service,
+// so validation, read-only transactions and auditing all apply, and returns
+// the planner's estimates for the root node.
+func explainPlan(ctx context.Context, query *service.QueryService, sql string) (*domain.PlanSummary, error) {
+	rows, err := query.Execute(ctx, domain.PrefixLastStatement(sql, "EXPLAIN (FORMAT JSON) "))
+	if err != nil {
+		return nil, err
+	}
+	plan, err := planValue(rows)
+	if err != nil {
+		return nil, err
+	}
+	return domain.ParseExplainJSON(plan)
+}
+
+// planValue returns the plan from the rows of an EXPLAIN (FORMAT JSON)
+// statement: a single row with a single column.
+func planValue(rows []map[string]any) (any, error) {
+	if len(rows) != 1 || len(rows[0]) != 1 {
+		return nil, domain.ErrBadPlan
+	}
+	// The single column is "QUERY PLAN", possibly renamed by RESULT_KEY_CASE.
+	for _, v := range rows[0] {
+		return v, nil
+	}
+	return nil, domain.ErrBadPlan
+}
@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const descGenerateSelect = "Build a ready-to-run SELECT for a table from its real column list, without executing it. " +
+	"Identifiers are quoted, so names with upper case, spaces or reserved words are handled. " +
+	"Pass the returned sql to the query tool, adding WHERE, ORDER BY or joins as needed."
+
+// defaultGenerateSelectLimit is the LIMIT used when the caller sets none.
+const defaultGenerateSelectLimit = 10
+
+// generateSelectResponse is a starter query for a single table.
+type generateSelectResponse struct {
+	SQL     string   `json:"sql"`
+	Columns []string `json:"columns"`
+}
+
+func generateSelectHandler(explorer port.SchemaExplorer, logger *slog.Logger) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tableName, ok := request.GetArguments()["table_name"].(string)
+		if !ok || tableName == "" {
+			return mcp.NewToolResultError("table_name is required"), nil
+		}
+		schema, _ := request.GetArguments()["schema"].(string)
+		tableName, schema, err := resolveTableArgs(tableName, schema)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		var wanted []string
+		if v, ok := request.GetArguments()["columns"]; ok {
+			items, ok := v.([]any)
+			if !ok {
+				return mcp.NewToolResultError("columns must be an array of column names"), nil
+			}
+			for _, item := range items {
+				name, ok := item.(string)
+				if !ok || name == "" {
+					return mcp.NewToolResultError("columns must be an array of column names"), nil
+				}
+				wanted = append(wanted, name)
+			}
+		}
+
+		limit := defaultGenerateSelectLimit
+		if v, ok := request.GetArguments()["limit"]; ok {
+			n, ok := v.(float64)
+			if !ok || n < 1 || n != float64(int(n)) {
+				return mcp.NewToolResultError("limit must be a positive integer"), nil
+			}
+			limit = int(n)
+		}
+
+		// Only the column list is needed.
+		ctx = port.WithDetailLevel(ctx, port.DetailBasic)
+		detail, err := explorer.DescribeTable(ctx, schema, tableName)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "generate select")), nil
+		}
+
+		columns, err := selectList(detail.Columns, wanted)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if len(columns) == 0 {
+			// Never fall back to SELECT *, which would include hidden columns.
+			return mcp.NewToolResultError("table has no columns to select"), nil
+		}
+
+		data, err := json.Marshal(generateSelectResponse{
+			SQL:     buildSelect(detail.Schema, detail.Name, columns, limit),
+			Columns: columns,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "generate select")), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+// selectList returns the wanted columns in the order given, or every column
+// in table order when wanted is empty. Names must exist in the table.
+func selectList(columns []port.ColumnInfo, wanted []string) ([]string, error) {
+	if len(wanted) == 0 {
+		names := make([]string, len(columns))
+		for i, col := range columns {
+			names[i] = col.Name
+		}
+		return names, nil
+	}
+
+	known := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		known[col.Name] = true
+	}
+	var unknown []string
+	for _, name := range wanted {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown columns: %s (use describe_table to list them)", strings.Join(unknown, ", "))
+	}
+	return wanted, nil
+}
+
+// buildSelect renders SELECT <columns> FROM <schema>.<table> LIMIT <limit>
+// with every identifier quoted.
+func buildSelect(schema, table string, columns []string, limit int) string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = domain.QuoteIdent(col)
+	}
+	return fmt.Sprintf("SELECT %s FROM %s LIMIT %d", strings.Join(quoted, ", "), domain.QualifiedName(schema, table), limit)
+}
@@ -0,0 +1,98 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// IdleWatchdog ends the server once no tool call has arrived for a while,
+// so a stdio server embedded in a long-lived agent releases its database
+// pool. A call that is still running keeps the server busy, however long it
+// takes.
+type IdleWatchdog struct {
+	timeout time.Duration
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	last     time.Time // when the last tool call started or ended
+	inFlight map[any]bool
+}
+
+// NewIdleWatchdog returns a watchdog that fires after timeout without tool
+// calls, counted from now. Register its hooks with WithIdleWatchdog and
+// start it with Run.
+func NewIdleWatchdog(timeout time.Duration, logger *slog.Logger) *IdleWatchdog {
+	return &IdleWatchdog{
+		timeout:  timeout,
+		logger:   logger,
+		last:     time.Now(),
+		inFlight: make(map[any]bool),
+	}
+}
+
+// WithIdleWatchdog records tool call activity for w.
+func WithIdleWatchdog(w *IdleWatchdog) ToolOption {
+	return func(o *toolOptions) {
+		o.idle = w
+	}
+}
+
+// addHooks tracks the start and end of each tool call.
+func (w *IdleWatchdog) addHooks(hooks *server.Hooks) {
+	hooks.AddBeforeCallTool(func(_ context.Context, id any, _ *mcp.CallToolRequest) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.inFlight[id] = true
+		w.last = time.Now()
+	})
+	done := func(id any) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.inFlight[id] {
+			delete(w.inFlight, id)
+			w.last = time.Now()
+		}
+	}
+	hooks.AddAfterCallTool(func(_ context.Context, id any, _ *mcp.CallToolRequest, _ any) { done(id) })
+	hooks.AddOnError(func(_ context.Context, id any, _ mcp.MCPMethod, _ any, _ error) { done(id) })
+}
+
+// idleFor reports how long the server has gone without tool calls, or
+// busy=true while one is running.
+func (w *IdleWatchdog) idleFor() (idle time.Duration, busy bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return time.Since(w.last), len(w.inFlight) > 0
+}
+
+// Run calls shutdown once the server has been idle for the timeout, then
+// returns. It also returns when ctx is done.
+func (w *IdleWatchdog) Run(ctx context.Context, shutdown func()) {
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		idle, busy := w.idleFor()
+		switch {
+		case busy:
+			timer.Reset(w.timeout)
+		case idle < w.timeout:
+			timer.Reset(w.timeout - idle)
+		default:
+			w.logger.Info("no tool calls within idle timeout, shutting down",
+				slog.Duration("idle_timeout", w.timeout))
+			shutdown()
+			return
+		}
+	}
+}
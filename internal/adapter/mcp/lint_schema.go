@@ -0,0 +1,165 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const descLintSchema = "Check the tables in the configured schemas for common design issues: tables without a primary key, " +
+	"foreign keys without a supporting index (declared or inferred from column names), nullable foreign key columns, " +
+	"text columns with no length or CHECK constraint, and stale or missing planner statistics. " +
+	"Each finding names the table and column, the check, a severity (warning or info) and a short explanation. " +
+	"Warnings come first. This describes every table, so it is slower than discover on large schemas."
+
+// Lint severities, most important first.
+const (
+	lintWarning = "warning"
+	lintInfo    = "info"
+)
+
+// lintSchemaResponse lists the findings over every checked table.
+type lintSchemaResponse struct {
+	TablesChecked int           `json:"tables_checked"`
+	Findings      []lintFinding `json:"findings"`
+}
+
+type lintFinding struct {
+	Schema   string `json:"schema"`
+	Table    string `json:"table"`
+	Column   string `json:"column,omitempty"`
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func lintSchemaHandler(explorer port.SchemaExplorer, logger *slog.Logger) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		schema, _ := request.GetArguments()["schema"].(string)
+
+		tables, err := explorer.ListTables(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "lint schema")), nil
+		}
+
+		// Stats age is only reported at the full detail level.
+		ctx = port.WithDetailLevel(ctx, port.DetailFull)
+		resp := lintSchemaResponse{Findings: []lintFinding{}}
+		for _, t := range tables {
+			if t.Type != "table" || (schema != "" && t.Schema != schema) {
+				continue
+			}
+			detail, err := explorer.DescribeTable(ctx, t.Schema, t.Name)
+			if err != nil {
+				return mcp.NewToolResultError(sanitizeError(logger, err, "lint schema")), nil
+			}
+			resp.TablesChecked++
+			resp.Findings = append(resp.Findings, lintTable(detail)...)
+		}
+
+		slices.SortStableFunc(resp.Findings, func(a, b lintFinding) int {
+			return cmp.Compare(lintSeverityRank(a.Severity), lintSeverityRank(b.Severity))
+		})
+
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "lint schema")), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+func lintSeverityRank(severity string) int {
+	if severity == lintWarning {
+		return 0
+	}
+	return 1
+}
+
+// lintTable runs every check against one table.
+func lintTable(d *port.TableDetail) []lintFinding {
+	var findings []lintFinding
+	add := func(column, check, severity, message string) {
+		findings = append(findings, lintFinding{
+			Schema: d.Schema, Table: d.Name, Column: column,
+			Check: check, Severity: severity, Message: message,
+		})
+	}
+
+	if !slices.ContainsFunc(d.Columns, func(c port.ColumnInfo) bool { return c.IsPrimaryKey }) {
+		add("", "no_primary_key", lintWarning,
+			"Table has no primary key; rows cannot be identified reliably and logical replication cannot replicate updates or deletes.")
+	}
+
+	indexColumns := make([][]string, 0, len(d.Indexes))
+	for _, idx := range d.Indexes {
+		indexColumns = append(indexColumns, idx.Columns)
+	}
+
+	fkColumns := make(map[string][]string)
+	for _, fk := range d.ForeignKeys {
+		fkColumns[fk.ConstraintName] = append(fkColumns[fk.ConstraintName], fk.ColumnName)
+	}
+	for _, name := range domain.UncoveredForeignKeys(fkColumns, indexColumns) {
+		cols := strings.Join(fkColumns[name], ", ")
+		add(cols, "missing_fk_index", lintWarning, fmt.Sprintf(
+			"Foreign key %q on (%s) has no supporting index; joins and deletes on the referenced table will scan this table.",
+			name, cols))
+	}
+
+	inferred := make(map[string][]string, len(d.InferredFKs))
+	references := make(map[string]string, len(d.InferredFKs))
+	for _, fk := range d.InferredFKs {
+		inferred[fk.ColumnName] = []string{fk.ColumnName}
+		references[fk.ColumnName] = fk.ReferencedTable
+	}
+	for _, col := range domain.UncoveredForeignKeys(inferred, indexColumns) {
+		add(col, "missing_fk_index", lintInfo, fmt.Sprintf(
+			"Column looks like a reference to %s but has no index; joins on it will scan this table. It has no FOREIGN KEY constraint either.",
+			references[col]))
+	}
+
+	nullable := make(map[string]bool, len(d.Columns))
+	checked := make(map[string]bool)
+	for _, c := range d.Columns {
+		nullable[c.Name] = c.IsNullable
+	}
+	for _, cc := range d.CheckConstraints {
+		for _, col := range cc.Columns {
+			checked[col] = true
+		}
+	}
+
+	reported := make(map[string]bool, len(d.ForeignKeys))
+	for _, fk := range d.ForeignKeys {
+		if !nullable[fk.ColumnName] || reported[fk.ColumnName] {
+			continue
+		}
+		reported[fk.ColumnName] = true
+		add(fk.ColumnName, "nullable_foreign_key", lintInfo, fmt.Sprintf(
+			"Foreign key column referencing %s is nullable; make it NOT NULL unless the relationship is optional.",
+			fk.ReferencedTable))
+	}
+
+	for _, c := range d.Columns {
+		if c.DataType != "text" || checked[c.Name] || len(c.AllowedValues) > 0 {
+			continue
+		}
+		add(c.Name, "unbounded_text", lintInfo,
+			"Text column has no length or CHECK constraint; consider varchar(n) or a CHECK if its values are meant to be short.")
+	}
+
+	if d.StatsAgeWarning != "" {
+		add("", "stale_statistics", lintWarning, d.StatsAgeWarning)
+	}
+	return findings
+}
@@ -0,0 +1,80 @@
+package mcp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var errInvalidPageToken = errors.New("page_token is invalid or was not issued by this server")
+
+// pageCursor is what a page token carries: the query being paged, the
+// offset of the next page and the per-call limit, 0 for the server default.
+type pageCursor struct {
+	SQL    string `json:"sql"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// pageTokens encodes and checks query page tokens. A token is the base64
+// JSON cursor and its HMAC-SHA256, so a client can hand it back but not
+// change the query or offset inside it.
+type pageTokens struct {
+	key []byte
+}
+
+// WithPageTokenKey signs query page tokens with key, a secret of their own,
+// so they stay valid across restarts and between servers sharing it.
+// Without it, or with an empty key, a random key is drawn at startup.
+func WithPageTokenKey(key []byte) ToolOption {
+	return func(o *toolOptions) {
+		o.pageKey = key
+	}
+}
+
+func newPageTokens(key []byte) *pageTokens {
+	if len(key) == 0 {
+		key = make([]byte, sha256.Size)
+		_, _ = rand.Read(key)
+	}
+	return &pageTokens{key: key}
+}
+
+func (p *pageTokens) encode(c pageCursor) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(p.sign(payload)), nil
+}
+
+func (p *pageTokens) decode(token string) (pageCursor, error) {
+	var c pageCursor
+	encPayload, encMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return c, errInvalidPageToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return c, errInvalidPageToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encMAC)
+	if err != nil || !hmac.Equal(mac, p.sign(payload)) {
+		return c, errInvalidPageToken
+	}
+	if err := json.Unmarshal(payload, &c); err != nil || c.SQL == "" || c.Offset < 0 || c.Limit < 0 {
+		return c, errInvalidPageToken
+	}
+	return c, nil
+}
+
+func (p *pageTokens) sign(payload []byte) []byte {
+	h := hmac.New(sha256.New, p.key)
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,41 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/guillermoBallester/isthmus/internal/core/service"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const descPing = "Check that the database is reachable and measure the round-trip latency of a trivial query (SELECT 1). " +
+	"It goes through the same connection pool, read-only transaction and query timeout as query, " +
+	"so use it to tell a slow database or network apart from a slow query."
+
+// pingSQL is the statement ping times.
+const pingSQL = "SELECT 1"
+
+// pingResponse reports the round-trip time of pingSQL.
+type pingResponse struct {
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+func pingHandler(query *service.QueryService, logger *slog.Logger) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx = service.WithToolName(ctx, "ping")
+		start := time.Now()
+		if _, err := query.Execute(ctx, pingSQL); err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "ping")), nil
+		}
+		latency := time.Since(start)
+
+		data, err := json.Marshal(pingResponse{LatencyMS: float64(latency.Microseconds()) / 1000})
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "ping")), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const descRecentActivity = "Report which tables are changing: the number of rows inserted, updated and deleted per table, " +
+	"from PostgreSQL's cumulative statistics (pg_stat_user_tables). Busiest tables come first. " +
+	"Counters accumulate since the statistics were last reset (see stats_reset), not since a fixed time, " +
+	"so compare two calls to see what changed in between. Use this to find hot tables cheaply, without triggers or logical decoding."
+
+// recentActivityResponse lists per-table change counters.
+type recentActivityResponse struct {
+	Tables []port.TableActivity `json:"tables"`
+}
+
+func recentActivityHandler(explorer port.SchemaExplorer, logger *slog.Logger) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		schema, _ := request.GetArguments()["schema"].(string)
+
+		activity, err := explorer.TableActivity(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "recent activity")), nil
+		}
+
+		resp := recentActivityResponse{Tables: []port.TableActivity{}}
+		for _, a := range activity {
+			if schema != "" && a.Schema != schema {
+				continue
+			}
+			resp.Tables = append(resp.Tables, a)
+		}
+
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "recent activity")), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
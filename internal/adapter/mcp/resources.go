@@ -0,0 +1,67 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// tableResourcePrefix is the URI prefix of table resources:
+// isthmus://tables/{schema}/{table}.
+const tableResourcePrefix = "isthmus://tables/"
+
+// WithTableResources exposes each of tables as a browsable MCP resource whose
+// contents are the table's structure, as returned by describe_table with
+// detail_level=basic.
+func WithTableResources(tables []port.TableInfo) ToolOption {
+	return func(o *toolOptions) {
+		o.tableResources = tables
+	}
+}
+
+func tableResourceURI(schema, table string) string {
+	return tableResourcePrefix + url.PathEscape(schema) + "/" + url.PathEscape(table)
+}
+
+func registerTableResources(s *server.MCPServer, explorer port.SchemaExplorer, tables []port.TableInfo, logger *slog.Logger) {
+	for _, t := range tables {
+		uri := tableResourceURI(t.Schema, t.Name)
+		opts := []mcp.ResourceOption{mcp.WithMIMEType("application/json")}
+		if t.Comment != "" {
+			opts = append(opts, mcp.WithResourceDescription(t.Comment))
+		}
+		s.AddResource(
+			mcp.NewResource(uri, t.Schema+"."+t.Name, opts...),
+			tableResourceHandler(explorer, t.Schema, t.Name, logger),
+		)
+	}
+}
+
+func tableResourceHandler(explorer port.SchemaExplorer, schema, table string, logger *slog.Logger) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		ctx = port.WithDetailLevel(ctx, port.DetailBasic)
+		detail, err := explorer.DescribeTable(ctx, schema, table)
+		if err != nil {
+			return nil, errors.New(sanitizeError(logger, err, "read resource"))
+		}
+
+		data, err := json.Marshal(detail)
+		if err != nil {
+			return nil, errors.New(sanitizeError(logger, err, "read resource"))
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+				Text:     string(data),
+			},
+		}, nil
+	}
+}
@@ -0,0 +1,237 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"golang.org/x/sync/errgroup"
+)
+
+const descSchemaGraph = "Draw an entity-relationship diagram of the tables in the configured schemas, in Graphviz DOT format. " +
+	"Each table is a node, grouped by schema, and each foreign key an edge from the referencing table to the referenced one, labelled with its columns. " +
+	"Set include_columns to list each table's columns and types, and include_inferred to add likely relationships inferred from column names as dashed edges. " +
+	"Render the result with Graphviz or any DOT viewer. Views are left out."
+
+// graphTable is a table in the schema graph with what its node and edges
+// need.
+type graphTable struct {
+	schema, name string
+	detail       *port.TableDetail
+}
+
+// maxGraphDescribes bounds the tables schema_graph describes at once, each
+// describe holding pool connections for its own catalog queries.
+const maxGraphDescribes = 4
+
+// schemaGraphHandler draws the graph from one catalog query for the foreign
+// keys of every table. Columns and inferred relationships come from a basic
+// describe of each drawn table, at most describeLimit (or
+// maxGraphDescribes, whichever is lower) at once.
+func schemaGraphHandler(explorer port.SchemaExplorer, logger *slog.Logger, describeLimit int) server.ToolHandlerFunc {
+	limit := maxGraphDescribes
+	if describeLimit > 0 && describeLimit < limit {
+		limit = describeLimit
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.GetArguments()
+		schema, _ := args["schema"].(string)
+		columns, _ := args["include_columns"].(bool)
+		inferred, _ := args["include_inferred"].(bool)
+
+		tables, err := explorer.ListTables(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "schema graph")), nil
+		}
+
+		var graph []graphTable
+		known := make(map[string]bool, len(tables))
+		for _, t := range tables {
+			if t.Type != "table" {
+				continue
+			}
+			known[t.Schema+"."+t.Name] = true
+			if schema != "" && t.Schema != schema {
+				continue
+			}
+			graph = append(graph, graphTable{schema: t.Schema, name: t.Name})
+		}
+
+		if columns || inferred {
+			err = describeGraphTables(ctx, explorer, graph, limit)
+		} else {
+			err = graphForeignKeys(ctx, explorer, graph)
+		}
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "schema graph")), nil
+		}
+
+		return mcp.NewToolResultText(schemaGraphDOT(graph, known, columns, inferred)), nil
+	}
+}
+
+// graphForeignKeys fills in the foreign keys of each table, which is all the
+// graph needs without columns or inferred relationships.
+func graphForeignKeys(ctx context.Context, explorer port.SchemaExplorer, graph []graphTable) error {
+	fks, err := explorer.ForeignKeys(ctx)
+	if err != nil {
+		return err
+	}
+	for i, t := range graph {
+		graph[i].detail = &port.TableDetail{Schema: t.schema, Name: t.name, ForeignKeys: fks[t.schema+"."+t.name]}
+	}
+	return nil
+}
+
+// describeGraphTables describes each table at the basic detail level, which
+// has its keys, columns and foreign keys, at most limit at once.
+func describeGraphTables(ctx context.Context, explorer port.SchemaExplorer, graph []graphTable, limit int) error {
+	g, gctx := errgroup.WithContext(port.WithDetailLevel(ctx, port.DetailBasic))
+	g.SetLimit(limit)
+	for i, t := range graph {
+		g.Go(func() error {
+			detail, err := explorer.DescribeTable(gctx, t.schema, t.name)
+			if err != nil {
+				return err
+			}
+			graph[i].detail = detail // each goroutine writes its own element
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// schemaGraphDOT renders tables as a DOT digraph, one cluster per schema.
+// known holds every table in the configured schemas, as "schema.table", to
+// resolve the tables foreign keys point to.
+func schemaGraphDOT(tables []graphTable, known map[string]bool, columns, inferred bool) string {
+	var b strings.Builder
+	b.WriteString("digraph schema {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, fontname=\"Helvetica\"];\n")
+	b.WriteString("\tedge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	bySchema := make(map[string][]graphTable)
+	var schemas []string
+	for _, t := range tables {
+		if _, ok := bySchema[t.schema]; !ok {
+			schemas = append(schemas, t.schema)
+		}
+		bySchema[t.schema] = append(bySchema[t.schema], t)
+	}
+	slices.Sort(schemas)
+
+	for _, s := range schemas {
+		fmt.Fprintf(&b, "\n\tsubgraph %s {\n", dotID("cluster_"+s))
+		fmt.Fprintf(&b, "\t\tlabel=%s;\n", dotID(s))
+		for _, t := range bySchema[s] {
+			fmt.Fprintf(&b, "\t\t%s [label=%s];\n", dotID(t.schema+"."+t.name), dotID(nodeLabel(t, columns)))
+		}
+		b.WriteString("\t}\n")
+	}
+
+	b.WriteString("\n")
+	for _, t := range tables {
+		from := t.schema + "." + t.name
+		for _, fk := range groupForeignKeys(t.detail.ForeignKeys) {
+			fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n",
+				dotID(from), dotID(referencedNode(t.schema, fk.table, known)), dotID(fk.label()))
+		}
+		if !inferred {
+			continue
+		}
+		for _, fk := range t.detail.InferredFKs {
+			fmt.Fprintf(&b, "\t%s -> %s [label=%s, style=dashed];\n",
+				dotID(from), dotID(referencedNode(t.schema, fk.ReferencedTable, known)),
+				dotID(fk.ColumnName+" -> "+fk.ReferencedColumn+" (inferred)"))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// nodeLabel is the table name, followed by one left-aligned line per column
+// when columns is set.
+func nodeLabel(t graphTable, columns bool) string {
+	if !columns {
+		return t.name
+	}
+	var b strings.Builder
+	b.WriteString(t.name + "\n")
+	for _, c := range t.detail.Columns {
+		line := c.Name + " " + c.DataType
+		if c.IsPrimaryKey {
+			line += " PK"
+		}
+		b.WriteString(line + "\\l")
+	}
+	return b.String()
+}
+
+// graphFK is one foreign key constraint, with its columns in order.
+type graphFK struct {
+	table            string
+	columns, targets []string
+}
+
+func (fk graphFK) label() string {
+	return strings.Join(fk.columns, ", ") + " -> " + strings.Join(fk.targets, ", ")
+}
+
+// groupForeignKeys merges the per-column rows of multi-column foreign keys
+// into one entry per constraint, in order of first appearance.
+func groupForeignKeys(fks []port.ForeignKey) []graphFK {
+	var grouped []graphFK
+	index := make(map[string]int, len(fks))
+	for _, fk := range fks {
+		i, ok := index[fk.ConstraintName]
+		if !ok {
+			i = len(grouped)
+			index[fk.ConstraintName] = i
+			grouped = append(grouped, graphFK{table: fk.ReferencedTable})
+		}
+		grouped[i].columns = append(grouped[i].columns, fk.ColumnName)
+		grouped[i].targets = append(grouped[i].targets, fk.ReferencedColumn)
+	}
+	return grouped
+}
+
+// referencedNode resolves a referenced table to its node: as given when
+// schema-qualified, else in the referencing table's schema, else in the one
+// other schema that has a table of that name. Unresolved tables keep the
+// referencing schema. Tables that are not drawn, such as those outside the
+// schema argument, appear as plain nodes outside any cluster.
+func referencedNode(schema, table string, known map[string]bool) string {
+	if strings.Contains(table, ".") {
+		return table
+	}
+	if known[schema+"."+table] {
+		return schema + "." + table
+	}
+	var match string
+	for node := range known {
+		if strings.HasSuffix(node, "."+table) {
+			if match != "" {
+				return schema + "." + table
+			}
+			match = node
+		}
+	}
+	if match != "" {
+		return match
+	}
+	return schema + "." + table
+}
+
+// dotID quotes s as a DOT identifier. Newlines become \n; a \l written by
+// the caller is kept as a left-justified line break.
+func dotID(s string) string {
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
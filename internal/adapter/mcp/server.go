@@ -10,14 +10,23 @@ import (
 )
 
 // NewServer creates an MCPServer with tools and logging hooks.
-func NewServer(version string, explorer port.SchemaExplorer, query *service.QueryService, logger *slog.Logger, tracer trace.Tracer, inst port.Instrumentation) *server.MCPServer {
+func NewServer(version string, explorer port.SchemaExplorer, query *service.QueryService, logger *slog.Logger, tracer trace.Tracer, inst port.Instrumentation, opts ...ToolOption) *server.MCPServer {
+	hooks := ToolCallHooks(logger, tracer, inst)
+	var o toolOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.idle != nil {
+		o.idle.addHooks(hooks)
+	}
+
 	s := server.NewMCPServer(
 		serverName,
 		version,
-		server.WithHooks(ToolCallHooks(logger, tracer, inst)),
+		server.WithHooks(hooks),
 	)
 
-	RegisterTools(s, explorer, query, logger)
+	RegisterTools(s, explorer, query, logger, opts...)
 
 	return s
 }
@@ -0,0 +1,166 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const descServerInfo = "Return information about this isthmus server: version, uptime, transport, " +
+	"whether queries run read-only or as EXPLAIN only, the default row limit and its per-call ceiling, the query timeout and its per-call bounds, " +
+	"whether query accepts batches of SELECT and SET LOCAL statements, " +
+	"which schemas are exposed, whether a policy file and column masking are active, " +
+	"whether the database is a primary or a standby replica (with its replication lag), " +
+	"and, when enabled, the tablespaces with their locations and sizes. " +
+	"Call this first to learn the operating constraints (e.g. that writes are disabled) before querying."
+
+// ServerInfo describes the running server. It is built from the resolved
+// config at startup; only the replication status and tablespaces are read per
+// call.
+type ServerInfo struct {
+	Version         string    `json:"version"`
+	StartedAt       time.Time `json:"started_at"`
+	Transport       string    `json:"transport"`
+	ReadOnly        bool      `json:"read_only"`
+	ExplainOnly     bool      `json:"explain_only"`
+	MaxRows         int       `json:"max_rows"`
+	MaxRowsCeiling  int       `json:"max_rows_ceiling"`
+	QueryTimeout    string    `json:"query_timeout"`
+	QueryTimeoutMin string    `json:"query_timeout_min"`
+	QueryTimeoutMax string    `json:"query_timeout_max"`
+	AnalyzeMaxCost  float64   `json:"analyze_max_cost,omitempty"` // 0 means analyze is never refused
+	MultiStatement  bool      `json:"multi_statement"`            // query accepts SELECT/SET LOCAL batches
+	Schemas         []string  `json:"schemas,omitempty"`          // empty means all non-system schemas
+	PolicyActive    bool      `json:"policy_active"`
+	MaskingActive   bool      `json:"masking_active"`
+}
+
+// serverInfoResponse adds uptime, replication status and tablespaces,
+// computed per call, to ServerInfo.
+type serverInfoResponse struct {
+	ServerInfo
+	UptimeSeconds int64                   `json:"uptime_seconds"`
+	Uptime        string                  `json:"uptime"`
+	Replication   *port.ReplicationStatus `json:"replication,omitempty"`
+	Tablespaces   []port.Tablespace       `json:"tablespaces,omitempty"`
+}
+
+// ToolOption configures optional tools registered by RegisterTools.
+type ToolOption func(*toolOptions)
+
+type toolOptions struct {
+	serverInfo     *ServerInfo
+	replication    port.ReplicationReporter
+	tablespaces    port.TablespaceLister
+	tableResources []port.TableInfo
+	analyzeMaxCost float64
+	replica        bool // a read replica serves query calls with prefer_replica
+	growthStore    port.SnapshotStore
+	descriptions   map[string]string // tool name -> description override
+	callTimeout    time.Duration     // bound on a whole tool invocation; 0 disables it
+	maxArgBytes    int               // longest string argument a tool call may carry; 0 is unlimited
+	customTools    []CustomTool      // operator-defined query tools
+	describeLimit  int               // most describe_table and lint_schema calls, and schema_graph describes, at once; 0 is unlimited
+	idle           *IdleWatchdog     // records tool call activity; nil when there is no idle timeout
+	pageKey        []byte            // signs query page tokens; random when empty
+	includeTiming  bool              // add a timing block to query and custom tool responses
+	echoSQL        bool              // add the SQL the executor ran to query responses
+}
+
+// WithServerInfo registers the server_info tool backed by info.
+func WithServerInfo(info ServerInfo) ToolOption {
+	return func(o *toolOptions) {
+		o.serverInfo = &info
+	}
+}
+
+// WithReplicationStatus adds the database's primary/standby status to
+// server_info, read from reporter on every call.
+func WithReplicationStatus(reporter port.ReplicationReporter) ToolOption {
+	return func(o *toolOptions) {
+		o.replication = reporter
+	}
+}
+
+// WithTablespaces adds the server's tablespaces, with their locations and
+// sizes, to server_info, read from lister on every call.
+func WithTablespaces(lister port.TablespaceLister) ToolOption {
+	return func(o *toolOptions) {
+		o.tablespaces = lister
+	}
+}
+
+// WithAnalyzeMaxCost makes the query tool refuse analyze=true when the
+// planner's estimated total cost exceeds maxCost, returning the estimated
+// plan instead. Zero or negative disables the guard.
+func WithAnalyzeMaxCost(maxCost float64) ToolOption {
+	return func(o *toolOptions) {
+		o.analyzeMaxCost = maxCost
+	}
+}
+
+// WithReplica tells the query tool that a read replica is configured, so it
+// honors prefer_replica by routing the call there (see
+// port.WithPreferReplica). Without it, prefer_replica is refused.
+func WithReplica(configured bool) ToolOption {
+	return func(o *toolOptions) {
+		o.replica = configured
+	}
+}
+
+// WithIncludeTiming adds a timing block to query and custom tool
+// responses, reporting how long validation, execution and masking took.
+func WithIncludeTiming(on bool) ToolOption {
+	return func(o *toolOptions) {
+		o.includeTiming = on
+	}
+}
+
+// WithExecutedSQL adds the SQL the executor sent to the database, after
+// limit rewriting and the other changes made on the way, to query tool
+// responses as executed_sql.
+func WithExecutedSQL(on bool) ToolOption {
+	return func(o *toolOptions) {
+		o.echoSQL = on
+	}
+}
+
+func serverInfoHandler(info ServerInfo, replication port.ReplicationReporter, tablespaces port.TablespaceLister, logger *slog.Logger) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		uptime := time.Since(info.StartedAt).Truncate(time.Second)
+		resp := serverInfoResponse{
+			ServerInfo:    info,
+			UptimeSeconds: int64(uptime.Seconds()),
+			Uptime:        uptime.String(),
+		}
+
+		if replication != nil {
+			// Non-fatal: the rest of server_info needs no database.
+			status, err := replication.ReplicationStatus(ctx)
+			if err != nil {
+				logger.Warn("reading replication status", slog.String("error", err.Error()))
+			}
+			resp.Replication = status
+		}
+
+		if tablespaces != nil {
+			list, err := tablespaces.Tablespaces(ctx)
+			if err != nil {
+				logger.Warn("listing tablespaces", slog.String("error", err.Error()))
+			}
+			resp.Tablespaces = list
+		}
+
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "server info")), nil
+		}
+
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
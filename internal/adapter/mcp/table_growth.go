@@ -0,0 +1,133 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const descTableGrowth = "Report how much each table has grown since the previous call. " +
+	"Every call records the current row estimates and on-disk sizes and compares them with the snapshot taken by the last call, " +
+	"so the first call only establishes a baseline. Sizes come from planner statistics and are approximate. " +
+	"Use this to spot fast-growing tables; call it periodically for a rough growth signal."
+
+// tableGrowthResponse compares the current table sizes with the previous
+// snapshot, if there was one.
+type tableGrowthResponse struct {
+	TakenAt         time.Time     `json:"taken_at"`
+	PreviousTakenAt *time.Time    `json:"previous_taken_at,omitempty"`
+	ElapsedSeconds  int64         `json:"elapsed_seconds,omitempty"`
+	Tables          []tableGrowth `json:"tables"`
+	Dropped         []string      `json:"dropped,omitempty"` // tables in the previous snapshot that no longer exist
+	Note            string        `json:"note,omitempty"`
+}
+
+type tableGrowth struct {
+	Schema      string `json:"schema"`
+	Name        string `json:"name"`
+	RowEstimate int64  `json:"row_estimate"`
+	TotalBytes  int64  `json:"total_bytes"`
+	RowDelta    *int64 `json:"row_delta,omitempty"`
+	BytesDelta  *int64 `json:"bytes_delta,omitempty"`
+	New         bool   `json:"new,omitempty"` // not present in the previous snapshot
+}
+
+// WithTableGrowth registers the table_growth tool, which keeps its snapshots
+// in store.
+func WithTableGrowth(store port.SnapshotStore) ToolOption {
+	return func(o *toolOptions) {
+		o.growthStore = store
+	}
+}
+
+func tableGrowthHandler(explorer port.SchemaExplorer, store port.SnapshotStore, logger *slog.Logger) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		schema, _ := request.GetArguments()["schema"].(string)
+
+		tables, err := explorer.ListTables(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "table growth")), nil
+		}
+
+		// The snapshot always covers every visible table, so filtering by
+		// schema does not skew the next call's deltas.
+		cur := port.SizeSnapshot{
+			TakenAt: time.Now().UTC(),
+			Tables:  make(map[string]port.TableSize, len(tables)),
+		}
+		for _, t := range tables {
+			cur.Tables[t.Schema+"."+t.Name] = port.TableSize{RowEstimate: t.RowEstimate, TotalBytes: t.TotalBytes}
+		}
+		prev, err := store.Swap(ctx, cur)
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "table growth")), nil
+		}
+
+		data, err := json.Marshal(buildTableGrowth(tables, cur, prev, schema))
+		if err != nil {
+			return mcp.NewToolResultError(sanitizeError(logger, err, "table growth")), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+// buildTableGrowth computes per-table deltas between prev and cur, limited to
+// schema when it is set, with the fastest-growing tables first.
+func buildTableGrowth(tables []port.TableInfo, cur port.SizeSnapshot, prev *port.SizeSnapshot, schema string) tableGrowthResponse {
+	resp := tableGrowthResponse{TakenAt: cur.TakenAt, Tables: []tableGrowth{}}
+	if prev == nil {
+		resp.Note = "first snapshot recorded; call again later to see growth"
+	} else {
+		resp.PreviousTakenAt = &prev.TakenAt
+		resp.ElapsedSeconds = int64(cur.TakenAt.Sub(prev.TakenAt).Seconds())
+	}
+
+	for _, t := range tables {
+		if schema != "" && t.Schema != schema {
+			continue
+		}
+		g := tableGrowth{Schema: t.Schema, Name: t.Name, RowEstimate: t.RowEstimate, TotalBytes: t.TotalBytes}
+		if prev != nil {
+			if old, ok := prev.Tables[t.Schema+"."+t.Name]; ok {
+				rows, bytes := t.RowEstimate-old.RowEstimate, t.TotalBytes-old.TotalBytes
+				g.RowDelta, g.BytesDelta = &rows, &bytes
+			} else {
+				g.New = true
+			}
+		}
+		resp.Tables = append(resp.Tables, g)
+	}
+
+	if prev != nil {
+		for key := range prev.Tables {
+			if _, ok := cur.Tables[key]; ok {
+				continue
+			}
+			if schema != "" && !strings.HasPrefix(key, schema+".") {
+				continue
+			}
+			resp.Dropped = append(resp.Dropped, key)
+		}
+		slices.Sort(resp.Dropped)
+	}
+
+	slices.SortStableFunc(resp.Tables, func(a, b tableGrowth) int {
+		return cmp.Compare(deref(b.BytesDelta), deref(a.BytesDelta))
+	})
+	return resp
+}
+
+func deref(p *int64) int64 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
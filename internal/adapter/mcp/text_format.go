@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+)
+
+// Output formats for describe_table.
+const (
+	formatJSON = "json"
+	formatText = "text"
+)
+
+// renderTableDetailText renders detail in the style of psql's \d: an aligned
+// column table followed by indexes and constraints.
+func renderTableDetailText(detail *port.TableDetail) string {
+	var b strings.Builder
+
+	title := detail.Name
+	if detail.Schema != "" {
+		title = detail.Schema + "." + detail.Name
+	}
+	fmt.Fprintf(&b, "Table %q\n", title)
+	if detail.Comment != "" {
+		fmt.Fprintf(&b, "%s\n", detail.Comment)
+	}
+
+	fkByColumn := make(map[string]string, len(detail.ForeignKeys))
+	for _, fk := range detail.ForeignKeys {
+		fkByColumn[fk.ColumnName] = fmt.Sprintf("FK -> %s(%s)", fk.ReferencedTable, fk.ReferencedColumn)
+	}
+
+	header := []string{"Column", "Type", "Nullable", "Default", "Key"}
+	rows := make([][]string, 0, len(detail.Columns))
+	for _, col := range detail.Columns {
+		nullable := ""
+		if !col.IsNullable {
+			nullable = "not null"
+		}
+		var keys []string
+		if col.IsPrimaryKey {
+			keys = append(keys, "PK")
+		}
+		if fk, ok := fkByColumn[col.Name]; ok {
+			keys = append(keys, fk)
+		}
+		rows = append(rows, []string{col.Name, col.DataType, nullable, col.DefaultValue, strings.Join(keys, ", ")})
+	}
+	writeAlignedTable(&b, header, rows)
+
+	if len(detail.Indexes) > 0 {
+		b.WriteString("Indexes:\n")
+		for _, idx := range detail.Indexes {
+			fmt.Fprintf(&b, "    %q %s\n", idx.Name, idx.Definition)
+		}
+	}
+	if len(detail.ForeignKeys) > 0 {
+		b.WriteString("Foreign-key constraints:\n")
+		for _, fk := range detail.ForeignKeys {
+			fmt.Fprintf(&b, "    %q FOREIGN KEY (%s) REFERENCES %s(%s)%s%s\n",
+				fk.ConstraintName, fk.ColumnName, fk.ReferencedTable, fk.ReferencedColumn,
+				referentialAction("UPDATE", fk.OnUpdate), referentialAction("DELETE", fk.OnDelete))
+		}
+	}
+	if len(detail.CheckConstraints) > 0 {
+		b.WriteString("Check constraints:\n")
+		for _, cc := range detail.CheckConstraints {
+			fmt.Fprintf(&b, "    %q %s\n", cc.Name, cc.Expression)
+		}
+	}
+
+	return b.String()
+}
+
+// referentialAction renders a foreign key's ON UPDATE or ON DELETE clause
+// like psql does, leaving out the default NO ACTION.
+func referentialAction(event, action string) string {
+	if action == "" || action == "NO ACTION" {
+		return ""
+	}
+	return " ON " + event + " " + action
+}
+
+// writeAlignedTable writes header and rows as a psql-style table with
+// " | " separators and a dashed rule under the header.
+func writeAlignedTable(b *strings.Builder, header []string, rows [][]string) {
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	writeRow := func(cells []string) {
+		parts := make([]string, len(cells))
+		for i, cell := range cells {
+			parts[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		b.WriteString(" " + strings.TrimRight(strings.Join(parts, " | "), " ") + "\n")
+	}
+
+	writeRow(header)
+	rule := make([]string, len(widths))
+	for i, w := range widths {
+		rule[i] = strings.Repeat("-", w+2)
+	}
+	b.WriteString(strings.Join(rule, "+") + "\n")
+	for _, row := range rows {
+		writeRow(row)
+	}
+}
@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// WithToolCallTimeout bounds every tool invocation to d, independently of the
+// per-statement query timeout, so a tool that issues several queries or waits
+// on a busy pool cannot run forever. Zero or negative disables the limit.
+func WithToolCallTimeout(d time.Duration) ToolOption {
+	return func(o *toolOptions) {
+		o.callTimeout = d
+	}
+}
+
+// withCallTimeout wraps handler so it runs under a context that expires after
+// d. When the deadline passes, the call returns a timeout error right away,
+// even if the handler has not yet noticed the canceled context.
+func withCallTimeout(name string, d time.Duration, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if d <= 0 {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		type outcome struct {
+			result *mcp.CallToolResult
+			err    error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			result, err := handler(ctx, request)
+			done <- outcome{result, err}
+		}()
+
+		select {
+		case out := <-done:
+			if out.err == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && (out.result == nil || out.result.IsError) {
+				return toolTimeoutResult(name, d), nil
+			}
+			return out.result, out.err
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return toolTimeoutResult(name, d), nil
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func toolTimeoutResult(name string, d time.Duration) *mcp.CallToolResult {
+	return mcp.NewToolResultError(fmt.Sprintf("%s: tool call timed out after %s", name, d))
+}
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"github.com/guillermoBallester/isthmus/internal/core/port"
@@ -30,14 +33,20 @@ const (
 		"column-level statistics from pg_stats (cardinality classification, null rates, enum-like values with frequencies, " +
 		"value ranges for dates/numbers); primary keys; foreign keys with referenced tables; indexes; " +
 		"check constraints; row estimate; table size; statistics freshness; sample rows (up to 5); " +
-		"and index usage statistics (scan counts per index). " +
+		"index usage statistics (scan counts per index); and recommendations such as foreign keys lacking an index. " +
 		"Use this to understand a table before writing queries. " +
 		"Pay attention to: foreign keys for JOIN paths; cardinality to know what to GROUP BY vs filter; " +
 		"enum-like columns show the allowed values; value ranges show date spans and numeric scales; " +
 		"null rates help you handle NULLs correctly in filters and JOINs; " +
 		"sample rows reveal actual data patterns; index usage shows which indexes are active vs unused."
 
-	descDescribeTableParam = "Name of the table to describe"
+	descDescribeTableParam = "Name of the table to describe, optionally schema-qualified (e.g. sales.orders)"
+
+	descDetailLevelParam = "How much detail to return: \"full\" (default) includes column statistics, " +
+		"sample rows and index usage; \"basic\" returns only structure (columns, keys, indexes, constraints) for a faster response."
+
+	descFormatParam = "Output format: \"json\" (default) returns the full structured detail; " +
+		"\"text\" returns a compact psql \\d-style table of columns, types, nullability, keys, indexes and constraints."
 
 	descQuery = "Execute a read-only SQL query against the database and return results as a JSON array of objects. " +
 		"A server-side row limit and query timeout are enforced. " +
@@ -45,22 +54,52 @@ const (
 		"Use JOINs based on foreign keys discovered via describe_table. " +
 		"Check column cardinality from describe_table to write efficient WHERE and GROUP BY clauses. " +
 		"Set explain=true to get the EXPLAIN plan instead of results. " +
-		"Set explain=true and analyze=true to get EXPLAIN ANALYZE (the query WILL be executed)."
+		"Set explain=true and analyze=true to get EXPLAIN ANALYZE (the query WILL be executed); " +
+		"add timing=false to skip per-node timing and reduce overhead. " +
+		"Add index_usage=true to also list the indexes the plan uses and flag sequential scans of large tables. " +
+		"Add row_estimates=true with analyze to flag plan nodes whose row estimates were badly off. " +
+		"The server may refuse analyze for queries whose estimated cost is too high and return the estimated plan instead."
 
-	descQueryParam = "SQL query to execute (SELECT statements only)"
+	descQueryParam = "SQL query to execute (SELECT statements only). Required unless page_token is set."
 )
 
-func RegisterTools(s *server.MCPServer, explorer port.SchemaExplorer, query *service.QueryService, logger *slog.Logger) {
-	s.AddTool(
+func RegisterTools(s *server.MCPServer, explorer port.SchemaExplorer, query *service.QueryService, logger *slog.Logger, opts ...ToolOption) {
+	var o toolOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	addTool := func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+		s.AddTool(tool, withCallTimeout(tool.Name, o.callTimeout, withArgLimit(tool.Name, o.maxArgBytes, handler)))
+	}
+	// describe_table and lint_schema share one limit: both run full describes.
+	describeSem := newSemaphore(o.describeLimit)
+
+	if o.serverInfo != nil {
+		addTool(
+			mcp.NewTool("server_info",
+				mcp.WithDescription(o.description("server_info", descServerInfo)),
+			),
+			serverInfoHandler(*o.serverInfo, o.replication, o.tablespaces, logger),
+		)
+	}
+
+	if len(o.tableResources) > 0 {
+		registerTableResources(s, explorer, o.tableResources, logger)
+	}
+
+	addTool(
 		mcp.NewTool("discover",
-			mcp.WithDescription(descDiscover),
+			mcp.WithDescription(o.description("discover", descDiscover)),
+			mcp.WithBoolean("size_breakdown",
+				mcp.Description("Also split each table's size into table, index and TOAST bytes, for capacity planning. Defaults to false."),
+			),
 		),
 		discoverHandler(explorer, logger),
 	)
 
-	s.AddTool(
+	addTool(
 		mcp.NewTool("describe_table",
-			mcp.WithDescription(descDescribeTable),
+			mcp.WithDescription(o.description("describe_table", descDescribeTable)),
 			mcp.WithString("table_name",
 				mcp.Required(),
 				mcp.Description(descDescribeTableParam),
@@ -68,15 +107,28 @@ func RegisterTools(s *server.MCPServer, explorer port.SchemaExplorer, query *ser
 			mcp.WithString("schema",
 				mcp.Description("Schema name (optional, resolves automatically if omitted)"),
 			),
+			mcp.WithString("detail_level",
+				mcp.Description(descDetailLevelParam),
+				mcp.Enum(string(port.DetailBasic), string(port.DetailFull)),
+			),
+			mcp.WithString("format",
+				mcp.Description(descFormatParam),
+				mcp.Enum(formatJSON, formatText),
+			),
+			mcp.WithString("column_name_pattern",
+				mcp.Description("Only return columns whose name matches this regular expression, e.g. \"^billing_\" (optional). Useful for very wide tables."),
+			),
+			mcp.WithNumber("max_columns",
+				mcp.Description("Return at most this many columns, in table order (optional, defaults to all). total_columns reports how many the table has when some are left out."),
+			),
 		),
-		describeTableHandler(explorer, logger),
+		withConcurrencyLimit("describe_table", describeSem, describeTableHandler(explorer, logger)),
 	)
 
-	s.AddTool(
+	addTool(
 		mcp.NewTool("query",
-			mcp.WithDescription(descQuery),
+			mcp.WithDescription(o.description("query", descQuery)),
 			mcp.WithString("sql",
-				mcp.Required(),
 				mcp.Description(descQueryParam),
 			),
 			mcp.WithBoolean("explain",
@@ -85,9 +137,137 @@ func RegisterTools(s *server.MCPServer, explorer port.SchemaExplorer, query *ser
 			mcp.WithBoolean("analyze",
 				mcp.Description("Include actual execution statistics (only used with explain=true, the query WILL be executed). Defaults to false."),
 			),
+			mcp.WithBoolean("explain_only",
+				mcp.Description("Preview the EXPLAIN plan without running the query, even if the server would run it. Cannot be combined with analyze. Defaults to false; setting it to false never overrides a server-wide explain-only mode."),
+			),
+			mcp.WithBoolean("timing",
+				mcp.Description("Measure per-node timing (only used with analyze=true). Set to false to run EXPLAIN (ANALYZE, TIMING OFF), which is cheaper. Defaults to true."),
+			),
+			mcp.WithBoolean("index_usage",
+				mcp.Description("Only used with explain=true. Return {\"plan\": ..., \"indexes_used\": [...], \"seq_scans\": [...], \"large_seq_scan\": bool} with the JSON plan, the indexes it reads, and its sequential scans, to check the query is index-served. Defaults to false."),
+			),
+			mcp.WithBoolean("row_estimates",
+				mcp.Description("Only used with explain=true and analyze=true. Return the JSON plan with \"misestimates\": plan nodes whose estimated rows were off from the actual rows by 10x or more, the \"worst_misestimate\", and a \"suggestion\" such as running ANALYZE. Can be combined with index_usage. Defaults to false."),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum rows to return for this call. Defaults to the server row limit; values above the server ceiling are capped."),
+			),
+			mcp.WithBoolean("include_schema",
+				mcp.Description("Return {\"columns\": [...], \"rows\": [...]} with each result column's name and PostgreSQL type, so the result shape is known even when no rows match. Defaults to false."),
+			),
+			mcp.WithString("timeout",
+				mcp.Description("Statement timeout for this call as a duration, e.g. \"30s\". Defaults to the server query timeout; values outside the server's minimum and maximum are clamped."),
+			),
+			mcp.WithBoolean("prefer_replica",
+				mcp.Description("Run this call on the server's read replica, for queries that tolerate slightly stale data. Only available when a replica is configured. Defaults to false (primary)."),
+			),
+			mcp.WithBoolean("paginate",
+				mcp.Description("Return {\"rows\": [...], \"next_page_token\": \"...\"} instead of a bare array. next_page_token is set when the page is full; pass it back as page_token to fetch the next page. Needs a single SELECT without its own LIMIT or OFFSET, and an ORDER BY for stable pages. Cannot be combined with explain. Defaults to false."),
+			),
+			mcp.WithString("page_token",
+				mcp.Description("next_page_token from a previous paginated call. It carries the query, offset and limit, so sql and limit must be omitted."),
+			),
+		),
+		queryHandler(query, logger, o.analyzeMaxCost, o.replica, newPageTokens(o.pageKey), o.includeTiming, o.echoSQL),
+	)
+
+	addTool(
+		mcp.NewTool("ping",
+			mcp.WithDescription(o.description("ping", descPing)),
 		),
-		queryHandler(query, logger),
+		pingHandler(query, logger),
 	)
+
+	addTool(
+		mcp.NewTool("filter_selectivity",
+			mcp.WithDescription(o.description("filter_selectivity", descFilterSelectivity)),
+			mcp.WithString("table_name",
+				mcp.Required(),
+				mcp.Description("Name of the table to filter, optionally schema-qualified"),
+			),
+			mcp.WithString("where",
+				mcp.Required(),
+				mcp.Description(descWhereParam),
+			),
+			mcp.WithString("schema",
+				mcp.Description("Schema name (optional, uses the search path if omitted)"),
+			),
+		),
+		filterSelectivityHandler(query, logger),
+	)
+
+	addTool(
+		mcp.NewTool("generate_select",
+			mcp.WithDescription(o.description("generate_select", descGenerateSelect)),
+			mcp.WithString("table_name",
+				mcp.Required(),
+				mcp.Description("Name of the table to select from, optionally schema-qualified"),
+			),
+			mcp.WithString("schema",
+				mcp.Description("Schema name (optional, resolves automatically if omitted)"),
+			),
+			mcp.WithArray("columns",
+				mcp.Description("Columns to select, in order (optional, defaults to every column)"),
+				mcp.WithStringItems(),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("LIMIT for the generated query. Defaults to 10."),
+			),
+		),
+		generateSelectHandler(explorer, logger),
+	)
+
+	addTool(
+		mcp.NewTool("recent_activity",
+			mcp.WithDescription(o.description("recent_activity", descRecentActivity)),
+			mcp.WithString("schema",
+				mcp.Description("Only report tables in this schema (optional)"),
+			),
+		),
+		recentActivityHandler(explorer, logger),
+	)
+
+	addTool(
+		mcp.NewTool("lint_schema",
+			mcp.WithDescription(o.description("lint_schema", descLintSchema)),
+			mcp.WithString("schema",
+				mcp.Description("Only check tables in this schema (optional)"),
+			),
+		),
+		withConcurrencyLimit("lint_schema", describeSem, lintSchemaHandler(explorer, logger)),
+	)
+
+	addTool(
+		mcp.NewTool("schema_graph",
+			mcp.WithDescription(o.description("schema_graph", descSchemaGraph)),
+			mcp.WithString("schema",
+				mcp.Description("Only draw tables in this schema (optional)"),
+			),
+			mcp.WithBoolean("include_columns",
+				mcp.Description("List each table's columns and types in its node. Defaults to false."),
+			),
+			mcp.WithBoolean("include_inferred",
+				mcp.Description("Add relationships inferred from column names as dashed edges. Defaults to false."),
+			),
+		),
+		schemaGraphHandler(explorer, logger, o.describeLimit),
+	)
+
+	if o.growthStore != nil {
+		addTool(
+			mcp.NewTool("table_growth",
+				mcp.WithDescription(o.description("table_growth", descTableGrowth)),
+				mcp.WithString("schema",
+					mcp.Description("Only report tables in this schema (optional). The snapshot still covers all tables."),
+				),
+			),
+			tableGrowthHandler(explorer, o.growthStore, logger),
+		)
+	}
+
+	for _, t := range o.customTools {
+		addTool(newCustomTool(t), customToolHandler(t, query, logger, o.includeTiming))
+	}
 }
 
 func discoverHandler(explorer port.SchemaExplorer, logger *slog.Logger) server.ToolHandlerFunc {
@@ -97,6 +277,14 @@ func discoverHandler(explorer port.SchemaExplorer, logger *slog.Logger) server.T
 			return mcp.NewToolResultError(sanitizeError(logger, err, "discover")), nil
 		}
 
+		if breakdown, _ := request.GetArguments()["size_breakdown"].(bool); !breakdown {
+			for _, s := range result.Schemas {
+				for i := range s.Tables {
+					s.Tables[i].SizeBreakdown = nil
+				}
+			}
+		}
+
 		data, err := json.Marshal(result)
 		if err != nil {
 			return mcp.NewToolResultError(sanitizeError(logger, err, "discover")), nil
@@ -114,11 +302,50 @@ func describeTableHandler(explorer port.SchemaExplorer, logger *slog.Logger) ser
 		}
 
 		schema, _ := request.GetArguments()["schema"].(string)
+		tableName, schema, err := resolveTableArgs(tableName, schema)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		if v, _ := request.GetArguments()["detail_level"].(string); v != "" {
+			level := port.DetailLevel(v)
+			if !level.Valid() {
+				return mcp.NewToolResultError(`detail_level must be "basic" or "full"`), nil
+			}
+			ctx = port.WithDetailLevel(ctx, level)
+		}
 
-		detail, err := explorer.DescribeTable(ctx, schema, tableName)
+		format, _ := request.GetArguments()["format"].(string)
+		if format != "" && format != formatJSON && format != formatText {
+			return mcp.NewToolResultError(`format must be "json" or "text"`), nil
+		}
+
+		var namePattern *regexp.Regexp
+		if v, _ := request.GetArguments()["column_name_pattern"].(string); v != "" {
+			re, err := regexp.Compile(v)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("column_name_pattern is not a valid regular expression: %v", err)), nil
+			}
+			namePattern = re
+		}
+		maxColumns := 0
+		if v, ok := request.GetArguments()["max_columns"]; ok {
+			n, ok := v.(float64)
+			if !ok || n < 1 || n != float64(int(n)) {
+				return mcp.NewToolResultError("max_columns must be a positive integer"), nil
+			}
+			maxColumns = int(n)
+		}
+
+		detail, err := explorer.DescribeTable(service.WithToolName(ctx, "describe_table"), schema, tableName)
 		if err != nil {
 			return mcp.NewToolResultError(sanitizeError(logger, err, "describe table")), nil
 		}
+		selectColumns(detail, namePattern, maxColumns)
+
+		if format == formatText {
+			return mcp.NewToolResultText(renderTableDetailText(detail)), nil
+		}
 
 		data, err := json.Marshal(detail)
 		if err != nil {
@@ -129,31 +356,288 @@ func describeTableHandler(explorer port.SchemaExplorer, logger *slog.Logger) ser
 	}
 }
 
-func queryHandler(query *service.QueryService, logger *slog.Logger) server.ToolHandlerFunc {
+// selectColumns keeps the columns whose name matches pattern, up to limit of
+// them in table order, and trims sample rows to the same columns. A nil
+// pattern matches every column and a zero limit keeps them all. TotalColumns is set
+// when anything was left out.
+func selectColumns(detail *port.TableDetail, pattern *regexp.Regexp, limit int) {
+	total := len(detail.Columns)
+	kept := detail.Columns[:0:0]
+	for _, col := range detail.Columns {
+		if limit > 0 && len(kept) == limit {
+			break
+		}
+		if pattern == nil || pattern.MatchString(col.Name) {
+			kept = append(kept, col)
+		}
+	}
+	if len(kept) == total {
+		return
+	}
+
+	detail.Columns = kept
+	detail.TotalColumns = total
+	names := make(map[string]bool, len(kept))
+	for _, col := range kept {
+		names[col.Name] = true
+	}
+	for _, row := range detail.SampleRows {
+		for k := range row {
+			if !names[k] {
+				delete(row, k)
+			}
+		}
+	}
+}
+
+// analyzeRefusedResponse is returned instead of EXPLAIN ANALYZE output when
+// the planner's cost estimate exceeds the configured limit.
+type analyzeRefusedResponse struct {
+	Note        string           `json:"note"`
+	Plan        []map[string]any `json:"plan"`
+	ExecutedSQL string           `json:"executed_sql,omitempty"`
+	Timing      *service.Timing  `json:"timing,omitempty"`
+}
+
+// planSummaryResponse is the query response for explain with index_usage
+// or row_estimates; only the requested summaries are included.
+type planSummaryResponse struct {
+	Plan any `json:"plan"`
+	*domain.IndexUsage
+	*domain.RowEstimates
+	ExecutedSQL string          `json:"executed_sql,omitempty"`
+	Timing      *service.Timing `json:"timing,omitempty"`
+}
+
+// queryResultWithSchema is the query response when include_schema is set.
+type queryResultWithSchema struct {
+	Columns     []port.ResultColumn `json:"columns"`
+	Rows        []map[string]any    `json:"rows"`
+	ExecutedSQL string              `json:"executed_sql,omitempty"`
+	Timing      *service.Timing     `json:"timing,omitempty"`
+}
+
+// queryPage is the query response when paginate or page_token is set.
+type queryPage struct {
+	Columns       []port.ResultColumn `json:"columns,omitempty"`
+	Rows          []map[string]any    `json:"rows"`
+	NextPageToken string              `json:"next_page_token,omitempty"`
+	ExecutedSQL   string              `json:"executed_sql,omitempty"`
+	Timing        *service.Timing     `json:"timing,omitempty"`
+}
+
+// queryRows is the query and custom tool response when the executed SQL or
+// timing is reported and the rows would otherwise be a bare array.
+type queryRows struct {
+	Rows        []map[string]any `json:"rows"`
+	ExecutedSQL string           `json:"executed_sql,omitempty"`
+	Timing      *service.Timing  `json:"timing,omitempty"`
+}
+
+// queryHandler serves the query tool. When analyzeMaxCost is positive,
+// analyze requests are first planned with EXPLAIN and only executed if the
+// estimated total cost is within the limit. prefer_replica is only honored
+// when replica is true. Paginated calls get their next page token from pages.
+// With includeTiming, the response carries the phase timings of every query
+// the call ran; with echoSQL, the SQL the executor last sent to the database.
+func queryHandler(query *service.QueryService, logger *slog.Logger, analyzeMaxCost float64, replica bool, pages *pageTokens, includeTiming, echoSQL bool) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		sql, ok := request.GetArguments()["sql"].(string)
-		if !ok || sql == "" {
+		sql, _ := request.GetArguments()["sql"].(string)
+		paginate, _ := request.GetArguments()["paginate"].(bool)
+		var cursor pageCursor
+		if token, _ := request.GetArguments()["page_token"].(string); token != "" {
+			if _, ok := request.GetArguments()["limit"]; ok || sql != "" {
+				return mcp.NewToolResultError("page_token cannot be combined with sql or limit; the token carries both"), nil
+			}
+			c, err := pages.decode(token)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			cursor, sql, paginate = c, c.SQL, true
+			if c.Limit > 0 {
+				ctx = port.WithMaxRows(ctx, c.Limit)
+			}
+		}
+		if sql == "" {
 			return mcp.NewToolResultError("sql is required"), nil
 		}
 
 		explain, _ := request.GetArguments()["explain"].(bool)
 		analyze, _ := request.GetArguments()["analyze"].(bool)
+		explainOnly, _ := request.GetArguments()["explain_only"].(bool)
+		if explainOnly && analyze {
+			return mcp.NewToolResultError("explain_only cannot be combined with analyze, which runs the query"), nil
+		}
+		timing := true
+		if v, ok := request.GetArguments()["timing"].(bool); ok {
+			timing = v
+		}
+		indexUsage, _ := request.GetArguments()["index_usage"].(bool)
+		if indexUsage && !explain {
+			return mcp.NewToolResultError("index_usage requires explain=true"), nil
+		}
+		rowEstimates, _ := request.GetArguments()["row_estimates"].(bool)
+		if rowEstimates && (!explain || !analyze) {
+			return mcp.NewToolResultError("row_estimates requires explain=true and analyze=true"), nil
+		}
+		jsonPlan := indexUsage || rowEstimates
+		if paginate && explain {
+			return mcp.NewToolResultError("paginate cannot be combined with explain"), nil
+		}
+		preferReplica, _ := request.GetArguments()["prefer_replica"].(bool)
+		if preferReplica && !replica {
+			return mcp.NewToolResultError("prefer_replica is not available: no read replica is configured"), nil
+		}
 
-		if explain {
-			if analyze {
-				sql = "EXPLAIN ANALYZE " + sql
-			} else {
-				sql = "EXPLAIN " + sql
+		if v, ok := request.GetArguments()["limit"]; ok {
+			limit, ok := v.(float64)
+			if !ok || limit < 1 || limit != float64(int(limit)) {
+				return mcp.NewToolResultError("limit must be a positive integer"), nil
 			}
+			ctx = port.WithMaxRows(ctx, int(limit))
+			cursor.Limit = int(limit)
+		}
+
+		if v, ok := request.GetArguments()["timeout"]; ok {
+			s, _ := v.(string)
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				return mcp.NewToolResultError(`timeout must be a positive duration such as "30s"`), nil
+			}
+			ctx = port.WithQueryTimeout(ctx, d)
+		}
+
+		includeSchema, _ := request.GetArguments()["include_schema"].(bool)
+		var columns []port.ResultColumn
+		if includeSchema {
+			ctx = port.WithResultColumns(ctx, &columns)
 		}
 
 		ctx = service.WithToolName(ctx, "query")
+		var phases *service.Timing
+		if includeTiming {
+			phases = &service.Timing{}
+			ctx = service.WithTiming(ctx, phases)
+		}
+		var executed string
+		if echoSQL {
+			ctx = port.WithExecutedSQL(ctx, &executed)
+		}
+		if explainOnly {
+			ctx = port.WithExplainOnly(ctx)
+		}
+		if preferReplica {
+			ctx = port.WithPreferReplica(ctx)
+		}
+
+		if explain && analyze && analyzeMaxCost > 0 {
+			plan, err := explainPlan(ctx, query, sql)
+			if err != nil {
+				return mcp.NewToolResultError(sanitizeError(logger, err, "query")), nil
+			}
+			if plan.TotalCost > analyzeMaxCost {
+				rows, err := query.Execute(ctx, domain.PrefixLastStatement(sql, "EXPLAIN "))
+				if err != nil {
+					return mcp.NewToolResultError(sanitizeError(logger, err, "query")), nil
+				}
+				resp := analyzeRefusedResponse{
+					Note: fmt.Sprintf("analyze skipped: estimated cost %.2f exceeds the server limit of %.2f, showing the estimated plan only",
+						plan.TotalCost, analyzeMaxCost),
+					Plan:        rowsOrEmpty(rows),
+					ExecutedSQL: executed,
+					Timing:      phases,
+				}
+				data, err := json.Marshal(resp)
+				if err != nil {
+					return mcp.NewToolResultError(sanitizeError(logger, err, "query")), nil
+				}
+				return mcp.NewToolResultText(string(data)), nil
+			}
+		}
+
+		if explain {
+			// In a batch, only the last statement returns rows.
+			switch {
+			case jsonPlan && analyze && !timing:
+				sql = domain.PrefixLastStatement(sql, "EXPLAIN (ANALYZE, TIMING OFF, FORMAT JSON) ")
+			case jsonPlan && analyze:
+				sql = domain.PrefixLastStatement(sql, "EXPLAIN (ANALYZE, FORMAT JSON) ")
+			case jsonPlan:
+				sql = domain.PrefixLastStatement(sql, "EXPLAIN (FORMAT JSON) ")
+			case analyze && !timing:
+				sql = domain.PrefixLastStatement(sql, "EXPLAIN (ANALYZE, TIMING OFF) ")
+			case analyze:
+				sql = domain.PrefixLastStatement(sql, "EXPLAIN ANALYZE ")
+			default:
+				sql = domain.PrefixLastStatement(sql, "EXPLAIN ")
+			}
+		}
+
+		var applied int
+		if paginate {
+			cursor.SQL = sql
+			paged, ok := domain.PageQuery(sql, cursor.Offset)
+			if !ok {
+				return mcp.NewToolResultError("paginate needs a single SELECT without its own LIMIT or OFFSET"), nil
+			}
+			sql = paged
+			ctx = port.WithAppliedRowLimit(ctx, &applied)
+		}
+
 		results, err := query.Execute(ctx, sql)
 		if err != nil {
 			return mcp.NewToolResultError(sanitizeError(logger, err, "query")), nil
 		}
+		results = rowsOrEmpty(results)
+
+		if jsonPlan {
+			plan, err := planValue(results)
+			if err != nil {
+				return mcp.NewToolResultError(sanitizeError(logger, err, "query")), nil
+			}
+			resp := planSummaryResponse{Plan: plan, ExecutedSQL: executed, Timing: phases}
+			if indexUsage {
+				if resp.IndexUsage, err = domain.PlanIndexUsage(plan, domain.DefaultLargeSeqScanRows); err != nil {
+					return mcp.NewToolResultError(sanitizeError(logger, err, "query")), nil
+				}
+			}
+			if rowEstimates {
+				if resp.RowEstimates, err = domain.PlanRowEstimates(plan, domain.DefaultMisestimateRatio); err != nil {
+					return mcp.NewToolResultError(sanitizeError(logger, err, "query")), nil
+				}
+			}
+			data, err := json.Marshal(resp)
+			if err != nil {
+				return mcp.NewToolResultError(sanitizeError(logger, err, "query")), nil
+			}
+			return mcp.NewToolResultText(string(data)), nil
+		}
 
-		data, err := json.Marshal(results)
+		var payload any = results
+		if paginate {
+			page := queryPage{Rows: results, ExecutedSQL: executed, Timing: phases}
+			if includeSchema {
+				page.Columns = columns
+			}
+			// A full page may be followed by more rows; a short one is the last.
+			if applied > 0 && len(results) >= applied {
+				next := cursor
+				next.Offset += len(results)
+				if page.NextPageToken, err = pages.encode(next); err != nil {
+					return mcp.NewToolResultError(sanitizeError(logger, err, "query")), nil
+				}
+			}
+			payload = page
+		} else if includeSchema {
+			if columns == nil {
+				columns = []port.ResultColumn{}
+			}
+			payload = queryResultWithSchema{Columns: columns, Rows: results, ExecutedSQL: executed, Timing: phases}
+		} else if includeTiming || echoSQL {
+			payload = queryRows{Rows: results, ExecutedSQL: executed, Timing: phases}
+		}
+
+		data, err := json.Marshal(payload)
 		if err != nil {
 			return mcp.NewToolResultError(sanitizeError(logger, err, "query")), nil
 		}
@@ -162,10 +646,33 @@ func queryHandler(query *service.QueryService, logger *slog.Logger) server.ToolH
 	}
 }
 
+// rowsOrEmpty returns rows, or an empty slice when there are none, so a
+// zero-row result is encoded as [] rather than null.
+func rowsOrEmpty(rows []map[string]any) []map[string]any {
+	if rows == nil {
+		return []map[string]any{}
+	}
+	return rows
+}
+
 // sanitizeError logs the full error for debugging and returns a safe message for the MCP client.
 // Validation errors (controlled by us) are passed through; infrastructure errors are redacted.
+// The DETAIL, HINT and WHERE fields of a PostgreSQL error are only ever logged, never
+// returned, and the executor clears them unless LOG_PG_ERROR_DETAIL is set.
 func sanitizeError(logger *slog.Logger, err error, operation string) string {
-	logger.Error("tool error", slog.String("operation", operation), slog.String("error", err.Error()))
+	attrs := []any{slog.String("operation", operation), slog.String("error", err.Error())}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		attrs = append(attrs, slog.String("pg.code", pgErr.Code))
+		for _, f := range []struct{ key, val string }{
+			{"pg.detail", pgErr.Detail}, {"pg.hint", pgErr.Hint}, {"pg.where", pgErr.Where},
+		} {
+			if f.val != "" {
+				attrs = append(attrs, slog.String(f.key, f.val))
+			}
+		}
+	}
+	logger.Error("tool error", attrs...)
 
 	if isValidationError(err) {
 		return fmt.Sprintf("%s: %v", operation, err)
@@ -176,16 +683,55 @@ func sanitizeError(logger *slog.Logger, err error, operation string) string {
 	if isConnectionError(err) {
 		return fmt.Sprintf("%s: database unavailable", operation)
 	}
+	if msg, ok := concurrencyErrorMessage(err); ok {
+		return fmt.Sprintf("%s: %s", operation, msg)
+	}
+	if msg, ok := permissionErrorMessage(err); ok {
+		return fmt.Sprintf("%s: %s", operation, msg)
+	}
 	return fmt.Sprintf("%s: internal error (check server logs)", operation)
 }
 
+// resolveTableArgs normalizes the table_name and schema arguments and rejects
+// those that cannot name a real table (see domain.ValidIdentifier). A
+// schema-qualified table_name such as sales.orders is split into its parts;
+// it must then agree with schema when both are given. An empty schema means
+// the default.
+func resolveTableArgs(tableName, schema string) (string, string, error) {
+	qualifier, table, err := domain.SplitQualifiedName(tableName)
+	if err != nil {
+		return "", "", fmt.Errorf("table_name: %w", err)
+	}
+	if qualifier != "" {
+		if schema != "" && schema != qualifier {
+			return "", "", fmt.Errorf("table_name %q names schema %q but schema is %q", tableName, qualifier, schema)
+		}
+		schema = qualifier
+	}
+	if err := domain.ValidIdentifier(table); err != nil {
+		return "", "", fmt.Errorf("table_name: %w", err)
+	}
+	if schema != "" {
+		if err := domain.ValidIdentifier(schema); err != nil {
+			return "", "", fmt.Errorf("schema: %w", err)
+		}
+	}
+	return table, schema, nil
+}
+
 // isValidationError returns true for errors we control and are safe to show to clients.
 func isValidationError(err error) bool {
 	return errors.Is(err, domain.ErrEmptyQuery) ||
 		errors.Is(err, domain.ErrNotAllowed) ||
 		errors.Is(err, domain.ErrMultiStatement) ||
 		errors.Is(err, domain.ErrParseFailed) ||
-		errors.Is(err, domain.ErrNotFound)
+		errors.Is(err, domain.ErrNotFound) ||
+		errors.Is(err, domain.ErrBadPredicate) ||
+		errors.Is(err, domain.ErrBadIdentifier) ||
+		errors.Is(err, domain.ErrAmbiguousName) ||
+		errors.Is(err, domain.ErrAmbiguousTable) ||
+		errors.Is(err, domain.ErrResultTooLarge) ||
+		errors.Is(err, domain.ErrBadParam)
 }
 
 // isTimeoutError returns true for timeout-related errors at any level.
@@ -219,3 +765,45 @@ func isConnectionError(err error) bool {
 	}
 	return false
 }
+
+// concurrencyErrorMessage returns a client-safe message for queries canceled
+// by lock contention or a serialization conflict. Read-only transactions
+// rarely hit these, but explicit locks (e.g. SELECT ... FOR UPDATE) can.
+// They are transient, so the message tells the caller a retry may help.
+func concurrencyErrorMessage(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+	switch pgErr.Code {
+	case "40P01": // deadlock_detected
+		return "query canceled due to a deadlock; retry may help", true
+	case "55P03": // lock_not_available
+		return "query canceled due to lock wait; retry may help", true
+	case "40001": // serialization_failure
+		return "query canceled due to serialization conflict; retry may help", true
+	}
+	return "", false
+}
+
+// permissionErrorMessage returns a client-safe message for privilege errors
+// and for objects the role cannot see. Postgres reports the object in the
+// message ("permission denied for table users", `relation "x" does not
+// exist`), which only names what the caller asked for, so it is passed on;
+// anything else about the error stays in the server logs.
+func permissionErrorMessage(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+	switch pgErr.Code {
+	case "42501": // insufficient_privilege
+		if strings.HasPrefix(pgErr.Message, "permission denied for ") {
+			return pgErr.Message, true
+		}
+		return "permission denied", true
+	case "42P01", "3F000", "42704": // undefined_table, invalid_schema_name, undefined_object
+		return pgErr.Message + " (or the server role cannot see it)", true
+	}
+	return "", false
+}
@@ -55,6 +55,31 @@ const e2eSchema = `
 		rating     SMALLINT NOT NULL CHECK (rating >= 1 AND rating <= 5),
 		body       TEXT
 	);
+	ALTER TABLE reviews ALTER COLUMN body SET STORAGE EXTERNAL;
+
+	-- FK without a supporting index (for missing-index recommendations).
+	CREATE TABLE product_tags (
+		id         SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		tag        TEXT COLLATE "C" NOT NULL
+	);
+
+	-- Legacy inheritance hierarchy.
+	CREATE TABLE events (
+		id          SERIAL PRIMARY KEY,
+		occurred_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+	CREATE TABLE click_events (target TEXT NOT NULL) INHERITS (events);
+	CREATE TABLE view_events (page TEXT NOT NULL) INHERITS (events);
+
+	-- Declarative partitioning; the parent holds no data itself.
+	CREATE TABLE readings (
+		id       INTEGER NOT NULL,
+		taken_on DATE NOT NULL,
+		value    NUMERIC NOT NULL
+	) PARTITION BY RANGE (taken_on);
+	CREATE TABLE readings_2025 PARTITION OF readings FOR VALUES FROM ('2025-01-01') TO ('2026-01-01');
+	CREATE TABLE readings_2026 PARTITION OF readings FOR VALUES FROM ('2026-01-01') TO ('2027-01-01');
 
 	CREATE VIEW active_products AS
 		SELECT id, name, price FROM products WHERE status = 'active';
@@ -82,6 +107,10 @@ const e2eSchema = `
 		(i % 5) + 1,
 		CASE WHEN i % 3 = 0 THEN NULL ELSE 'Review ' || i END
 	FROM generate_series(1, 200) AS i;
+
+	INSERT INTO readings (id, taken_on, value)
+	SELECT i, DATE '2025-01-01' + (i % 730), i * 1.5
+	FROM generate_series(1, 5000) AS i;
 `
 
 // setupE2E starts a Postgres testcontainer, applies the schema, runs ANALYZE,
@@ -169,6 +198,37 @@ func TestE2E_MCPTools(t *testing.T) {
 		assert.Greater(t, products.RowEstimate, int64(0))
 		assert.Equal(t, 8, products.ColumnCount)
 		assert.True(t, products.HasIndexes)
+		assert.Nil(t, products.SizeBreakdown, "size breakdown is opt-in")
+	})
+
+	t.Run("discover/size_breakdown", func(t *testing.T) {
+		result := callToolE2E(t, s, "discover", map[string]any{"size_breakdown": true})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+
+		var discovery port.DiscoveryResult
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &discovery))
+
+		tableMap := make(map[string]port.TableInfo)
+		for _, schema := range discovery.Schemas {
+			if schema.Name != "public" {
+				continue
+			}
+			for _, tbl := range schema.Tables {
+				tableMap[tbl.Name] = tbl
+			}
+		}
+
+		// reviews has a primary key index and a text column, so all three
+		// parts are non-zero and add up to the total.
+		reviews := tableMap["reviews"]
+		require.NotNil(t, reviews.SizeBreakdown)
+		sizes := reviews.SizeBreakdown
+		assert.Greater(t, sizes.TableBytes, int64(0))
+		assert.Greater(t, sizes.IndexBytes, int64(0))
+		assert.Greater(t, sizes.ToastBytes, int64(0))
+		assert.Equal(t, reviews.TotalBytes, sizes.TableBytes+sizes.IndexBytes+sizes.ToastBytes)
+
+		assert.Nil(t, tableMap["active_products"].SizeBreakdown, "views have no size breakdown")
 	})
 
 	t.Run("describe_table", func(t *testing.T) {
@@ -238,8 +298,60 @@ func TestE2E_MCPTools(t *testing.T) {
 		indexNames := make(map[string]bool)
 		for _, u := range detail.IndexUsage {
 			indexNames[u.Name] = true
+			assert.GreaterOrEqual(t, u.BloatBytes, int64(0), u.Name)
+			assert.LessOrEqual(t, u.BloatBytes, u.SizeBytes, u.Name)
+			assert.GreaterOrEqual(t, u.BloatRatio, 0.0, u.Name)
+			assert.Less(t, u.BloatRatio, 1.0, u.Name)
 		}
 		assert.True(t, indexNames["products_pkey"], "should include products_pkey")
+
+		// Bloat is reported for every index, even when it is 0.
+		var raw struct {
+			IndexUsage []map[string]any `json:"index_usage"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &raw))
+		for _, u := range raw.IndexUsage {
+			assert.Contains(t, u, "bloat_bytes")
+			assert.Contains(t, u, "bloat_ratio")
+		}
+	})
+
+	t.Run("describe_table/unindexed_fk", func(t *testing.T) {
+		result := callToolE2E(t, s, "describe_table", map[string]any{"table_name": "product_tags"})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+
+		var detail port.TableDetail
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &detail))
+		require.Len(t, detail.Recommendations, 1)
+		assert.Contains(t, detail.Recommendations[0], "product_id")
+		assert.Contains(t, detail.Recommendations[0], "no supporting index")
+	})
+
+	t.Run("describe_table/fk_actions", func(t *testing.T) {
+		result := callToolE2E(t, s, "describe_table", map[string]any{"table_name": "product_tags"})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+		var detail port.TableDetail
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &detail))
+		require.Len(t, detail.ForeignKeys, 1)
+		assert.Equal(t, "CASCADE", detail.ForeignKeys[0].OnDelete)
+		assert.Equal(t, "NO ACTION", detail.ForeignKeys[0].OnUpdate)
+
+		result = callToolE2E(t, s, "describe_table", map[string]any{"table_name": "products"})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &detail))
+		require.NotEmpty(t, detail.ForeignKeys)
+		assert.Equal(t, "NO ACTION", detail.ForeignKeys[0].OnDelete, "the default action")
+	})
+
+	t.Run("describe_table/indexed_fk_no_recommendation", func(t *testing.T) {
+		result := callToolE2E(t, s, "describe_table", map[string]any{"table_name": "products"})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+
+		var detail port.TableDetail
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &detail))
+		for _, rec := range detail.Recommendations {
+			assert.NotContains(t, rec, "category_id", "category_id is indexed")
+		}
 	})
 
 	t.Run("describe_table/schema_arg", func(t *testing.T) {
@@ -255,6 +367,97 @@ func TestE2E_MCPTools(t *testing.T) {
 		assert.Equal(t, "products", detail.Name)
 	})
 
+	t.Run("describe_table/inheritance_parent", func(t *testing.T) {
+		result := callToolE2E(t, s, "describe_table", map[string]any{"table_name": "events"})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+
+		var detail port.TableDetail
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &detail))
+		assert.Empty(t, detail.Parents)
+		assert.Equal(t, []string{"click_events", "view_events"}, detail.Children)
+	})
+
+	t.Run("describe_table/inheritance_child", func(t *testing.T) {
+		result := callToolE2E(t, s, "describe_table", map[string]any{"table_name": "click_events"})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+
+		var detail port.TableDetail
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &detail))
+		assert.Equal(t, []string{"events"}, detail.Parents)
+		assert.Empty(t, detail.Children)
+	})
+
+	t.Run("describe_table/no_inheritance", func(t *testing.T) {
+		result := callToolE2E(t, s, "describe_table", map[string]any{"table_name": "products"})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+		assert.NotContains(t, toolText(result), `"parents"`)
+		assert.NotContains(t, toolText(result), `"children"`)
+	})
+
+	t.Run("describe_table/partitioned_size", func(t *testing.T) {
+		describe := func(table string) port.TableDetail {
+			result := callToolE2E(t, s, "describe_table", map[string]any{"table_name": table})
+			require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+			var detail port.TableDetail
+			require.NoError(t, json.Unmarshal([]byte(toolText(result)), &detail))
+			return detail
+		}
+
+		parent := describe("readings")
+		first := describe("readings_2025")
+		second := describe("readings_2026")
+
+		assert.Equal(t, []string{"readings_2025", "readings_2026"}, parent.Children)
+		assert.Greater(t, first.TotalBytes, int64(0))
+		assert.Greater(t, second.TotalBytes, int64(0))
+		assert.Equal(t, first.TotalBytes+second.TotalBytes, parent.TotalBytes, "parent size covers all partitions")
+		assert.Equal(t, first.RowEstimate+second.RowEstimate, parent.RowEstimate)
+		assert.InDelta(t, 5000, parent.RowEstimate, 500)
+	})
+
+	t.Run("describe_table/column_storage", func(t *testing.T) {
+		columns := func(table string) map[string]port.ColumnInfo {
+			result := callToolE2E(t, s, "describe_table", map[string]any{"table_name": table})
+			require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+			var detail port.TableDetail
+			require.NoError(t, json.Unmarshal([]byte(toolText(result)), &detail))
+			byName := make(map[string]port.ColumnInfo, len(detail.Columns))
+			for _, col := range detail.Columns {
+				byName[col.Name] = col
+			}
+			return byName
+		}
+
+		products := columns("products")
+		assert.Equal(t, "extended", products["metadata"].Storage)
+		assert.Equal(t, "pglz", products["metadata"].Compression, "server default_toast_compression")
+		assert.Empty(t, products["id"].Storage)
+
+		reviews := columns("reviews")
+		assert.Equal(t, "external", reviews["body"].Storage)
+		assert.Empty(t, reviews["body"].Compression, "external storage is not compressed")
+		assert.Empty(t, reviews["rating"].Storage, "fixed-width columns are plain")
+		assert.Empty(t, reviews["rating"].Compression)
+	})
+
+	t.Run("describe_table/column_collation", func(t *testing.T) {
+		result := callToolE2E(t, s, "describe_table", map[string]any{"table_name": "product_tags"})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+		var detail port.TableDetail
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &detail))
+
+		collations := make(map[string]string, len(detail.Columns))
+		for _, col := range detail.Columns {
+			collations[col.Name] = col.Collation
+		}
+		assert.Equal(t, "C", collations["tag"])
+		assert.Empty(t, collations["id"], "non-collatable types have no collation")
+
+		result = callToolE2E(t, s, "describe_table", map[string]any{"table_name": "products"})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+		assert.NotContains(t, toolText(result), `"collation"`, "the database default is not reported")
+	})
+
 	t.Run("describe_table/not_found", func(t *testing.T) {
 		result := callToolE2E(t, s, "describe_table", map[string]any{"table_name": "nonexistent_table"})
 		assert.True(t, result.IsError)
@@ -274,6 +477,34 @@ func TestE2E_MCPTools(t *testing.T) {
 		assert.Contains(t, rows[0], "category")
 	})
 
+	t.Run("query/paginate", func(t *testing.T) {
+		var ids []any
+		args := map[string]any{"sql": "SELECT id FROM categories ORDER BY id", "paginate": true, "limit": 2}
+		for range 10 {
+			result := callToolE2E(t, s, "query", args)
+			require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+			var page queryPage
+			require.NoError(t, json.Unmarshal([]byte(toolText(result)), &page))
+			assert.LessOrEqual(t, len(page.Rows), 2)
+			for _, row := range page.Rows {
+				ids = append(ids, row["id"])
+			}
+			if page.NextPageToken == "" {
+				break
+			}
+			args = map[string]any{"page_token": page.NextPageToken}
+		}
+
+		result := callToolE2E(t, s, "query", map[string]any{"sql": "SELECT id FROM categories ORDER BY id"})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+		var all []map[string]any
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &all))
+		require.Len(t, ids, len(all), "pages cover every row once")
+		for i, row := range all {
+			assert.Equal(t, row["id"], ids[i])
+		}
+	})
+
 	t.Run("query/rejects_insert", func(t *testing.T) {
 		result := callToolE2E(t, s, "query", map[string]any{
 			"sql": "INSERT INTO categories (name) VALUES ('test')",
@@ -310,6 +541,89 @@ func TestE2E_MCPTools(t *testing.T) {
 		planText, _ := rows[0]["QUERY PLAN"].(string)
 		assert.Contains(t, planText, "actual", "EXPLAIN ANALYZE should include actual timing")
 	})
+
+	t.Run("filter_selectivity", func(t *testing.T) {
+		result := callToolE2E(t, s, "filter_selectivity", map[string]any{
+			"table_name": "products",
+			"where":      "status = 'active'",
+		})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+
+		var resp filterSelectivityResponse
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+		assert.Equal(t, `"products"`, resp.Table)
+		assert.Equal(t, int64(100), resp.EstimatedTotalRows)
+		// 60 of 100 products are active; ANALYZE statistics should land close.
+		assert.InDelta(t, 60, resp.EstimatedRows, 15)
+		assert.InDelta(t, 0.6, resp.Selectivity, 0.15)
+	})
+
+	t.Run("filter_selectivity/schema_qualified", func(t *testing.T) {
+		result := callToolE2E(t, s, "filter_selectivity", map[string]any{
+			"table_name": "reviews",
+			"schema":     "public",
+			"where":      "rating = 5 AND body IS NOT NULL",
+		})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+
+		var resp filterSelectivityResponse
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+		assert.Equal(t, `"public"."reviews"`, resp.Table)
+		assert.Less(t, resp.EstimatedRows, resp.EstimatedTotalRows)
+	})
+
+	t.Run("filter_selectivity/rejects_injection", func(t *testing.T) {
+		result := callToolE2E(t, s, "filter_selectivity", map[string]any{
+			"table_name": "products",
+			"where":      "true UNION SELECT 1",
+		})
+		assert.True(t, result.IsError)
+		assert.Contains(t, toolText(result), "boolean expression")
+	})
+
+	t.Run("filter_selectivity/unknown_column", func(t *testing.T) {
+		result := callToolE2E(t, s, "filter_selectivity", map[string]any{
+			"table_name": "products",
+			"where":      "no_such_column = 1",
+		})
+		assert.True(t, result.IsError)
+	})
+
+	t.Run("lint_schema", func(t *testing.T) {
+		result := callToolE2E(t, s, "lint_schema", map[string]any{"schema": "public"})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+		var resp lintSchemaResponse
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+		assert.Positive(t, resp.TablesChecked)
+
+		found := make(map[string]string)
+		for _, f := range resp.Findings {
+			found[f.Table+"."+f.Column+":"+f.Check] = f.Severity
+		}
+		assert.Equal(t, "info", found["reviews.product_id:missing_fk_index"], "inferred FK without an index")
+		assert.Equal(t, "warning", found["product_tags.product_id:missing_fk_index"], "declared FK without an index")
+		assert.NotContains(t, found, "products.category_id:missing_fk_index", "indexed FK")
+		assert.Equal(t, "warning", found["readings.:no_primary_key"])
+		assert.NotContains(t, found, "reviews.:no_primary_key")
+	})
+
+	t.Run("schema_graph", func(t *testing.T) {
+		result := callToolE2E(t, s, "schema_graph", map[string]any{
+			"schema": "public", "include_columns": true, "include_inferred": true,
+		})
+		require.False(t, result.IsError, "unexpected error: %s", toolText(result))
+		dot := toolText(result)
+
+		assert.True(t, strings.HasPrefix(dot, "digraph schema {"))
+		for _, table := range []string{"products", "categories", "reviews", "product_tags"} {
+			assert.Contains(t, dot, `"public.`+table+`" [label="`+table+`\n`, table)
+		}
+		assert.NotContains(t, dot, `"public.active_products"`, "views are left out")
+		assert.Contains(t, dot, `"public.products" -> "public.categories" [label="category_id -> id"];`)
+		assert.Contains(t, dot, `"public.product_tags" -> "public.products" [label="product_id -> id"];`)
+		assert.Contains(t, dot, `"public.reviews" -> "public.products" [label="product_id -> id (inferred)", style=dashed];`)
+		assert.Contains(t, dot, `id integer PK\l`)
+	})
 }
 
 var e2eSessionCounter atomic.Int64
@@ -1,14 +1,23 @@
 package mcp
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"io"
 	"log/slog"
+	"maps"
+	"net"
 
+	"github.com/guillermoBallester/isthmus/internal/adapter/postgres"
 	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"github.com/guillermoBallester/isthmus/internal/core/port"
 	"github.com/guillermoBallester/isthmus/internal/core/service"
@@ -26,8 +35,19 @@ type mockExplorer struct {
 	schemas   []port.SchemaInfo
 	tables    []port.TableInfo
 	detail    *port.TableDetail
+	details   map[string]*port.TableDetail // by table name; tables not in it get detail
 	discovery *port.DiscoveryResult
+	activity  []port.TableActivity
+	fks       map[string][]port.ForeignKey // by "schema.table"; nil builds them from details
 	err       error
+	delay     time.Duration // Discover sleeps this long, or until ctx is done
+
+	lastDetailLevel port.DetailLevel // captures the level requested via context
+	lastSchema      string
+	lastTable       string
+	describes       int // DescribeTable calls
+
+	mu sync.Mutex // DescribeTable may be called concurrently
 }
 
 func (m *mockExplorer) ListSchemas(_ context.Context) ([]port.SchemaInfo, error) {
@@ -38,27 +58,91 @@ func (m *mockExplorer) ListTables(_ context.Context) ([]port.TableInfo, error) {
 	return m.tables, m.err
 }
 
-func (m *mockExplorer) DescribeTable(_ context.Context, _, _ string) (*port.TableDetail, error) {
+func (m *mockExplorer) DescribeTable(ctx context.Context, schema, table string) (*port.TableDetail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.describes++
+	m.lastDetailLevel = port.DetailLevelFromContext(ctx)
+	m.lastSchema, m.lastTable = schema, table
+	if d, ok := m.details[table]; ok {
+		return d, m.err
+	}
 	return m.detail, m.err
 }
 
-func (m *mockExplorer) Discover(_ context.Context) (*port.DiscoveryResult, error) {
+func (m *mockExplorer) Discover(ctx context.Context) (*port.DiscoveryResult, error) {
+	if m.delay > 0 {
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
 	return m.discovery, m.err
 }
 
+func (m *mockExplorer) TableActivity(_ context.Context) ([]port.TableActivity, error) {
+	return m.activity, m.err
+}
+
+func (m *mockExplorer) ForeignKeys(_ context.Context) (map[string][]port.ForeignKey, error) {
+	if m.fks != nil || m.err != nil {
+		return m.fks, m.err
+	}
+	fks := make(map[string][]port.ForeignKey)
+	for _, d := range m.details {
+		if len(d.ForeignKeys) > 0 {
+			fks[d.Schema+"."+d.Name] = d.ForeignKeys
+		}
+	}
+	return fks, nil
+}
+
 // --- mock QueryExecutor ---
 
 type mockExecutor struct {
 	result  []map[string]any
+	columns []port.ResultColumn // reported when the context asks for them
+	applied int                 // row limit reported when the context asks for it
 	err     error
 	lastSQL string // captures the SQL passed to Execute
+	lastCtx context.Context
 }
 
-func (m *mockExecutor) Execute(_ context.Context, sql string) ([]map[string]any, error) {
+func (m *mockExecutor) Execute(ctx context.Context, sql string) ([]map[string]any, error) {
 	m.lastSQL = sql
+	m.lastCtx = ctx
+	if cols := port.ResultColumnsFromContext(ctx); cols != nil && m.err == nil {
+		*cols = m.columns
+	}
+	if n := port.AppliedRowLimitFromContext(ctx); n != nil {
+		*n = m.applied
+	}
+	if dst := port.ExecutedSQLFromContext(ctx); dst != nil {
+		*dst = "/* executed */ " + sql
+	}
 	return m.result, m.err
 }
 
+// planExecutor answers EXPLAIN (FORMAT JSON) with a fixed row estimate for
+// filtered and unfiltered scans and a fixed total cost, recording every
+// statement it sees.
+type planExecutor struct {
+	filteredRows, totalRows int
+	totalCost               float64
+	sqls                    []string
+}
+
+func (p *planExecutor) Execute(_ context.Context, sql string) ([]map[string]any, error) {
+	p.sqls = append(p.sqls, sql)
+	rows := p.totalRows
+	if strings.Contains(sql, " WHERE ") {
+		rows = p.filteredRows
+	}
+	plan := []any{map[string]any{"Plan": map[string]any{"Node Type": "Seq Scan", "Total Cost": p.totalCost, "Plan Rows": float64(rows)}}}
+	return []map[string]any{{"QUERY PLAN": plan}}, nil
+}
+
 // --- helpers ---
 
 func callTool(t *testing.T, s *server.MCPServer, toolName string, args map[string]any) *mcp.CallToolResult {
@@ -152,6 +236,31 @@ func TestDiscover_HappyPath(t *testing.T) {
 	assert.Equal(t, "users", discovery.Schemas[0].Tables[0].Name)
 }
 
+func TestDiscover_SizeBreakdown(t *testing.T) {
+	newExplorer := func() *mockExplorer {
+		return &mockExplorer{
+			discovery: &port.DiscoveryResult{
+				Schemas: []port.SchemaOverview{{
+					Name: "public",
+					Tables: []port.TableInfo{{
+						Schema: "public", Name: "users", Type: "table", TotalBytes: 4096,
+						SizeBreakdown: &port.TableSizes{TableBytes: 2048, IndexBytes: 1024, ToastBytes: 1024},
+					}},
+				}},
+			},
+		}
+	}
+
+	result := callTool(t, setupServer(newExplorer(), nil), "discover", nil)
+	assert.NotContains(t, toolText(result), "size_breakdown")
+
+	result = callTool(t, setupServer(newExplorer(), nil), "discover", map[string]any{"size_breakdown": true})
+	var discovery port.DiscoveryResult
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &discovery))
+	assert.Equal(t, &port.TableSizes{TableBytes: 2048, IndexBytes: 1024, ToastBytes: 1024},
+		discovery.Schemas[0].Tables[0].SizeBreakdown)
+}
+
 func TestDiscover_Error(t *testing.T) {
 	explorer := &mockExplorer{err: fmt.Errorf("permission denied")}
 	s := setupServer(explorer, nil)
@@ -199,6 +308,79 @@ func TestDescribeTable_MissingTableName(t *testing.T) {
 	assert.Contains(t, toolText(result), "table_name is required")
 }
 
+func TestTableTools_RejectSuspiciousIdentifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     map[string]any
+		contains string
+	}{
+		{"semicolon in table", map[string]any{"table_name": "users; DROP TABLE users"}, "table_name: invalid identifier"},
+		{"quote breakout", map[string]any{"table_name": `users"; DROP TABLE users; --`}, "contains a semicolon"},
+		{"newline in table", map[string]any{"table_name": "users\nDROP TABLE users"}, "contains a control character"},
+		{"newline in schema", map[string]any{"table_name": "users", "schema": "public\n"}, "schema: invalid identifier"},
+	}
+	for _, tool := range []string{"describe_table", "generate_select", "filter_selectivity"} {
+		for _, tt := range tests {
+			t.Run(tool+"/"+tt.name, func(t *testing.T) {
+				args := maps.Clone(tt.args)
+				args["where"] = "id = 1"
+				result := callTool(t, setupServer(&mockExplorer{}, nil), tool, args)
+				require.True(t, result.IsError, toolText(result))
+				assert.Contains(t, toolText(result), tt.contains)
+			})
+		}
+	}
+}
+
+func TestDescribeTable_QualifiedTableName(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   map[string]any
+		schema string
+		table  string
+	}{
+		{"plain", map[string]any{"table_name": "orders"}, "", "orders"},
+		{"separate schema", map[string]any{"table_name": "orders", "schema": "sales"}, "sales", "orders"},
+		{"unquoted", map[string]any{"table_name": "sales.orders"}, "sales", "orders"},
+		{"quoted", map[string]any{"table_name": `"My Schema"."My Table"`}, "My Schema", "My Table"},
+		{"quoted dot", map[string]any{"table_name": `"v1.2"`}, "", "v1.2"},
+		{"matching schema", map[string]any{"table_name": "sales.orders", "schema": "sales"}, "sales", "orders"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explorer := &mockExplorer{detail: &port.TableDetail{Name: "orders"}}
+			result := callTool(t, setupServer(explorer, nil), "describe_table", tt.args)
+			require.False(t, result.IsError, toolText(result))
+			assert.Equal(t, tt.schema, explorer.lastSchema)
+			assert.Equal(t, tt.table, explorer.lastTable)
+		})
+	}
+}
+
+func TestTableTools_RejectBadQualifiedNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     map[string]any
+		contains string
+	}{
+		{"too deep", map[string]any{"table_name": "db.sales.orders"}, "expected schema.table"},
+		{"empty part", map[string]any{"table_name": "sales."}, "empty name part"},
+		{"unterminated quote", map[string]any{"table_name": `"sales.orders`}, "unterminated"},
+		{"schema conflict", map[string]any{"table_name": "sales.orders", "schema": "public"}, `but schema is "public"`},
+	}
+	for _, tool := range []string{"describe_table", "generate_select", "filter_selectivity"} {
+		for _, tt := range tests {
+			t.Run(tool+"/"+tt.name, func(t *testing.T) {
+				args := maps.Clone(tt.args)
+				args["where"] = "id = 1"
+				result := callTool(t, setupServer(&mockExplorer{}, nil), tool, args)
+				require.True(t, result.IsError, toolText(result))
+				assert.Contains(t, toolText(result), tt.contains)
+			})
+		}
+	}
+}
+
 func TestDescribeTable_Error(t *testing.T) {
 	explorer := &mockExplorer{err: fmt.Errorf("table not found")}
 	s := setupServer(explorer, nil)
@@ -208,6 +390,222 @@ func TestDescribeTable_Error(t *testing.T) {
 	assert.Contains(t, toolText(result), "internal error")
 }
 
+func TestDescribeTable_DetailLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		want port.DetailLevel
+	}{
+		{"default full", map[string]any{"table_name": "users"}, port.DetailFull},
+		{"basic", map[string]any{"table_name": "users", "detail_level": "basic"}, port.DetailBasic},
+		{"explicit full", map[string]any{"table_name": "users", "detail_level": "full"}, port.DetailFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explorer := &mockExplorer{detail: &port.TableDetail{Name: "users"}}
+			s := setupServer(explorer, nil)
+
+			result := callTool(t, s, "describe_table", tt.args)
+			assert.False(t, result.IsError)
+			assert.Equal(t, tt.want, explorer.lastDetailLevel)
+		})
+	}
+}
+
+func TestDescribeTable_InvalidDetailLevel(t *testing.T) {
+	s := setupServer(&mockExplorer{detail: &port.TableDetail{Name: "users"}}, nil)
+
+	result := callTool(t, s, "describe_table", map[string]any{"table_name": "users", "detail_level": "verbose"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, toolText(result), "detail_level")
+}
+
+func TestDescribeTable_FormatText(t *testing.T) {
+	explorer := &mockExplorer{
+		detail: &port.TableDetail{
+			Schema: "public",
+			Name:   "orders",
+			Columns: []port.ColumnInfo{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true, DefaultValue: "nextval('orders_id_seq'::regclass)"},
+				{Name: "customer_id", DataType: "integer", IsNullable: true},
+				{Name: "status", DataType: "text"},
+			},
+			ForeignKeys: []port.ForeignKey{
+				{ConstraintName: "orders_customer_id_fkey", ColumnName: "customer_id", ReferencedTable: "customers", ReferencedColumn: "id", OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+			},
+			Indexes: []port.IndexInfo{
+				{Name: "orders_pkey", Definition: "CREATE UNIQUE INDEX orders_pkey ON public.orders USING btree (id)", IsUnique: true},
+			},
+		},
+	}
+	s := setupServer(explorer, nil)
+
+	result := callTool(t, s, "describe_table", map[string]any{"table_name": "orders", "format": "text"})
+	require.False(t, result.IsError)
+	text := toolText(result)
+
+	assert.Contains(t, text, `Table "public.orders"`)
+	for _, col := range []string{"id", "customer_id", "status"} {
+		assert.Contains(t, text, col)
+	}
+	assert.Regexp(t, `(?m)^ id\s+\| integer\s+\| not null \| nextval.* \| PK$`, text)
+	assert.Contains(t, text, "FK -> customers(id)")
+	assert.Contains(t, text, "Indexes:")
+	assert.Contains(t, text, `"orders_pkey" CREATE UNIQUE INDEX`)
+	assert.Contains(t, text, "Foreign-key constraints:")
+	assert.Contains(t, text, `"orders_customer_id_fkey" FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE`+"\n")
+}
+
+func TestDescribeTable_InvalidFormat(t *testing.T) {
+	s := setupServer(&mockExplorer{detail: &port.TableDetail{Name: "users"}}, nil)
+
+	result := callTool(t, s, "describe_table", map[string]any{"table_name": "users", "format": "yaml"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, toolText(result), "format")
+}
+
+// wideTable returns a table with n columns named col_000, col_001, ... and
+// one sample row holding a value for each.
+func wideTable(n int) *port.TableDetail {
+	detail := &port.TableDetail{Schema: "public", Name: "wide"}
+	row := make(map[string]any, n)
+	for i := range n {
+		name := fmt.Sprintf("col_%03d", i)
+		detail.Columns = append(detail.Columns, port.ColumnInfo{Name: name, DataType: "integer"})
+		row[name] = i
+	}
+	detail.SampleRows = []map[string]any{row}
+	return detail
+}
+
+func TestDescribeTable_WideTableDefaultsToAllColumns(t *testing.T) {
+	s := setupServer(&mockExplorer{detail: wideTable(400)}, nil)
+
+	result := callTool(t, s, "describe_table", map[string]any{"table_name": "wide"})
+	require.False(t, result.IsError, toolText(result))
+	var detail port.TableDetail
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &detail))
+	assert.Len(t, detail.Columns, 400)
+	assert.Zero(t, detail.TotalColumns, "nothing was left out")
+}
+
+func TestDescribeTable_ColumnSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		want []string
+	}{
+		{"max_columns", map[string]any{"max_columns": float64(3)}, []string{"col_000", "col_001", "col_002"}},
+		{"pattern", map[string]any{"column_name_pattern": "^col_1[0-9]9$"}, []string{"col_109", "col_119", "col_129", "col_139", "col_149", "col_159", "col_169", "col_179", "col_189", "col_199"}},
+		{"pattern and max_columns", map[string]any{"column_name_pattern": "5$", "max_columns": float64(2)}, []string{"col_005", "col_015"}},
+		{"no match", map[string]any{"column_name_pattern": "^email$"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := setupServer(&mockExplorer{detail: wideTable(400)}, nil)
+			args := map[string]any{"table_name": "wide"}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+
+			result := callTool(t, s, "describe_table", args)
+			require.False(t, result.IsError, toolText(result))
+			var detail port.TableDetail
+			require.NoError(t, json.Unmarshal([]byte(toolText(result)), &detail))
+
+			names := []string{}
+			for _, col := range detail.Columns {
+				names = append(names, col.Name)
+			}
+			assert.Equal(t, tt.want, names)
+			assert.Equal(t, 400, detail.TotalColumns)
+			require.Len(t, detail.SampleRows, 1)
+			assert.Len(t, detail.SampleRows[0], len(tt.want), "sample rows are trimmed to the selected columns")
+		})
+	}
+}
+
+func TestDescribeTable_InvalidColumnSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"bad pattern", map[string]any{"column_name_pattern": "col_("}, "column_name_pattern"},
+		{"zero max_columns", map[string]any{"max_columns": float64(0)}, "max_columns"},
+		{"fractional max_columns", map[string]any{"max_columns": 2.5}, "max_columns"},
+		{"string max_columns", map[string]any{"max_columns": "10"}, "max_columns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := setupServer(&mockExplorer{detail: wideTable(10)}, nil)
+			args := map[string]any{"table_name": "wide"}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+
+			result := callTool(t, s, "describe_table", args)
+			assert.True(t, result.IsError)
+			assert.Contains(t, toolText(result), tt.want)
+		})
+	}
+}
+
+func TestFilterSelectivity_HappyPath(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	exec := &planExecutor{filteredRows: 25, totalRows: 1000}
+	querySvc := service.NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, logger, nil, nil, nil)
+	s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+	RegisterTools(s, &mockExplorer{}, querySvc, logger)
+
+	result := callTool(t, s, "filter_selectivity", map[string]any{
+		"table_name": "orders",
+		"schema":     "sales",
+		"where":      "status = 'paid'",
+	})
+	require.False(t, result.IsError, toolText(result))
+
+	var resp filterSelectivityResponse
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+	assert.Equal(t, int64(25), resp.EstimatedRows)
+	assert.Equal(t, int64(1000), resp.EstimatedTotalRows)
+	assert.InDelta(t, 0.025, resp.Selectivity, 1e-9)
+
+	require.Len(t, exec.sqls, 2)
+	assert.Equal(t, `EXPLAIN (FORMAT JSON) SELECT 1 FROM "sales"."orders" WHERE status = 'paid'`, exec.sqls[0])
+	assert.Equal(t, `EXPLAIN (FORMAT JSON) SELECT 1 FROM "sales"."orders"`, exec.sqls[1])
+}
+
+func TestFilterSelectivity_InvalidPredicate(t *testing.T) {
+	for _, where := range []string{"true; DROP TABLE orders", "true UNION SELECT 1", "1 ORDER BY 1"} {
+		t.Run(where, func(t *testing.T) {
+			exec := &mockExecutor{}
+			s := setupServer(&mockExplorer{}, exec)
+
+			result := callTool(t, s, "filter_selectivity", map[string]any{"table_name": "orders", "where": where})
+			assert.True(t, result.IsError)
+			assert.Contains(t, toolText(result), "invalid where")
+			assert.Empty(t, exec.lastSQL, "nothing should reach the executor")
+		})
+	}
+}
+
+func TestFilterSelectivity_MissingArgs(t *testing.T) {
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"where": "true"}, "table_name is required"},
+		{map[string]any{"table_name": "orders"}, "where is required"},
+	}
+	for _, tt := range tests {
+		s := setupServer(&mockExplorer{}, &mockExecutor{})
+		result := callTool(t, s, "filter_selectivity", tt.args)
+		assert.True(t, result.IsError)
+		assert.Contains(t, toolText(result), tt.want)
+	}
+}
+
 func TestQuery_HappyPath(t *testing.T) {
 	executor := &mockExecutor{
 		result: []map[string]any{{"id": 1, "name": "alice"}},
@@ -269,54 +667,1669 @@ func TestQuery_WithExplainAnalyze(t *testing.T) {
 	assert.Equal(t, "EXPLAIN ANALYZE SELECT id FROM users", executor.lastSQL)
 }
 
-func TestQuery_ValidationErrorPassthrough(t *testing.T) {
-	executor := &mockExecutor{}
+func TestQuery_WithExplainAnalyzeTimingOff(t *testing.T) {
+	executor := &mockExecutor{
+		result: []map[string]any{{"QUERY PLAN": "Seq Scan on users (actual rows=1 loops=1)"}},
+	}
 	s := setupServer(&mockExplorer{}, executor)
 
-	result := callTool(t, s, "query", map[string]any{"sql": "DROP TABLE users"})
-	assert.True(t, result.IsError)
-	assert.Contains(t, toolText(result), "only SELECT queries are allowed")
+	result := callTool(t, s, "query", map[string]any{
+		"sql":     "SELECT id FROM users",
+		"explain": true,
+		"analyze": true,
+		"timing":  false,
+	})
+	assert.False(t, result.IsError)
+	assert.Equal(t, "EXPLAIN (ANALYZE, TIMING OFF) SELECT id FROM users", executor.lastSQL)
 }
 
-// --- sanitizeError tests ---
-
-func TestSanitizeError_ValidationPassthrough(t *testing.T) {
+// setupAnalyzeGuardServer registers the tools over a planExecutor with the
+// analyze cost guard set to maxCost.
+func setupAnalyzeGuardServer(exec *planExecutor, maxCost float64) *server.MCPServer {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	querySvc := service.NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, logger, nil, nil, nil)
+	s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+	RegisterTools(s, &mockExplorer{}, querySvc, logger, WithAnalyzeMaxCost(maxCost))
+	return s
+}
+
+func TestQuery_AnalyzeWithinCostLimit(t *testing.T) {
+	exec := &planExecutor{totalRows: 10, totalCost: 120}
+	s := setupAnalyzeGuardServer(exec, 1000)
+
+	result := callTool(t, s, "query", map[string]any{
+		"sql":     "SELECT id FROM users",
+		"explain": true,
+		"analyze": true,
+	})
+	require.False(t, result.IsError, toolText(result))
+	assert.Equal(t, []string{
+		"EXPLAIN (FORMAT JSON) SELECT id FROM users",
+		"EXPLAIN ANALYZE SELECT id FROM users",
+	}, exec.sqls)
+}
+
+func TestQuery_AnalyzeOverCostLimit(t *testing.T) {
+	exec := &planExecutor{totalRows: 5000000, totalCost: 182340}
+	s := setupAnalyzeGuardServer(exec, 50000)
+
+	result := callTool(t, s, "query", map[string]any{
+		"sql":     "SELECT id FROM orders",
+		"explain": true,
+		"analyze": true,
+	})
+	require.False(t, result.IsError, toolText(result))
+
+	var resp analyzeRefusedResponse
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+	assert.Contains(t, resp.Note, "analyze skipped")
+	assert.Contains(t, resp.Note, "182340.00")
+	assert.Len(t, resp.Plan, 1)
+	assert.Equal(t, []string{
+		"EXPLAIN (FORMAT JSON) SELECT id FROM orders",
+		"EXPLAIN SELECT id FROM orders",
+	}, exec.sqls, "the query must never run under ANALYZE")
+}
+
+func TestQuery_CostLimitIgnoredWithoutAnalyze(t *testing.T) {
+	exec := &planExecutor{totalCost: 182340}
+	s := setupAnalyzeGuardServer(exec, 50000)
+
+	result := callTool(t, s, "query", map[string]any{
+		"sql":     "SELECT id FROM orders",
+		"explain": true,
+	})
+	require.False(t, result.IsError, toolText(result))
+	assert.Equal(t, []string{"EXPLAIN SELECT id FROM orders"}, exec.sqls)
+}
+
+func TestQuery_Limit(t *testing.T) {
+	executor := &mockExecutor{result: []map[string]any{{"id": 1}}}
+	s := setupServer(&mockExplorer{}, executor)
+
+	result := callTool(t, s, "query", map[string]any{"sql": "SELECT id FROM users", "limit": 5000})
+	assert.False(t, result.IsError)
+
+	// The handler forwards the request; the executor clamps it to its ceiling.
+	n, ok := port.MaxRowsFromContext(executor.lastCtx)
+	assert.True(t, ok)
+	assert.Equal(t, 5000, n)
+}
+
+func TestQuery_NoLimit(t *testing.T) {
+	executor := &mockExecutor{result: []map[string]any{{"id": 1}}}
+	s := setupServer(&mockExplorer{}, executor)
+
+	callTool(t, s, "query", map[string]any{"sql": "SELECT id FROM users"})
+
+	_, ok := port.MaxRowsFromContext(executor.lastCtx)
+	assert.False(t, ok)
+}
+
+func TestQuery_InvalidLimit(t *testing.T) {
+	for _, limit := range []any{0, -3, 2.5, "10"} {
+		executor := &mockExecutor{}
+		s := setupServer(&mockExplorer{}, executor)
+
+		result := callTool(t, s, "query", map[string]any{"sql": "SELECT 1", "limit": limit})
+		assert.True(t, result.IsError, "limit %v", limit)
+		assert.Contains(t, toolText(result), "limit must be a positive integer")
+		assert.Empty(t, executor.lastSQL)
+	}
+}
+
+func TestQuery_Timeout(t *testing.T) {
+	executor := &mockExecutor{result: []map[string]any{{"id": 1}}}
+	s := setupServer(&mockExplorer{}, executor)
+
+	result := callTool(t, s, "query", map[string]any{"sql": "SELECT id FROM users", "timeout": "2h"})
+	assert.False(t, result.IsError)
+
+	// The handler forwards the request; the executor clamps it to its bounds.
+	d, ok := port.QueryTimeoutFromContext(executor.lastCtx)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Hour, d)
+}
+
+func TestQuery_InvalidTimeout(t *testing.T) {
+	for _, timeout := range []any{"", "soon", "-5s", "0s", 30} {
+		executor := &mockExecutor{}
+		s := setupServer(&mockExplorer{}, executor)
+
+		result := callTool(t, s, "query", map[string]any{"sql": "SELECT 1", "timeout": timeout})
+		assert.True(t, result.IsError, "timeout %v", timeout)
+		assert.Contains(t, toolText(result), "timeout must be a positive duration")
+		assert.Empty(t, executor.lastSQL)
+	}
+}
+
+func TestQuery_IncludeSchema_EmptyResult(t *testing.T) {
+	executor := &mockExecutor{columns: []port.ResultColumn{
+		{Name: "id", Type: "integer", TypeOID: 23},
+		{Name: "price", Type: "numeric(10,2)", TypeOID: 1700},
+	}}
+	s := setupServer(&mockExplorer{}, executor)
+
+	result := callTool(t, s, "query", map[string]any{
+		"sql":            "SELECT id, price FROM products WHERE false",
+		"include_schema": true,
+	})
+	require.False(t, result.IsError, toolText(result))
+	assert.JSONEq(t, `{
+		"columns": [
+			{"name": "id", "type": "integer", "type_oid": 23},
+			{"name": "price", "type": "numeric(10,2)", "type_oid": 1700}
+		],
+		"rows": []
+	}`, toolText(result))
+}
+
+func TestQuery_WithoutIncludeSchema(t *testing.T) {
+	executor := &mockExecutor{result: []map[string]any{{"id": 1}}, columns: []port.ResultColumn{{Name: "id"}}}
+	s := setupServer(&mockExplorer{}, executor)
+
+	result := callTool(t, s, "query", map[string]any{"sql": "SELECT id FROM users"})
+	require.False(t, result.IsError, toolText(result))
+	assert.JSONEq(t, `[{"id": 1}]`, toolText(result))
+	assert.Nil(t, port.ResultColumnsFromContext(executor.lastCtx), "columns are only described on request")
+}
 
+func TestQuery_EmptyResult(t *testing.T) {
 	tests := []struct {
-		name     string
-		err      error
-		contains string
+		name string
+		args map[string]any
 	}{
-		{"empty query", domain.ErrEmptyQuery, "empty query"},
-		{"not allowed", domain.ErrNotAllowed, "only SELECT"},
-		{"multi statement", domain.ErrMultiStatement, "multiple statements"},
-		{"parse error", fmt.Errorf("%w: syntax error", domain.ErrParseFailed), "failed to parse SQL"},
+		{"query", map[string]any{"sql": "SELECT id FROM users WHERE false"}},
+		{"explain", map[string]any{"sql": "SELECT id FROM users WHERE false", "explain": true}},
+		{"explain analyze", map[string]any{"sql": "SELECT id FROM users WHERE false", "explain": true, "analyze": true}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			msg := sanitizeError(logger, tt.err, "query")
-			assert.Contains(t, msg, tt.contains)
+			s := setupServer(&mockExplorer{}, &mockExecutor{})
+
+			result := callTool(t, s, "query", tt.args)
+			require.False(t, result.IsError, toolText(result))
+			assert.Equal(t, "[]", toolText(result), "zero rows encode as an empty array, not null")
 		})
 	}
 }
 
-func TestSanitizeError_Timeout(t *testing.T) {
+func TestQuery_IncludeTiming(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	newServer := func(exec *mockExecutor, opts ...ToolOption) *server.MCPServer {
+		querySvc := service.NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, logger, nil, nil, nil)
+		s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+		RegisterTools(s, &mockExplorer{}, querySvc, logger, opts...)
+		return s
+	}
+	ordersTool := CustomTool{Name: "all_orders", Description: "All orders.", SQL: "SELECT id FROM orders"}
 
-	msg := sanitizeError(logger, context.DeadlineExceeded, "query")
-	assert.Contains(t, msg, "query timed out")
+	tests := []struct {
+		name string
+		tool string
+		args map[string]any
+		keys []string
+	}{
+		{"rows are wrapped", "query", map[string]any{"sql": "SELECT id FROM users"}, []string{"rows", "timing"}},
+		{"with schema", "query", map[string]any{"sql": "SELECT id FROM users", "include_schema": true}, []string{"columns", "rows", "timing"}},
+		{"paginated", "query", map[string]any{"sql": "SELECT id FROM users ORDER BY id", "paginate": true}, []string{"rows", "timing"}},
+		{"custom tool", "all_orders", nil, []string{"rows", "timing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := &mockExecutor{result: []map[string]any{{"id": 1}}, columns: []port.ResultColumn{{Name: "id"}}}
+			s := newServer(exec, WithIncludeTiming(true), WithCustomTools([]CustomTool{ordersTool}))
 
-	pgErr := &pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"}
-	msg = sanitizeError(logger, pgErr, "query")
-	assert.Contains(t, msg, "query timed out")
+			result := callTool(t, s, tt.tool, tt.args)
+			require.False(t, result.IsError, toolText(result))
+			var resp map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+			var keys []string
+			for k := range resp {
+				keys = append(keys, k)
+			}
+			assert.ElementsMatch(t, tt.keys, keys)
+
+			var timing service.Timing
+			require.NoError(t, json.Unmarshal(resp["timing"], &timing))
+			assert.JSONEq(t, `[{"id": 1}]`, string(resp["rows"]))
+			assert.Contains(t, string(resp["timing"]), `"validation_ms"`)
+			assert.Contains(t, string(resp["timing"]), `"execution_ms"`)
+			assert.Contains(t, string(resp["timing"]), `"masking_ms"`)
+		})
+	}
+
+	t.Run("off by default", func(t *testing.T) {
+		s := newServer(&mockExecutor{result: []map[string]any{{"id": 1}}})
+		result := callTool(t, s, "query", map[string]any{"sql": "SELECT id FROM users"})
+		require.False(t, result.IsError, toolText(result))
+		assert.JSONEq(t, `[{"id": 1}]`, toolText(result))
+	})
 }
 
-func TestSanitizeError_Generic(t *testing.T) {
+func TestQuery_ExecutedSQL(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	newServer := func(exec *mockExecutor, opts ...ToolOption) *server.MCPServer {
+		querySvc := service.NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, logger, nil, nil, nil)
+		s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+		RegisterTools(s, &mockExplorer{}, querySvc, logger, opts...)
+		return s
+	}
 
-	msg := sanitizeError(logger, fmt.Errorf("unexpected pg error: relation OID 12345"), "describe table")
-	assert.Contains(t, msg, "internal error")
-	assert.Contains(t, msg, "check server logs")
-	assert.NotContains(t, msg, "OID")
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"rows are wrapped", map[string]any{"sql": "SELECT id FROM users"}, `{
+			"rows": [{"id": 1}],
+			"executed_sql": "/* executed */ SELECT id FROM users"
+		}`},
+		{"explain", map[string]any{"sql": "SELECT id FROM users", "explain": true}, `{
+			"rows": [{"id": 1}],
+			"executed_sql": "/* executed */ EXPLAIN SELECT id FROM users"
+		}`},
+		{"paginated", map[string]any{"sql": "SELECT id FROM users ORDER BY id", "paginate": true}, `{
+			"rows": [{"id": 1}],
+			"executed_sql": "/* executed */ SELECT id FROM users ORDER BY id"
+		}`},
+		{"with schema", map[string]any{"sql": "SELECT id FROM users", "include_schema": true}, `{
+			"columns": [{"name": "id", "type": "integer", "type_oid": 23}],
+			"rows": [{"id": 1}],
+			"executed_sql": "/* executed */ SELECT id FROM users"
+		}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := &mockExecutor{
+				result:  []map[string]any{{"id": 1}},
+				columns: []port.ResultColumn{{Name: "id", Type: "integer", TypeOID: 23}},
+			}
+			result := callTool(t, newServer(exec, WithExecutedSQL(true)), "query", tt.args)
+			require.False(t, result.IsError, toolText(result))
+			assert.JSONEq(t, tt.want, toolText(result))
+		})
+	}
+
+	t.Run("off by default", func(t *testing.T) {
+		s := newServer(&mockExecutor{result: []map[string]any{{"id": 1}}})
+		result := callTool(t, s, "query", map[string]any{"sql": "SELECT id FROM users"})
+		require.False(t, result.IsError, toolText(result))
+		assert.JSONEq(t, `[{"id": 1}]`, toolText(result))
+	})
+}
+
+func TestQuery_Paginate(t *testing.T) {
+	executor := &mockExecutor{result: []map[string]any{{"id": 1}, {"id": 2}}, applied: 2}
+	s := setupServer(&mockExplorer{}, executor)
+
+	result := callToolE2E(t, s, "query", map[string]any{
+		"sql":      "SELECT id FROM users ORDER BY id",
+		"paginate": true,
+		"limit":    2,
+	})
+	require.False(t, result.IsError, toolText(result))
+	var page queryPage
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &page))
+	assert.Len(t, page.Rows, 2)
+	require.NotEmpty(t, page.NextPageToken, "a full page has a next page token")
+	assert.Equal(t, "SELECT id FROM users ORDER BY id", executor.lastSQL)
+
+	// The token replays the query and limit at the next offset.
+	executor.result = []map[string]any{{"id": 3}}
+	result = callToolE2E(t, s, "query", map[string]any{"page_token": page.NextPageToken})
+	require.False(t, result.IsError, toolText(result))
+	assert.JSONEq(t, `{"rows": [{"id": 3}]}`, toolText(result), "a short page is the last")
+	assert.Equal(t, "SELECT id FROM users ORDER BY id OFFSET 2", executor.lastSQL)
+	n, ok := port.MaxRowsFromContext(executor.lastCtx)
+	assert.True(t, ok)
+	assert.Equal(t, 2, n)
+}
+
+func TestPageTokens_RandomKeyWhenUnset(t *testing.T) {
+	token, err := newPageTokens(nil).encode(pageCursor{SQL: "SELECT id FROM users", Offset: 10})
+	require.NoError(t, err)
+
+	_, err = newPageTokens(nil).decode(token)
+	assert.ErrorIs(t, err, errInvalidPageToken, "each unkeyed signer draws its own key")
+	_, err = newPageTokens([]byte{}).decode(token)
+	assert.ErrorIs(t, err, errInvalidPageToken, "an empty key is not used as is")
+}
+
+func TestQuery_PageTokenRejected(t *testing.T) {
+	executor := &mockExecutor{result: []map[string]any{{"id": 1}}, applied: 1}
+	s := setupServer(&mockExplorer{}, executor)
+
+	result := callToolE2E(t, s, "query", map[string]any{"sql": "SELECT id FROM users", "paginate": true})
+	require.False(t, result.IsError, toolText(result))
+	var page queryPage
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &page))
+	require.NotEmpty(t, page.NextPageToken)
+
+	payload, mac, _ := strings.Cut(page.NextPageToken, ".")
+	forged, err := newPageTokens([]byte("another key")).encode(pageCursor{SQL: "SELECT id FROM users", Offset: 1000})
+	require.NoError(t, err)
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	for name, token := range map[string]string{
+		"other key":        forged,
+		"swapped payload":  forgedPayload + "." + mac,
+		"truncated mac":    payload + "." + mac[:len(mac)-2],
+		"no mac":           payload,
+		"not base64":       "!!." + mac,
+		"unrelated string": "page-2",
+	} {
+		executor.lastSQL = ""
+		result := callToolE2E(t, s, "query", map[string]any{"page_token": token})
+		assert.True(t, result.IsError, name)
+		assert.Contains(t, toolText(result), "page_token is invalid", name)
+		assert.Empty(t, executor.lastSQL, name)
+	}
+
+	result = callToolE2E(t, s, "query", map[string]any{"page_token": page.NextPageToken, "sql": "SELECT secret FROM keys"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, toolText(result), "page_token cannot be combined with sql or limit")
+}
+
+func TestQuery_PaginateRefused(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"own limit", map[string]any{"sql": "SELECT id FROM users LIMIT 5", "paginate": true},
+			"paginate needs a single SELECT without its own LIMIT or OFFSET"},
+		{"explain", map[string]any{"sql": "SELECT id FROM users", "paginate": true, "explain": true},
+			"paginate cannot be combined with explain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &mockExecutor{}
+			s := setupServer(&mockExplorer{}, executor)
+
+			result := callTool(t, s, "query", tt.args)
+			assert.True(t, result.IsError)
+			assert.Contains(t, toolText(result), tt.want)
+			assert.Empty(t, executor.lastSQL)
+		})
+	}
+}
+
+func TestQuery_ValidationErrorPassthrough(t *testing.T) {
+	executor := &mockExecutor{}
+	s := setupServer(&mockExplorer{}, executor)
+
+	result := callTool(t, s, "query", map[string]any{"sql": "DROP TABLE users"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, toolText(result), "only SELECT queries are allowed")
+}
+
+// --- sanitizeError tests ---
+
+func TestSanitizeError_ValidationPassthrough(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name     string
+		err      error
+		contains string
+	}{
+		{"empty query", domain.ErrEmptyQuery, "empty query"},
+		{"not allowed", domain.ErrNotAllowed, "only SELECT"},
+		{"rejected statement", &domain.RejectedStatementError{Kind: "UPDATE", Suggestion: "wrap as a SELECT"}, "detected UPDATE statement; wrap as a SELECT"},
+		{"multi statement", domain.ErrMultiStatement, "multiple statements"},
+		{"parse error", fmt.Errorf("%w: syntax error", domain.ErrParseFailed), "failed to parse SQL"},
+		{"bad identifier", domain.ValidIdentifier("users;"), "invalid identifier"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := sanitizeError(logger, tt.err, "query")
+			assert.Contains(t, msg, tt.contains)
+		})
+	}
+}
+
+func TestSanitizeError_Timeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	msg := sanitizeError(logger, context.DeadlineExceeded, "query")
+	assert.Contains(t, msg, "query timed out")
+
+	pgErr := &pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"}
+	msg = sanitizeError(logger, pgErr, "query")
+	assert.Contains(t, msg, "query timed out")
+}
+
+func TestSanitizeError_Generic(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	msg := sanitizeError(logger, fmt.Errorf("unexpected pg error: relation OID 12345"), "describe table")
+	assert.Contains(t, msg, "internal error")
+	assert.Contains(t, msg, "check server logs")
+	assert.NotContains(t, msg, "OID")
+}
+
+func TestSanitizeError_NeverReturnsPgErrorDetail(t *testing.T) {
+	const secret = "alice@example.com"
+	codes := map[string]string{
+		"unique violation":       "23505",
+		"insufficient privilege": "42501",
+		"undefined table":        "42P01",
+		"serialization failure":  "40001",
+		"statement timeout":      "57014",
+		"internal":               "XX000",
+	}
+	for name, code := range codes {
+		t.Run(name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+			pgErr := &pgconn.PgError{
+				Code:    code,
+				Message: "permission denied for table users",
+				Detail:  "Key (email)=(" + secret + ") already exists.",
+				Hint:    "Remove " + secret + " first.",
+				Where:   "PL/pgSQL function check_email('" + secret + "')",
+			}
+
+			msg := sanitizeError(logger, fmt.Errorf("executing query: %w", pgErr), "query")
+			assert.NotContains(t, msg, secret)
+			assert.NotContains(t, msg, "Key (email)")
+
+			// Detail that survives the executor is kept for the server log.
+			assert.Contains(t, logs.String(), "pg.detail=")
+			assert.Contains(t, logs.String(), "pg.code="+code)
+		})
+	}
+}
+
+func TestSanitizeError_OmitsEmptyPgErrorDetail(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	sanitizeError(logger, &pgconn.PgError{Code: "XX000", Message: "could not read block"}, "query")
+	assert.Contains(t, logs.String(), "pg.code=XX000")
+	assert.NotContains(t, logs.String(), "pg.detail")
+	assert.NotContains(t, logs.String(), "pg.hint")
+	assert.NotContains(t, logs.String(), "pg.where")
+}
+
+func TestSanitizeError_Concurrency(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			"deadlock",
+			&pgconn.PgError{Code: "40P01", Message: "deadlock detected", Detail: "Process 4242 waits for ShareLock on transaction 991"},
+			"query: query canceled due to a deadlock; retry may help",
+		},
+		{
+			"lock timeout",
+			&pgconn.PgError{Code: "55P03", Message: "canceling statement due to lock timeout"},
+			"query: query canceled due to lock wait; retry may help",
+		},
+		{
+			"serialization failure wrapped",
+			fmt.Errorf("executing query: %w", &pgconn.PgError{Code: "40001", Message: "could not serialize access due to concurrent update"}),
+			"query: query canceled due to serialization conflict; retry may help",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := sanitizeError(logger, tt.err, "query")
+			assert.Equal(t, tt.want, msg)
+			assert.NotContains(t, msg, "4242", "details stay in the server logs")
+		})
+	}
+}
+
+func TestSanitizeError_Permission(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			"table privilege",
+			&pgconn.PgError{Code: "42501", Message: "permission denied for table payroll"},
+			"query: permission denied for table payroll",
+		},
+		{
+			"schema privilege wrapped",
+			fmt.Errorf("executing query: %w", &pgconn.PgError{Code: "42501", Message: "permission denied for schema finance"}),
+			"query: permission denied for schema finance",
+		},
+		{
+			"other privilege message",
+			&pgconn.PgError{Code: "42501", Message: "must be owner of table payroll"},
+			"query: permission denied",
+		},
+		{
+			"relation not visible",
+			&pgconn.PgError{Code: "42P01", Message: `relation "payroll" does not exist`},
+			`query: relation "payroll" does not exist (or the server role cannot see it)`,
+		},
+		{
+			"schema not visible",
+			&pgconn.PgError{Code: "3F000", Message: `schema "finance" does not exist`},
+			`query: schema "finance" does not exist (or the server role cannot see it)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sanitizeError(logger, tt.err, "query"))
+		})
+	}
+}
+
+func TestSanitizeError_OtherPgErrorStaysGeneric(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	msg := sanitizeError(logger, &pgconn.PgError{Code: "XX000", Message: "could not read block 7 in file base/16384/2619"}, "query")
+	assert.Contains(t, msg, "internal error")
+	assert.NotContains(t, msg, "block")
+}
+
+// rpc sends a single JSON-RPC request on a fresh session and returns the raw result.
+func rpc(t *testing.T, s *server.MCPServer, session, method string, params map[string]any) json.RawMessage {
+	t.Helper()
+	ctx := s.WithContext(context.Background(), server.NewInProcessSession(session, nil))
+	reqBytes, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	respBytes, _ := json.Marshal(s.HandleMessage(ctx, reqBytes))
+
+	var resp struct {
+		Result json.RawMessage           `json:"result"`
+		Error  *struct{ Message string } `json:"error,omitempty"`
+	}
+	require.NoError(t, json.Unmarshal(respBytes, &resp))
+	require.Nil(t, resp.Error, "unexpected RPC error: %v", resp.Error)
+	return resp.Result
+}
+
+func TestTableResources(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	explorer := &mockExplorer{detail: &port.TableDetail{
+		Schema:  "public",
+		Name:    "users",
+		Columns: []port.ColumnInfo{{Name: "id", DataType: "integer", IsPrimaryKey: true}},
+	}}
+	s := server.NewMCPServer("test", "0.1.0")
+	RegisterTools(s, explorer, nil, logger, WithTableResources([]port.TableInfo{
+		{Schema: "public", Name: "users", Comment: "Registered users"},
+		{Schema: "sales", Name: "orders"},
+	}))
+
+	var list mcp.ListResourcesResult
+	require.NoError(t, json.Unmarshal(rpc(t, s, "list", "resources/list", nil), &list))
+	require.Len(t, list.Resources, 2)
+	uris := map[string]mcp.Resource{}
+	for _, r := range list.Resources {
+		uris[r.URI] = r
+	}
+	require.Contains(t, uris, "isthmus://tables/public/users")
+	require.Contains(t, uris, "isthmus://tables/sales/orders")
+	assert.Equal(t, "public.users", uris["isthmus://tables/public/users"].Name)
+	assert.Equal(t, "Registered users", uris["isthmus://tables/public/users"].Description)
+
+	raw := rpc(t, s, "read", "resources/read", map[string]any{"uri": "isthmus://tables/public/users"})
+	var read struct {
+		Contents []mcp.TextResourceContents `json:"contents"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &read))
+	require.Len(t, read.Contents, 1)
+	assert.Equal(t, "application/json", read.Contents[0].MIMEType)
+
+	var detail port.TableDetail
+	require.NoError(t, json.Unmarshal([]byte(read.Contents[0].Text), &detail))
+	assert.Equal(t, "users", detail.Name)
+	assert.Equal(t, port.DetailBasic, explorer.lastDetailLevel, "resources use the cheap structural view")
+}
+
+func TestTableResources_NotRegisteredByDefault(t *testing.T) {
+	s := setupServer(&mockExplorer{}, nil)
+
+	var initResult mcp.InitializeResult
+	require.NoError(t, json.Unmarshal(rpc(t, s, "init", "initialize", map[string]any{
+		"protocolVersion": "2025-03-26",
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "test", "version": "1.0"},
+	}), &initResult))
+	assert.Nil(t, initResult.Capabilities.Resources, "resources capability should not be advertised")
+}
+
+func TestServerInfo_HappyPath(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+	RegisterTools(s, &mockExplorer{err: fmt.Errorf("database must not be touched")}, nil, logger,
+		WithServerInfo(ServerInfo{
+			Version:       "1.2.3",
+			StartedAt:     time.Now().Add(-90 * time.Second),
+			Transport:     "stdio",
+			ReadOnly:      true,
+			MaxRows:       100,
+			QueryTimeout:  "10s",
+			Schemas:       []string{"public", "analytics"},
+			PolicyActive:  true,
+			MaskingActive: true,
+		}),
+	)
+
+	result := callTool(t, s, "server_info", map[string]any{})
+	assert.False(t, result.IsError)
+
+	var info map[string]any
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &info))
+	assert.Equal(t, "1.2.3", info["version"])
+	assert.Equal(t, "stdio", info["transport"])
+	assert.Equal(t, true, info["read_only"])
+	assert.Equal(t, false, info["explain_only"])
+	assert.Equal(t, []any{"public", "analytics"}, info["schemas"])
+	assert.Equal(t, true, info["policy_active"])
+	assert.Equal(t, true, info["masking_active"])
+	assert.GreaterOrEqual(t, info["uptime_seconds"], float64(90))
+	assert.NotEmpty(t, info["uptime"])
+	assert.NotContains(t, info, "replication", "no reporter configured")
+}
+
+// stubReplication returns a fixed replication status.
+type stubReplication struct {
+	status *port.ReplicationStatus
+	err    error
+}
+
+func (s stubReplication) ReplicationStatus(_ context.Context) (*port.ReplicationStatus, error) {
+	return s.status, s.err
+}
+
+func TestServerInfo_Replication(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	lag := 4.5
+	replayedAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		reporter stubReplication
+		want     any
+	}{
+		{
+			"standby",
+			stubReplication{status: &port.ReplicationStatus{Role: "standby", InRecovery: true, LagSeconds: &lag, LastReplayAt: &replayedAt}},
+			map[string]any{"role": "standby", "in_recovery": true, "lag_seconds": 4.5, "last_replay_at": "2026-03-01T09:00:00Z"},
+		},
+		{
+			"primary",
+			stubReplication{status: &port.ReplicationStatus{Role: "primary"}},
+			map[string]any{"role": "primary", "in_recovery": false},
+		},
+		{
+			"error is omitted",
+			stubReplication{err: fmt.Errorf("connection refused")},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+			RegisterTools(s, &mockExplorer{}, nil, logger,
+				WithServerInfo(ServerInfo{Version: "1.2.3", StartedAt: time.Now()}),
+				WithReplicationStatus(tt.reporter),
+			)
+
+			result := callTool(t, s, "server_info", map[string]any{})
+			require.False(t, result.IsError, toolText(result))
+			var info map[string]any
+			require.NoError(t, json.Unmarshal([]byte(toolText(result)), &info))
+			assert.Equal(t, tt.want, info["replication"])
+			assert.Equal(t, "1.2.3", info["version"])
+		})
+	}
+}
+
+// stubTablespaces returns a fixed tablespace list.
+type stubTablespaces struct {
+	tablespaces []port.Tablespace
+	err         error
+}
+
+func (s stubTablespaces) Tablespaces(_ context.Context) ([]port.Tablespace, error) {
+	return s.tablespaces, s.err
+}
+
+func TestServerInfo_Tablespaces(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	size := int64(23 << 20)
+
+	tests := []struct {
+		name   string
+		lister stubTablespaces
+		want   any
+	}{
+		{
+			"sizes readable or not",
+			stubTablespaces{tablespaces: []port.Tablespace{
+				{Name: "pg_default", SizeBytes: &size, SizeHuman: "23 MB"},
+				{Name: "fast_ssd", Location: "/mnt/ssd/pg"},
+			}},
+			[]any{
+				map[string]any{"name": "pg_default", "size_bytes": float64(size), "size_human": "23 MB"},
+				map[string]any{"name": "fast_ssd", "location": "/mnt/ssd/pg"},
+			},
+		},
+		{
+			"error is omitted",
+			stubTablespaces{err: fmt.Errorf("permission denied")},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+			RegisterTools(s, &mockExplorer{}, nil, logger,
+				WithServerInfo(ServerInfo{Version: "1.2.3", StartedAt: time.Now()}),
+				WithTablespaces(tt.lister),
+			)
+
+			result := callTool(t, s, "server_info", map[string]any{})
+			require.False(t, result.IsError, toolText(result))
+			var info map[string]any
+			require.NoError(t, json.Unmarshal([]byte(toolText(result)), &info))
+			assert.Equal(t, tt.want, info["tablespaces"])
+			assert.Equal(t, "1.2.3", info["version"])
+		})
+	}
+}
+
+func TestServerInfo_NotRegisteredByDefault(t *testing.T) {
+	s := setupServer(&mockExplorer{}, nil)
+	assert.Nil(t, s.GetTool("server_info"))
+}
+
+// memorySnapshotStore keeps the last snapshot in memory.
+type memorySnapshotStore struct {
+	last *port.SizeSnapshot
+}
+
+func (m *memorySnapshotStore) Swap(_ context.Context, cur port.SizeSnapshot) (*port.SizeSnapshot, error) {
+	prev := m.last
+	m.last = &cur
+	return prev, nil
+}
+
+func TestTableGrowth_NotRegisteredByDefault(t *testing.T) {
+	s := setupServer(&mockExplorer{}, nil)
+	assert.Nil(t, s.GetTool("table_growth"))
+}
+
+func TestTableGrowth(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	explorer := &mockExplorer{tables: []port.TableInfo{
+		{Schema: "public", Name: "orders", RowEstimate: 1000, TotalBytes: 80000},
+		{Schema: "public", Name: "users", RowEstimate: 50, TotalBytes: 8192},
+	}}
+	store := &memorySnapshotStore{}
+	newServer := func() *server.MCPServer {
+		s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+		RegisterTools(s, explorer, nil, logger, WithTableGrowth(store))
+		return s
+	}
+
+	result := callTool(t, newServer(), "table_growth", map[string]any{})
+	require.False(t, result.IsError, toolText(result))
+	var first tableGrowthResponse
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &first))
+	assert.NotEmpty(t, first.Note, "first call only records a baseline")
+	assert.Nil(t, first.PreviousTakenAt)
+	require.Len(t, first.Tables, 2)
+	assert.Nil(t, first.Tables[0].BytesDelta)
+
+	explorer.tables = []port.TableInfo{
+		{Schema: "public", Name: "orders", RowEstimate: 1500, TotalBytes: 120000},
+		{Schema: "audit", Name: "events", RowEstimate: 10, TotalBytes: 8192},
+	}
+	result = callTool(t, newServer(), "table_growth", map[string]any{})
+	require.False(t, result.IsError, toolText(result))
+	var second tableGrowthResponse
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &second))
+	assert.Empty(t, second.Note)
+	require.NotNil(t, second.PreviousTakenAt)
+	require.Len(t, second.Tables, 2)
+
+	orders := second.Tables[0]
+	assert.Equal(t, "orders", orders.Name, "fastest-growing table first")
+	require.NotNil(t, orders.RowDelta)
+	assert.Equal(t, int64(500), *orders.RowDelta)
+	assert.Equal(t, int64(40000), *orders.BytesDelta)
+
+	events := second.Tables[1]
+	assert.True(t, events.New)
+	assert.Nil(t, events.BytesDelta)
+	assert.Equal(t, []string{"public.users"}, second.Dropped)
+
+	// Filtering by schema narrows the report but not the snapshot.
+	result = callTool(t, newServer(), "table_growth", map[string]any{"schema": "audit"})
+	require.False(t, result.IsError, toolText(result))
+	var third tableGrowthResponse
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &third))
+	require.Len(t, third.Tables, 1)
+	assert.Equal(t, "events", third.Tables[0].Name)
+	assert.Empty(t, third.Dropped)
+	assert.Len(t, store.last.Tables, 2)
+}
+
+func TestGenerateSelect(t *testing.T) {
+	explorer := &mockExplorer{detail: &port.TableDetail{
+		Schema: "Sales",
+		Name:   "order items",
+		Columns: []port.ColumnInfo{
+			{Name: "id", DataType: "integer"},
+			{Name: "Quantity", DataType: "integer"},
+			{Name: "select", DataType: "text"},
+		},
+	}}
+	validator := domain.NewPgQueryValidator()
+
+	tests := []struct {
+		name    string
+		args    map[string]any
+		wantSQL string
+	}{
+		{"all columns", map[string]any{}, `SELECT "id", "Quantity", "select" FROM "Sales"."order items" LIMIT 10`},
+		{"chosen columns and limit", map[string]any{"columns": []any{"select", "id"}, "limit": float64(25)}, `SELECT "select", "id" FROM "Sales"."order items" LIMIT 25`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]any{"table_name": "order items"}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+			result := callTool(t, setupServer(explorer, nil), "generate_select", args)
+			require.False(t, result.IsError, toolText(result))
+
+			var resp generateSelectResponse
+			require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+			assert.Equal(t, tt.wantSQL, resp.SQL)
+			require.NoError(t, validator.Validate(resp.SQL), "generated SQL must pass the query validator")
+			assert.Equal(t, port.DetailBasic, explorer.lastDetailLevel, "only the column list is needed")
+		})
+	}
+}
+
+func TestGenerateSelect_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		detail *port.TableDetail
+		args   map[string]any
+		want   string
+	}{
+		{"missing table", &port.TableDetail{}, map[string]any{}, "table_name is required"},
+		{"unknown column", &port.TableDetail{Name: "users", Columns: []port.ColumnInfo{{Name: "id"}}}, map[string]any{"table_name": "users", "columns": []any{"id", "ssn"}}, "unknown columns: ssn"},
+		{"bad columns", &port.TableDetail{Name: "users"}, map[string]any{"table_name": "users", "columns": "id"}, "columns must be an array"},
+		{"bad limit", &port.TableDetail{Name: "users"}, map[string]any{"table_name": "users", "limit": float64(0)}, "limit must be a positive integer"},
+		{"no columns", &port.TableDetail{Name: "users"}, map[string]any{"table_name": "users"}, "no columns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := callTool(t, setupServer(&mockExplorer{detail: tt.detail}, nil), "generate_select", tt.args)
+			assert.True(t, result.IsError)
+			assert.Contains(t, toolText(result), tt.want)
+		})
+	}
+}
+
+func TestRecentActivity(t *testing.T) {
+	explorer := &mockExplorer{activity: []port.TableActivity{
+		{Schema: "public", Name: "orders", Inserts: 120, Updates: 40, Deletes: 5, TotalChanges: 165},
+		{Schema: "audit", Name: "events", Inserts: 90, TotalChanges: 90},
+	}}
+
+	result := callTool(t, setupServer(explorer, nil), "recent_activity", map[string]any{})
+	require.False(t, result.IsError, toolText(result))
+	var all recentActivityResponse
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &all))
+	assert.Equal(t, explorer.activity, all.Tables)
+
+	result = callTool(t, setupServer(explorer, nil), "recent_activity", map[string]any{"schema": "audit"})
+	require.False(t, result.IsError, toolText(result))
+	var audit recentActivityResponse
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &audit))
+	require.Len(t, audit.Tables, 1)
+	assert.Equal(t, "events", audit.Tables[0].Name)
+}
+
+func TestRecentActivity_Error(t *testing.T) {
+	explorer := &mockExplorer{err: fmt.Errorf("relation OID 12345 vanished")}
+
+	result := callTool(t, setupServer(explorer, nil), "recent_activity", map[string]any{})
+	require.True(t, result.IsError)
+	assert.Equal(t, "recent activity: internal error (check server logs)", toolText(result))
+}
+
+func TestLintSchema(t *testing.T) {
+	explorer := &mockExplorer{
+		tables: []port.TableInfo{
+			{Schema: "public", Name: "reviews", Type: "table"},
+			{Schema: "public", Name: "review_summary", Type: "view"},
+			{Schema: "audit", Name: "events", Type: "table"},
+		},
+		detail: &port.TableDetail{
+			Schema: "public",
+			Name:   "reviews",
+			Columns: []port.ColumnInfo{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "product_id", DataType: "integer"},
+				{Name: "user_id", DataType: "integer", IsNullable: true},
+				{Name: "body", DataType: "text", IsNullable: true},
+				{Name: "status", DataType: "text", AllowedValues: []string{"draft", "published"}},
+			},
+			ForeignKeys: []port.ForeignKey{
+				{ConstraintName: "reviews_user_id_fkey", ColumnName: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+			},
+			InferredFKs: []port.InferredForeignKey{
+				{ColumnName: "product_id", ReferencedTable: "products", ReferencedColumn: "id"},
+			},
+			Indexes: []port.IndexInfo{
+				{Name: "reviews_pkey", IsUnique: true, Columns: []string{"id"}},
+			},
+			StatsAgeWarning: "No ANALYZE has been run on this table. Statistics may be missing or inaccurate.",
+		},
+	}
+
+	result := callTool(t, setupServer(explorer, nil), "lint_schema", map[string]any{"schema": "public"})
+	require.False(t, result.IsError, toolText(result))
+	var resp lintSchemaResponse
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+	assert.Equal(t, 1, resp.TablesChecked, "views and other schemas are skipped")
+	assert.Equal(t, port.DetailFull, explorer.lastDetailLevel)
+
+	type check struct{ column, check, severity string }
+	var got []check
+	for _, f := range resp.Findings {
+		assert.Equal(t, "reviews", f.Table)
+		assert.NotEmpty(t, f.Message)
+		got = append(got, check{f.Column, f.Check, f.Severity})
+	}
+	assert.Equal(t, []check{
+		{"user_id", "missing_fk_index", "warning"},
+		{"", "stale_statistics", "warning"},
+		{"product_id", "missing_fk_index", "info"},
+		{"user_id", "nullable_foreign_key", "info"},
+		{"body", "unbounded_text", "info"},
+	}, got)
+}
+
+func TestLintSchema_NoPrimaryKey(t *testing.T) {
+	explorer := &mockExplorer{
+		tables: []port.TableInfo{{Schema: "public", Name: "events", Type: "table"}},
+		detail: &port.TableDetail{
+			Schema:  "public",
+			Name:    "events",
+			Columns: []port.ColumnInfo{{Name: "payload", DataType: "jsonb"}},
+		},
+	}
+
+	result := callTool(t, setupServer(explorer, nil), "lint_schema", map[string]any{})
+	require.False(t, result.IsError, toolText(result))
+	var resp lintSchemaResponse
+	require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+	require.Len(t, resp.Findings, 1)
+	assert.Equal(t, "no_primary_key", resp.Findings[0].Check)
+	assert.Equal(t, "warning", resp.Findings[0].Severity)
+}
+
+func TestLintSchema_Error(t *testing.T) {
+	explorer := &mockExplorer{err: fmt.Errorf("relation OID 12345 vanished")}
+
+	result := callTool(t, setupServer(explorer, nil), "lint_schema", map[string]any{})
+	require.True(t, result.IsError)
+	assert.Equal(t, "lint schema: internal error (check server logs)", toolText(result))
+}
+
+func TestSchemaGraph(t *testing.T) {
+	explorer := &mockExplorer{
+		tables: []port.TableInfo{
+			{Schema: "public", Name: "users", Type: "table"},
+			{Schema: "public", Name: "orders", Type: "table"},
+			{Schema: "public", Name: "order_totals", Type: "view"},
+			{Schema: "audit", Name: "events", Type: "table"},
+		},
+		details: map[string]*port.TableDetail{
+			"users": {Schema: "public", Name: "users", Columns: []port.ColumnInfo{
+				{Name: "id", DataType: "integer", IsPrimaryKey: true},
+				{Name: "email", DataType: "text"},
+			}},
+			"orders": {
+				Schema: "public", Name: "orders",
+				Columns: []port.ColumnInfo{{Name: "id", DataType: "integer", IsPrimaryKey: true}},
+				ForeignKeys: []port.ForeignKey{
+					{ConstraintName: "orders_user_fkey", ColumnName: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+					{ConstraintName: "orders_ship_fkey", ColumnName: "ship_country", ReferencedTable: "addresses", ReferencedColumn: "country"},
+					{ConstraintName: "orders_ship_fkey", ColumnName: "ship_zip", ReferencedTable: "addresses", ReferencedColumn: "zip"},
+				},
+			},
+			"events": {
+				Schema: "audit", Name: "events",
+				ForeignKeys: []port.ForeignKey{
+					{ConstraintName: "events_order_fkey", ColumnName: "order_id", ReferencedTable: "orders", ReferencedColumn: "id"},
+				},
+				InferredFKs: []port.InferredForeignKey{
+					{ColumnName: "user_id", ReferencedTable: "public.users", ReferencedColumn: "id"},
+				},
+			},
+		},
+	}
+	s := setupServer(explorer, nil)
+
+	result := callTool(t, s, "schema_graph", nil)
+	require.False(t, result.IsError, toolText(result))
+	dot := toolText(result)
+	assert.True(t, strings.HasPrefix(dot, "digraph schema {"), dot)
+	assert.Zero(t, explorer.describes, "foreign keys alone come from one catalog query")
+	assert.Contains(t, dot, `subgraph "cluster_audit" {`)
+	assert.Contains(t, dot, `"public.users" [label="users"];`)
+	assert.Contains(t, dot, `"audit.events" [label="events"];`)
+	assert.NotContains(t, dot, "order_totals", "views are left out")
+	assert.Contains(t, dot, `"public.orders" -> "public.users" [label="user_id -> id"];`)
+	assert.Contains(t, dot, `"public.orders" -> "public.addresses" [label="ship_country, ship_zip -> country, zip"];`,
+		"multi-column keys are one edge")
+	assert.Contains(t, dot, `"audit.events" -> "public.orders" [label="order_id -> id"];`,
+		"an unqualified table found only in another schema")
+	assert.NotContains(t, dot, "inferred")
+
+	result = callToolE2E(t, s, "schema_graph", map[string]any{
+		"schema": "audit", "include_columns": true, "include_inferred": true,
+	})
+	require.False(t, result.IsError, toolText(result))
+	dot = toolText(result)
+	assert.Equal(t, port.DetailBasic, explorer.lastDetailLevel)
+	assert.Equal(t, 1, explorer.describes, "only the drawn table is described")
+	assert.NotContains(t, dot, `"public.users" [label="users`, "only the requested schema is drawn")
+	assert.Contains(t, dot, `"audit.events" -> "public.orders" [label="order_id -> id"];`)
+	assert.Contains(t, dot, `"audit.events" -> "public.users" [label="user_id -> id (inferred)", style=dashed];`)
+
+	result = callToolE2E(t, s, "schema_graph", map[string]any{"schema": "public", "include_columns": true})
+	dot = toolText(result)
+	assert.Contains(t, dot, `"public.users" [label="users\nid integer PK\lemail text\l"];`)
+	assert.Less(t, strings.Index(dot, `"public.users" [`), strings.Index(dot, `"public.orders" [`),
+		"tables described concurrently keep their order")
+}
+
+func TestSchemaGraph_Error(t *testing.T) {
+	explorer := &mockExplorer{err: fmt.Errorf("relation OID 12345 vanished")}
+
+	result := callTool(t, setupServer(explorer, nil), "schema_graph", map[string]any{})
+	require.True(t, result.IsError)
+	assert.Equal(t, "schema graph: internal error (check server logs)", toolText(result))
+}
+
+func TestToolDescriptions_Override(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+	RegisterTools(s, &mockExplorer{}, nil, logger, WithToolDescriptions(map[string]string{
+		"query": "Run read-only SQL. Customer PII must never leave the EU schema.",
+	}))
+
+	assert.Equal(t, "Run read-only SQL. Customer PII must never leave the EU schema.", s.GetTool("query").Tool.Description)
+	assert.Equal(t, descDiscover, s.GetTool("discover").Tool.Description, "tools without an override keep the default")
+}
+
+func TestValidateToolDescriptions(t *testing.T) {
+	require.NoError(t, ValidateToolDescriptions(nil))
+	require.NoError(t, ValidateToolDescriptions(map[string]string{"table_growth": "Growth since the last call."}))
+
+	err := ValidateToolDescriptions(map[string]string{"run_sql": "Run SQL."})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown tool "run_sql"`)
+
+	err = ValidateToolDescriptions(map[string]string{"query": "  "})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "empty")
+}
+
+func TestQuery_ExplainOnlyPerCall(t *testing.T) {
+	inner := &mockExecutor{result: []map[string]any{{"QUERY PLAN": "Seq Scan on users"}}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	querySvc := service.NewQueryService(domain.NewPgQueryValidator(), postgres.NewExplainOnRequestExecutor(inner), port.NoopAuditor{}, logger, nil, nil, nil)
+	newServer := func() *server.MCPServer {
+		s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+		RegisterTools(s, &mockExplorer{}, querySvc, logger)
+		return s
+	}
+
+	result := callTool(t, newServer(), "query", map[string]any{"sql": "SELECT id FROM users", "explain_only": true})
+	require.False(t, result.IsError, toolText(result))
+	assert.Equal(t, "EXPLAIN SELECT id FROM users", inner.lastSQL)
+
+	result = callTool(t, newServer(), "query", map[string]any{"sql": "SELECT id FROM users"})
+	require.False(t, result.IsError, toolText(result))
+	assert.Equal(t, "SELECT id FROM users", inner.lastSQL, "queries run normally without the flag")
+}
+
+func TestQuery_ExplainOnlyCannotDisableGlobal(t *testing.T) {
+	inner := &mockExecutor{result: []map[string]any{{"QUERY PLAN": "Seq Scan on users"}}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	querySvc := service.NewQueryService(domain.NewPgQueryValidator(), postgres.NewExplainOnlyExecutor(inner), port.NoopAuditor{}, logger, nil, nil, nil)
+	s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+	RegisterTools(s, &mockExplorer{}, querySvc, logger)
+
+	result := callTool(t, s, "query", map[string]any{"sql": "SELECT id FROM users", "explain_only": false})
+	require.False(t, result.IsError, toolText(result))
+	assert.Equal(t, "EXPLAIN SELECT id FROM users", inner.lastSQL)
+}
+
+func TestQuery_PreferReplica(t *testing.T) {
+	primary := &mockExecutor{result: []map[string]any{{"id": 1}}}
+	replica := &mockExecutor{result: []map[string]any{{"id": 1}}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	querySvc := service.NewQueryService(domain.NewPgQueryValidator(), postgres.NewReplicaRoutingExecutor(primary, replica), port.NoopAuditor{}, logger, nil, nil, nil)
+	newServer := func() *server.MCPServer {
+		s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+		RegisterTools(s, &mockExplorer{}, querySvc, logger, WithReplica(true))
+		return s
+	}
+
+	result := callTool(t, newServer(), "query", map[string]any{"sql": "SELECT id FROM users", "prefer_replica": true})
+	require.False(t, result.IsError, toolText(result))
+	assert.Contains(t, replica.lastSQL, "SELECT id FROM users")
+	assert.Empty(t, primary.lastSQL)
+
+	replica.lastSQL = ""
+	result = callTool(t, newServer(), "query", map[string]any{"sql": "SELECT id FROM users"})
+	require.False(t, result.IsError, toolText(result))
+	assert.Contains(t, primary.lastSQL, "SELECT id FROM users", "calls stay on the primary by default")
+	assert.Empty(t, replica.lastSQL)
+}
+
+func TestQuery_PreferReplicaWithoutReplica(t *testing.T) {
+	executor := &mockExecutor{}
+
+	result := callTool(t, setupServer(&mockExplorer{}, executor), "query", map[string]any{"sql": "SELECT id FROM users", "prefer_replica": true})
+	assert.True(t, result.IsError)
+	assert.Contains(t, toolText(result), "no read replica is configured")
+	assert.Empty(t, executor.lastSQL, "nothing is executed")
+
+	result = callTool(t, setupServer(&mockExplorer{}, executor), "query", map[string]any{"sql": "SELECT id FROM users", "prefer_replica": false})
+	require.False(t, result.IsError, toolText(result))
+}
+
+func TestQuery_ExplainOnlyWithAnalyzeRejected(t *testing.T) {
+	executor := &mockExecutor{}
+	s := setupServer(&mockExplorer{}, executor)
+
+	result := callTool(t, s, "query", map[string]any{
+		"sql":          "SELECT id FROM users",
+		"explain":      true,
+		"analyze":      true,
+		"explain_only": true,
+	})
+	assert.True(t, result.IsError)
+	assert.Contains(t, toolText(result), "explain_only")
+	assert.Empty(t, executor.lastSQL, "nothing is executed")
+}
+
+func TestIdleWatchdog(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	discovery := &port.DiscoveryResult{Schemas: []port.SchemaOverview{{Name: "public"}}}
+
+	t.Run("tool calls keep the server alive", func(t *testing.T) {
+		idle := NewIdleWatchdog(100*time.Millisecond, logger)
+		s := NewServer("test", &mockExplorer{discovery: discovery}, nil, logger, nil, nil, WithIdleWatchdog(idle))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stopped := make(chan struct{})
+		go idle.Run(ctx, func() { close(stopped) })
+
+		for range 5 {
+			time.Sleep(40 * time.Millisecond)
+			callToolE2E(t, s, "discover", nil)
+		}
+		select {
+		case <-stopped:
+			t.Fatal("shut down while tool calls kept arriving")
+		default:
+		}
+
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("no shutdown after the idle timeout")
+		}
+	})
+
+	t.Run("a running call is not idle", func(t *testing.T) {
+		idle := NewIdleWatchdog(30*time.Millisecond, logger)
+		s := NewServer("test", &mockExplorer{discovery: discovery, delay: 200 * time.Millisecond}, nil, logger, nil, nil,
+			WithIdleWatchdog(idle))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stopped := make(chan struct{})
+		go idle.Run(ctx, func() { close(stopped) })
+
+		result := callTool(t, s, "discover", nil)
+		require.False(t, result.IsError, toolText(result))
+		select {
+		case <-stopped:
+			t.Fatal("shut down during a tool call")
+		default:
+		}
+	})
+
+	t.Run("stdio server stops", func(t *testing.T) {
+		idle := NewIdleWatchdog(50*time.Millisecond, logger)
+		s := NewServer("test", &mockExplorer{discovery: discovery}, nil, logger, nil, nil, WithIdleWatchdog(idle))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go idle.Run(ctx, cancel)
+
+		stdin, w := io.Pipe()
+		defer w.Close()
+		done := make(chan error, 1)
+		go func() { done <- server.NewStdioServer(s).Listen(ctx, stdin, io.Discard) }()
+
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, context.Canceled)
+		case <-time.After(2 * time.Second):
+			t.Fatal("stdio server still running after the idle timeout")
+		}
+	})
+}
+
+func TestToolCallTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	discovery := &port.DiscoveryResult{Schemas: []port.SchemaOverview{{Name: "public"}}}
+
+	t.Run("slow call times out", func(t *testing.T) {
+		s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+		RegisterTools(s, &mockExplorer{discovery: discovery, delay: time.Second}, nil, logger,
+			WithToolCallTimeout(20*time.Millisecond))
+
+		start := time.Now()
+		result := callTool(t, s, "discover", nil)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "discover: tool call timed out after 20ms", toolText(result))
+	})
+
+	t.Run("fast call is unaffected", func(t *testing.T) {
+		s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+		RegisterTools(s, &mockExplorer{discovery: discovery, delay: time.Millisecond}, nil, logger,
+			WithToolCallTimeout(time.Second))
+
+		result := callTool(t, s, "discover", nil)
+		assert.False(t, result.IsError, toolText(result))
+		assert.Contains(t, toolText(result), "public")
+	})
+
+	t.Run("handler ignoring the context", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		handler := withCallTimeout("stuck", 20*time.Millisecond,
+			func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				<-release
+				return mcp.NewToolResultText("late"), nil
+			})
+
+		result, err := handler(context.Background(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, toolText(result), "stuck: tool call timed out")
+	})
+}
+
+func TestMaxArgBytes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	newServer := func(explorer *mockExplorer, executor *mockExecutor) *server.MCPServer {
+		querySvc := service.NewQueryService(domain.NewPgQueryValidator(), executor, port.NoopAuditor{}, logger, nil, nil, nil)
+		s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+		RegisterTools(s, explorer, querySvc, logger, WithMaxArgBytes(64))
+		return s
+	}
+
+	t.Run("oversized table_name", func(t *testing.T) {
+		explorer := &mockExplorer{detail: &port.TableDetail{Schema: "public", Name: "users"}}
+		s := newServer(explorer, &mockExecutor{})
+
+		result := callTool(t, s, "describe_table", map[string]any{"table_name": strings.Repeat("x", 1<<20)})
+		assert.True(t, result.IsError)
+		assert.Equal(t, `describe_table: argument "table_name" is 1048576 bytes, over the 64-byte limit`, toolText(result))
+	})
+
+	t.Run("oversized sql never reaches the executor", func(t *testing.T) {
+		executor := &mockExecutor{result: []map[string]any{{"id": 1}}}
+		s := newServer(&mockExplorer{}, executor)
+
+		result := callToolE2E(t, s, "query", map[string]any{"sql": "SELECT 1 WHERE 'a' = '" + strings.Repeat("a", 64) + "'"})
+		assert.True(t, result.IsError)
+		assert.Contains(t, toolText(result), `argument "sql"`)
+		assert.Empty(t, executor.lastSQL)
+
+		result = callToolE2E(t, s, "query", map[string]any{"sql": "SELECT id FROM users"})
+		assert.False(t, result.IsError, toolText(result))
+		assert.Equal(t, "SELECT id FROM users", executor.lastSQL)
+	})
+
+	t.Run("nested strings count", func(t *testing.T) {
+		handler := withArgLimit("custom", 8, func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("ran"), nil
+		})
+		var request mcp.CallToolRequest
+		request.Params.Arguments = map[string]any{"tags": []any{"ok", map[string]any{"note": "much too long"}}}
+
+		result, err := handler(context.Background(), request)
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, toolText(result), `argument "tags" is 13 bytes`)
+
+		request.Params.Arguments = map[string]any{"tags": []any{"ok", 12345678901.0}, "n": true}
+		result, err = handler(context.Background(), request)
+		require.NoError(t, err)
+		assert.Equal(t, "ran", toolText(result))
+	})
+}
+
+// slowDescribeExplorer holds every DescribeTable call for a moment and
+// records how many ran at once.
+type slowDescribeExplorer struct {
+	mockExplorer
+	inFlight, peak atomic.Int32
+}
+
+func (e *slowDescribeExplorer) DescribeTable(ctx context.Context, schema, table string) (*port.TableDetail, error) {
+	n := e.inFlight.Add(1)
+	defer e.inFlight.Add(-1)
+	for p := e.peak.Load(); n > p && !e.peak.CompareAndSwap(p, n); p = e.peak.Load() {
+	}
+	select {
+	case <-time.After(30 * time.Millisecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &port.TableDetail{Schema: "public", Name: table}, nil
+}
+
+func TestDescribeConcurrency(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	run := func(t *testing.T, explorer *slowDescribeExplorer, opts ...ToolOption) {
+		t.Helper()
+		s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+		RegisterTools(s, explorer, nil, logger, opts...)
+
+		var wg sync.WaitGroup
+		for i := range 6 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result := callToolE2E(t, s, "describe_table", map[string]any{"table_name": fmt.Sprintf("t%d", i)})
+				assert.False(t, result.IsError, toolText(result))
+			}()
+		}
+		wg.Wait()
+	}
+
+	t.Run("limited", func(t *testing.T) {
+		explorer := &slowDescribeExplorer{}
+		run(t, explorer, WithDescribeConcurrency(2))
+		assert.Equal(t, int32(2), explorer.peak.Load())
+	})
+
+	t.Run("unlimited by default", func(t *testing.T) {
+		explorer := &slowDescribeExplorer{}
+		run(t, explorer)
+		assert.Greater(t, explorer.peak.Load(), int32(2))
+	})
+
+	t.Run("waiting call gives up with its context", func(t *testing.T) {
+		sem := newSemaphore(1)
+		sem <- struct{}{} // the only slot is taken
+		handler := withConcurrencyLimit("describe_table", sem,
+			func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				t.Error("handler must not run without a slot")
+				return nil, nil
+			})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		result, err := handler(ctx, mcp.CallToolRequest{})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, toolText(result), "describe_table: canceled while waiting for a free slot")
+	})
+}
+
+func TestPing(t *testing.T) {
+	t.Run("reports latency", func(t *testing.T) {
+		exec := &mockExecutor{result: []map[string]any{{"?column?": 1}}}
+		result := callTool(t, setupServer(&mockExplorer{}, exec), "ping", nil)
+		require.False(t, result.IsError, toolText(result))
+
+		var resp pingResponse
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+		assert.GreaterOrEqual(t, resp.LatencyMS, 0.0)
+		assert.Equal(t, "SELECT 1", exec.lastSQL)
+	})
+
+	t.Run("database unavailable", func(t *testing.T) {
+		exec := &mockExecutor{err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+		result := callTool(t, setupServer(&mockExplorer{}, exec), "ping", nil)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "ping: database unavailable", toolText(result))
+	})
+}
+
+func TestCustomTools(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ordersSince := CustomTool{
+		Name:        "orders_since",
+		Description: "Orders placed since a given time.",
+		SQL:         "SELECT id FROM orders WHERE created_at >= $1 AND total > $2",
+		Params: []CustomToolParam{
+			{Name: "since", Type: domain.ParamTimestamp},
+			{Name: "min_total", Type: domain.ParamNumeric, Description: "Smallest order total"},
+		},
+	}
+	newServer := func(exec *mockExecutor) *server.MCPServer {
+		querySvc := service.NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, logger, nil, nil, nil)
+		s := server.NewMCPServer("test", "0.1.0", server.WithToolCapabilities(true))
+		RegisterTools(s, &mockExplorer{}, querySvc, logger, WithCustomTools([]CustomTool{ordersSince}))
+		return s
+	}
+
+	t.Run("registered with typed params", func(t *testing.T) {
+		tool := newServer(&mockExecutor{}).GetTool("orders_since")
+		require.NotNil(t, tool)
+		assert.Equal(t, "Orders placed since a given time.", tool.Tool.Description)
+		assert.ElementsMatch(t, []string{"since", "min_total"}, tool.Tool.InputSchema.Required)
+		assert.Equal(t, "number", tool.Tool.InputSchema.Properties["min_total"].(map[string]any)["type"])
+	})
+
+	t.Run("binds arguments", func(t *testing.T) {
+		exec := &mockExecutor{result: []map[string]any{{"id": 7}}}
+		result := callTool(t, newServer(exec), "orders_since", map[string]any{
+			"since":     "2024-01-01",
+			"min_total": 99.5,
+		})
+		require.False(t, result.IsError, toolText(result))
+		assert.Contains(t, toolText(result), `"id":7`)
+		assert.Contains(t, exec.lastSQL, "(E'2024-01-01'::timestamptz)")
+		assert.Contains(t, exec.lastSQL, "(E'99.5'::numeric)")
+	})
+
+	t.Run("empty result", func(t *testing.T) {
+		result := callTool(t, newServer(&mockExecutor{}), "orders_since", map[string]any{
+			"since":     "2024-01-01",
+			"min_total": 99.5,
+		})
+		require.False(t, result.IsError, toolText(result))
+		assert.Equal(t, "[]", toolText(result))
+	})
+
+	t.Run("quotes are escaped", func(t *testing.T) {
+		exec := &mockExecutor{}
+		result := callTool(t, newServer(exec), "orders_since", map[string]any{
+			"since":     "2024-01-01'); DROP TABLE orders; --",
+			"min_total": 1,
+		})
+		require.False(t, result.IsError, toolText(result))
+		assert.Contains(t, exec.lastSQL, "(E'2024-01-01''); DROP TABLE orders; --'::timestamptz)")
+	})
+
+	t.Run("missing argument", func(t *testing.T) {
+		exec := &mockExecutor{}
+		result := callTool(t, newServer(exec), "orders_since", map[string]any{"since": "2024-01-01"})
+		assert.True(t, result.IsError)
+		assert.Contains(t, toolText(result), "min_total is required")
+		assert.Empty(t, exec.lastSQL)
+	})
+
+	t.Run("wrong argument type", func(t *testing.T) {
+		exec := &mockExecutor{}
+		result := callTool(t, newServer(exec), "orders_since", map[string]any{"since": 20240101, "min_total": 1})
+		assert.True(t, result.IsError)
+		assert.Contains(t, toolText(result), "expected a string")
+		assert.Empty(t, exec.lastSQL)
+	})
+}
+
+func TestQuery_RowEstimates(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Nested Loop", "Plan Rows": 5, "Actual Rows": 4000, "Actual Loops": 1, "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "users", "Plan Rows": 5, "Actual Rows": 4000, "Actual Loops": 1},
+		{"Node Type": "Index Scan", "Relation Name": "orders", "Index Name": "idx_orders_user_id", "Plan Rows": 1, "Actual Rows": 1, "Actual Loops": 4000}
+	]}}]`
+
+	t.Run("flags misestimated nodes", func(t *testing.T) {
+		exec := &mockExecutor{result: []map[string]any{{"QUERY PLAN": plan}}}
+		result := callTool(t, setupServer(&mockExplorer{}, exec), "query", map[string]any{
+			"sql":           "SELECT o.id FROM users u JOIN orders o ON o.user_id = u.id WHERE u.country = 'ES'",
+			"explain":       true,
+			"analyze":       true,
+			"row_estimates": true,
+		})
+		require.False(t, result.IsError, toolText(result))
+		assert.True(t, strings.HasPrefix(exec.lastSQL, "EXPLAIN (ANALYZE, FORMAT JSON) "), exec.lastSQL)
+
+		var resp map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+		assert.NotContains(t, resp, "indexes_used", "index usage is only included when asked for")
+		var worst domain.Misestimate
+		require.NoError(t, json.Unmarshal(resp["worst_misestimate"], &worst))
+		assert.Equal(t, "Nested Loop", worst.NodeType)
+		assert.InDelta(t, 800, worst.Ratio, 1e-9)
+		assert.Contains(t, string(resp["suggestion"]), "ANALYZE on users")
+	})
+
+	t.Run("combined with index_usage", func(t *testing.T) {
+		exec := &mockExecutor{result: []map[string]any{{"QUERY PLAN": plan}}}
+		result := callTool(t, setupServer(&mockExplorer{}, exec), "query", map[string]any{
+			"sql":           "SELECT id FROM users",
+			"explain":       true,
+			"analyze":       true,
+			"timing":        false,
+			"index_usage":   true,
+			"row_estimates": true,
+		})
+		require.False(t, result.IsError, toolText(result))
+		assert.Equal(t, "EXPLAIN (ANALYZE, TIMING OFF, FORMAT JSON) SELECT id FROM users", exec.lastSQL)
+		var resp map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+		assert.Contains(t, resp, "indexes_used")
+		assert.Contains(t, resp, "misestimates")
+	})
+
+	t.Run("requires analyze", func(t *testing.T) {
+		exec := &mockExecutor{}
+		result := callTool(t, setupServer(&mockExplorer{}, exec), "query", map[string]any{
+			"sql":           "SELECT id FROM users",
+			"explain":       true,
+			"row_estimates": true,
+		})
+		assert.True(t, result.IsError)
+		assert.Contains(t, toolText(result), "row_estimates requires explain=true and analyze=true")
+		assert.Empty(t, exec.lastSQL)
+	})
+}
+
+func TestValidateCustomTools(t *testing.T) {
+	require.NoError(t, ValidateCustomTools(nil))
+	require.NoError(t, ValidateCustomTools([]CustomTool{{Name: "orders_since"}, {Name: "order_count"}}))
+
+	err := ValidateCustomTools([]CustomTool{{Name: "query"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"query" is a built-in tool`)
+
+	err = ValidateCustomTools([]CustomTool{{Name: "order_count"}, {Name: "order_count"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "defined twice")
+}
+
+func TestQuery_IndexUsage(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Nested Loop", "Plan Rows": 10, "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "users", "Plan Rows": 25000},
+		{"Node Type": "Index Scan", "Relation Name": "orders", "Index Name": "idx_orders_user_id", "Plan Rows": 1}
+	]}}]`
+
+	t.Run("summarizes the JSON plan", func(t *testing.T) {
+		exec := &mockExecutor{result: []map[string]any{{"QUERY PLAN": plan}}}
+		result := callTool(t, setupServer(&mockExplorer{}, exec), "query", map[string]any{
+			"sql":         "SELECT o.id FROM users u JOIN orders o ON o.user_id = u.id",
+			"explain":     true,
+			"index_usage": true,
+		})
+		require.False(t, result.IsError, toolText(result))
+		assert.True(t, strings.HasPrefix(exec.lastSQL, "EXPLAIN (FORMAT JSON) "), exec.lastSQL)
+
+		var resp struct {
+			Plan         json.RawMessage  `json:"plan"`
+			IndexesUsed  []string         `json:"indexes_used"`
+			SeqScans     []domain.SeqScan `json:"seq_scans"`
+			LargeSeqScan bool             `json:"large_seq_scan"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(toolText(result)), &resp))
+		assert.NotEmpty(t, resp.Plan)
+		assert.Equal(t, []string{"idx_orders_user_id"}, resp.IndexesUsed)
+		assert.Equal(t, []domain.SeqScan{{Relation: "users", Rows: 25000, Large: true}}, resp.SeqScans)
+		assert.True(t, resp.LargeSeqScan)
+	})
+
+	t.Run("analyze without timing", func(t *testing.T) {
+		exec := &mockExecutor{result: []map[string]any{{"QUERY PLAN": plan}}}
+		result := callTool(t, setupServer(&mockExplorer{}, exec), "query", map[string]any{
+			"sql":         "SELECT id FROM users",
+			"explain":     true,
+			"analyze":     true,
+			"timing":      false,
+			"index_usage": true,
+		})
+		require.False(t, result.IsError, toolText(result))
+		assert.Equal(t, "EXPLAIN (ANALYZE, TIMING OFF, FORMAT JSON) SELECT id FROM users", exec.lastSQL)
+	})
+
+	t.Run("requires explain", func(t *testing.T) {
+		exec := &mockExecutor{}
+		result := callTool(t, setupServer(&mockExplorer{}, exec), "query", map[string]any{
+			"sql":         "SELECT id FROM users",
+			"index_usage": true,
+		})
+		assert.True(t, result.IsError)
+		assert.Contains(t, toolText(result), "index_usage requires explain=true")
+		assert.Empty(t, exec.lastSQL)
+	})
+}
+
+func TestValidateToolMasking(t *testing.T) {
+	custom := []CustomTool{{Name: "orders_report"}}
+	require.NoError(t, ValidateToolMasking(nil, custom))
+	require.NoError(t, ValidateToolMasking(map[string]domain.ToolMasking{
+		"query":         {RedactPlanLiterals: true},
+		"orders_report": {SkipColumns: true},
+	}, custom))
+
+	err := ValidateToolMasking(map[string]domain.ToolMasking{"profile_table": {SkipColumns: true}}, custom)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `tool_masking: unknown tool "profile_table"`)
 }
@@ -0,0 +1,186 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadContextFile reads table and column descriptions from a JSON file kept
+// outside the policy: either a dbt manifest.json, or a flat object mapping
+// "schema.table" to a table description and "schema.table.column" to a
+// column description.
+//
+//	{
+//	  "public.orders": "One row per checkout",
+//	  "public.orders.amount_cents": "Order total in cents (USD)"
+//	}
+//
+// From a manifest, models, seeds, snapshots and sources are read under the
+// schema and relation name they are built as; other nodes, such as tests,
+// are skipped.
+func LoadContextFile(path string) (ContextConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ContextConfig{}, fmt.Errorf("reading context file: %w", err)
+	}
+
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return ContextConfig{}, fmt.Errorf("parsing context file %s: %w", path, err)
+	}
+	if _, ok := top["nodes"]; ok {
+		if _, ok := top["metadata"]; ok {
+			cc, err := parseManifest(data)
+			if err != nil {
+				return ContextConfig{}, fmt.Errorf("parsing dbt manifest %s: %w", path, err)
+			}
+			return cc, nil
+		}
+	}
+
+	cc, err := parseDescriptionMap(top)
+	if err != nil {
+		return ContextConfig{}, fmt.Errorf("parsing context file %s: %w", path, err)
+	}
+	return cc, nil
+}
+
+// dbtManifest holds the parts of a dbt manifest.json that carry
+// documentation.
+type dbtManifest struct {
+	Nodes   map[string]dbtNode `json:"nodes"`
+	Sources map[string]dbtNode `json:"sources"`
+}
+
+// dbtNode is a model, seed, snapshot or source in a manifest. Alias is the
+// relation a model is built as, Identifier the relation a source reads.
+type dbtNode struct {
+	ResourceType string               `json:"resource_type"`
+	Schema       string               `json:"schema"`
+	Name         string               `json:"name"`
+	Alias        string               `json:"alias"`
+	Identifier   string               `json:"identifier"`
+	Description  string               `json:"description"`
+	Columns      map[string]dbtColumn `json:"columns"`
+}
+
+type dbtColumn struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// relation returns the table name a node is stored under.
+func (n dbtNode) relation() string {
+	switch {
+	case n.Identifier != "":
+		return n.Identifier
+	case n.Alias != "":
+		return n.Alias
+	}
+	return n.Name
+}
+
+func parseManifest(data []byte) (ContextConfig, error) {
+	var m dbtManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return ContextConfig{}, err
+	}
+
+	cc := ContextConfig{Tables: make(map[string]TableContext)}
+	add := func(n dbtNode) {
+		if n.Schema == "" || n.relation() == "" {
+			return
+		}
+		tc := TableContext{Description: strings.TrimSpace(n.Description)}
+		for key, col := range n.Columns {
+			name := col.Name
+			if name == "" {
+				name = key
+			}
+			desc := strings.TrimSpace(col.Description)
+			if desc == "" {
+				continue
+			}
+			if tc.Columns == nil {
+				tc.Columns = make(map[string]ColumnContext)
+			}
+			tc.Columns[name] = ColumnContext{Description: desc}
+		}
+		if tc.Description == "" && len(tc.Columns) == 0 {
+			return
+		}
+		cc.Tables[n.Schema+"."+n.relation()] = tc
+	}
+	for _, n := range m.Nodes {
+		switch n.ResourceType {
+		case "model", "seed", "snapshot":
+			add(n)
+		}
+	}
+	for _, n := range m.Sources {
+		add(n)
+	}
+	return cc, nil
+}
+
+func parseDescriptionMap(top map[string]json.RawMessage) (ContextConfig, error) {
+	cc := ContextConfig{Tables: make(map[string]TableContext)}
+	for key, raw := range top {
+		var desc string
+		if err := json.Unmarshal(raw, &desc); err != nil {
+			return ContextConfig{}, fmt.Errorf("description for %q must be a string", key)
+		}
+		parts := strings.Split(key, ".")
+		for _, p := range parts {
+			if p == "" {
+				return ContextConfig{}, fmt.Errorf("key %q has an empty name", key)
+			}
+		}
+		switch len(parts) {
+		case 2:
+			tc := cc.Tables[key]
+			tc.Description = desc
+			cc.Tables[key] = tc
+		case 3:
+			table := parts[0] + "." + parts[1]
+			tc := cc.Tables[table]
+			if tc.Columns == nil {
+				tc.Columns = make(map[string]ColumnContext)
+			}
+			tc.Columns[parts[2]] = ColumnContext{Description: desc}
+			cc.Tables[table] = tc
+		default:
+			return ContextConfig{}, fmt.Errorf("key %q must be \"schema.table\" or \"schema.table.column\"", key)
+		}
+	}
+	return cc, nil
+}
+
+// MergeDescriptions adds the table and column descriptions in src to dst.
+// The policy is the operator's word, so a description dst already has is
+// kept; masks, hidden columns and other directives in dst are untouched.
+func MergeDescriptions(dst *ContextConfig, src ContextConfig) {
+	for key, tc := range src.Tables {
+		if dst.Tables == nil {
+			dst.Tables = make(map[string]TableContext)
+		}
+		existing := dst.Tables[key]
+		if existing.Description == "" {
+			existing.Description = tc.Description
+		}
+		for col, cc := range tc.Columns {
+			prev, ok := existing.Columns[col]
+			if ok && prev.Description != "" {
+				continue
+			}
+			if existing.Columns == nil {
+				existing.Columns = make(map[string]ColumnContext)
+			}
+			prev.Description = cc.Description
+			existing.Columns[col] = prev
+		}
+		dst.Tables[key] = existing
+	}
+}
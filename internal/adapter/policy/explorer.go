@@ -2,27 +2,60 @@ package policy
 
 import (
 	"context"
+	"slices"
 
 	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/guillermoBallester/isthmus/internal/core/service"
 )
 
 // PolicyExplorer decorates a SchemaExplorer with policy-based context enrichment.
 // It merges business descriptions from the policy YAML into explorer responses
-// and applies column masking to sample rows.
+// and applies column masking to sample rows, unless the policy's tool_masking
+// skips it for the calling tool.
 type PolicyExplorer struct {
-	inner  port.SchemaExplorer
-	policy *Policy
-	masks  map[string]domain.MaskType
+	inner     port.SchemaExplorer
+	policy    *Policy
+	masks     map[string]domain.MaskType
+	patterns  []domain.MaskPattern
+	jsonMasks map[string][]domain.JSONPathMask
+	lister    port.ColumnLister
+}
+
+// ExplorerOption configures a PolicyExplorer.
+type ExplorerOption func(*PolicyExplorer)
+
+// WithColumnLister lets the explorer check hidden columns against the
+// catalog, so table column counts leave out only hidden columns that exist.
+// Without it, column counts include hidden columns.
+func WithColumnLister(lister port.ColumnLister) ExplorerOption {
+	return func(p *PolicyExplorer) {
+		p.lister = lister
+	}
 }
 
 // NewPolicyExplorer wraps an existing SchemaExplorer with context enrichment and sample row masking.
-func NewPolicyExplorer(inner port.SchemaExplorer, pol *Policy, masks map[string]domain.MaskType) *PolicyExplorer {
-	return &PolicyExplorer{inner: inner, policy: pol, masks: masks}
+func NewPolicyExplorer(inner port.SchemaExplorer, pol *Policy, masks map[string]domain.MaskType, opts ...ExplorerOption) *PolicyExplorer {
+	p := &PolicyExplorer{
+		inner:     inner,
+		policy:    pol,
+		masks:     masks,
+		patterns:  MaskPatterns(pol.ColumnPatterns),
+		jsonMasks: JSONMaskSpec(pol.Context),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 func (p *PolicyExplorer) ListSchemas(ctx context.Context) ([]port.SchemaInfo, error) {
-	return p.inner.ListSchemas(ctx)
+	schemas, err := p.inner.ListSchemas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	MergeSchemaInfoList(schemas, p.policy.Context)
+	return schemas, nil
 }
 
 func (p *PolicyExplorer) ListTables(ctx context.Context) ([]port.TableInfo, error) {
@@ -30,7 +63,11 @@ func (p *PolicyExplorer) ListTables(ctx context.Context) ([]port.TableInfo, erro
 	if err != nil {
 		return nil, err
 	}
-	MergeTableInfoList(tables, p.policy.Context)
+	columns, err := p.hiddenTableColumns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	MergeTableInfoList(tables, p.policy.Context, columns)
 	return tables, nil
 }
 
@@ -40,7 +77,10 @@ func (p *PolicyExplorer) DescribeTable(ctx context.Context, schema, tableName st
 		return nil, err
 	}
 	MergeTableDetail(detail, p.policy.Context)
-	domain.MaskRows(detail.SampleRows, p.masks)
+	if !p.policy.ToolMasking[service.ToolNameFromContext(ctx)].SkipColumns {
+		domain.MaskRowsJSON(detail.SampleRows, p.jsonMasks, nil)
+		domain.MaskRows(detail.SampleRows, p.sampleMasks(detail))
+	}
 	return detail, nil
 }
 
@@ -49,8 +89,69 @@ func (p *PolicyExplorer) Discover(ctx context.Context) (*port.DiscoveryResult, e
 	if err != nil {
 		return nil, err
 	}
+	columns, err := p.hiddenTableColumns(ctx)
+	if err != nil {
+		return nil, err
+	}
 	for i := range result.Schemas {
-		MergeTableInfoList(result.Schemas[i].Tables, p.policy.Context)
+		result.Schemas[i].SchemaLabel = p.policy.Context.SchemaLabel(result.Schemas[i].Name)
+		MergeTableInfoList(result.Schemas[i].Tables, p.policy.Context, columns)
 	}
 	return result, nil
 }
+
+func (p *PolicyExplorer) TableActivity(ctx context.Context) ([]port.TableActivity, error) {
+	return p.inner.TableActivity(ctx)
+}
+
+// ForeignKeys leaves out the foreign key columns the policy hides, as
+// DescribeTable does.
+func (p *PolicyExplorer) ForeignKeys(ctx context.Context) (map[string][]port.ForeignKey, error) {
+	fks, err := p.inner.ForeignKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for key, tc := range p.policy.Context.Tables {
+		if hidden := hiddenColumns(tc); len(hidden) > 0 && len(fks[key]) > 0 {
+			fks[key] = slices.DeleteFunc(fks[key], func(fk port.ForeignKey) bool { return hidden[fk.ColumnName] })
+		}
+	}
+	return fks, nil
+}
+
+// hiddenTableColumns lists the current columns of the tables that hide
+// some, or returns nil without a column lister.
+func (p *PolicyExplorer) hiddenTableColumns(ctx context.Context) (map[string][]string, error) {
+	tables := tablesWithHiddenColumns(p.policy.Context)
+	if p.lister == nil || len(tables) == 0 {
+		return nil, nil
+	}
+	return p.lister.ColumnNames(ctx, nil, tables)
+}
+
+// sampleMasks resolves the masks for a table's sample rows: the table's or
+// its schema's default mask covers every column without an explicit mask,
+// and column patterns are evaluated against the table's actual columns and
+// data types.
+func (p *PolicyExplorer) sampleMasks(detail *port.TableDetail) map[string]domain.MaskType {
+	masks := p.masks
+	if def := p.policy.Context.DefaultMask(detail.Schema, detail.Name); def != "" {
+		masks = make(map[string]domain.MaskType, len(p.masks)+len(detail.Columns))
+		for k, v := range p.masks {
+			masks[k] = v
+		}
+		for _, col := range detail.Columns {
+			if _, ok := masks[col.Name]; !ok {
+				masks[col.Name] = def
+			}
+		}
+	}
+	if len(p.patterns) == 0 {
+		return masks
+	}
+	columns := make(map[string]string, len(detail.Columns))
+	for _, col := range detail.Columns {
+		columns[col.Name] = col.DataType
+	}
+	return domain.ResolveColumnMasks(columns, masks, p.patterns)
+}
@@ -3,6 +3,11 @@ package policy
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"gopkg.in/yaml.v3"
@@ -10,6 +15,92 @@ import (
 
 // LoadFromFile reads a YAML policy file and returns a validated Policy.
 func LoadFromFile(path string) (*Policy, error) {
+	pol, err := parseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validate(pol); err != nil {
+		return nil, fmt.Errorf("validating policy: %w", err)
+	}
+
+	return pol, nil
+}
+
+// LoadFromFiles reads several YAML policy files and merges them, in order,
+// into one validated Policy.
+//
+// Merge rules:
+//   - Table contexts are unioned. A table key present in more than one file
+//     is merged column by column; its description, and any column defined in
+//     both files, must be identical or loading fails.
+//   - Schema contexts are unioned; a schema with different default masks in
+//     two files fails loading, as does a table's default mask.
+//   - column_patterns are concatenated in file order, so earlier files win.
+//   - tool_descriptions are unioned; a tool described differently in two
+//     files fails loading.
+//   - tools are unioned; a tool defined differently in two files fails
+//     loading.
+//   - tool_masking is unioned; a tool with different rules in two files
+//     fails loading.
+//   - Mask conflicts are checked across the merged result, exactly as they
+//     are within a single file.
+func LoadFromFiles(paths []string) (*Policy, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no policy files given")
+	}
+
+	merged := &Policy{}
+	origins := mergeOrigins{
+		schemas:     make(map[string]string),
+		tables:      make(map[string]string),
+		tools:       make(map[string]string),
+		customTools: make(map[string]string),
+		toolMasking: make(map[string]string),
+	}
+	for _, path := range paths {
+		pol, err := parseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := validate(pol); err != nil {
+			return nil, fmt.Errorf("validating policy %s: %w", path, err)
+		}
+		if err := mergeInto(merged, pol, path, origins); err != nil {
+			return nil, fmt.Errorf("merging policy %s: %w", path, err)
+		}
+	}
+
+	if err := validate(merged); err != nil {
+		return nil, fmt.Errorf("validating merged policy: %w", err)
+	}
+
+	return merged, nil
+}
+
+// FilesInDir returns the *.yaml and *.yml files in dir, sorted by name so the
+// merge order is deterministic.
+func FilesInDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy directory: %w", err)
+	}
+
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch filepath.Ext(e.Name()) {
+		case ".yaml", ".yml":
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func parseFile(path string) (*Policy, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("reading policy file: %w", err)
@@ -17,14 +108,150 @@ func LoadFromFile(path string) (*Policy, error) {
 
 	var pol Policy
 	if err := yaml.Unmarshal(data, &pol); err != nil {
-		return nil, fmt.Errorf("parsing policy YAML: %w", err)
+		return nil, fmt.Errorf("parsing policy YAML %s: %w", path, err)
+	}
+	for _, tool := range pol.Tools {
+		for i := range tool.Params {
+			if tool.Params[i].Name == "" {
+				tool.Params[i].Name = fmt.Sprintf("p%d", i+1)
+			}
+		}
 	}
+	return &pol, nil
+}
 
-	if err := validate(&pol); err != nil {
-		return nil, fmt.Errorf("validating policy: %w", err)
+// mergeOrigins records which file first defined each table key and tool
+// description so conflicts can name both files.
+type mergeOrigins struct {
+	schemas     map[string]string
+	tables      map[string]string
+	tools       map[string]string
+	customTools map[string]string
+	toolMasking map[string]string
+}
+
+// mergeInto adds src (read from path) to dst.
+func mergeInto(dst, src *Policy, path string, origins mergeOrigins) error {
+	for key, tc := range src.Context.Tables {
+		if dst.Context.Tables == nil {
+			dst.Context.Tables = make(map[string]TableContext)
+		}
+		existing, ok := dst.Context.Tables[key]
+		if !ok {
+			cols := make(map[string]ColumnContext, len(tc.Columns))
+			for col, cc := range tc.Columns {
+				cols[col] = cc
+			}
+			dst.Context.Tables[key] = TableContext{Description: tc.Description, DefaultMask: tc.DefaultMask, MaxRows: tc.MaxRows, QueryTimeout: tc.QueryTimeout, Columns: cols}
+			origins.tables[key] = path
+			continue
+		}
+
+		prevPath := origins.tables[key]
+		if tc.Description != "" {
+			if existing.Description != "" && existing.Description != tc.Description {
+				return fmt.Errorf("table %q has conflicting descriptions in %s and %s", key, prevPath, path)
+			}
+			existing.Description = tc.Description
+		}
+		if tc.DefaultMask != "" {
+			if existing.DefaultMask != "" && existing.DefaultMask != tc.DefaultMask {
+				return fmt.Errorf("table %q has conflicting default masks in %s and %s", key, prevPath, path)
+			}
+			existing.DefaultMask = tc.DefaultMask
+		}
+		if tc.MaxRows != 0 {
+			if existing.MaxRows != 0 && existing.MaxRows != tc.MaxRows {
+				return fmt.Errorf("table %q has conflicting max_rows in %s and %s", key, prevPath, path)
+			}
+			existing.MaxRows = tc.MaxRows
+		}
+		if tc.QueryTimeout != 0 {
+			if existing.QueryTimeout != 0 && existing.QueryTimeout != tc.QueryTimeout {
+				return fmt.Errorf("table %q has conflicting query_timeout in %s and %s", key, prevPath, path)
+			}
+			existing.QueryTimeout = tc.QueryTimeout
+		}
+		if existing.Columns == nil && len(tc.Columns) > 0 {
+			existing.Columns = make(map[string]ColumnContext, len(tc.Columns))
+		}
+		for col, cc := range tc.Columns {
+			if prev, exists := existing.Columns[col]; exists && !prev.equal(cc) {
+				return fmt.Errorf("column %q of table %q is defined differently in %s and %s", col, key, prevPath, path)
+			}
+			existing.Columns[col] = cc
+		}
+		dst.Context.Tables[key] = existing
 	}
 
-	return &pol, nil
+	for name, sc := range src.Context.Schemas {
+		if dst.Context.Schemas == nil {
+			dst.Context.Schemas = make(map[string]SchemaContext)
+		}
+		if prev, ok := dst.Context.Schemas[name]; ok {
+			if sc.DefaultMask != "" {
+				if prev.DefaultMask != "" && prev.DefaultMask != sc.DefaultMask {
+					return fmt.Errorf("schema %q has conflicting default masks in %s and %s", name, origins.schemas[name], path)
+				}
+				prev.DefaultMask = sc.DefaultMask
+			}
+			if sc.Label != "" {
+				if prev.Label != "" && prev.Label != sc.Label {
+					return fmt.Errorf("schema %q has conflicting labels in %s and %s", name, origins.schemas[name], path)
+				}
+				prev.Label = sc.Label
+			}
+			dst.Context.Schemas[name] = prev
+			continue
+		}
+		dst.Context.Schemas[name] = sc
+		origins.schemas[name] = path
+	}
+
+	dst.ColumnPatterns = append(dst.ColumnPatterns, src.ColumnPatterns...)
+
+	for tool, desc := range src.ToolDescriptions {
+		if dst.ToolDescriptions == nil {
+			dst.ToolDescriptions = make(map[string]string)
+		}
+		if prev, ok := dst.ToolDescriptions[tool]; ok {
+			if prev != desc {
+				return fmt.Errorf("tool %q has conflicting descriptions in %s and %s", tool, origins.tools[tool], path)
+			}
+			continue
+		}
+		dst.ToolDescriptions[tool] = desc
+		origins.tools[tool] = path
+	}
+
+	for name, tool := range src.Tools {
+		if dst.Tools == nil {
+			dst.Tools = make(map[string]CustomTool)
+		}
+		if prev, ok := dst.Tools[name]; ok {
+			if prev.Description != tool.Description || prev.SQL != tool.SQL || !slices.Equal(prev.Params, tool.Params) {
+				return fmt.Errorf("tool %q is defined differently in %s and %s", name, origins.customTools[name], path)
+			}
+			continue
+		}
+		dst.Tools[name] = tool
+		origins.customTools[name] = path
+	}
+
+	for name, rule := range src.ToolMasking {
+		if dst.ToolMasking == nil {
+			dst.ToolMasking = make(map[string]ToolMaskingRule)
+		}
+		if prev, ok := dst.ToolMasking[name]; ok {
+			if prev != rule {
+				return fmt.Errorf("tool %q has conflicting tool_masking rules in %s and %s", name, origins.toolMasking[name], path)
+			}
+			continue
+		}
+		dst.ToolMasking[name] = rule
+		origins.toolMasking[name] = path
+	}
+	return nil
 }
 
 func validate(pol *Policy) error {
@@ -33,11 +260,33 @@ func validate(pol *Policy) error {
 		table string
 	}
 	seen := make(map[string]maskOrigin)
+	seenJSON := make(map[string]maskOrigin) // column + "\x00" + path
+
+	for name, sc := range pol.Context.Schemas {
+		if name == "" {
+			return fmt.Errorf("context.schemas contains an empty key")
+		}
+		if sc.DefaultMask == "" && sc.Label == "" {
+			return fmt.Errorf("context.schemas[%q].default_mask: required unless label is set", name)
+		}
+		if !sc.DefaultMask.Valid() {
+			return fmt.Errorf("context.schemas[%q].default_mask: invalid value %q (allowed: redact, hash, partial, null)", name, sc.DefaultMask)
+		}
+	}
 
 	for key, tc := range pol.Context.Tables {
 		if key == "" {
 			return fmt.Errorf("context.tables contains an empty key")
 		}
+		if !tc.DefaultMask.Valid() {
+			return fmt.Errorf("context.tables[%q].default_mask: invalid value %q (allowed: redact, hash, partial, null)", key, tc.DefaultMask)
+		}
+		if tc.MaxRows < 0 {
+			return fmt.Errorf("context.tables[%q].max_rows: must be positive, got %d", key, tc.MaxRows)
+		}
+		if tc.QueryTimeout < 0 {
+			return fmt.Errorf("context.tables[%q].query_timeout: must be positive, got %s", key, tc.QueryTimeout)
+		}
 		for col, cc := range tc.Columns {
 			if col == "" {
 				return fmt.Errorf("context.tables[%q].columns contains an empty key", key)
@@ -45,6 +294,24 @@ func validate(pol *Policy) error {
 			if !cc.Mask.Valid() {
 				return fmt.Errorf("context.tables[%q].columns[%q].mask: invalid value %q (allowed: redact, hash, partial, null)", key, col, cc.Mask)
 			}
+			if cc.Mask != "" && len(cc.JSONMasks) > 0 {
+				return fmt.Errorf("context.tables[%q].columns[%q]: mask and json_masks cannot be combined", key, col)
+			}
+			for path, mask := range cc.JSONMasks {
+				if err := domain.ValidJSONPath(path); err != nil {
+					return fmt.Errorf("context.tables[%q].columns[%q].json_masks: %w", key, col, err)
+				}
+				if mask == "" || !mask.Valid() {
+					return fmt.Errorf("context.tables[%q].columns[%q].json_masks[%q]: invalid value %q (allowed: redact, hash, partial, null)", key, col, path, mask)
+				}
+				if prev, exists := seenJSON[col+"\x00"+path]; exists && prev.mask != mask {
+					return fmt.Errorf(
+						"column %q has conflicting json_masks for %q: %q in %s vs %q in %s",
+						col, path, prev.mask, prev.table, mask, key,
+					)
+				}
+				seenJSON[col+"\x00"+path] = maskOrigin{mask, key}
+			}
 			if cc.Mask == "" {
 				continue
 			}
@@ -57,5 +324,65 @@ func validate(pol *Policy) error {
 			seen[col] = maskOrigin{cc.Mask, key}
 		}
 	}
+
+	for i, cp := range pol.ColumnPatterns {
+		if cp.Name == "" {
+			return fmt.Errorf("column_patterns[%d].name is required", i)
+		}
+		if _, err := regexp.Compile(cp.Name); err != nil {
+			return fmt.Errorf("column_patterns[%d].name: invalid regex %q: %w", i, cp.Name, err)
+		}
+		if cp.Mask == "" || !cp.Mask.Valid() {
+			return fmt.Errorf("column_patterns[%d].mask: invalid value %q (allowed: redact, hash, partial, null)", i, cp.Mask)
+		}
+	}
+
+	for name, tool := range pol.Tools {
+		if err := validateCustomTool(name, tool); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toolNamePattern is what custom tool and parameter names must look like.
+var toolNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// validateCustomTool checks a tool definition, including that its SQL is a
+// single statement the query validator accepts and that every $N
+// placeholder has a typed parameter.
+func validateCustomTool(name string, tool CustomTool) error {
+	if !toolNamePattern.MatchString(name) {
+		return fmt.Errorf("tools[%q]: name must be lowercase letters, digits and underscores, starting with a letter", name)
+	}
+	if strings.TrimSpace(tool.Description) == "" {
+		return fmt.Errorf("tools[%q].description is required", name)
+	}
+	if strings.TrimSpace(tool.SQL) == "" {
+		return fmt.Errorf("tools[%q].sql is required", name)
+	}
+	if err := domain.NewPgQueryValidator().Validate(tool.SQL); err != nil {
+		return fmt.Errorf("tools[%q].sql: %w", name, err)
+	}
+	n, err := domain.ParamCount(tool.SQL)
+	if err != nil {
+		return fmt.Errorf("tools[%q].sql: %w", name, err)
+	}
+	if n != len(tool.Params) {
+		return fmt.Errorf("tools[%q]: sql uses %d parameters but params declares %d", name, n, len(tool.Params))
+	}
+	seen := make(map[string]bool, len(tool.Params))
+	for i, p := range tool.Params {
+		if !toolNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("tools[%q].params[%d].name: invalid name %q", name, i, p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("tools[%q].params[%d].name: duplicate name %q", name, i, p.Name)
+		}
+		seen[p.Name] = true
+		if !p.Type.Valid() {
+			return fmt.Errorf("tools[%q].params[%d].type: invalid value %q (allowed: text, integer, numeric, boolean, timestamp, date, uuid)", name, i, p.Type)
+		}
+	}
 	return nil
 }
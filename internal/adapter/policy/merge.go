@@ -1,18 +1,29 @@
 package policy
 
 import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"github.com/guillermoBallester/isthmus/internal/core/port"
 )
 
 // MergeTableDetail enriches a TableDetail with business context from the policy.
 // YAML descriptions are only applied when the existing Postgres comment is empty,
-// so operator-set COMMENT ON values always take precedence.
+// so operator-set COMMENT ON values always take precedence. Columns marked
+// hidden are removed.
 func MergeTableDetail(detail *port.TableDetail, ctx ContextConfig) {
 	if detail == nil {
 		return
 	}
 
+	detail.SchemaLabel = ctx.SchemaLabel(detail.Schema)
+
 	key := detail.Schema + "." + detail.Name
 	tc, ok := ctx.Tables[key]
 	if !ok {
@@ -28,19 +39,134 @@ func MergeTableDetail(detail *port.TableDetail, ctx ContextConfig) {
 			detail.Columns[i].Comment = cc.Description
 		}
 	}
+
+	if hidden := hiddenColumns(tc); len(hidden) > 0 {
+		hideColumns(detail, hidden)
+	}
+}
+
+// hiddenColumns returns the set of columns the table context marks hidden.
+func hiddenColumns(tc TableContext) map[string]bool {
+	var hidden map[string]bool
+	for name, cc := range tc.Columns {
+		if cc.Hidden {
+			if hidden == nil {
+				hidden = make(map[string]bool)
+			}
+			hidden[name] = true
+		}
+	}
+	return hidden
+}
+
+// hideColumns removes every trace of the hidden columns from detail: the
+// column itself, sample row values, and any foreign key, index, check
+// constraint or recommendation that would name it.
+func hideColumns(detail *port.TableDetail, hidden map[string]bool) {
+	anyHidden := func(cols []string) bool {
+		return slices.ContainsFunc(cols, func(c string) bool { return hidden[c] })
+	}
+
+	detail.Columns = slices.DeleteFunc(detail.Columns, func(c port.ColumnInfo) bool { return hidden[c.Name] })
+
+	var droppedFKs []string
+	detail.ForeignKeys = slices.DeleteFunc(detail.ForeignKeys, func(fk port.ForeignKey) bool {
+		if hidden[fk.ColumnName] {
+			droppedFKs = append(droppedFKs, fk.ConstraintName)
+			return true
+		}
+		return false
+	})
+	detail.InferredFKs = slices.DeleteFunc(detail.InferredFKs, func(fk port.InferredForeignKey) bool { return hidden[fk.ColumnName] })
+	detail.CheckConstraints = slices.DeleteFunc(detail.CheckConstraints, func(ck port.CheckConstraint) bool { return anyHidden(ck.Columns) })
+
+	droppedIndexes := make(map[string]bool)
+	detail.Indexes = slices.DeleteFunc(detail.Indexes, func(idx port.IndexInfo) bool {
+		if anyHidden(idx.Columns) {
+			droppedIndexes[idx.Name] = true
+			return true
+		}
+		return false
+	})
+	detail.IndexUsage = slices.DeleteFunc(detail.IndexUsage, func(u port.IndexUsage) bool { return droppedIndexes[u.Name] })
+
+	// FK index recommendations quote the constraint name, index bloat
+	// recommendations the index name.
+	dropped := slices.AppendSeq(droppedFKs, maps.Keys(droppedIndexes))
+	detail.Recommendations = slices.DeleteFunc(detail.Recommendations, func(rec string) bool {
+		return slices.ContainsFunc(dropped, func(name string) bool {
+			return strings.Contains(rec, fmt.Sprintf("%q", name))
+		})
+	})
+
+	for _, row := range detail.SampleRows {
+		for name := range hidden {
+			delete(row, name)
+		}
+	}
 }
 
 // MergeTableInfoList enriches a list of TableInfo with business context.
-// Same precedence rule: YAML descriptions only fill empty comments.
-func MergeTableInfoList(tables []port.TableInfo, ctx ContextConfig) {
+// Descriptions from the policy fill in missing database comments. Hidden
+// columns are left out of ColumnCount; columns lists the existing columns of
+// each table ("schema.table"), and only hidden columns found there are
+// subtracted, so a misspelt or dropped column does not skew the count.
+func MergeTableInfoList(tables []port.TableInfo, ctx ContextConfig, columns map[string][]string) {
 	for i, t := range tables {
+		tables[i].SchemaLabel = ctx.SchemaLabel(t.Schema)
 		key := t.Schema + "." + t.Name
-		if tc, ok := ctx.Tables[key]; ok && t.Comment == "" && tc.Description != "" {
+		tc, ok := ctx.Tables[key]
+		if !ok {
+			continue
+		}
+		if t.Comment == "" && tc.Description != "" {
 			tables[i].Comment = tc.Description
 		}
+		n := 0
+		for name := range hiddenColumns(tc) {
+			if slices.Contains(columns[key], name) {
+				n++
+			}
+		}
+		if n > 0 {
+			tables[i].ColumnCount = max(t.ColumnCount-n, 0)
+		}
 	}
 }
 
+// tablesWithHiddenColumns returns, as "schema.table", the policy tables
+// that hide at least one column.
+func tablesWithHiddenColumns(ctx ContextConfig) []string {
+	var keys []string
+	for key, tc := range ctx.Tables {
+		if len(hiddenColumns(tc)) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// MergeSchemaInfoList sets the display label of each schema that has one.
+func MergeSchemaInfoList(schemas []port.SchemaInfo, ctx ContextConfig) {
+	for i, s := range schemas {
+		schemas[i].SchemaLabel = ctx.SchemaLabel(s.Name)
+	}
+}
+
+// FilterTables returns the tables that have an entry under context.tables,
+// preserving order. It is used to curate which tables are exposed as MCP
+// resources.
+func FilterTables(tables []port.TableInfo, ctx ContextConfig) []port.TableInfo {
+	var out []port.TableInfo
+	for _, t := range tables {
+		if _, ok := ctx.Tables[t.Schema+"."+t.Name]; ok {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
 // MaskSpec extracts a column-name → mask-type map from the policy for use in query masking.
 func MaskSpec(ctx ContextConfig) map[string]domain.MaskType {
 	spec := make(map[string]domain.MaskType)
@@ -53,3 +179,124 @@ func MaskSpec(ctx ContextConfig) map[string]domain.MaskType {
 	}
 	return spec
 }
+
+// JSONMaskSpec extracts a column-name → JSON path masks map from the policy
+// for use in query masking. Paths are sorted, so masks apply in a fixed order.
+func JSONMaskSpec(ctx ContextConfig) map[string][]domain.JSONPathMask {
+	spec := make(map[string][]domain.JSONPathMask)
+	for _, tc := range ctx.Tables {
+		for col, cc := range tc.Columns {
+			for path, mask := range cc.JSONMasks {
+				if !slices.ContainsFunc(spec[col], func(m domain.JSONPathMask) bool { return m.Path == path }) {
+					spec[col] = append(spec[col], domain.JSONPathMask{Path: path, Mask: mask})
+				}
+			}
+		}
+	}
+	for col := range spec {
+		slices.SortFunc(spec[col], func(a, b domain.JSONPathMask) int { return strings.Compare(a.Path, b.Path) })
+	}
+	return spec
+}
+
+// RowLimitSpec extracts the max_rows of every table that sets one, for use
+// in query row limits.
+func RowLimitSpec(ctx ContextConfig) map[domain.TableRef]int {
+	spec := make(map[domain.TableRef]int)
+	for key, tc := range ctx.Tables {
+		if tc.MaxRows > 0 {
+			schema, table, _ := strings.Cut(key, ".")
+			spec[domain.TableRef{Schema: schema, Name: table}] = tc.MaxRows
+		}
+	}
+	return spec
+}
+
+// TimeoutSpec extracts the query_timeout of every table that sets one, for
+// use in query statement timeouts.
+func TimeoutSpec(ctx ContextConfig) map[domain.TableRef]time.Duration {
+	spec := make(map[domain.TableRef]time.Duration)
+	for key, tc := range ctx.Tables {
+		if tc.QueryTimeout > 0 {
+			schema, table, _ := strings.Cut(key, ".")
+			spec[domain.TableRef{Schema: schema, Name: table}] = tc.QueryTimeout
+		}
+	}
+	return spec
+}
+
+// DefaultMaskSpec extracts the table and schema default masks from the
+// policy. They stay scoped to their tables: a query is masked with them only
+// for the tables it reads, whose columns are looked up when it runs.
+func DefaultMaskSpec(ctx ContextConfig) (tables map[domain.TableRef]domain.MaskType, schemas map[string]domain.MaskType) {
+	for key, tc := range ctx.Tables {
+		if tc.DefaultMask == "" {
+			continue
+		}
+		if tables == nil {
+			tables = make(map[domain.TableRef]domain.MaskType)
+		}
+		schema, table, _ := strings.Cut(key, ".")
+		tables[domain.TableRef{Schema: schema, Name: table}] = tc.DefaultMask
+	}
+	for name, sc := range ctx.Schemas {
+		if sc.DefaultMask == "" {
+			continue
+		}
+		if schemas == nil {
+			schemas = make(map[string]domain.MaskType)
+		}
+		schemas[name] = sc.DefaultMask
+	}
+	return tables, schemas
+}
+
+// MissingMaskedColumns returns, sorted, every column with an explicit mask in
+// the policy ("schema.table.column") that lister does not find, including
+// those of tables that do not exist. Query masking matches result columns by
+// name and skips names that never appear, so such a typo would otherwise go
+// unnoticed.
+func MissingMaskedColumns(ctx context.Context, lister port.ColumnLister, cc ContextConfig) ([]string, error) {
+	var tables []string
+	for key, tc := range cc.Tables {
+		for _, col := range tc.Columns {
+			if col.Mask != "" || len(col.JSONMasks) > 0 {
+				tables = append(tables, key)
+				break
+			}
+		}
+	}
+	if len(tables) == 0 {
+		return nil, nil
+	}
+
+	columns, err := lister.ColumnNames(ctx, nil, tables)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, key := range tables {
+		existing := columns[key]
+		for name, col := range cc.Tables[key].Columns {
+			if (col.Mask != "" || len(col.JSONMasks) > 0) && !slices.Contains(existing, name) {
+				missing = append(missing, key+"."+name)
+			}
+		}
+	}
+	slices.Sort(missing)
+	return missing, nil
+}
+
+// MaskPatterns compiles the policy's column patterns for use in masking.
+// Patterns must have passed validation.
+func MaskPatterns(patterns []ColumnPattern) []domain.MaskPattern {
+	compiled := make([]domain.MaskPattern, 0, len(patterns))
+	for _, cp := range patterns {
+		compiled = append(compiled, domain.MaskPattern{
+			Name:     regexp.MustCompile(cp.Name),
+			DataType: cp.DataType,
+			Mask:     cp.Mask,
+		})
+	}
+	return compiled
+}
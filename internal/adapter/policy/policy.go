@@ -2,33 +2,172 @@ package policy
 
 import (
 	"fmt"
+	"maps"
+	"time"
 
 	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"gopkg.in/yaml.v3"
 )
 
 // Policy holds operator-controlled configuration loaded from a YAML file.
-// Supports data dictionary context and column-level PII masking.
+// Supports data dictionary context, column-level PII masking, overrides
+// for the MCP tool descriptions shown to the model, custom query tools and
+// per-tool masking overrides.
 type Policy struct {
-	Context ContextConfig `yaml:"context"`
+	Context          ContextConfig              `yaml:"context"`
+	ColumnPatterns   []ColumnPattern            `yaml:"column_patterns"`
+	ToolDescriptions map[string]string          `yaml:"tool_descriptions"` // tool name -> replacement description
+	Tools            map[string]CustomTool      `yaml:"tools"`             // tool name -> operator-defined query
+	ToolMasking      map[string]ToolMaskingRule `yaml:"tool_masking"`      // tool name -> masking override
+}
+
+// ToolMaskingRule changes how masking applies to the output of one tool,
+// built-in or custom. Tools without a rule mask columns as usual.
+//
+//	tool_masking:
+//	  orders_report:
+//	    skip_columns: true
+//	  query:
+//	    redact_plan_literals: true
+type ToolMaskingRule struct {
+	SkipColumns        bool `yaml:"skip_columns"`         // leave result and sample row columns unmasked
+	RedactPlanLiterals bool `yaml:"redact_plan_literals"` // scrub quoted literals from EXPLAIN output
+}
+
+// ToolMaskSpec converts the tool_masking rules for the query service.
+func (p *Policy) ToolMaskSpec() map[string]domain.ToolMasking {
+	if len(p.ToolMasking) == 0 {
+		return nil
+	}
+	spec := make(map[string]domain.ToolMasking, len(p.ToolMasking))
+	for name, rule := range p.ToolMasking {
+		spec[name] = domain.ToolMasking{SkipColumns: rule.SkipColumns, RedactPlanLiterals: rule.RedactPlanLiterals}
+	}
+	return spec
+}
+
+// CustomTool is a named, parameterized read-only query registered as its
+// own MCP tool. Params type the $1, $2, ... placeholders of SQL, in order.
+//
+//	tools:
+//	  active_users:
+//	    description: "Users created after a date"
+//	    sql: "SELECT id, email FROM users WHERE created_at > $1"
+//	    params:
+//	      - name: since
+//	        type: timestamp
+type CustomTool struct {
+	Description string      `yaml:"description"`
+	SQL         string      `yaml:"sql"`
+	Params      []ToolParam `yaml:"params"`
+}
+
+// ToolParam declares one parameter of a CustomTool. Every parameter is
+// required.
+type ToolParam struct {
+	Name        string           `yaml:"name"`
+	Type        domain.ParamType `yaml:"type"`
+	Description string           `yaml:"description,omitempty"`
+}
+
+// UnmarshalYAML also accepts a bare type, as in "params: [timestamp]". Such
+// a parameter is named after its position (p1, p2, ...) once loaded.
+func (tp *ToolParam) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		tp.Type = domain.ParamType(value.Value)
+		return nil
+	}
+	type alias ToolParam
+	var a alias
+	if err := value.Decode(&a); err != nil {
+		return fmt.Errorf("decoding tool parameter: %w", err)
+	}
+	*tp = ToolParam(a)
+	return nil
+}
+
+// ColumnPattern masks every column whose name matches the Name regex and,
+// optionally, whose data type equals DataType. Patterns are evaluated in
+// order; explicit column masks under context.tables take precedence.
+//
+//	column_patterns:
+//	  - name: "email"
+//	    data_type: "character varying"
+//	    mask: "redact"
+type ColumnPattern struct {
+	Name     string          `yaml:"name"`
+	DataType string          `yaml:"data_type,omitempty"`
+	Mask     domain.MaskType `yaml:"mask"`
 }
 
 // ContextConfig maps fully-qualified table names (schema.table) to
-// business descriptions that are merged into MCP tool responses.
+// business descriptions that are merged into MCP tool responses, and schema
+// names to display labels and schema-wide masking defaults.
 type ContextConfig struct {
-	Tables map[string]TableContext `yaml:"tables"`
+	Schemas map[string]SchemaContext `yaml:"schemas"`
+	Tables  map[string]TableContext  `yaml:"tables"`
+}
+
+// SchemaContext holds rules for every table in a schema, and a
+// human-readable label reported next to the real schema name.
+//
+//	schemas:
+//	  pii:
+//	    default_mask: "redact"
+//	  app_v2_prod:
+//	    label: "Production app"
+type SchemaContext struct {
+	DefaultMask domain.MaskType `yaml:"default_mask,omitempty"`
+	Label       string          `yaml:"label,omitempty"`
 }
 
-// TableContext provides business descriptions and masking rules for a table and its columns.
+// TableContext provides business descriptions and masking rules for a table
+// and its columns. DefaultMask masks every column without an explicit mask
+// and overrides the schema's default. MaxRows caps the rows of any query that
+// reads the table; it can only lower the configured limit. QueryTimeout caps
+// the statement timeout of such queries the same way.
 type TableContext struct {
-	Description string                   `yaml:"description"`
-	Columns     map[string]ColumnContext `yaml:"columns"`
+	Description  string                   `yaml:"description"`
+	DefaultMask  domain.MaskType          `yaml:"default_mask,omitempty"`
+	MaxRows      int                      `yaml:"max_rows,omitempty"`
+	QueryTimeout time.Duration            `yaml:"query_timeout,omitempty"`
+	Columns      map[string]ColumnContext `yaml:"columns"`
 }
 
-// ColumnContext holds a column's business description and optional mask directive.
+// DefaultMask returns the default mask for a table ("schema.table"): the
+// table's own default_mask, else its schema's, else "".
+func (c ContextConfig) DefaultMask(schema, table string) domain.MaskType {
+	if m := c.Tables[schema+"."+table].DefaultMask; m != "" {
+		return m
+	}
+	return c.Schemas[schema].DefaultMask
+}
+
+// SchemaLabel returns the display label for schema, or "".
+func (c ContextConfig) SchemaLabel(schema string) string {
+	return c.Schemas[schema].Label
+}
+
+// ColumnContext holds a column's business description and optional mask and
+// hidden directives. A hidden column is left out of describe_table entirely.
+// JSONMasks masks fields inside a json or jsonb column instead of the whole
+// value, keyed by dot-separated path:
+//
+//	metadata:
+//	  json_masks:
+//	    ssn: "redact"
+//	    contact.phone: "partial"
 type ColumnContext struct {
-	Description string          `yaml:"description"`
-	Mask        domain.MaskType `yaml:"mask,omitempty"`
+	Description string                     `yaml:"description"`
+	Mask        domain.MaskType            `yaml:"mask,omitempty"`
+	JSONMasks   map[string]domain.MaskType `yaml:"json_masks,omitempty"`
+	Hidden      bool                       `yaml:"hidden,omitempty"`
+}
+
+// equal reports whether two column contexts hold the same directives.
+func (cc ColumnContext) equal(other ColumnContext) bool {
+	return cc.Description == other.Description && cc.Mask == other.Mask &&
+		cc.Hidden == other.Hidden && maps.Equal(cc.JSONMasks, other.JSONMasks)
 }
 
 // UnmarshalYAML supports both the new struct format and the legacy plain-string format.
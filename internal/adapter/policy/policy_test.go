@@ -5,9 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/guillermoBallester/isthmus/internal/core/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -70,6 +72,26 @@ context:
 	assert.Equal(t, "Full name", customers.Columns["name"].Description)
 }
 
+func TestLoadFromFile_HiddenColumn(t *testing.T) {
+	yaml := `
+context:
+  tables:
+    public.customers:
+      columns:
+        ssn:
+          hidden: true
+        email: "Customer email"
+`
+	path := writeTempFile(t, yaml)
+
+	pol, err := LoadFromFile(path)
+	require.NoError(t, err)
+
+	customers := pol.Context.Tables["public.customers"]
+	assert.True(t, customers.Columns["ssn"].Hidden)
+	assert.False(t, customers.Columns["email"].Hidden)
+}
+
 func TestLoadFromFile_MixedFormats(t *testing.T) {
 	yaml := `
 context:
@@ -241,6 +263,151 @@ func TestMergeTableDetail_NilDetail(t *testing.T) {
 
 // --- MergeTableInfoList tests ---
 
+func TestMergeTableDetail_HiddenColumns(t *testing.T) {
+	ctx := ContextConfig{
+		Tables: map[string]TableContext{
+			"public.users": {
+				Columns: map[string]ColumnContext{
+					"ssn":   {Hidden: true},
+					"email": {Description: "User email", Mask: domain.MaskRedact},
+				},
+			},
+		},
+	}
+
+	detail := &port.TableDetail{
+		Schema: "public",
+		Name:   "users",
+		Columns: []port.ColumnInfo{
+			{Name: "id", IsPrimaryKey: true},
+			{Name: "email"},
+			{Name: "ssn"},
+			{Name: "ssn_issuer_id"},
+		},
+		ForeignKeys: []port.ForeignKey{
+			{ConstraintName: "users_ssn_fkey", ColumnName: "ssn", ReferencedTable: "ssn_registry"},
+			{ConstraintName: "users_issuer_fkey", ColumnName: "ssn_issuer_id", ReferencedTable: "issuers"},
+		},
+		InferredFKs: []port.InferredForeignKey{{ColumnName: "ssn", ReferencedTable: "ssns"}},
+		Indexes: []port.IndexInfo{
+			{Name: "users_pkey", Columns: []string{"id"}},
+			{Name: "users_email_ssn_idx", Columns: []string{"email", "ssn"}},
+		},
+		IndexUsage: []port.IndexUsage{{Name: "users_pkey"}, {Name: "users_email_ssn_idx"}},
+		CheckConstraints: []port.CheckConstraint{
+			{Name: "ssn_format", Columns: []string{"ssn"}},
+			{Name: "email_format", Columns: []string{"email"}},
+		},
+		Recommendations: []string{
+			`Foreign key "users_ssn_fkey" on (ssn) has no supporting index`,
+			`Foreign key "users_issuer_fkey" on (ssn_issuer_id) has no supporting index`,
+			`Index "users_email_ssn_idx" is about 40% bloat`,
+		},
+		SampleRows: []map[string]any{{"id": 1, "email": "a@example.com", "ssn": "123-45-6789"}},
+	}
+
+	MergeTableDetail(detail, ctx)
+
+	names := make([]string, 0, len(detail.Columns))
+	for _, c := range detail.Columns {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"id", "email", "ssn_issuer_id"}, names)
+	require.Len(t, detail.ForeignKeys, 1)
+	assert.Equal(t, "users_issuer_fkey", detail.ForeignKeys[0].ConstraintName)
+	assert.Empty(t, detail.InferredFKs)
+	require.Len(t, detail.Indexes, 1)
+	assert.Equal(t, "users_pkey", detail.Indexes[0].Name)
+	assert.Equal(t, []port.IndexUsage{{Name: "users_pkey"}}, detail.IndexUsage)
+	require.Len(t, detail.CheckConstraints, 1)
+	assert.Equal(t, "email_format", detail.CheckConstraints[0].Name)
+	assert.Equal(t, []string{`Foreign key "users_issuer_fkey" on (ssn_issuer_id) has no supporting index`}, detail.Recommendations)
+	assert.Equal(t, map[string]any{"id": 1, "email": "a@example.com"}, detail.SampleRows[0])
+}
+
+func TestMergeTableInfoList_HiddenColumnsNotCounted(t *testing.T) {
+	ctx := ContextConfig{
+		Tables: map[string]TableContext{
+			"public.users": {Columns: map[string]ColumnContext{
+				"ssn":   {Hidden: true},
+				"email": {Description: "User email"},
+			}},
+		},
+	}
+
+	tables := []port.TableInfo{
+		{Schema: "public", Name: "users", ColumnCount: 5},
+		{Schema: "public", Name: "orders", ColumnCount: 4},
+	}
+
+	MergeTableInfoList(tables, ctx, map[string][]string{"public.users": {"id", "email", "ssn", "name", "created_at"}})
+
+	assert.Equal(t, 4, tables[0].ColumnCount)
+	assert.Equal(t, 4, tables[1].ColumnCount)
+}
+
+func TestMergeTableInfoList_MissingHiddenColumnsNotSubtracted(t *testing.T) {
+	ctx := ContextConfig{
+		Tables: map[string]TableContext{
+			"public.users": {Columns: map[string]ColumnContext{
+				"ssn":      {Hidden: true},
+				"ssn_typo": {Hidden: true},
+				"dropped":  {Hidden: true},
+			}},
+		},
+	}
+	tables := []port.TableInfo{{Schema: "public", Name: "users", ColumnCount: 3}}
+
+	MergeTableInfoList(tables, ctx, map[string][]string{"public.users": {"id", "email", "ssn"}})
+	assert.Equal(t, 2, tables[0].ColumnCount, "only ssn exists")
+
+	tables = []port.TableInfo{{Schema: "public", Name: "users", ColumnCount: 3}}
+	MergeTableInfoList(tables, ctx, nil)
+	assert.Equal(t, 3, tables[0].ColumnCount, "nothing is subtracted without the table's columns")
+}
+
+func TestPolicyExplorer_ListTablesChecksHiddenColumns(t *testing.T) {
+	pol := &Policy{Context: ContextConfig{
+		Tables: map[string]TableContext{
+			"public.users": {Columns: map[string]ColumnContext{
+				"ssn":     {Hidden: true},
+				"dropped": {Hidden: true},
+			}},
+			"public.orders": {Description: "Orders"},
+		},
+	}}
+	inner := &mockExplorer{listTablesResult: []port.TableInfo{{Schema: "public", Name: "users", ColumnCount: 3}}}
+	lister := &stubColumnLister{columns: map[string][]string{"public.users": {"id", "email", "ssn"}}}
+
+	tables, err := NewPolicyExplorer(inner, pol, nil, WithColumnLister(lister)).ListTables(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, tables[0].ColumnCount)
+	assert.Equal(t, []string{"public.users"}, lister.names)
+}
+
+func TestPolicyExplorer_ForeignKeysHidesColumns(t *testing.T) {
+	pol := &Policy{Context: ContextConfig{
+		Tables: map[string]TableContext{
+			"public.orders": {Columns: map[string]ColumnContext{"referrer_id": {Hidden: true}}},
+		},
+	}}
+	inner := &mockExplorer{foreignKeys: map[string][]port.ForeignKey{
+		"public.orders": {
+			{ConstraintName: "orders_user_fkey", ColumnName: "user_id", ReferencedTable: "users", ReferencedColumn: "id"},
+			{ConstraintName: "orders_referrer_fkey", ColumnName: "referrer_id", ReferencedTable: "users", ReferencedColumn: "id"},
+		},
+		"public.reviews": {
+			{ConstraintName: "reviews_referrer_fkey", ColumnName: "referrer_id", ReferencedTable: "users", ReferencedColumn: "id"},
+		},
+	}}
+
+	fks, err := NewPolicyExplorer(inner, pol, nil).ForeignKeys(context.Background())
+	require.NoError(t, err)
+	require.Len(t, fks["public.orders"], 1)
+	assert.Equal(t, "orders_user_fkey", fks["public.orders"][0].ConstraintName)
+	assert.Len(t, fks["public.reviews"], 1, "only the table that hides the column loses its key")
+}
+
 func TestMergeTableInfoList(t *testing.T) {
 	ctx := ContextConfig{
 		Tables: map[string]TableContext{
@@ -255,7 +422,7 @@ func TestMergeTableInfoList(t *testing.T) {
 		{Schema: "public", Name: "products", Comment: ""},
 	}
 
-	MergeTableInfoList(tables, ctx)
+	MergeTableInfoList(tables, ctx, nil)
 
 	assert.Equal(t, "Platform users", tables[0].Comment)
 	assert.Equal(t, "Existing comment", tables[1].Comment)
@@ -264,6 +431,30 @@ func TestMergeTableInfoList(t *testing.T) {
 
 // --- MaskSpec tests ---
 
+func TestFilterTables(t *testing.T) {
+	tables := []port.TableInfo{
+		{Schema: "public", Name: "users"},
+		{Schema: "public", Name: "orders"},
+		{Schema: "analytics", Name: "users"},
+		{Schema: "public", Name: "audit_log"},
+	}
+	ctx := ContextConfig{
+		Tables: map[string]TableContext{
+			"public.users":    {Description: "Users"},
+			"public.orders":   {},
+			"public.invoices": {}, // in policy but not in the database
+		},
+	}
+
+	got := FilterTables(tables, ctx)
+	assert.Equal(t, []port.TableInfo{
+		{Schema: "public", Name: "users"},
+		{Schema: "public", Name: "orders"},
+	}, got)
+
+	assert.Empty(t, FilterTables(tables, ContextConfig{}))
+}
+
 func TestMaskSpec(t *testing.T) {
 	ctx := ContextConfig{
 		Tables: map[string]TableContext{
@@ -300,6 +491,236 @@ func TestMaskSpec_Empty(t *testing.T) {
 	assert.Empty(t, spec)
 }
 
+func TestRowLimitSpec(t *testing.T) {
+	path := writeTempFile(t, `
+context:
+  tables:
+    public.users:
+      description: "no limit"
+    hr.salaries:
+      max_rows: 10
+`)
+	pol, err := LoadFromFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[domain.TableRef]int{{Schema: "hr", Name: "salaries"}: 10}, RowLimitSpec(pol.Context))
+
+	_, err = LoadFromFile(writeTempFile(t, "context:\n  tables:\n    hr.salaries:\n      max_rows: -1\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `context.tables["hr.salaries"].max_rows`)
+}
+
+func TestTimeoutSpec(t *testing.T) {
+	path := writeTempFile(t, `
+context:
+  tables:
+    public.users:
+      description: "no timeout"
+    hr.salaries:
+      query_timeout: 2s
+    public.events:
+      max_rows: 100
+      query_timeout: 1m30s
+`)
+	pol, err := LoadFromFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[domain.TableRef]time.Duration{
+		{Schema: "hr", Name: "salaries"}:   2 * time.Second,
+		{Schema: "public", Name: "events"}: 90 * time.Second,
+	}, TimeoutSpec(pol.Context))
+
+	_, err = LoadFromFile(writeTempFile(t, "context:\n  tables:\n    hr.salaries:\n      query_timeout: -1s\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `context.tables["hr.salaries"].query_timeout`)
+
+	_, err = LoadFromFile(writeTempFile(t, "context:\n  tables:\n    hr.salaries:\n      query_timeout: soon\n"))
+	require.Error(t, err)
+}
+
+func TestLoadFromFiles_QueryTimeoutConflict(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", "context:\n  tables:\n    hr.salaries:\n      query_timeout: 2s\n")
+	second := writeFileIn(t, dir, "b.yaml", "context:\n  tables:\n    hr.salaries:\n      query_timeout: 5s\n")
+
+	_, err := LoadFromFiles([]string{first, second})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "conflicting query_timeout")
+
+	same := writeFileIn(t, dir, "c.yaml", "context:\n  tables:\n    hr.salaries:\n      description: Payroll\n      query_timeout: 2s\n")
+	pol, err := LoadFromFiles([]string{first, same})
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, pol.Context.Tables["hr.salaries"].QueryTimeout)
+}
+
+func TestLoadFromFiles_MaxRowsConflict(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", "context:\n  tables:\n    hr.salaries:\n      max_rows: 10\n")
+	second := writeFileIn(t, dir, "b.yaml", "context:\n  tables:\n    hr.salaries:\n      max_rows: 20\n")
+	third := writeFileIn(t, dir, "c.yaml", "context:\n  tables:\n    hr.salaries:\n      description: \"Pay\"\n")
+
+	_, err := LoadFromFiles([]string{first, second})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `table "hr.salaries" has conflicting max_rows`)
+
+	pol, err := LoadFromFiles([]string{third, first})
+	require.NoError(t, err)
+	assert.Equal(t, 10, pol.Context.Tables["hr.salaries"].MaxRows)
+}
+
+// --- Default mask tests ---
+
+func TestLoadFromFile_DefaultMasks(t *testing.T) {
+	yaml := `
+context:
+  schemas:
+    pii:
+      default_mask: "redact"
+  tables:
+    public.payments:
+      default_mask: "hash"
+`
+	path := writeTempFile(t, yaml)
+
+	pol, err := LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, domain.MaskRedact, pol.Context.Schemas["pii"].DefaultMask)
+	assert.Equal(t, domain.MaskHash, pol.Context.Tables["public.payments"].DefaultMask)
+}
+
+func TestLoadFromFile_DefaultMaskInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"schema unknown mask", "context:\n  schemas:\n    pii:\n      default_mask: \"scramble\"\n", `context.schemas["pii"].default_mask`},
+		{"schema without mask", "context:\n  schemas:\n    pii: {}\n", `context.schemas["pii"].default_mask`},
+		{"table unknown mask", "context:\n  tables:\n    public.users:\n      default_mask: \"scramble\"\n", `context.tables["public.users"].default_mask`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFromFile(writeTempFile(t, tt.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestContextConfig_DefaultMask(t *testing.T) {
+	ctx := ContextConfig{
+		Schemas: map[string]SchemaContext{"pii": {DefaultMask: domain.MaskRedact}},
+		Tables: map[string]TableContext{
+			"pii.cards":     {DefaultMask: domain.MaskNull},
+			"pii.people":    {Description: "no default of its own"},
+			"public.events": {DefaultMask: domain.MaskHash},
+		},
+	}
+
+	assert.Equal(t, domain.MaskNull, ctx.DefaultMask("pii", "cards"), "table default overrides the schema's")
+	assert.Equal(t, domain.MaskRedact, ctx.DefaultMask("pii", "people"))
+	assert.Equal(t, domain.MaskRedact, ctx.DefaultMask("pii", "unlisted"))
+	assert.Equal(t, domain.MaskHash, ctx.DefaultMask("public", "events"))
+	assert.Empty(t, ctx.DefaultMask("public", "users"))
+}
+
+// stubColumnLister returns fixed columns and records what was asked for.
+type stubColumnLister struct {
+	columns        map[string][]string
+	schemas, names []string
+}
+
+func (s *stubColumnLister) ColumnNames(_ context.Context, schemas, tables []string) (map[string][]string, error) {
+	s.schemas, s.names = schemas, tables
+	return s.columns, nil
+}
+
+func TestDefaultMaskSpec(t *testing.T) {
+	cc := ContextConfig{
+		Schemas: map[string]SchemaContext{
+			"pii": {DefaultMask: domain.MaskRedact},
+			"app": {Label: "App"},
+		},
+		Tables: map[string]TableContext{
+			"pii.cards":    {DefaultMask: domain.MaskNull},
+			"public.audit": {DefaultMask: domain.MaskHash},
+			"public.users": {Description: "not covered"},
+		},
+	}
+
+	tables, schemas := DefaultMaskSpec(cc)
+	assert.Equal(t, map[domain.TableRef]domain.MaskType{
+		{Schema: "pii", Name: "cards"}:    domain.MaskNull,
+		{Schema: "public", Name: "audit"}: domain.MaskHash,
+	}, tables)
+	assert.Equal(t, map[string]domain.MaskType{"pii": domain.MaskRedact}, schemas, "a schema with only a label has no default")
+
+	tables, schemas = DefaultMaskSpec(ContextConfig{})
+	assert.Nil(t, tables)
+	assert.Nil(t, schemas)
+}
+
+func TestPolicyExplorer_SampleRowsDefaultMask(t *testing.T) {
+	pol := &Policy{Context: ContextConfig{
+		Schemas: map[string]SchemaContext{"pii": {DefaultMask: domain.MaskRedact}},
+		Tables: map[string]TableContext{
+			"pii.people": {Columns: map[string]ColumnContext{"email": {Mask: domain.MaskNull}}},
+		},
+	}}
+	detail := func(schema string) *port.TableDetail {
+		return &port.TableDetail{
+			Schema:     schema,
+			Name:       "people",
+			Columns:    []port.ColumnInfo{{Name: "id"}, {Name: "email"}},
+			SampleRows: []map[string]any{{"id": 1, "email": "a@example.com"}},
+		}
+	}
+
+	pe := NewPolicyExplorer(&mockExplorer{describeResult: detail("pii")}, pol, MaskSpec(pol.Context))
+	got, err := pe.DescribeTable(context.Background(), "pii", "people")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": "***", "email": nil}, got.SampleRows[0])
+
+	pe = NewPolicyExplorer(&mockExplorer{describeResult: detail("public")}, pol, nil)
+	got, err = pe.DescribeTable(context.Background(), "public", "people")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": 1, "email": "a@example.com"}, got.SampleRows[0], "the pii default does not reach other schemas")
+}
+
+func TestMissingMaskedColumns(t *testing.T) {
+	cc := ContextConfig{
+		Tables: map[string]TableContext{
+			"public.users": {Columns: map[string]ColumnContext{
+				"email":  {Mask: domain.MaskRedact},
+				"emial":  {Mask: domain.MaskHash}, // typo
+				"nick":   {Description: "not masked, not checked"},
+				"hidden": {Hidden: true},
+			}},
+			"public.gone": {Columns: map[string]ColumnContext{"ssn": {Mask: domain.MaskRedact}}},
+			"public.docs": {Description: "no masks"},
+		},
+	}
+	lister := &stubColumnLister{columns: map[string][]string{
+		"public.users": {"id", "email"},
+	}}
+
+	missing, err := MissingMaskedColumns(context.Background(), lister, cc)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"public.gone.ssn", "public.users.emial"}, missing)
+	assert.Nil(t, lister.schemas)
+	assert.ElementsMatch(t, []string{"public.users", "public.gone"}, lister.names)
+}
+
+func TestMissingMaskedColumns_NoMasks(t *testing.T) {
+	lister := &stubColumnLister{}
+	missing, err := MissingMaskedColumns(context.Background(), lister, ContextConfig{
+		Tables: map[string]TableContext{"public.users": {Description: "users"}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+	assert.Nil(t, lister.names, "the catalog is not queried without masks")
+}
+
 // --- Conflict detection tests ---
 
 func TestLoadFromFile_ConflictingMasks(t *testing.T) {
@@ -343,6 +764,106 @@ context:
 	assert.Len(t, pol.Context.Tables, 2)
 }
 
+func TestLoadFromFile_ColumnPatterns(t *testing.T) {
+	yaml := `
+column_patterns:
+  - name: "email"
+    data_type: "character varying"
+    mask: "redact"
+  - name: "^ssn$"
+    mask: "null"
+`
+	path := writeTempFile(t, yaml)
+
+	pol, err := LoadFromFile(path)
+	require.NoError(t, err)
+	require.Len(t, pol.ColumnPatterns, 2)
+	assert.Equal(t, ColumnPattern{Name: "email", DataType: "character varying", Mask: domain.MaskRedact}, pol.ColumnPatterns[0])
+
+	patterns := MaskPatterns(pol.ColumnPatterns)
+	require.Len(t, patterns, 2)
+	assert.True(t, patterns[0].Matches("work_email", "character varying"))
+	assert.False(t, patterns[1].Matches("ssn_hash", ""))
+}
+
+func TestLoadFromFile_ColumnPatternsInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		contains string
+	}{
+		{"missing name", "column_patterns:\n  - mask: redact\n", "name is required"},
+		{"bad regex", "column_patterns:\n  - name: \"(\"\n    mask: redact\n", "invalid regex"},
+		{"missing mask", "column_patterns:\n  - name: email\n", "mask"},
+		{"unknown mask", "column_patterns:\n  - name: email\n    mask: scramble\n", "scramble"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFromFile(writeTempFile(t, tt.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.contains)
+		})
+	}
+}
+
+func TestLoadFromFile_JSONMasks(t *testing.T) {
+	yaml := `
+context:
+  tables:
+    public.users:
+      columns:
+        metadata:
+          description: "Free-form profile data"
+          json_masks:
+            ssn: "redact"
+            contact.phone: "partial"
+    public.orders:
+      columns:
+        metadata:
+          json_masks:
+            ssn: "redact"
+`
+	pol, err := LoadFromFile(writeTempFile(t, yaml))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]domain.JSONPathMask{
+		"metadata": {
+			{Path: "contact.phone", Mask: domain.MaskPartial},
+			{Path: "ssn", Mask: domain.MaskRedact},
+		},
+	}, JSONMaskSpec(pol.Context))
+	assert.Empty(t, MaskSpec(pol.Context), "the column itself is not masked")
+}
+
+func TestLoadFromFile_JSONMasksInvalid(t *testing.T) {
+	column := func(body string) string {
+		return "context:\n  tables:\n    public.users:\n      columns:\n        metadata:\n" + body
+	}
+	tests := []struct {
+		name     string
+		yaml     string
+		contains string
+	}{
+		{"empty key", column("          json_masks:\n            contact..phone: redact\n"), "empty key"},
+		{"unknown mask", column("          json_masks:\n            ssn: scramble\n"), "scramble"},
+		{"missing mask", column("          json_masks:\n            ssn: \"\"\n"), "invalid value"},
+		{"with column mask", column("          mask: redact\n          json_masks:\n            ssn: redact\n"), "cannot be combined"},
+		{
+			"conflicting tables",
+			column("          json_masks:\n            ssn: redact\n") +
+				"    public.orders:\n      columns:\n        metadata:\n          json_masks:\n            ssn: hash\n",
+			"conflicting json_masks",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFromFile(writeTempFile(t, tt.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.contains)
+		})
+	}
+}
+
 // --- PolicyExplorer tests ---
 
 func TestPolicyExplorer_DescribeTable(t *testing.T) {
@@ -406,6 +927,86 @@ func TestPolicyExplorer_DescribeTable_MasksSampleRows(t *testing.T) {
 	assert.Equal(t, 1, detail.SampleRows[0]["id"])
 }
 
+func TestPolicyExplorer_DescribeTable_MasksSampleJSON(t *testing.T) {
+	inner := &mockExplorer{
+		describeResult: &port.TableDetail{
+			Schema:  "public",
+			Name:    "users",
+			Columns: []port.ColumnInfo{{Name: "id"}, {Name: "metadata"}},
+			SampleRows: []map[string]any{
+				{"id": 1, "metadata": map[string]any{"ssn": "123-45-6789", "plan": "pro"}},
+			},
+		},
+	}
+	pol := &Policy{Context: ContextConfig{Tables: map[string]TableContext{
+		"public.users": {Columns: map[string]ColumnContext{
+			"metadata": {JSONMasks: map[string]domain.MaskType{"ssn": domain.MaskRedact}},
+		}},
+	}}}
+	pe := NewPolicyExplorer(inner, pol, MaskSpec(pol.Context))
+
+	detail, err := pe.DescribeTable(context.Background(), "public", "users")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"ssn": "***", "plan": "pro"}, detail.SampleRows[0]["metadata"])
+}
+
+func TestPolicyExplorer_DescribeTable_ToolMasking(t *testing.T) {
+	newDetail := func() *port.TableDetail {
+		return &port.TableDetail{
+			Schema:     "public",
+			Name:       "users",
+			Columns:    []port.ColumnInfo{{Name: "id"}, {Name: "email"}},
+			SampleRows: []map[string]any{{"id": 1, "email": "alice@example.com"}},
+		}
+	}
+	pol := &Policy{ToolMasking: map[string]ToolMaskingRule{"describe_table": {SkipColumns: true}}}
+	masks := map[string]domain.MaskType{"email": domain.MaskRedact}
+
+	pe := NewPolicyExplorer(&mockExplorer{describeResult: newDetail()}, pol, masks)
+	detail, err := pe.DescribeTable(service.WithToolName(context.Background(), "describe_table"), "public", "users")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", detail.SampleRows[0]["email"], "describe_table skips masking")
+
+	pe = NewPolicyExplorer(&mockExplorer{describeResult: newDetail()}, pol, masks)
+	detail, err = pe.DescribeTable(context.Background(), "public", "users")
+	require.NoError(t, err)
+	assert.Equal(t, "***", detail.SampleRows[0]["email"], "other callers, such as table resources, are still masked")
+}
+
+func TestPolicyExplorer_DescribeTable_ColumnPatterns(t *testing.T) {
+	inner := &mockExplorer{
+		describeResult: &port.TableDetail{
+			Schema: "public",
+			Name:   "users",
+			Columns: []port.ColumnInfo{
+				{Name: "id", DataType: "integer"},
+				{Name: "email", DataType: "character varying"},
+				{Name: "backup_email", DataType: "character varying"},
+				{Name: "email_verified", DataType: "boolean"},
+			},
+			SampleRows: []map[string]any{
+				{"id": 1, "email": "alice@example.com", "backup_email": "a@backup.example", "email_verified": true},
+			},
+		},
+	}
+
+	pol := &Policy{
+		ColumnPatterns: []ColumnPattern{{Name: "email", DataType: "character varying", Mask: domain.MaskRedact}},
+	}
+	// The explicit mask on "email" takes precedence over the pattern.
+	masks := map[string]domain.MaskType{"email": domain.MaskNull}
+	pe := NewPolicyExplorer(inner, pol, masks)
+
+	detail, err := pe.DescribeTable(context.Background(), "public", "users")
+	require.NoError(t, err)
+
+	row := detail.SampleRows[0]
+	assert.Nil(t, row["email"], "explicit mask wins over pattern")
+	assert.Equal(t, "***", row["backup_email"], "pattern applies to matching name and type")
+	assert.Equal(t, true, row["email_verified"], "data type mismatch is not masked")
+	assert.Equal(t, 1, row["id"])
+}
+
 func TestPolicyExplorer_ListTables(t *testing.T) {
 	inner := &mockExplorer{
 		listTablesResult: []port.TableInfo{
@@ -475,6 +1076,71 @@ func TestPolicyExplorer_Discover(t *testing.T) {
 	assert.Equal(t, "Existing", result.Schemas[0].Tables[1].Comment) // not overwritten
 }
 
+func TestPolicyExplorer_SchemaLabels(t *testing.T) {
+	inner := &mockExplorer{
+		listSchemasResult: []port.SchemaInfo{{Name: "app_v2_prod"}, {Name: "public"}},
+		listTablesResult:  []port.TableInfo{{Schema: "app_v2_prod", Name: "orders"}, {Schema: "public", Name: "users"}},
+		describeResult:    &port.TableDetail{Schema: "app_v2_prod", Name: "orders"},
+		discoverResult: &port.DiscoveryResult{Schemas: []port.SchemaOverview{
+			{Name: "app_v2_prod", Tables: []port.TableInfo{{Schema: "app_v2_prod", Name: "orders"}}},
+		}},
+	}
+	pol := &Policy{Context: ContextConfig{
+		Schemas: map[string]SchemaContext{"app_v2_prod": {Label: "Production app"}},
+	}}
+	pe := NewPolicyExplorer(inner, pol, nil)
+	ctx := context.Background()
+
+	schemas, err := pe.ListSchemas(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []port.SchemaInfo{{Name: "app_v2_prod", SchemaLabel: "Production app"}, {Name: "public"}}, schemas)
+
+	tables, err := pe.ListTables(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "app_v2_prod", tables[0].Schema, "the real name is kept for queries")
+	assert.Equal(t, "Production app", tables[0].SchemaLabel)
+	assert.Empty(t, tables[1].SchemaLabel)
+
+	detail, err := pe.DescribeTable(ctx, "app_v2_prod", "orders")
+	require.NoError(t, err)
+	assert.Equal(t, "Production app", detail.SchemaLabel)
+
+	result, err := pe.Discover(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Production app", result.Schemas[0].SchemaLabel)
+	assert.Equal(t, "Production app", result.Schemas[0].Tables[0].SchemaLabel)
+}
+
+func TestLoadFromFiles_SchemaLabels(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", `
+context:
+  schemas:
+    app_v2_prod:
+      label: "Production app"
+`)
+	second := writeFileIn(t, dir, "b.yaml", `
+context:
+  schemas:
+    app_v2_prod:
+      default_mask: "redact"
+`)
+	third := writeFileIn(t, dir, "c.yaml", `
+context:
+  schemas:
+    app_v2_prod:
+      label: "Staging app"
+`)
+
+	pol, err := LoadFromFiles([]string{first, second})
+	require.NoError(t, err)
+	assert.Equal(t, SchemaContext{DefaultMask: domain.MaskRedact, Label: "Production app"}, pol.Context.Schemas["app_v2_prod"])
+
+	_, err = LoadFromFiles([]string{first, third})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `schema "app_v2_prod" has conflicting labels`)
+}
+
 // --- helpers ---
 
 type mockExplorer struct {
@@ -482,6 +1148,7 @@ type mockExplorer struct {
 	listTablesResult  []port.TableInfo
 	describeResult    *port.TableDetail
 	discoverResult    *port.DiscoveryResult
+	foreignKeys       map[string][]port.ForeignKey
 }
 
 func (m *mockExplorer) ListSchemas(_ context.Context) ([]port.SchemaInfo, error) {
@@ -500,6 +1167,579 @@ func (m *mockExplorer) Discover(_ context.Context) (*port.DiscoveryResult, error
 	return m.discoverResult, nil
 }
 
+func (m *mockExplorer) TableActivity(_ context.Context) ([]port.TableActivity, error) {
+	return nil, nil
+}
+
+func (m *mockExplorer) ForeignKeys(_ context.Context) (map[string][]port.ForeignKey, error) {
+	return m.foreignKeys, nil
+}
+
+// --- LoadFromFiles tests ---
+
+func TestLoadFromFiles_MergesTablesAndPatterns(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", `
+context:
+  tables:
+    public.users:
+      description: "Registered platform users"
+      columns:
+        email:
+          mask: "redact"
+column_patterns:
+  - name: "ssn"
+    mask: "null"
+`)
+	second := writeFileIn(t, dir, "b.yaml", `
+context:
+  tables:
+    public.users:
+      columns:
+        mrr: "Monthly Recurring Revenue in cents"
+    public.orders:
+      description: "Purchase orders"
+column_patterns:
+  - name: "phone"
+    mask: "partial"
+`)
+
+	pol, err := LoadFromFiles([]string{first, second})
+	require.NoError(t, err)
+	require.Len(t, pol.Context.Tables, 2)
+
+	users := pol.Context.Tables["public.users"]
+	assert.Equal(t, "Registered platform users", users.Description)
+	assert.Equal(t, domain.MaskRedact, users.Columns["email"].Mask)
+	assert.Equal(t, "Monthly Recurring Revenue in cents", users.Columns["mrr"].Description)
+	assert.Equal(t, "Purchase orders", pol.Context.Tables["public.orders"].Description)
+
+	require.Len(t, pol.ColumnPatterns, 2)
+	assert.Equal(t, "ssn", pol.ColumnPatterns[0].Name)
+	assert.Equal(t, "phone", pol.ColumnPatterns[1].Name)
+}
+
+func TestLoadFromFiles_CrossFileMaskConflict(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", `
+context:
+  tables:
+    public.users:
+      columns:
+        email:
+          mask: "redact"
+`)
+	second := writeFileIn(t, dir, "b.yaml", `
+context:
+  tables:
+    public.orders:
+      columns:
+        email:
+          mask: "hash"
+`)
+
+	_, err := LoadFromFiles([]string{first, second})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "conflicting masks")
+	assert.Contains(t, err.Error(), "email")
+}
+
+func TestLoadFromFiles_DuplicateTableConflictingDescription(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", `
+context:
+  tables:
+    public.users:
+      description: "Registered platform users"
+`)
+	second := writeFileIn(t, dir, "b.yaml", `
+context:
+  tables:
+    public.users:
+      description: "Something else"
+`)
+
+	_, err := LoadFromFiles([]string{first, second})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "conflicting descriptions")
+	assert.Contains(t, err.Error(), first)
+	assert.Contains(t, err.Error(), second)
+}
+
+func TestLoadFromFiles_DefaultMaskConflict(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", `
+context:
+  schemas:
+    pii:
+      default_mask: "redact"
+  tables:
+    public.users:
+      default_mask: "hash"
+`)
+	second := writeFileIn(t, dir, "b.yaml", `
+context:
+  schemas:
+    pii:
+      default_mask: "null"
+`)
+	third := writeFileIn(t, dir, "c.yaml", `
+context:
+  tables:
+    public.users:
+      default_mask: "null"
+`)
+
+	_, err := LoadFromFiles([]string{first, second})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `schema "pii" has conflicting default masks`)
+
+	_, err = LoadFromFiles([]string{first, third})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `table "public.users" has conflicting default masks`)
+
+	pol, err := LoadFromFiles([]string{first, first})
+	require.NoError(t, err, "identical defaults are allowed")
+	assert.Equal(t, domain.MaskRedact, pol.Context.Schemas["pii"].DefaultMask)
+	assert.Equal(t, domain.MaskHash, pol.Context.Tables["public.users"].DefaultMask)
+}
+
+func TestLoadFromFiles_DuplicateColumnConflict(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", `
+context:
+  tables:
+    public.users:
+      columns:
+        mrr: "Monthly Recurring Revenue in cents"
+`)
+	second := writeFileIn(t, dir, "b.yaml", `
+context:
+  tables:
+    public.users:
+      columns:
+        mrr: "Monthly Recurring Revenue in dollars"
+`)
+
+	_, err := LoadFromFiles([]string{first, second})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `column "mrr"`)
+}
+
+func TestLoadFromFiles_JSONMaskConflict(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", `
+context:
+  tables:
+    public.users:
+      columns:
+        metadata:
+          json_masks:
+            ssn: "redact"
+`)
+	second := writeFileIn(t, dir, "b.yaml", `
+context:
+  tables:
+    public.users:
+      columns:
+        metadata:
+          json_masks:
+            ssn: "hash"
+`)
+
+	_, err := LoadFromFiles([]string{first, second})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `column "metadata"`)
+
+	// The same paths in both files merge cleanly.
+	third := writeFileIn(t, dir, "c.yaml", `
+context:
+  tables:
+    public.users:
+      columns:
+        metadata:
+          json_masks:
+            ssn: "redact"
+`)
+	_, err = LoadFromFiles([]string{first, third})
+	require.NoError(t, err)
+}
+
+func TestLoadFromFiles_IdenticalDuplicatesAllowed(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+context:
+  tables:
+    public.users:
+      description: "Registered platform users"
+      columns:
+        email:
+          mask: "redact"
+`
+	first := writeFileIn(t, dir, "a.yaml", content)
+	second := writeFileIn(t, dir, "b.yaml", content)
+
+	pol, err := LoadFromFiles([]string{first, second})
+	require.NoError(t, err)
+	assert.Len(t, pol.Context.Tables["public.users"].Columns, 1)
+}
+
+func TestLoadFromFiles_ToolDescriptions(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", `
+tool_descriptions:
+  query: "Run read-only SQL. Never select columns tagged as restricted."
+`)
+	second := writeFileIn(t, dir, "b.yaml", `
+tool_descriptions:
+  query: "Run read-only SQL. Never select columns tagged as restricted."
+  discover: "List the data warehouse tables."
+`)
+
+	pol, err := LoadFromFiles([]string{first, second})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"query":    "Run read-only SQL. Never select columns tagged as restricted.",
+		"discover": "List the data warehouse tables.",
+	}, pol.ToolDescriptions)
+
+	third := writeFileIn(t, dir, "c.yaml", `
+tool_descriptions:
+  query: "Something else"
+`)
+	_, err = LoadFromFiles([]string{first, third})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `tool "query" has conflicting descriptions`)
+	assert.Contains(t, err.Error(), first)
+}
+
+func TestLoadFromFile_CustomTools(t *testing.T) {
+	path := writeTempFile(t, `
+tools:
+  orders_since:
+    description: "Orders placed since a given time."
+    sql: "SELECT id, total FROM public.orders WHERE created_at >= $1 AND status = $2"
+    params:
+      - name: since
+        type: timestamp
+        description: "Lower bound, inclusive"
+      - name: status
+        type: text
+  active_users:
+    description: "Users that logged in recently."
+    sql: "SELECT id FROM public.users WHERE last_login > now() - make_interval(days => $1)"
+    params: [integer]
+`)
+
+	pol, err := LoadFromFile(path)
+	require.NoError(t, err)
+	require.Len(t, pol.Tools, 2)
+
+	orders := pol.Tools["orders_since"]
+	assert.Equal(t, "Orders placed since a given time.", orders.Description)
+	assert.Equal(t, []ToolParam{
+		{Name: "since", Type: domain.ParamTimestamp, Description: "Lower bound, inclusive"},
+		{Name: "status", Type: domain.ParamText},
+	}, orders.Params)
+
+	assert.Equal(t, []ToolParam{{Name: "p1", Type: domain.ParamInteger}}, pol.Tools["active_users"].Params,
+		"shorthand params are named by position")
+}
+
+func TestLoadFromFile_CustomToolsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    string
+		wantErr string
+	}{
+		{
+			name: "write statement",
+			tool: `
+    description: "Delete orders"
+    sql: "DELETE FROM public.orders"`,
+			wantErr: `tools["t"].sql`,
+		},
+		{
+			name: "param count mismatch",
+			tool: `
+    description: "Orders"
+    sql: "SELECT * FROM public.orders WHERE id = $1 AND status = $2"
+    params: [integer]`,
+			wantErr: "sql uses 2 parameters but params declares 1",
+		},
+		{
+			name: "unknown param type",
+			tool: `
+    description: "Orders"
+    sql: "SELECT * FROM public.orders WHERE id = $1"
+    params: [bigserial]`,
+			wantErr: `tools["t"].params[0].type: invalid value "bigserial"`,
+		},
+		{
+			name: "duplicate param name",
+			tool: `
+    description: "Orders"
+    sql: "SELECT * FROM public.orders WHERE id = $1 OR id = $2"
+    params:
+      - {name: id, type: integer}
+      - {name: id, type: integer}`,
+			wantErr: `duplicate name "id"`,
+		},
+		{
+			name: "missing description",
+			tool: `
+    sql: "SELECT 1"`,
+			wantErr: `tools["t"].description is required`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTempFile(t, "tools:\n  t:"+tt.tool+"\n")
+			_, err := LoadFromFile(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	path := writeTempFile(t, `
+tools:
+  Orders-Since:
+    description: "Orders"
+    sql: "SELECT 1"
+`)
+	_, err := LoadFromFile(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "name must be lowercase letters")
+}
+
+func TestLoadFromFiles_CustomToolConflict(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", `
+tools:
+  order_count:
+    description: "Number of orders."
+    sql: "SELECT count(*) FROM public.orders"
+`)
+	second := writeFileIn(t, dir, "b.yaml", `
+tools:
+  order_count:
+    description: "Number of orders."
+    sql: "SELECT count(*) FROM public.orders"
+  user_count:
+    description: "Number of users."
+    sql: "SELECT count(*) FROM public.users"
+`)
+
+	pol, err := LoadFromFiles([]string{first, second})
+	require.NoError(t, err, "identical definitions merge")
+	assert.Len(t, pol.Tools, 2)
+
+	third := writeFileIn(t, dir, "c.yaml", `
+tools:
+  order_count:
+    description: "Number of open orders."
+    sql: "SELECT count(*) FROM public.orders WHERE status = 'open'"
+`)
+	_, err = LoadFromFiles([]string{first, third})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `tool "order_count" is defined differently`)
+	assert.Contains(t, err.Error(), first)
+	assert.Contains(t, err.Error(), third)
+}
+
+func TestLoadFromFiles_ToolMasking(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFileIn(t, dir, "a.yaml", `
+tool_masking:
+  query:
+    redact_plan_literals: true
+`)
+	second := writeFileIn(t, dir, "b.yaml", `
+tool_masking:
+  query:
+    redact_plan_literals: true
+  orders_report:
+    skip_columns: true
+`)
+
+	pol, err := LoadFromFiles([]string{first, second})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]domain.ToolMasking{
+		"query":         {RedactPlanLiterals: true},
+		"orders_report": {SkipColumns: true},
+	}, pol.ToolMaskSpec())
+
+	third := writeFileIn(t, dir, "c.yaml", `
+tool_masking:
+  query:
+    skip_columns: true
+`)
+	_, err = LoadFromFiles([]string{first, third})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `tool "query" has conflicting tool_masking rules`)
+	assert.Contains(t, err.Error(), first)
+}
+
+func TestLoadFromFiles_Empty(t *testing.T) {
+	_, err := LoadFromFiles(nil)
+	require.Error(t, err)
+}
+
+func TestFilesInDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFileIn(t, dir, "b.yml", "context: {}")
+	writeFileIn(t, dir, "a.yaml", "context: {}")
+	writeFileIn(t, dir, "notes.txt", "ignored")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub.yaml"), 0o755))
+
+	paths, err := FilesInDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yml")}, paths)
+}
+
+func TestFilesInDir_Missing(t *testing.T) {
+	_, err := FilesInDir(filepath.Join(t.TempDir(), "nope"))
+	require.Error(t, err)
+}
+
+// --- Context file tests ---
+
+const sampleManifest = `{
+  "metadata": {"dbt_schema_version": "https://schemas.getdbt.com/dbt/manifest/v12.json"},
+  "nodes": {
+    "model.shop.orders": {
+      "resource_type": "model", "schema": "analytics", "name": "orders", "alias": "fct_orders",
+      "description": "One row per completed checkout",
+      "columns": {
+        "order_id": {"name": "order_id", "description": "Primary key"},
+        "amount_cents": {"name": "amount_cents", "description": "Order total in cents (USD)"},
+        "notes": {"name": "notes", "description": ""}
+      }
+    },
+    "seed.shop.countries": {
+      "resource_type": "seed", "schema": "analytics", "name": "countries",
+      "description": "ISO 3166 country codes", "columns": {}
+    },
+    "test.shop.not_null_orders_order_id": {
+      "resource_type": "test", "schema": "analytics_dbt_test__audit", "name": "not_null_orders_order_id",
+      "description": "should be skipped", "columns": {}
+    }
+  },
+  "sources": {
+    "source.shop.app.users": {
+      "resource_type": "source", "schema": "public", "name": "users", "identifier": "app_users",
+      "description": "Registered platform users",
+      "columns": {"email": {"name": "email", "description": "Login email"}}
+    }
+  }
+}`
+
+func TestLoadContextFile_DBTManifest(t *testing.T) {
+	path := writeFileIn(t, t.TempDir(), "manifest.json", sampleManifest)
+
+	cc, err := LoadContextFile(path)
+	require.NoError(t, err)
+	assert.Len(t, cc.Tables, 3, "tests are skipped")
+
+	orders := cc.Tables["analytics.fct_orders"]
+	assert.Equal(t, "One row per completed checkout", orders.Description)
+	assert.Equal(t, "Order total in cents (USD)", orders.Columns["amount_cents"].Description)
+	assert.NotContains(t, orders.Columns, "notes", "undocumented columns are skipped")
+
+	assert.Equal(t, "ISO 3166 country codes", cc.Tables["analytics.countries"].Description)
+	assert.Equal(t, "Login email", cc.Tables["public.app_users"].Columns["email"].Description)
+}
+
+func TestLoadContextFile_DescriptionMap(t *testing.T) {
+	path := writeFileIn(t, t.TempDir(), "context.json", `{
+  "public.orders": "Purchase orders",
+  "public.orders.amount_cents": "Order total in cents (USD)",
+  "sales.leads.score": "Lead score, 0 to 100"
+}`)
+
+	cc, err := LoadContextFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Purchase orders", cc.Tables["public.orders"].Description)
+	assert.Equal(t, "Order total in cents (USD)", cc.Tables["public.orders"].Columns["amount_cents"].Description)
+	assert.Empty(t, cc.Tables["sales.leads"].Description)
+	assert.Equal(t, "Lead score, 0 to 100", cc.Tables["sales.leads"].Columns["score"].Description)
+}
+
+func TestLoadContextFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"not json", `public.orders: "Purchase orders"`, "parsing context file"},
+		{"bare table", `{"orders": "Purchase orders"}`, `must be "schema.table" or "schema.table.column"`},
+		{"too many parts", `{"db.public.orders.id": "Key"}`, `must be "schema.table" or "schema.table.column"`},
+		{"empty part", `{"public..id": "Key"}`, "empty name"},
+		{"non-string description", `{"public.orders": {"description": "x"}}`, "must be a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFileIn(t, t.TempDir(), "context.json", tt.content)
+			_, err := LoadContextFile(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	_, err := LoadContextFile(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestMergeDescriptions(t *testing.T) {
+	path := writeFileIn(t, t.TempDir(), "manifest.json", sampleManifest)
+	fromDBT, err := LoadContextFile(path)
+	require.NoError(t, err)
+
+	pol, err := LoadFromFile(writeTempFile(t, `
+context:
+  tables:
+    analytics.fct_orders:
+      description: "Orders, as finance counts them"
+      columns:
+        order_id:
+          mask: "hash"
+        amount_cents: "Total charged, tax included"
+`))
+	require.NoError(t, err)
+
+	MergeDescriptions(&pol.Context, fromDBT)
+
+	orders := pol.Context.Tables["analytics.fct_orders"]
+	assert.Equal(t, "Orders, as finance counts them", orders.Description, "policy description wins")
+	assert.Equal(t, "Total charged, tax included", orders.Columns["amount_cents"].Description)
+	assert.Equal(t, "Primary key", orders.Columns["order_id"].Description, "filled in from dbt")
+	assert.Equal(t, domain.MaskHash, orders.Columns["order_id"].Mask, "mask kept")
+	assert.Equal(t, "Registered platform users", pol.Context.Tables["public.app_users"].Description)
+
+	detail := &port.TableDetail{
+		Schema: "analytics",
+		Name:   "fct_orders",
+		Columns: []port.ColumnInfo{
+			{Name: "order_id", DataType: "bigint"},
+			{Name: "amount_cents", DataType: "integer"},
+		},
+	}
+	MergeTableDetail(detail, pol.Context)
+	assert.Equal(t, "Orders, as finance counts them", detail.Comment)
+	assert.Equal(t, "Primary key", detail.Columns[0].Comment)
+	assert.Equal(t, "Total charged, tax included", detail.Columns[1].Comment)
+}
+
+func writeFileIn(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing temp file: %v", err)
+	}
+	return path
+}
+
 func writeTempFile(t *testing.T, content string) string {
 	t.Helper()
 	dir := t.TempDir()
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ttlCache holds the most recently built value of T. A zero ttl disables
+// caching so the value is rebuilt on every lookup.
+type ttlCache[T any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	value   T
+	valid   bool
+	builtAt time.Time
+}
+
+func newTTLCache[T any](ttl time.Duration) *ttlCache[T] {
+	return &ttlCache[T]{ttl: ttl, now: time.Now}
+}
+
+// get returns the cached value, calling build when it is missing or expired.
+// The mutex is held across build so concurrent callers share one catalog scan.
+// Errors are not cached.
+func (c *ttlCache[T]) get(ctx context.Context, build func(context.Context) (T, error)) (T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.valid && c.ttl > 0 && c.now().Sub(c.builtAt) < c.ttl {
+		return c.value, nil
+	}
+
+	v, err := build(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	c.value, c.valid = v, true
+	c.builtAt = c.now()
+	return v, nil
+}
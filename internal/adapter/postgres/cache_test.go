@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLCache_ReuseAndExpiry(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	cache := newTTLCache[[]port.SchemaInfo](time.Minute)
+	cache.now = clock.now
+
+	calls := 0
+	build := func(context.Context) ([]port.SchemaInfo, error) {
+		calls++
+		return []port.SchemaInfo{{Name: "public"}}, nil
+	}
+
+	for range 3 {
+		_, err := cache.get(context.Background(), build)
+		require.NoError(t, err)
+		clock.t = clock.t.Add(15 * time.Second)
+	}
+	assert.Equal(t, 1, calls, "reused within the TTL")
+
+	clock.t = clock.t.Add(time.Minute)
+	_, err := cache.get(context.Background(), build)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls, "rebuilt after expiry")
+}
+
+func TestTTLCache_EmptyResultIsCached(t *testing.T) {
+	t.Parallel()
+	cache := newTTLCache[[]port.TableInfo](time.Minute)
+
+	calls := 0
+	build := func(context.Context) ([]port.TableInfo, error) {
+		calls++
+		return nil, nil
+	}
+	for range 2 {
+		_, err := cache.get(context.Background(), build)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, calls, "a database without tables is a valid result")
+}
+
+func TestTTLCache_ErrorNotCached(t *testing.T) {
+	t.Parallel()
+	cache := newTTLCache[[]port.TableInfo](time.Minute)
+
+	_, err := cache.get(context.Background(), func(context.Context) ([]port.TableInfo, error) {
+		return nil, errors.New("connection reset")
+	})
+	require.Error(t, err)
+
+	tables, err := cache.get(context.Background(), func(context.Context) ([]port.TableInfo, error) {
+		return []port.TableInfo{{Schema: "public", Name: "users"}}, nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, tables, 1)
+}
+
+func TestExplorer_SchemaCacheServesCopies(t *testing.T) {
+	t.Parallel()
+	// No pool: any catalog query would panic, so results must come from the cache.
+	e := NewExplorer(nil, nil, WithSchemaCacheTTL(time.Minute))
+	_, err := e.tableCache.get(context.Background(), func(context.Context) ([]port.TableInfo, error) {
+		return []port.TableInfo{{Schema: "public", Name: "users", ColumnCount: 5}}, nil
+	})
+	require.NoError(t, err)
+
+	first, err := e.ListTables(context.Background())
+	require.NoError(t, err)
+	first[0].ColumnCount = 4 // e.g. a policy hiding a column
+
+	second, err := e.ListTables(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5, second[0].ColumnCount, "callers cannot modify the cached result")
+}
@@ -0,0 +1,265 @@
+package postgres
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+)
+
+// attachCheckValues sets ColumnInfo.AllowedValues for columns restricted to a
+// fixed list by a single-column CHECK (col IN (...)) constraint. Unlike the
+// pg_stats most common values it needs no data, so it also works on empty or
+// never-analyzed tables.
+func attachCheckValues(detail *port.TableDetail) {
+	allowed := make(map[string][]string)
+	for _, ck := range detail.CheckConstraints {
+		if len(ck.Columns) != 1 {
+			continue
+		}
+		col, values, ok := parseCheckInList(ck.Expression)
+		if !ok || col != ck.Columns[0] {
+			continue
+		}
+		allowed[col] = values
+	}
+	if len(allowed) == 0 {
+		return
+	}
+	for i := range detail.Columns {
+		if values, ok := allowed[detail.Columns[i].Name]; ok {
+			detail.Columns[i].AllowedValues = values
+		}
+	}
+}
+
+// parseCheckInList extracts the column and the allowed values from a check
+// constraint of the form "col IN (v1, v2, ...)". It accepts both that form
+// and the one pg_get_constraintdef prints for it:
+//
+//	CHECK ((status = ANY (ARRAY['active'::text, 'inactive'::text])))
+//	CHECK (((kind)::text = ANY ((ARRAY['a'::character varying, 'b'::character varying])::text[])))
+//
+// Values must be string or numeric literals. Any other expression, including
+// NOT IN and lists combined with AND/OR, reports ok=false.
+func parseCheckInList(expr string) (column string, values []string, ok bool) {
+	s := strings.TrimSpace(expr)
+	s = strings.TrimSpace(strings.TrimSuffix(s, "NOT VALID"))
+	if len(s) < 5 || !strings.EqualFold(s[:5], "CHECK") {
+		return "", nil, false
+	}
+	s = unwrapParens(s[5:])
+
+	column, rest, ok := cutColumnRef(s)
+	if !ok {
+		return "", nil, false
+	}
+
+	var list string
+	switch {
+	case hasPrefixFold(rest, "IN"):
+		inner, ok := parenBody(strings.TrimSpace(rest[2:]))
+		if !ok {
+			return "", nil, false
+		}
+		list = inner
+	case hasPrefixFold(rest, "= ANY"):
+		inner, ok := parenBody(strings.TrimSpace(rest[5:]))
+		if !ok {
+			return "", nil, false
+		}
+		inner, _ = cutCast(unwrapParens(inner))
+		inner = unwrapParens(inner)
+		if !hasPrefixFold(inner, "ARRAY[") || !strings.HasSuffix(inner, "]") {
+			return "", nil, false
+		}
+		list = inner[len("ARRAY[") : len(inner)-1]
+	default:
+		return "", nil, false
+	}
+
+	for _, item := range splitTopLevel(list) {
+		v, ok := literalValue(item)
+		if !ok {
+			return "", nil, false
+		}
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return "", nil, false
+	}
+	return column, values, true
+}
+
+// cutColumnRef reads a column reference such as status, "Status" or
+// (status)::text from the start of s and returns the column name and the
+// remainder after it.
+func cutColumnRef(s string) (column, rest string, ok bool) {
+	depth := 0
+	for strings.HasPrefix(s, "(") {
+		s = strings.TrimSpace(s[1:])
+		depth++
+	}
+
+	if strings.HasPrefix(s, `"`) {
+		end := 1
+		for {
+			i := strings.IndexByte(s[end:], '"')
+			if i < 0 {
+				return "", "", false
+			}
+			end += i + 1
+			if !strings.HasPrefix(s[end:], `"`) {
+				break
+			}
+			end++ // doubled quote inside the name
+		}
+		column = strings.ReplaceAll(s[1:end-1], `""`, `"`)
+		s = s[end:]
+	} else {
+		end := 0
+		for end < len(s) && isIdentChar(s[end]) {
+			end++
+		}
+		if end == 0 {
+			return "", "", false
+		}
+		column, s = s[:end], s[end:]
+	}
+
+	for ; depth > 0; depth-- {
+		s = strings.TrimSpace(s)
+		if !strings.HasPrefix(s, ")") {
+			return "", "", false
+		}
+		s = s[1:]
+	}
+
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "::") {
+		// A cast on the column, e.g. (status)::text. The type name may
+		// contain spaces, so it ends at the operator.
+		end := strings.IndexAny(s, "=")
+		if in := indexFold(s, " IN "); in >= 0 && (end < 0 || in < end) {
+			end = in
+		}
+		if end < 0 {
+			return "", "", false
+		}
+		s = s[end:]
+	}
+	return column, strings.TrimSpace(s), true
+}
+
+// literalValue returns the value of a string or numeric literal, ignoring a
+// trailing cast such as 'active'::text or 3::smallint.
+func literalValue(item string) (string, bool) {
+	item, _ = cutCast(strings.TrimSpace(item))
+	item = unwrapParens(item)
+	if len(item) >= 2 && item[0] == '\'' && item[len(item)-1] == '\'' {
+		body := item[1 : len(item)-1]
+		if strings.Count(body, "'")%2 != 0 {
+			return "", false
+		}
+		return strings.ReplaceAll(body, "''", "'"), true
+	}
+	if _, err := strconv.ParseFloat(item, 64); err == nil {
+		return item, true
+	}
+	return "", false
+}
+
+// unwrapParens strips parentheses that enclose the whole of s.
+func unwrapParens(s string) string {
+	s = strings.TrimSpace(s)
+	for strings.HasPrefix(s, "(") && closingIndex(s) == len(s)-1 {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	return s
+}
+
+// parenBody returns what is inside s when s is exactly one parenthesized
+// group.
+func parenBody(s string) (string, bool) {
+	if !strings.HasPrefix(s, "(") || closingIndex(s) != len(s)-1 {
+		return "", false
+	}
+	return s[1 : len(s)-1], true
+}
+
+// cutCast removes a trailing "::type" cast that is outside any quotes or
+// brackets.
+func cutCast(s string) (string, bool) {
+	depth, inQuote := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case depth == 0 && c == ':' && strings.HasPrefix(s[i:], "::"):
+			return strings.TrimSpace(s[:i]), true
+		}
+	}
+	return s, false
+}
+
+// closingIndex returns the index of the bracket that closes the one at s[0],
+// or -1.
+func closingIndex(s string) int {
+	depth, inQuote := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits s at commas that are outside quotes and brackets.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, inQuote, start := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if strings.TrimSpace(s[start:]) != "" {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func indexFold(s, substr string) int {
+	return strings.Index(strings.ToUpper(s), strings.ToUpper(substr))
+}
@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ColumnLister reads column names from the catalog of the server the pool
+// is connected to.
+type ColumnLister struct {
+	pool *pgxpool.Pool
+}
+
+func NewColumnLister(pool *pgxpool.Pool) *ColumnLister {
+	return &ColumnLister{pool: pool}
+}
+
+func (l *ColumnLister) ColumnNames(ctx context.Context, schemas, tables []string) (map[string][]string, error) {
+	columns := make(map[string][]string)
+	if len(schemas) == 0 && len(tables) == 0 {
+		return columns, nil
+	}
+
+	rows, err := l.pool.Query(ctx, queryColumnNames, schemas, tables)
+	if err != nil {
+		return nil, fmt.Errorf("querying column names: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schema, table, column string
+		if err := rows.Scan(&schema, &table, &column); err != nil {
+			return nil, fmt.Errorf("scanning column name: %w", err)
+		}
+		key := schema + "." + table
+		columns[key] = append(columns[key], column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating column names: %w", err)
+	}
+	return columns, nil
+}
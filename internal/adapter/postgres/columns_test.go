@@ -0,0 +1,33 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guillermoBallester/isthmus/internal/adapter/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnNames_SchemasAndTables(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+	_, err := pool.Exec(ctx, `
+		CREATE SCHEMA pii;
+		CREATE TABLE pii.people (id int, full_name text, email text);
+		ALTER TABLE pii.people DROP COLUMN full_name;`)
+	require.NoError(t, err)
+
+	lister := postgres.NewColumnLister(pool)
+	columns, err := lister.ColumnNames(ctx, []string{"pii"}, []string{"public.orders", "public.customer_emails"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"pii.people":             {"id", "email"}, // dropped columns are left out
+		"public.orders":          {"id", "customer_id", "total", "created_at"},
+		"public.customer_emails": {"id", "email"},
+	}, columns)
+
+	columns, err = lister.ColumnNames(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, columns)
+}
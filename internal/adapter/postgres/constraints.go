@@ -0,0 +1,59 @@
+package postgres
+
+import "github.com/guillermoBallester/isthmus/internal/core/port"
+
+// Column constraint labels reported in ColumnInfo.Constraints.
+const (
+	constraintPrimaryKey = "primary_key"
+	constraintForeignKey = "foreign_key"
+	constraintUnique     = "unique"
+	constraintCheck      = "check"
+	constraintNotNull    = "not_null"
+)
+
+// summarizeColumnConstraints fills ColumnInfo.Constraints from the primary
+// key, foreign key, index and check data already fetched for the table, so a
+// single column entry shows every constraint that applies to it. A column is
+// only "unique" when a unique index covers it alone; the primary key already
+// implies uniqueness and is not repeated.
+func summarizeColumnConstraints(detail *port.TableDetail) {
+	fkCols := make(map[string]bool, len(detail.ForeignKeys))
+	for _, fk := range detail.ForeignKeys {
+		fkCols[fk.ColumnName] = true
+	}
+
+	uniqueCols := make(map[string]bool)
+	for _, idx := range detail.Indexes {
+		if idx.IsUnique && len(idx.Columns) == 1 {
+			uniqueCols[idx.Columns[0]] = true
+		}
+	}
+
+	checkCols := make(map[string]bool)
+	for _, ck := range detail.CheckConstraints {
+		for _, col := range ck.Columns {
+			checkCols[col] = true
+		}
+	}
+
+	for i := range detail.Columns {
+		col := &detail.Columns[i]
+		var constraints []string
+		if col.IsPrimaryKey {
+			constraints = append(constraints, constraintPrimaryKey)
+		}
+		if fkCols[col.Name] {
+			constraints = append(constraints, constraintForeignKey)
+		}
+		if uniqueCols[col.Name] && !col.IsPrimaryKey {
+			constraints = append(constraints, constraintUnique)
+		}
+		if checkCols[col.Name] {
+			constraints = append(constraints, constraintCheck)
+		}
+		if !col.IsNullable {
+			constraints = append(constraints, constraintNotNull)
+		}
+		col.Constraints = constraints
+	}
+}
@@ -2,40 +2,280 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/guillermoBallester/isthmus/internal/core/port"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Executor struct {
-	pool         *pgxpool.Pool
-	readOnly     bool
-	maxRows      int
-	queryTimeout time.Duration
+	pool           *pgxpool.Pool
+	readOnly       bool
+	maxRows        int           // default row limit
+	maxRowsCeiling int           // hard cap for per-call limits
+	queryTimeout   time.Duration // default statement timeout
+	timeoutFloor   time.Duration // shortest timeout a call may get
+	timeoutCeiling time.Duration // longest timeout a call may get
+	redactPlans    bool          // scrub quoted literals from EXPLAIN output
+	pgErrorDetail  bool          // keep DETAIL, HINT and WHERE on returned PgErrors
+	multiStatement bool          // run validated batches statement by statement
+	rollbackAlways bool          // end every transaction with ROLLBACK instead of COMMIT
+	injectLimit    bool          // put the row limit on the query's own LIMIT clause where possible
+	planHints      bool          // keep a leading pg_hint_plan comment at the start of the executed SQL
+	maxResultBytes int64         // estimated in-memory size a result may reach while it is read; 0 is unlimited
+	strictExplain  bool          // refuse plain EXPLAIN of writes too, not just EXPLAIN ANALYZE
+	searchPath     string        // quoted schema list for SET LOCAL search_path; empty keeps the session's
+	duplicates     DuplicateColumns
 }
 
-func NewExecutor(pool *pgxpool.Pool, readOnly bool, maxRows int, queryTimeout time.Duration) *Executor {
-	return &Executor{
-		pool:         pool,
-		readOnly:     readOnly,
-		maxRows:      maxRows,
-		queryTimeout: queryTimeout,
+// ExecutorOption configures optional Executor behavior.
+type ExecutorOption func(*Executor)
+
+// WithMaxRowsCeiling caps per-call row limits (see port.WithMaxRows). Values
+// below the default maxRows are ignored; without this option the ceiling
+// equals maxRows, so callers can only lower the limit.
+func WithMaxRowsCeiling(n int) ExecutorOption {
+	return func(e *Executor) {
+		if n > e.maxRowsCeiling {
+			e.maxRowsCeiling = n
+		}
+	}
+}
+
+// WithQueryTimeoutBounds clamps every call's statement timeout, per-call (see
+// port.WithQueryTimeout) or default, to [floor, ceiling]. Without it the
+// ceiling equals the default timeout, so callers can only shorten it, and
+// there is no floor. A zero bound leaves that side unchanged.
+func WithQueryTimeoutBounds(floor, ceiling time.Duration) ExecutorOption {
+	return func(e *Executor) {
+		if floor > 0 {
+			e.timeoutFloor = floor
+		}
+		if ceiling > 0 {
+			e.timeoutCeiling = ceiling
+		}
+	}
+}
+
+// WithPlanLiteralRedaction replaces quoted literals in EXPLAIN output with
+// '***' (see domain.RedactPlanLiterals) when on is true.
+func WithPlanLiteralRedaction(on bool) ExecutorOption {
+	return func(e *Executor) {
+		e.redactPlans = on
+	}
+}
+
+// WithPgErrorDetail keeps the DETAIL, HINT and WHERE fields of PostgreSQL
+// errors when keep is true. By default they are cleared before the error
+// leaves Execute, since they can echo row data (e.g. the duplicate key of a
+// unique violation) into logs, traces and the audit trail.
+func WithPgErrorDetail(keep bool) ExecutorOption {
+	return func(e *Executor) {
+		e.pgErrorDetail = keep
+	}
+}
+
+// WithMultiStatement runs semicolon-separated batches, already checked by a
+// validator built with domain.WithMultiStatement, in one transaction when on
+// is true. The row limit and EXPLAIN handling apply to the last statement,
+// whose rows are returned; the others only affect the transaction, e.g.
+// through SET LOCAL.
+func WithMultiStatement(on bool) ExecutorOption {
+	return func(e *Executor) {
+		e.multiStatement = on
+	}
+}
+
+// WithRollbackAlways ends every transaction with ROLLBACK instead of COMMIT
+// when on is true, including EXPLAIN ANALYZE, which still runs the statement.
+// Reads are unaffected, but anything a called function wrote, e.g. a
+// volatile function used in a SELECT on a writable connection, is discarded.
+func WithRollbackAlways(on bool) ExecutorOption {
+	return func(e *Executor) {
+		e.rollbackAlways = on
+	}
+}
+
+// WithLimitInjection applies the row limit by rewriting the LIMIT clause of
+// every SELECT that allows it (see domain.InjectLimit), not just ordered
+// ones. Queries it cannot rewrite, such as a bare UNION, are still wrapped
+// in "SELECT * FROM (...) LIMIT n".
+func WithLimitInjection(on bool) ExecutorOption {
+	return func(e *Executor) {
+		e.injectLimit = on
+	}
+}
+
+// WithPlanHints keeps a pg_hint_plan hint comment ("/*+ ... */") at the start
+// of the SQL that is executed when on is true (see domain.SplitPlanHint).
+// Without it, wrapping the query for the row limit moves the hint out of the
+// position pg_hint_plan reads, and rewriting its LIMIT drops it.
+func WithPlanHints(on bool) ExecutorOption {
+	return func(e *Executor) {
+		e.planHints = on
+	}
+}
+
+// WithMaxResultMemory fails a query with domain.ErrResultTooLarge once the
+// rows read so far take more than n bytes in memory, as estimated while they
+// are scanned. Zero, the default, disables the guard.
+func WithMaxResultMemory(n int64) ExecutorOption {
+	return func(e *Executor) {
+		e.maxResultBytes = n
+	}
+}
+
+// WithStrictExplain refuses EXPLAIN of a statement that writes even without
+// ANALYZE, when on is true. EXPLAIN ANALYZE of a write is always refused,
+// since it runs the statement; see Execute.
+func WithStrictExplain(on bool) ExecutorOption {
+	return func(e *Executor) {
+		e.strictExplain = on
+	}
+}
+
+// WithSearchPath pins search_path to schemas, in order, for every query,
+// so unqualified names resolve only in those schemas (and in pg_catalog,
+// which PostgreSQL searches implicitly). It backs domain.WithAllowedSchemas,
+// which only sees the schemas a query names. An empty list leaves the
+// session's search_path alone.
+func WithSearchPath(schemas []string) ExecutorOption {
+	return func(e *Executor) {
+		quoted := make([]string, len(schemas))
+		for i, s := range schemas {
+			quoted[i] = pgx.Identifier{s}.Sanitize()
+		}
+		e.searchPath = strings.Join(quoted, ", ")
+	}
+}
+
+// WithDuplicateColumns sets how results with repeated column names are
+// returned. The default is DuplicateColumnsError.
+func WithDuplicateColumns(mode DuplicateColumns) ExecutorOption {
+	return func(e *Executor) {
+		e.duplicates = mode
+	}
+}
+
+func NewExecutor(pool *pgxpool.Pool, readOnly bool, maxRows int, queryTimeout time.Duration, opts ...ExecutorOption) *Executor {
+	e := &Executor{
+		pool:           pool,
+		readOnly:       readOnly,
+		maxRows:        maxRows,
+		maxRowsCeiling: maxRows,
+		queryTimeout:   queryTimeout,
+		timeoutCeiling: queryTimeout,
+		duplicates:     DuplicateColumnsError,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// rowLimit returns the LIMIT for this call: the per-call limit from ctx,
+// clamped to the ceiling, or the default maxRows, and then to the row cap
+// from ctx, if any.
+func (e *Executor) rowLimit(ctx context.Context) int {
+	n, ok := port.MaxRowsFromContext(ctx)
+	if !ok {
+		n = e.maxRows
+	}
+	n = min(n, e.maxRowsCeiling)
+	if c, ok := port.RowCapFromContext(ctx); ok {
+		n = min(n, c)
 	}
+	return n
 }
 
+// timeout returns the statement timeout for this call: the per-call timeout
+// from ctx or the default, clamped to the floor and ceiling, and then to the
+// timeout cap from ctx, if any.
+func (e *Executor) timeout(ctx context.Context) time.Duration {
+	d, ok := port.QueryTimeoutFromContext(ctx)
+	if !ok {
+		d = e.queryTimeout
+	}
+	if e.timeoutCeiling > 0 {
+		d = min(d, e.timeoutCeiling)
+	}
+	d = max(d, e.timeoutFloor)
+	if c, ok := port.TimeoutCapFromContext(ctx); ok {
+		d = min(d, c)
+	}
+	return d
+}
+
+// Execute runs sql, which must already have passed the validator, in a
+// guarded transaction. As a last line of defence it refuses EXPLAIN ANALYZE
+// of any statement that writes, including a SELECT with a data-modifying
+// WITH clause, however the statement got here.
 func (e *Executor) Execute(ctx context.Context, sql string) ([]map[string]any, error) {
-	ctx, cancel := context.WithTimeout(ctx, e.queryTimeout)
+	results, err := e.execute(ctx, sql)
+	if err != nil && !e.pgErrorDetail {
+		scrubPgErrorDetail(err)
+	}
+	return results, err
+}
+
+func (e *Executor) execute(ctx context.Context, sql string) ([]map[string]any, error) {
+	timeout := e.timeout(ctx)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// EXPLAIN statements cannot be wrapped in a subquery
+	stmts := []string{sql}
+	if e.multiStatement {
+		split, err := domain.SplitStatements(sql)
+		if err != nil {
+			return nil, fmt.Errorf("splitting statements: %w", err)
+		}
+		if len(split) > 0 {
+			stmts = split
+		}
+	}
+	for _, stmt := range stmts {
+		if kind, analyze := domain.ExplainedWrite(stmt); kind != "" && (analyze || e.strictExplain) {
+			return nil, explainWriteError(kind, analyze)
+		}
+	}
+	last := stmts[len(stmts)-1]
+
+	var hint string
+	if e.planHints {
+		hint, last = domain.SplitPlanHint(last)
+	}
+
+	// EXPLAIN statements cannot be wrapped in a subquery. Ordered and
+	// DISTINCT queries get the limit on their own LIMIT clause, so the
+	// row order they ask for is the order returned; with limit injection,
+	// so does every other query that allows it.
+	rewriteLimit := domain.RewriteLimit
+	if e.injectLimit {
+		rewriteLimit = domain.InjectLimit
+	}
 	var wrappedSQL string
-	if isExplain(sql) {
-		wrappedSQL = sql
+	if isExplain(last) {
+		wrappedSQL = last
+	} else if rewritten, ok := rewriteLimit(last, e.rowLimit(ctx)); ok {
+		wrappedSQL = rewritten
 	} else {
-		wrappedSQL = fmt.Sprintf("SELECT * FROM (%s) AS _q LIMIT %d", sql, e.maxRows)
+		wrappedSQL = fmt.Sprintf("SELECT * FROM (%s) AS _q LIMIT %d", last, e.rowLimit(ctx))
+	}
+	if hint != "" {
+		wrappedSQL = hint + " " + wrappedSQL
+	}
+	if n := port.AppliedRowLimitFromContext(ctx); n != nil && !isExplain(last) {
+		*n = e.rowLimit(ctx)
+	}
+	if dst := port.ExecutedSQLFromContext(ctx); dst != nil {
+		*dst = strings.Join(append(slices.Clone(stmts[:len(stmts)-1]), wrappedSQL), "; ")
 	}
 
 	tx, err := e.pool.BeginTx(ctx, pgx.TxOptions{
@@ -46,12 +286,20 @@ func (e *Executor) Execute(ctx context.Context, sql string) ([]map[string]any, e
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	// Enforce statement timeout at the database level so PostgreSQL cancels
-	// the query server-side even if the Go context is cancelled first.
-	// SET LOCAL scopes to this transaction only — no global side effects.
-	timeoutMS := e.queryTimeout.Milliseconds()
-	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL statement_timeout = '%d'", timeoutMS)); err != nil {
-		return nil, fmt.Errorf("setting statement timeout: %w", err)
+	if err := e.setGuards(ctx, tx, timeout); err != nil {
+		return nil, err
+	}
+
+	// The leading statements of a batch run for their effect on the
+	// transaction; their rows are discarded. The guards are set again after
+	// each one, since a SELECT can change settings through set_config.
+	for _, stmt := range stmts[:len(stmts)-1] {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("executing query: %w", err)
+		}
+		if err := e.setGuards(ctx, tx, timeout); err != nil {
+			return nil, err
+		}
 	}
 
 	rows, err := tx.Query(ctx, wrappedSQL)
@@ -59,23 +307,109 @@ func (e *Executor) Execute(ctx context.Context, sql string) ([]map[string]any, e
 		return nil, fmt.Errorf("executing query: %w", err)
 	}
 	defer rows.Close()
+	fields := rows.FieldDescriptions()
 
-	results, err := rowsToMaps(rows)
+	results, err := rowsToMaps(rows, e.duplicates, e.maxResultBytes)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
+	if order := port.ColumnOrderFromContext(ctx); order != nil {
+		// rowsToMaps succeeded, so the keys resolve without error.
+		*order, _ = resultKeys(fields, e.duplicates)
+	}
+	if cols := port.ResultColumnsFromContext(ctx); cols != nil {
+		if *cols, err = describeResultColumns(ctx, tx, fields, e.duplicates); err != nil {
+			return nil, err
+		}
+	}
+
+	if e.rollbackAlways {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, fmt.Errorf("rolling back transaction: %w", err)
+		}
+	} else if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("committing transaction: %w", err)
 	}
 
+	if e.redactPlans && isExplain(last) {
+		domain.RedactPlanLiterals(results)
+	}
+
 	return results, nil
 }
 
+// setGuards applies the per-transaction settings every query runs under.
+func (e *Executor) setGuards(ctx context.Context, tx pgx.Tx, timeout time.Duration) error {
+	// Enforce statement timeout at the database level so PostgreSQL cancels
+	// the query server-side even if the Go context is cancelled first.
+	// SET LOCAL scopes to this transaction only — no global side effects.
+	timeoutMS := timeout.Milliseconds()
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL statement_timeout = '%d'", timeoutMS)); err != nil {
+		return fmt.Errorf("setting statement timeout: %w", err)
+	}
+
+	// Belt and braces on top of the READ ONLY access mode: anything in this
+	// transaction that would start or inherit a transaction still sees the
+	// session as read-only, even if a statement slips past the validator.
+	if e.readOnly {
+		if _, err := tx.Exec(ctx, "SET LOCAL default_transaction_read_only = on"); err != nil {
+			return fmt.Errorf("setting read-only guard: %w", err)
+		}
+	}
+
+	if e.searchPath != "" {
+		if _, err := tx.Exec(ctx, "SET LOCAL search_path = "+e.searchPath); err != nil {
+			return fmt.Errorf("setting search_path: %w", err)
+		}
+	}
+	return nil
+}
+
+// PlanHintsLoaded reports whether the pg_hint_plan module is loaded in the
+// sessions of pool, e.g. through shared_preload_libraries. Hint comments are
+// ignored without it.
+func PlanHintsLoaded(ctx context.Context, pool *pgxpool.Pool) (bool, error) {
+	var loaded bool
+	if err := pool.QueryRow(ctx, queryPlanHintsLoaded).Scan(&loaded); err != nil {
+		return false, fmt.Errorf("checking for pg_hint_plan: %w", err)
+	}
+	return loaded, nil
+}
+
+// explainWriteError refuses an EXPLAIN of a write, naming the statement.
+func explainWriteError(kind string, analyze bool) error {
+	if analyze {
+		return &domain.RejectedStatementError{Kind: "EXPLAIN ANALYZE " + kind,
+			Suggestion: "EXPLAIN ANALYZE runs the statement; drop analyze to see the plan only"}
+	}
+	return &domain.RejectedStatementError{Kind: "EXPLAIN " + kind,
+		Suggestion: "explain the SELECT that finds the affected rows instead"}
+}
+
 func isExplain(sql string) bool {
 	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(sql)), "EXPLAIN")
 }
 
+// lastStatement returns the last statement of a batch, or sql itself when it
+// holds a single statement or does not parse.
+func lastStatement(sql string) string {
+	stmts, err := domain.SplitStatements(sql)
+	if err != nil || len(stmts) < 2 {
+		return sql
+	}
+	return stmts[len(stmts)-1]
+}
+
+// scrubPgErrorDetail clears the fields of a wrapped PgError that may carry row
+// data. The message, SQLSTATE and position are kept.
+func scrubPgErrorDetail(err error) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		pgErr.Detail, pgErr.Hint, pgErr.Where = "", "", ""
+	}
+}
+
 func (e *Executor) accessMode() pgx.TxAccessMode {
 	if e.readOnly {
 		return pgx.ReadOnly
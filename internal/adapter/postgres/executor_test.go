@@ -2,11 +2,17 @@ package postgres_test
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/guillermoBallester/isthmus/internal/adapter/postgres"
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -21,6 +27,47 @@ func TestExecute_Explain(t *testing.T) {
 	assert.NotEmpty(t, results)
 }
 
+func TestExecute_ExplainAnalyzeWriteRefused(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+	_, err := pool.Exec(ctx, "INSERT INTO customers (name, email) VALUES ('keep', NULL)")
+	require.NoError(t, err)
+
+	// Even on a writable connection the delete never runs.
+	executor := postgres.NewExecutor(pool, false, 100, 10*time.Second)
+	_, err = executor.Execute(ctx, "EXPLAIN ANALYZE WITH d AS (DELETE FROM customers RETURNING id) SELECT id FROM d")
+	require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+	// Without ANALYZE the plan is only shown, unless the executor is strict.
+	results, err := executor.Execute(ctx, "EXPLAIN DELETE FROM customers")
+	require.NoError(t, err)
+	assert.NotEmpty(t, results)
+	_, err = postgres.NewExecutor(pool, false, 100, 10*time.Second, postgres.WithStrictExplain(true)).
+		Execute(ctx, "EXPLAIN DELETE FROM customers")
+	require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+	var n int
+	require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM customers").Scan(&n))
+	assert.Equal(t, 1, n)
+}
+
+func TestExecute_ExplainRedactsLiterals(t *testing.T) {
+	pool := setupTestDB(t)
+	executor := postgres.NewExecutor(pool, true, 100, 10*time.Second,
+		postgres.WithPlanLiteralRedaction(true))
+	ctx := context.Background()
+
+	results, err := executor.Execute(ctx, "EXPLAIN SELECT * FROM customers WHERE email = 'alice@example.com'")
+	require.NoError(t, err)
+
+	var plan strings.Builder
+	for _, row := range results {
+		plan.WriteString(row["QUERY PLAN"].(string))
+	}
+	assert.NotContains(t, plan.String(), "alice@example.com")
+	assert.Contains(t, plan.String(), "'***'")
+}
+
 func TestExecute_Select_RowLimit(t *testing.T) {
 	pool := setupTestDB(t)
 	ctx := context.Background()
@@ -39,6 +86,256 @@ func TestExecute_Select_RowLimit(t *testing.T) {
 	assert.Len(t, results, 3, "should be limited to maxRows=3")
 }
 
+func TestExecute_Select_OrderedLimitMatchesWrapped(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+
+	for i := 0; i < 8; i++ {
+		_, err := pool.Exec(ctx, "INSERT INTO customers (name, email) VALUES ($1, NULL)", fmt.Sprintf("user%d", i))
+		require.NoError(t, err)
+	}
+
+	sql := "SELECT id, name, row_number() OVER (ORDER BY id) AS rn FROM customers ORDER BY id DESC"
+	executor := postgres.NewExecutor(pool, true, 3, 10*time.Second)
+	rewritten, err := executor.Execute(ctx, sql)
+	require.NoError(t, err)
+
+	rows, err := pool.Query(ctx, "SELECT id FROM ("+sql+") AS _q LIMIT 3")
+	require.NoError(t, err)
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	require.NoError(t, err)
+
+	require.Len(t, rewritten, 3)
+	for i, row := range rewritten {
+		assert.Equal(t, ids[i], row["id"], "row %d", i)
+	}
+	assert.Greater(t, rewritten[0]["id"], rewritten[2]["id"], "ORDER BY id DESC is kept")
+}
+
+func TestExecute_LimitInjection(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+
+	for i := 0; i < 8; i++ {
+		_, err := pool.Exec(ctx, "INSERT INTO customers (name, email) VALUES ($1, NULL)", fmt.Sprintf("user%d", i))
+		require.NoError(t, err)
+	}
+
+	wrapped := postgres.NewExecutor(pool, true, 3, 10*time.Second)
+	injected := postgres.NewExecutor(pool, true, 3, 10*time.Second, postgres.WithLimitInjection(true))
+	for _, sql := range []string{
+		"SELECT id, name FROM customers",                                          // injected
+		"WITH c AS (SELECT id, name FROM customers) SELECT id, name FROM c",       // injected
+		"SELECT id, name FROM customers UNION ALL SELECT id, name FROM customers", // wrapped
+		"SELECT id, name FROM customers LIMIT 2",                                  // kept
+	} {
+		want, err := wrapped.Execute(ctx, sql)
+		require.NoError(t, err, sql)
+		got, err := injected.Execute(ctx, sql)
+		require.NoError(t, err, sql)
+		assert.Len(t, got, len(want), sql)
+		assert.LessOrEqual(t, len(got), 3, sql)
+	}
+}
+
+func TestExecute_MaxResultMemory(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+	const sql = "SELECT g AS id, repeat('x', 1000000) AS body FROM generate_series(1, 50) g"
+
+	guarded := postgres.NewExecutor(pool, true, 100, 10*time.Second, postgres.WithMaxResultMemory(5_000_000))
+	_, err := guarded.Execute(ctx, sql)
+	require.ErrorIs(t, err, domain.ErrResultTooLarge)
+
+	rows, err := guarded.Execute(ctx, "SELECT g AS id, repeat('x', 1000) AS body FROM generate_series(1, 50) g")
+	require.NoError(t, err, "small results pass")
+	assert.Len(t, rows, 50)
+
+	unguarded := postgres.NewExecutor(pool, true, 100, 10*time.Second)
+	rows, err = unguarded.Execute(ctx, sql)
+	require.NoError(t, err)
+	assert.Len(t, rows, 50)
+}
+
+func TestExecute_PlanHints(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+
+	// current_query() returns the SQL the server is running, so it shows
+	// where the hint ended up after wrapping or rewriting.
+	hinted := postgres.NewExecutor(pool, true, 3, 10*time.Second, postgres.WithPlanHints(true))
+	for _, sql := range []string{
+		"/*+ SeqScan(customers) */ SELECT current_query() AS q",            // wrapped
+		"/*+ SeqScan(customers) */ SELECT current_query() AS q ORDER BY 1", // rewritten
+	} {
+		rows, err := hinted.Execute(ctx, sql)
+		require.NoError(t, err, sql)
+		require.Len(t, rows, 1, sql)
+		assert.True(t, strings.HasPrefix(rows[0]["q"].(string), "/*+ SeqScan(customers) */ "), rows[0]["q"])
+	}
+
+	rows, err := hinted.Execute(ctx, "/*+ SeqScan(customers) */ SELECT current_query() AS q")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Contains(t, rows[0]["q"], "LIMIT 3", "the row limit still applies")
+
+	plain := postgres.NewExecutor(pool, true, 3, 10*time.Second)
+	rows, err = plain.Execute(ctx, "/*+ SeqScan(customers) */ SELECT current_query() AS q ORDER BY 1")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.NotContains(t, rows[0]["q"], "SeqScan", "without the option, rewriting drops the comment")
+}
+
+func TestExecute_Select_PerCallLimitClampedToCeiling(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		_, err := pool.Exec(ctx, "INSERT INTO customers (name, email) VALUES ($1, $2)", "user", nil)
+		require.NoError(t, err)
+	}
+
+	executor := postgres.NewExecutor(pool, true, 3, 10*time.Second, postgres.WithMaxRowsCeiling(6))
+
+	results, err := executor.Execute(port.WithMaxRows(ctx, 5), "SELECT id FROM customers")
+	require.NoError(t, err)
+	assert.Len(t, results, 5, "per-call limit within the ceiling is honored")
+
+	results, err = executor.Execute(port.WithMaxRows(ctx, 1000), "SELECT id FROM customers")
+	require.NoError(t, err)
+	assert.Len(t, results, 6, "per-call limit above the ceiling is clamped")
+}
+
+func TestExecute_ReadOnlyGuard(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+	executor := postgres.NewExecutor(pool, true, 100, 10*time.Second)
+
+	results, err := executor.Execute(ctx, "SELECT current_setting('default_transaction_read_only') AS guard")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "on", results[0]["guard"])
+
+	// nextval writes to the sequence and must be rejected in a read-only session.
+	_, err = executor.Execute(ctx, "SELECT nextval('customers_id_seq')")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read-only transaction")
+
+	// The guard is transaction-scoped and does not leak onto pooled connections.
+	var setting string
+	require.NoError(t, pool.QueryRow(ctx, "SHOW default_transaction_read_only").Scan(&setting))
+	assert.Equal(t, "off", setting)
+}
+
+func TestExecute_SearchPath(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+	_, err := pool.Exec(ctx, "CREATE SCHEMA app; CREATE TABLE app.notes (id int); INSERT INTO app.notes VALUES (1)")
+	require.NoError(t, err)
+	executor := postgres.NewExecutor(pool, true, 100, 10*time.Second,
+		postgres.WithSearchPath([]string{"app"}))
+
+	results, err := executor.Execute(ctx, "SELECT * FROM notes")
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	// customers is in public, outside the pinned search_path.
+	_, err = executor.Execute(ctx, "SELECT * FROM customers")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `relation "customers" does not exist`)
+
+	// The setting is transaction-scoped and does not leak onto pooled connections.
+	var path string
+	require.NoError(t, pool.QueryRow(ctx, "SHOW search_path").Scan(&path))
+	assert.Equal(t, `"$user", public`, path)
+}
+
+func TestExecute_RollbackAlways(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+
+	// A volatile function with a side effect, called from a SELECT on a
+	// writable connection.
+	_, err := pool.Exec(ctx, `
+		CREATE TABLE visits (at timestamptz NOT NULL DEFAULT now());
+		CREATE FUNCTION log_visit() RETURNS int LANGUAGE sql VOLATILE AS
+			'INSERT INTO visits DEFAULT VALUES RETURNING 1';
+	`)
+	require.NoError(t, err)
+
+	visits := func() int {
+		var n int
+		require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM visits").Scan(&n))
+		return n
+	}
+
+	executor := postgres.NewExecutor(pool, false, 100, 10*time.Second,
+		postgres.WithRollbackAlways(true))
+	for _, sql := range []string{
+		"SELECT log_visit() AS visit",
+		"EXPLAIN ANALYZE SELECT log_visit()",
+	} {
+		results, err := executor.Execute(ctx, sql)
+		require.NoError(t, err, sql)
+		assert.NotEmpty(t, results, sql)
+	}
+	assert.Zero(t, visits(), "rolled back transactions leave nothing behind")
+
+	// Without the option the same call commits its side effect.
+	_, err = postgres.NewExecutor(pool, false, 100, 10*time.Second).Execute(ctx, "SELECT log_visit() AS visit")
+	require.NoError(t, err)
+	assert.Equal(t, 1, visits())
+}
+
+func TestExecute_DuplicateColumns(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, "INSERT INTO customers (name, email) VALUES ('alice', 'alice@example.com')")
+	require.NoError(t, err)
+	const selfJoin = "SELECT a.id, b.id, a.name FROM customers a JOIN customers b ON a.id = b.id"
+
+	_, err = postgres.NewExecutor(pool, true, 100, 10*time.Second).Execute(ctx, selfJoin)
+	require.ErrorIs(t, err, domain.ErrAmbiguousName)
+	assert.Contains(t, err.Error(), `"id"`)
+
+	executor := postgres.NewExecutor(pool, true, 100, 10*time.Second,
+		postgres.WithDuplicateColumns(postgres.DuplicateColumnsSuffix))
+	results, err := executor.Execute(ctx, selfJoin)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{"id", "id_2", "name"}, slices.Sorted(maps.Keys(results[0])))
+	assert.Equal(t, results[0]["id"], results[0]["id_2"])
+}
+
+func TestExecute_MultiStatement(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+	executor := postgres.NewExecutor(pool, true, 2, 10*time.Second,
+		postgres.WithMultiStatement(true))
+
+	// SET LOCAL applies to the statements after it; only the last
+	// statement's rows are returned, under the row limit.
+	results, err := executor.Execute(ctx,
+		"SET LOCAL work_mem = '7MB'; SELECT 1 AS ignored; SELECT current_setting('work_mem') AS work_mem FROM generate_series(1, 5)")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "7MB", results[0]["work_mem"])
+
+	// A SELECT that lifts the timeout through set_config does not outlive
+	// its statement: the guard is set again before the next one.
+	results, err = executor.Execute(ctx,
+		"SELECT set_config('statement_timeout', '0', true); SELECT current_setting('statement_timeout') AS timeout")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "10s", results[0]["timeout"])
+
+	// The settings are transaction-scoped.
+	var setting string
+	require.NoError(t, pool.QueryRow(ctx, "SHOW work_mem").Scan(&setting))
+	assert.NotEqual(t, "7MB", setting)
+}
+
 func TestExecute_StatementTimeout(t *testing.T) {
 	pool := setupTestDB(t)
 	ctx := context.Background()
@@ -61,3 +358,56 @@ func TestExecute_StatementTimeout(t *testing.T) {
 		"expected timeout-related error, got: %s", err,
 	)
 }
+
+func TestExecute_PerCallTimeoutClampedToCeiling(t *testing.T) {
+	pool := setupTestDB(t)
+
+	// The per-call request asks for 30s, but the ceiling defaults to the 1s
+	// query timeout.
+	executor := postgres.NewExecutor(pool, true, 100, 1*time.Second)
+	ctx := port.WithQueryTimeout(context.Background(), 30*time.Second)
+
+	start := time.Now()
+	_, err := executor.Execute(ctx, "SELECT pg_sleep(10)")
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestExecute_ResultColumnsForEmptyResult(t *testing.T) {
+	pool := setupTestDB(t)
+	executor := postgres.NewExecutor(pool, true, 100, 5*time.Second)
+
+	var cols []port.ResultColumn
+	ctx := port.WithResultColumns(context.Background(), &cols)
+	rows, err := executor.Execute(ctx, "SELECT 1 AS id, 'x'::varchar(20) AS name, 1.5::numeric(10,2) AS price, now() AS at WHERE false")
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+
+	assert.Equal(t, []port.ResultColumn{
+		{Name: "id", Type: "integer", TypeOID: 23},
+		{Name: "name", Type: "character varying(20)", TypeOID: 1043},
+		{Name: "price", Type: "numeric(10,2)", TypeOID: 1700},
+		{Name: "at", Type: "timestamp with time zone", TypeOID: 1184},
+	}, cols)
+}
+
+func TestExecute_NumericIntervalMoney(t *testing.T) {
+	pool := setupTestDB(t)
+	executor := postgres.NewExecutor(pool, true, 100, 10*time.Second)
+
+	results, err := executor.Execute(context.Background(), `SELECT
+		12345678901234567890.123456789::numeric AS big,
+		'NaN'::numeric AS not_a_number,
+		ARRAY[1.5, 2.25]::numeric[] AS amounts,
+		'1 year 2 months 3 days 04:05:06.5'::interval AS span,
+		'-1234.56'::money AS balance`)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	row := results[0]
+	assert.Equal(t, "12345678901234567890.123456789", row["big"])
+	assert.Equal(t, "NaN", row["not_a_number"])
+	assert.Equal(t, []any{"1.5", "2.25"}, row["amounts"])
+	assert.Equal(t, "1 year 2 mons 3 days 04:05:06.5", row["span"])
+	assert.Equal(t, "-1234.56", row["balance"])
+}
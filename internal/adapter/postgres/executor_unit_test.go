@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorRowLimit(t *testing.T) {
+	t.Parallel()
+	e := NewExecutor(nil, true, 100, time.Second, WithMaxRowsCeiling(1000))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{"default", ctx, 100},
+		{"below default", port.WithMaxRows(ctx, 10), 10},
+		{"between default and ceiling", port.WithMaxRows(ctx, 500), 500},
+		{"above ceiling is clamped", port.WithMaxRows(ctx, 1_000_000), 1000},
+		{"non-positive ignored", port.WithMaxRows(ctx, 0), 100},
+		{"cap below default", port.WithRowCap(ctx, 10), 10},
+		{"cap above default", port.WithRowCap(ctx, 500), 100},
+		{"cap wins over per-call limit", port.WithRowCap(port.WithMaxRows(ctx, 500), 50), 50},
+		{"lowest cap wins", port.WithRowCap(port.WithRowCap(ctx, 5), 50), 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, e.rowLimit(tt.ctx))
+		})
+	}
+}
+
+func TestExecutorRowLimit_DefaultCeilingIsMaxRows(t *testing.T) {
+	t.Parallel()
+	e := NewExecutor(nil, true, 100, time.Second)
+
+	assert.Equal(t, 100, e.rowLimit(port.WithMaxRows(context.Background(), 5000)))
+	assert.Equal(t, 20, e.rowLimit(port.WithMaxRows(context.Background(), 20)))
+}
+
+func TestExecutorRowLimit_CeilingBelowMaxRowsIgnored(t *testing.T) {
+	t.Parallel()
+	e := NewExecutor(nil, true, 100, time.Second, WithMaxRowsCeiling(10))
+
+	assert.Equal(t, 100, e.rowLimit(port.WithMaxRows(context.Background(), 5000)))
+}
+
+func TestExecutorTimeout(t *testing.T) {
+	t.Parallel()
+	e := NewExecutor(nil, true, 100, 10*time.Second, WithQueryTimeoutBounds(time.Second, time.Minute))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want time.Duration
+	}{
+		{"default", ctx, 10 * time.Second},
+		{"shorter than default", port.WithQueryTimeout(ctx, 5*time.Second), 5 * time.Second},
+		{"between default and ceiling", port.WithQueryTimeout(ctx, 30*time.Second), 30 * time.Second},
+		{"above ceiling is clamped", port.WithQueryTimeout(ctx, time.Hour), time.Minute},
+		{"below floor is raised", port.WithQueryTimeout(ctx, time.Millisecond), time.Second},
+		{"non-positive ignored", port.WithQueryTimeout(ctx, 0), 10 * time.Second},
+		{"cap lowers the default", port.WithTimeoutCap(ctx, 3*time.Second), 3 * time.Second},
+		{"cap wins over a longer per-call timeout", port.WithTimeoutCap(port.WithQueryTimeout(ctx, 30*time.Second), 3*time.Second), 3 * time.Second},
+		{"cap wins over the floor", port.WithTimeoutCap(ctx, 500*time.Millisecond), 500 * time.Millisecond},
+		{"cap above the timeout changes nothing", port.WithTimeoutCap(ctx, time.Hour), 10 * time.Second},
+		{"lowest of several caps", port.WithTimeoutCap(port.WithTimeoutCap(ctx, 2*time.Second), 4*time.Second), 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, e.timeout(tt.ctx))
+		})
+	}
+}
+
+func TestExecutorTimeout_DefaultCeilingIsQueryTimeout(t *testing.T) {
+	t.Parallel()
+	e := NewExecutor(nil, true, 100, 10*time.Second)
+
+	assert.Equal(t, 10*time.Second, e.timeout(port.WithQueryTimeout(context.Background(), time.Hour)))
+	assert.Equal(t, time.Millisecond, e.timeout(port.WithQueryTimeout(context.Background(), time.Millisecond)), "no floor by default")
+}
+
+func TestScrubPgErrorDetail(t *testing.T) {
+	t.Parallel()
+	pgErr := &pgconn.PgError{
+		Code:    "23505",
+		Message: `duplicate key value violates unique constraint "users_email_key"`,
+		Detail:  "Key (email)=(alice@example.com) already exists.",
+		Hint:    "Use alice@example.com only once.",
+		Where:   "SQL statement \"INSERT INTO users VALUES ('alice@example.com')\"",
+	}
+	err := fmt.Errorf("executing query: %w", pgErr)
+
+	scrubPgErrorDetail(err)
+	assert.Empty(t, pgErr.Detail)
+	assert.Empty(t, pgErr.Hint)
+	assert.Empty(t, pgErr.Where)
+	assert.Equal(t, "23505", pgErr.Code)
+	assert.Contains(t, pgErr.Message, "users_email_key")
+
+	scrubPgErrorDetail(errors.New("not a pg error")) // no-op
+}
+
+func TestExecutorRefusesExplainedWrites(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	// No pool: every case must be refused before a connection is needed.
+	e := NewExecutor(nil, false, 100, time.Second, WithMultiStatement(true))
+
+	for _, sql := range []string{
+		"EXPLAIN ANALYZE DELETE FROM customers",
+		"EXPLAIN (ANALYZE, FORMAT JSON) UPDATE customers SET name = 'x'",
+		"EXPLAIN ANALYZE WITH d AS (DELETE FROM customers RETURNING id) SELECT id FROM d",
+		"EXPLAIN ANALYZE SELECT id INTO copy FROM customers",
+		"EXPLAIN ANALYZE INSERT INTO customers (name) VALUES ('x'); SELECT 1",
+	} {
+		_, err := e.Execute(ctx, sql)
+		assert.ErrorIs(t, err, domain.ErrNotAllowed, sql)
+		assert.ErrorContains(t, err, "EXPLAIN ANALYZE", sql)
+	}
+
+	strict := NewExecutor(nil, false, 100, time.Second, WithStrictExplain(true))
+	_, err := strict.Execute(ctx, "EXPLAIN DELETE FROM customers")
+	assert.ErrorIs(t, err, domain.ErrNotAllowed)
+	assert.ErrorContains(t, err, "detected EXPLAIN DELETE statement")
+}
+
+func TestExecutorReportsExecutedSQL(t *testing.T) {
+	t.Parallel()
+	// The SQL is recorded before the transaction starts, so a pool that
+	// cannot connect is enough.
+	pool, err := pgxpool.New(context.Background(), "postgres://isthmus@127.0.0.1:1/none?connect_timeout=1")
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	tests := []struct {
+		name string
+		sql  string
+		opts []ExecutorOption
+		ctx  func(context.Context) context.Context
+		want string
+	}{
+		{name: "wrapped", sql: "SELECT id FROM customers",
+			want: "SELECT * FROM (SELECT id FROM customers) AS _q LIMIT 100"},
+		{name: "ordered query gets its own limit", sql: "SELECT id FROM customers ORDER BY id",
+			want: "SELECT id FROM customers ORDER BY id LIMIT 100"},
+		{name: "per-call limit", sql: "SELECT id FROM customers",
+			ctx:  func(ctx context.Context) context.Context { return port.WithMaxRows(ctx, 5) },
+			want: "SELECT * FROM (SELECT id FROM customers) AS _q LIMIT 5"},
+		{name: "explain is not wrapped", sql: "EXPLAIN SELECT id FROM customers",
+			want: "EXPLAIN SELECT id FROM customers"},
+		{name: "batch", sql: "SET LOCAL work_mem = '64MB'; SELECT 1", opts: []ExecutorOption{WithMultiStatement(true)},
+			want: "SET LOCAL work_mem = '64MB'; SELECT * FROM (SELECT 1) AS _q LIMIT 100"},
+		{name: "plan hint kept in front", sql: "/*+ SeqScan(c) */ SELECT id FROM customers c", opts: []ExecutorOption{WithPlanHints(true)},
+			want: "/*+ SeqScan(c) */ SELECT * FROM (SELECT id FROM customers c) AS _q LIMIT 100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			e := NewExecutor(pool, true, 100, time.Second, tt.opts...)
+			ctx := context.Background()
+			if tt.ctx != nil {
+				ctx = tt.ctx(ctx)
+			}
+			var executed string
+			_, err := e.Execute(port.WithExecutedSQL(ctx, &executed), tt.sql)
+			require.Error(t, err, "no database to run on")
+			assert.Equal(t, tt.want, executed)
+		})
+	}
+}
@@ -3,22 +3,36 @@ package postgres
 import (
 	"context"
 
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"github.com/guillermoBallester/isthmus/internal/core/port"
 )
 
-// ExplainOnlyExecutor wraps a QueryExecutor and forces all queries through EXPLAIN.
-// Non-EXPLAIN queries are automatically prefixed with "EXPLAIN ".
+// ExplainOnlyExecutor wraps a QueryExecutor and forces queries through EXPLAIN.
+// Non-EXPLAIN queries are automatically prefixed with "EXPLAIN "; in a batch,
+// the prefix goes before the last statement, whose rows are returned.
+//
+// Built with NewExplainOnlyExecutor it forces every query, whatever the
+// context says. Built with NewExplainOnRequestExecutor it only forces calls
+// whose context carries port.WithExplainOnly.
 type ExplainOnlyExecutor struct {
-	inner port.QueryExecutor
+	inner     port.QueryExecutor
+	onRequest bool
 }
 
 func NewExplainOnlyExecutor(inner port.QueryExecutor) *ExplainOnlyExecutor {
 	return &ExplainOnlyExecutor{inner: inner}
 }
 
+// NewExplainOnRequestExecutor returns an executor that runs queries normally
+// unless the call opted in to explain-only.
+func NewExplainOnRequestExecutor(inner port.QueryExecutor) *ExplainOnlyExecutor {
+	return &ExplainOnlyExecutor{inner: inner, onRequest: true}
+}
+
 func (e *ExplainOnlyExecutor) Execute(ctx context.Context, sql string) ([]map[string]any, error) {
-	if !isExplain(sql) {
-		sql = "EXPLAIN " + sql
+	force := !e.onRequest || port.ExplainOnlyFromContext(ctx)
+	if force && !isExplain(lastStatement(sql)) {
+		sql = domain.PrefixLastStatement(sql, "EXPLAIN ")
 	}
 	return e.inner.Execute(ctx, sql)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/guillermoBallester/isthmus/internal/core/port"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,6 +32,9 @@ func TestExplainOnlyExecutor(t *testing.T) {
 		{"mixed case explain is passed through", "Explain SELECT 1", "Explain SELECT 1"},
 		{"leading whitespace SELECT", "  SELECT 1", "EXPLAIN   SELECT 1"},
 		{"leading whitespace EXPLAIN", "  EXPLAIN SELECT 1", "  EXPLAIN SELECT 1"},
+		{"batch explains its last statement", "SET LOCAL work_mem = '64MB'; SELECT 1", "SET LOCAL work_mem = '64MB'; EXPLAIN SELECT 1"},
+		{"batch starting with EXPLAIN", "EXPLAIN SELECT 1; SELECT 2", "EXPLAIN SELECT 1; EXPLAIN SELECT 2"},
+		{"batch ending with EXPLAIN", "SELECT 1; EXPLAIN SELECT 2", "SELECT 1; EXPLAIN SELECT 2"},
 	}
 
 	for _, tt := range tests {
@@ -44,3 +48,32 @@ func TestExplainOnlyExecutor(t *testing.T) {
 		})
 	}
 }
+
+func TestExplainOnlyExecutor_GlobalCannotBeBypassed(t *testing.T) {
+	t.Parallel()
+	inner := &capturingExecutor{}
+	e := NewExplainOnlyExecutor(inner)
+
+	// No per-call flag at all: the global setting still applies.
+	_, _ = e.Execute(context.Background(), "SELECT 1")
+	assert.Equal(t, "EXPLAIN SELECT 1", inner.lastSQL)
+
+	// A context that also opts in changes nothing.
+	_, _ = e.Execute(port.WithExplainOnly(context.Background()), "SELECT 1")
+	assert.Equal(t, "EXPLAIN SELECT 1", inner.lastSQL)
+}
+
+func TestExplainOnRequestExecutor(t *testing.T) {
+	t.Parallel()
+	inner := &capturingExecutor{}
+	e := NewExplainOnRequestExecutor(inner)
+
+	_, _ = e.Execute(context.Background(), "SELECT 1")
+	assert.Equal(t, "SELECT 1", inner.lastSQL, "runs normally without the per-call flag")
+
+	_, _ = e.Execute(port.WithExplainOnly(context.Background()), "SELECT 1")
+	assert.Equal(t, "EXPLAIN SELECT 1", inner.lastSQL)
+
+	_, _ = e.Execute(port.WithExplainOnly(context.Background()), "EXPLAIN SELECT 1")
+	assert.Equal(t, "EXPLAIN SELECT 1", inner.lastSQL, "EXPLAIN is passed through")
+}
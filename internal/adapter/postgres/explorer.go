@@ -3,23 +3,145 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"github.com/guillermoBallester/isthmus/internal/core/port"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
 )
 
 type Explorer struct {
 	pool    *pgxpool.Pool
 	schemas []string // empty means all non-system schemas
+	hidden  []string // never listed or described, even when in schemas
+	pkIndex *pkIndexCache
+
+	// ListSchemas and ListTables results, reused for the schema cache TTL.
+	schemaCache *ttlCache[[]port.SchemaInfo]
+	tableCache  *ttlCache[[]port.TableInfo]
+
+	fkInference    fkInferenceOptions
+	skipSamples    bool // never fetch sample rows
+	skipIndexUsage bool // never fetch index usage statistics
+	sampleRows     bool // estimate rows of never-analyzed tables with TABLESAMPLE
+
+	resolveAmbiguous bool // pick the first schema when a table name without one matches several
+}
+
+// ExplorerOption configures optional Explorer behavior.
+type ExplorerOption func(*Explorer)
+
+// WithPKIndexTTL sets how long the primary-key index used for FK inference is
+// cached. Zero disables caching.
+func WithPKIndexTTL(ttl time.Duration) ExplorerOption {
+	return func(e *Explorer) {
+		e.pkIndex = newPKIndexCache(ttl)
+	}
+}
+
+// WithFKInferenceScope sets which schemas inferred foreign keys may point
+// into. The default, FKInferenceScopeSchema, stays within the table's schema.
+func WithFKInferenceScope(scope FKInferenceScope) ExplorerOption {
+	return func(e *Explorer) {
+		e.fkInference.scope = scope
+	}
+}
+
+// WithFKInferenceHighConfidenceOnly drops "medium" confidence inferred
+// foreign keys when on is true.
+func WithFKInferenceHighConfidenceOnly(on bool) ExplorerOption {
+	return func(e *Explorer) {
+		e.fkInference.highConfidenceOnly = on
+	}
+}
+
+// WithFKInferenceMax caps the number of inferred foreign keys reported per
+// table, keeping the highest-confidence ones. Zero, the default, keeps all.
+func WithFKInferenceMax(n int) ExplorerOption {
+	return func(e *Explorer) {
+		e.fkInference.max = n
+	}
+}
+
+// WithSchemaCacheTTL reuses ListSchemas and ListTables results (and so
+// Discover) for ttl. Zero, the default, queries the catalog on every call.
+// DescribeTable is never cached.
+func WithSchemaCacheTTL(ttl time.Duration) ExplorerOption {
+	return func(e *Explorer) {
+		e.schemaCache = newTTLCache[[]port.SchemaInfo](ttl)
+		e.tableCache = newTTLCache[[]port.TableInfo](ttl)
+	}
+}
+
+// WithSampleRows controls whether DescribeTable fetches sample rows at the
+// full detail level. They are on by default.
+func WithSampleRows(on bool) ExplorerOption {
+	return func(e *Explorer) {
+		e.skipSamples = !on
+	}
+}
+
+// WithIndexUsage controls whether DescribeTable fetches index usage
+// statistics at the full detail level. They are on by default.
+func WithIndexUsage(on bool) ExplorerOption {
+	return func(e *Explorer) {
+		e.skipIndexUsage = !on
+	}
+}
+
+// WithRowEstimateSampling makes DescribeTable estimate the row count of a
+// table that has data but no planner estimate, because it was never
+// analyzed, by sampling it (see sampleRowEstimate). It is off by default.
+func WithRowEstimateSampling(on bool) ExplorerOption {
+	return func(e *Explorer) {
+		e.sampleRows = on
+	}
 }
 
-func NewExplorer(pool *pgxpool.Pool, schemas []string) *Explorer {
-	return &Explorer{pool: pool, schemas: schemas}
+// WithResolveAmbiguous controls what DescribeTable does when it is called
+// without a schema and the table name exists in several schemas. By default
+// it returns domain.ErrAmbiguousTable listing them; with on it describes the
+// table in the first schema in alphabetical order.
+func WithResolveAmbiguous(on bool) ExplorerOption {
+	return func(e *Explorer) {
+		e.resolveAmbiguous = on
+	}
 }
 
+// WithHiddenSchemas hides the given schemas from every listing and from
+// DescribeTable, whether or not an allowlist of schemas is set.
+func WithHiddenSchemas(schemas []string) ExplorerOption {
+	return func(e *Explorer) {
+		e.hidden = schemas
+	}
+}
+
+func NewExplorer(pool *pgxpool.Pool, schemas []string, opts ...ExplorerOption) *Explorer {
+	e := &Explorer{
+		pool:        pool,
+		schemas:     schemas,
+		pkIndex:     newPKIndexCache(DefaultPKIndexTTL),
+		schemaCache: newTTLCache[[]port.SchemaInfo](0),
+		tableCache:  newTTLCache[[]port.TableInfo](0),
+		fkInference: fkInferenceOptions{scope: FKInferenceScopeSchema},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ListSchemas returns the schemas in scope. Callers get their own copy of a
+// cached result, so they may modify it.
 func (e *Explorer) ListSchemas(ctx context.Context) ([]port.SchemaInfo, error) {
-	filter, args := schemaFilter(e.schemas, "s.schema_name", 1)
+	schemas, err := e.schemaCache.get(ctx, e.listSchemas)
+	return slices.Clone(schemas), err
+}
+
+func (e *Explorer) listSchemas(ctx context.Context) ([]port.SchemaInfo, error) {
+	filter, args := schemaFilter(e.schemas, e.hidden, "s.schema_name", 1)
 	query := fmt.Sprintf(queryListSchemas, filter)
 
 	rows, err := e.pool.Query(ctx, query, args...)
@@ -39,8 +161,15 @@ func (e *Explorer) ListSchemas(ctx context.Context) ([]port.SchemaInfo, error) {
 	return schemas, rows.Err()
 }
 
+// ListTables returns the tables and views in scope. Callers get their own copy
+// of a cached result, so they may modify it (e.g. to merge policy context).
 func (e *Explorer) ListTables(ctx context.Context) ([]port.TableInfo, error) {
-	filter, args := schemaFilter(e.schemas, "t.table_schema", 1)
+	tables, err := e.tableCache.get(ctx, e.listTables)
+	return slices.Clone(tables), err
+}
+
+func (e *Explorer) listTables(ctx context.Context) ([]port.TableInfo, error) {
+	filter, args := schemaFilter(e.schemas, e.hidden, "t.table_schema", 1)
 	query := fmt.Sprintf(queryListTables, filter)
 
 	rows, err := e.pool.Query(ctx, query, args...)
@@ -52,13 +181,21 @@ func (e *Explorer) ListTables(ctx context.Context) ([]port.TableInfo, error) {
 	var tables []port.TableInfo
 	for rows.Next() {
 		var t port.TableInfo
+		var indexBytes, toastBytes int64
 		if err := rows.Scan(
 			&t.Schema, &t.Name, &t.Type, &t.RowEstimate,
-			&t.TotalBytes, &t.SizeHuman, &t.ColumnCount, &t.HasIndexes,
-			&t.Comment,
+			&t.TotalBytes, &t.SizeHuman, &indexBytes, &toastBytes,
+			&t.ColumnCount, &t.HasIndexes, &t.Comment,
 		); err != nil {
 			return nil, fmt.Errorf("scanning table row: %w", err)
 		}
+		if t.Type == "table" {
+			t.SizeBreakdown = &port.TableSizes{
+				TableBytes: t.TotalBytes - indexBytes - toastBytes,
+				IndexBytes: indexBytes,
+				ToastBytes: toastBytes,
+			}
+		}
 		tables = append(tables, t)
 	}
 	return tables, rows.Err()
@@ -94,9 +231,65 @@ func (e *Explorer) Discover(ctx context.Context) (*port.DiscoveryResult, error)
 	return result, nil
 }
 
+// TableActivity reports the insert, update and delete counters of every table
+// in the configured schemas.
+func (e *Explorer) TableActivity(ctx context.Context) ([]port.TableActivity, error) {
+	filter, args := schemaFilter(e.schemas, e.hidden, "s.schemaname", 1)
+	query := fmt.Sprintf(queryTableActivity, filter)
+
+	rows, err := e.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying table activity: %w", err)
+	}
+	defer rows.Close()
+
+	var activity []port.TableActivity
+	for rows.Next() {
+		var a port.TableActivity
+		if err := rows.Scan(&a.Schema, &a.Name, &a.Inserts, &a.Updates, &a.Deletes, &a.StatsReset); err != nil {
+			return nil, fmt.Errorf("scanning table activity row: %w", err)
+		}
+		a.TotalChanges = a.Inserts + a.Updates + a.Deletes
+		activity = append(activity, a)
+	}
+	return activity, rows.Err()
+}
+
+// ForeignKeys returns the foreign keys of every table in the allowed
+// schemas, keyed by "schema.table", with one catalog query.
+func (e *Explorer) ForeignKeys(ctx context.Context) (map[string][]port.ForeignKey, error) {
+	filter, args := schemaFilter(e.schemas, e.hidden, "n.nspname", 1)
+	query := fmt.Sprintf(queryAllForeignKeys, filter)
+
+	rows, err := e.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	fks := make(map[string][]port.ForeignKey)
+	for rows.Next() {
+		var schema, table string
+		var fk port.ForeignKey
+		if err := rows.Scan(&schema, &table, &fk.ConstraintName, &fk.ColumnName, &fk.ReferencedTable, &fk.ReferencedColumn, &fk.OnDelete, &fk.OnUpdate, &fk.Comment); err != nil {
+			return nil, fmt.Errorf("scanning fk: %w", err)
+		}
+		fks[schema+"."+table] = append(fks[schema+"."+table], fk)
+	}
+	return fks, rows.Err()
+}
+
+// DescribeTable returns the structure and statistics of one table. An
+// explicit schema outside the configured schemas, or a hidden one, is
+// reported as not found, like a table that does not exist, so hidden schemas
+// stay hidden.
 func (e *Explorer) DescribeTable(ctx context.Context, schema, tableName string) (*port.TableDetail, error) {
 	detail := &port.TableDetail{Name: tableName}
 
+	if schema != "" && ((len(e.schemas) > 0 && !slices.Contains(e.schemas, schema)) || slices.Contains(e.hidden, schema)) {
+		return nil, fmt.Errorf("table %q %w in schema %q", tableName, domain.ErrNotFound, schema)
+	}
+
 	var err error
 	if schema != "" {
 		detail.Schema = schema
@@ -108,53 +301,129 @@ func (e *Explorer) DescribeTable(ctx context.Context, schema, tableName string)
 		return nil, err
 	}
 
-	// Fetch table size and row estimate from pg_class.
-	detail.RowEstimate, detail.TotalBytes, detail.SizeHuman, err = e.fetchTableSize(ctx, detail.Schema, tableName)
-	if err != nil {
+	full := port.DetailLevelFromContext(ctx) == port.DetailFull
+
+	// Independent fetchers run concurrently on the shared pool. Each writes a
+	// distinct field of detail. Structural fetchers are fatal and cancel the
+	// group; enrichment fetchers are non-fatal and swallow their errors.
+	g, gctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
 		// Non-fatal: views and some system objects may not have size info.
-		detail.RowEstimate = 0
-		detail.TotalBytes = 0
-		detail.SizeHuman = ""
-	}
+		var err error
+		detail.RowEstimate, detail.TotalBytes, detail.SizeHuman, err = e.fetchTableSize(gctx, detail.Schema, tableName)
+		if err != nil {
+			detail.RowEstimate, detail.TotalBytes, detail.SizeHuman = 0, 0, ""
+			return nil
+		}
+		if e.sampleRows && detail.RowEstimate <= 0 && detail.TotalBytes > 0 {
+			// Non-fatal: keep the catalog estimate if sampling fails.
+			// An analyzed table that is really empty counts as zero and
+			// keeps its estimate without a note.
+			if rows, note, err := e.sampleRowEstimate(gctx, detail.Schema, tableName, detail.TotalBytes); err == nil && rows > 0 {
+				detail.RowEstimate, detail.RowEstimateNote = rows, note
+			}
+		}
+		return nil
+	})
 
-	detail.Columns, err = e.fetchColumns(ctx, detail.Schema, tableName)
-	if err != nil {
-		return nil, err
-	}
+	g.Go(func() error {
+		var err error
+		detail.Columns, err = e.fetchColumns(gctx, detail.Schema, tableName)
+		if err != nil {
+			return err
+		}
+		if err := e.markPrimaryKeys(gctx, detail); err != nil {
+			return err
+		}
+		// Non-fatal: storage details are enrichment, not essential.
+		_ = e.markColumnStorage(gctx, detail)
+		return nil
+	})
 
-	if err := e.markPrimaryKeys(ctx, detail); err != nil {
-		return nil, err
-	}
+	g.Go(func() error {
+		var err error
+		detail.ForeignKeys, err = e.fetchForeignKeys(gctx, detail.Schema, tableName)
+		return err
+	})
 
-	// Enrich columns with pg_stats profiling data.
-	if err := e.fetchColumnStats(ctx, detail.Schema, tableName, detail.Columns, detail.RowEstimate); err != nil {
-		// Non-fatal: stats may not be available (e.g., never analyzed).
-		// Columns are still returned without stats.
-		_ = err
-	}
+	g.Go(func() error {
+		var err error
+		detail.Indexes, err = e.fetchIndexes(gctx, detail.Schema, tableName)
+		return err
+	})
 
-	detail.ForeignKeys, err = e.fetchForeignKeys(ctx, detail.Schema, tableName)
-	if err != nil {
-		return nil, err
+	g.Go(func() error {
+		// Non-fatal: check constraints are enrichment, not essential.
+		detail.CheckConstraints, _ = e.fetchCheckConstraints(gctx, detail.Schema, tableName)
+		return nil
+	})
+
+	var activity domain.RowEstimateActivity
+	g.Go(func() error {
+		// Non-fatal: inheritance is enrichment, not essential.
+		detail.Parents, detail.Children, _ = e.fetchInheritance(gctx, detail.Schema, tableName)
+		return nil
+	})
+
+	if full {
+		g.Go(func() error {
+			activity, _ = e.fetchStatsActivity(gctx, detail.Schema, tableName)
+			detail.StatsAge = activity.LastAnalyze
+			return nil
+		})
+
+		if !e.skipSamples {
+			g.Go(func() error {
+				detail.SampleRows, _ = fetchSampleRows(gctx, e.pool, detail.Schema, tableName)
+				return nil
+			})
+		}
+
+		if !e.skipIndexUsage {
+			g.Go(func() error {
+				detail.IndexUsage, _ = fetchIndexUsage(gctx, e.pool, detail.Schema, tableName)
+				// Non-fatal: without an estimate bloat is reported as 0.
+				if bloat, err := fetchIndexBloat(gctx, e.pool, detail.Schema, tableName); err == nil {
+					for i, u := range detail.IndexUsage {
+						detail.IndexUsage[i].BloatBytes = bloat[u.Name].bytes
+						detail.IndexUsage[i].BloatRatio = bloat[u.Name].ratio
+					}
+				}
+				return nil
+			})
+		}
 	}
 
-	detail.Indexes, err = e.fetchIndexes(ctx, detail.Schema, tableName)
-	if err != nil {
+	if err := g.Wait(); err != nil {
 		return nil, err
 	}
 
-	detail.CheckConstraints, err = e.fetchCheckConstraints(ctx, detail.Schema, tableName)
-	if err != nil {
-		// Non-fatal: check constraints are enrichment, not essential.
-		_ = err
+	// The remaining steps depend on results gathered above.
+
+	// Enrich columns with pg_stats profiling data. Needs RowEstimate.
+	if full {
+		if err := e.fetchColumnStats(ctx, detail.Schema, tableName, detail.Columns, detail.RowEstimate); err != nil {
+			// Non-fatal: stats may not be available (e.g., never analyzed).
+			// Columns are still returned without stats.
+			_ = err
+		}
 	}
 
-	// Fetch stats freshness.
-	detail.StatsAge, err = e.fetchStatsAge(ctx, detail.Schema, tableName)
-	if err != nil {
-		_ = err
+	// Inferred FKs (non-fatal). Needs primary key marks and declared FKs.
+	detail.InferredFKs, _ = e.inferForeignKeys(ctx, detail)
+
+	detail.Recommendations = append(detail.Recommendations, fkIndexRecommendations(detail.ForeignKeys, detail.Indexes)...)
+
+	summarizeColumnConstraints(detail)
+	attachCheckValues(detail)
+
+	if !full {
+		return detail, nil
 	}
 
+	detail.Recommendations = append(detail.Recommendations, indexBloatRecommendations(detail.Schema, detail.IndexUsage)...)
+
 	// Stats age warning.
 	if detail.StatsAge != nil {
 		age := time.Since(*detail.StatsAge)
@@ -165,17 +434,7 @@ func (e *Explorer) DescribeTable(ctx context.Context, schema, tableName string)
 		detail.StatsAgeWarning = "No ANALYZE has been run on this table. Statistics may be missing or inaccurate."
 	}
 
-	// Sample rows (non-fatal).
-	detail.SampleRows, err = fetchSampleRows(ctx, e.pool, detail.Schema, tableName)
-	if err != nil {
-		_ = err
-	}
-
-	// Index usage (non-fatal).
-	detail.IndexUsage, err = fetchIndexUsage(ctx, e.pool, detail.Schema, tableName)
-	if err != nil {
-		_ = err
-	}
+	detail.RowEstimateConfidence = domain.ClassifyRowEstimate(detail.RowEstimate, activity, time.Now())
 
 	return detail, nil
 }
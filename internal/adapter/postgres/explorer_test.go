@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/guillermoBallester/isthmus/internal/adapter/postgres"
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/guillermoBallester/isthmus/internal/core/port"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -167,6 +169,38 @@ func TestListSchemas_SchemaFilter(t *testing.T) {
 		assert.Contains(t, names, "internal")
 		assert.Contains(t, names, "public")
 	})
+
+	t.Run("hidden schemas are left out without an allowlist", func(t *testing.T) {
+		explorer := postgres.NewExplorer(pool, nil, postgres.WithHiddenSchemas([]string{"internal"}))
+		schemas, err := explorer.ListSchemas(ctx)
+		require.NoError(t, err)
+
+		names := make([]string, len(schemas))
+		for i, s := range schemas {
+			names[i] = s.Name
+		}
+		assert.Contains(t, names, "app")
+		assert.NotContains(t, names, "internal")
+
+		tables, err := explorer.ListTables(ctx)
+		require.NoError(t, err)
+		for _, tbl := range tables {
+			assert.NotEqual(t, "internal", tbl.Schema)
+		}
+
+		_, err = explorer.DescribeTable(ctx, "internal", "jobs")
+		require.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = explorer.DescribeTable(ctx, "", "jobs")
+		require.ErrorIs(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("hidden schemas are subtracted from the allowlist", func(t *testing.T) {
+		explorer := postgres.NewExplorer(pool, []string{"app", "internal"}, postgres.WithHiddenSchemas([]string{"internal"}))
+		schemas, err := explorer.ListSchemas(ctx)
+		require.NoError(t, err)
+		require.Len(t, schemas, 1)
+		assert.Equal(t, "app", schemas[0].Name)
+	})
 }
 
 func TestListTables(t *testing.T) {
@@ -243,6 +277,37 @@ func TestDescribeTable_ForeignKeys(t *testing.T) {
 	assert.Equal(t, "id", fk.ReferencedColumn)
 }
 
+func TestForeignKeys(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `
+		ALTER TABLE customers ADD UNIQUE (id, email);
+		CREATE TABLE invoices (
+			id             SERIAL PRIMARY KEY,
+			customer_id    INTEGER,
+			customer_email TEXT,
+			CONSTRAINT invoices_customer_fkey FOREIGN KEY (customer_id, customer_email)
+				REFERENCES customers (id, email) ON DELETE CASCADE
+		);
+	`)
+	require.NoError(t, err)
+
+	explorer := postgres.NewExplorer(pool, []string{"public"})
+	fks, err := explorer.ForeignKeys(ctx)
+	require.NoError(t, err)
+
+	detail, err := explorer.DescribeTable(ctx, "public", "orders")
+	require.NoError(t, err)
+	assert.Equal(t, detail.ForeignKeys, fks["public.orders"], "the same keys as describe_table")
+
+	assert.Equal(t, []port.ForeignKey{
+		{ConstraintName: "invoices_customer_fkey", ColumnName: "customer_id", ReferencedTable: "customers", ReferencedColumn: "id", OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+		{ConstraintName: "invoices_customer_fkey", ColumnName: "customer_email", ReferencedTable: "customers", ReferencedColumn: "email", OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+	}, fks["public.invoices"], "multi-column keys in key order")
+	assert.NotContains(t, fks, "public.customers")
+}
+
 func TestDescribeTable_Indexes(t *testing.T) {
 	pool := setupTestDB(t)
 	explorer := postgres.NewExplorer(pool, nil)
@@ -260,6 +325,36 @@ func TestDescribeTable_Indexes(t *testing.T) {
 	assert.False(t, indexNames["idx_orders_customer"])
 }
 
+func TestDescribeTable_IndexAndConstraintComments(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `
+		ALTER TABLE orders ADD CONSTRAINT orders_total_positive CHECK (total >= 0);
+		COMMENT ON INDEX idx_orders_customer IS 'Speeds up the per-customer order history page';
+		COMMENT ON CONSTRAINT orders_total_positive ON orders IS 'Refunds are separate rows';
+		COMMENT ON CONSTRAINT orders_customer_id_fkey ON orders IS 'Orders survive only while the customer exists';
+	`)
+	require.NoError(t, err)
+
+	explorer := postgres.NewExplorer(pool, nil)
+	detail, err := explorer.DescribeTable(ctx, "", "orders")
+	require.NoError(t, err)
+
+	indexComments := make(map[string]string)
+	for _, idx := range detail.Indexes {
+		indexComments[idx.Name] = idx.Comment
+	}
+	assert.Equal(t, "Speeds up the per-customer order history page", indexComments["idx_orders_customer"])
+	assert.Empty(t, indexComments["orders_pkey"])
+
+	require.Len(t, detail.CheckConstraints, 1)
+	assert.Equal(t, "Refunds are separate rows", detail.CheckConstraints[0].Comment)
+
+	require.Len(t, detail.ForeignKeys, 1)
+	assert.Equal(t, "Orders survive only while the customer exists", detail.ForeignKeys[0].Comment)
+}
+
 func TestDescribeTable_NotFound(t *testing.T) {
 	pool := setupTestDB(t)
 	explorer := postgres.NewExplorer(pool, nil)
@@ -270,6 +365,49 @@ func TestDescribeTable_NotFound(t *testing.T) {
 	assert.Contains(t, err.Error(), "nonexistent")
 }
 
+func TestDescribeTable_SchemaAllowlist(t *testing.T) {
+	pool := setupMultiSchemaDB(t)
+	explorer := postgres.NewExplorer(pool, []string{"app"})
+	ctx := context.Background()
+
+	detail, err := explorer.DescribeTable(ctx, "app", "users")
+	require.NoError(t, err)
+	assert.Equal(t, "app", detail.Schema)
+
+	_, err = explorer.DescribeTable(ctx, "internal", "jobs")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, domain.ErrNotFound, "a table outside the allowlist looks like a missing one")
+
+	_, err = explorer.DescribeTable(ctx, "", "jobs")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestDescribeTable_AmbiguousName(t *testing.T) {
+	pool := setupMultiSchemaDB(t)
+	ctx := context.Background()
+	_, err := pool.Exec(ctx, "CREATE TABLE internal.users (id SERIAL PRIMARY KEY)")
+	require.NoError(t, err)
+
+	explorer := postgres.NewExplorer(pool, nil)
+	_, err = explorer.DescribeTable(ctx, "", "users")
+	require.ErrorIs(t, err, domain.ErrAmbiguousTable)
+	assert.Contains(t, err.Error(), "app, internal")
+
+	detail, err := explorer.DescribeTable(ctx, "internal", "users")
+	require.NoError(t, err, "an explicit schema resolves the ambiguity")
+	assert.Equal(t, "internal", detail.Schema)
+
+	scoped := postgres.NewExplorer(pool, []string{"app"})
+	detail, err = scoped.DescribeTable(ctx, "", "users")
+	require.NoError(t, err, "schemas outside the allowlist are not candidates")
+	assert.Equal(t, "app", detail.Schema)
+
+	resolving := postgres.NewExplorer(pool, nil, postgres.WithResolveAmbiguous(true))
+	detail, err = resolving.DescribeTable(ctx, "", "users")
+	require.NoError(t, err)
+	assert.Equal(t, "app", detail.Schema, "first schema alphabetically")
+}
+
 func TestListTables_SchemaFilter(t *testing.T) {
 	pool := setupMultiSchemaDB(t)
 	ctx := context.Background()
@@ -315,6 +453,38 @@ func TestListTables_SchemaFilter(t *testing.T) {
 	})
 }
 
+func TestTableActivity_SchemaFilter(t *testing.T) {
+	pool := setupMultiSchemaDB(t)
+	ctx := context.Background()
+
+	conn, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, "INSERT INTO app.users (name) SELECT 'u' || g FROM generate_series(1, 3) g")
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, "UPDATE app.users SET name = 'renamed' WHERE id = 1")
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, "INSERT INTO internal.jobs DEFAULT VALUES")
+	require.NoError(t, err)
+	// Backends flush their counters lazily; force it so the next read sees them.
+	_, err = conn.Exec(ctx, "SELECT pg_stat_force_next_flush()")
+	require.NoError(t, err)
+	conn.Release()
+
+	explorer := postgres.NewExplorer(pool, []string{"app"})
+	var activity []port.TableActivity
+	require.Eventually(t, func() bool {
+		activity, err = explorer.TableActivity(ctx)
+		return err == nil && len(activity) == 1 && activity[0].TotalChanges == 4
+	}, 5*time.Second, 100*time.Millisecond)
+
+	got := activity[0]
+	assert.Equal(t, "app", got.Schema)
+	assert.Equal(t, "users", got.Name)
+	assert.Equal(t, int64(3), got.Inserts)
+	assert.Equal(t, int64(1), got.Updates)
+	assert.Equal(t, int64(0), got.Deletes)
+}
+
 func TestDescribeTable_WithSchema(t *testing.T) {
 	pool := setupTestDB(t)
 	explorer := postgres.NewExplorer(pool, nil)
@@ -352,3 +522,144 @@ func TestDescribeTable_SchemaFilter_BlocksAccess(t *testing.T) {
 	assert.Contains(t, err.Error(), "config")
 	assert.Contains(t, err.Error(), "app")
 }
+
+func TestDescribeTable_InferredForeignKeys_SameNameAcrossSchemas(t *testing.T) {
+	pool := setupMultiSchemaDB(t)
+	ctx := context.Background()
+
+	// internal.users shares its name with app.users but has a uuid PK.
+	_, err := pool.Exec(ctx, `
+		CREATE TABLE internal.users (user_uuid UUID PRIMARY KEY);
+		CREATE TABLE app.orders (id SERIAL PRIMARY KEY, user_id INTEGER NOT NULL);
+		CREATE TABLE internal.sessions (id SERIAL PRIMARY KEY, user_id UUID NOT NULL);
+	`)
+	require.NoError(t, err)
+
+	explorer := postgres.NewExplorer(pool, nil)
+
+	orders, err := explorer.DescribeTable(ctx, "app", "orders")
+	require.NoError(t, err)
+	require.Len(t, orders.InferredFKs, 1)
+	assert.Equal(t, "users", orders.InferredFKs[0].ReferencedTable)
+	assert.Equal(t, "id", orders.InferredFKs[0].ReferencedColumn, "should resolve to app.users, not internal.users")
+
+	sessions, err := explorer.DescribeTable(ctx, "internal", "sessions")
+	require.NoError(t, err)
+	require.Len(t, sessions.InferredFKs, 1)
+	assert.Equal(t, "users", sessions.InferredFKs[0].ReferencedTable)
+	assert.Equal(t, "user_uuid", sessions.InferredFKs[0].ReferencedColumn, "should resolve to internal.users")
+}
+
+func TestDescribeTable_InferredForeignKeys_Scope(t *testing.T) {
+	pool := setupMultiSchemaDB(t)
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `
+		CREATE TABLE internal.plans (id SERIAL PRIMARY KEY);
+		CREATE TABLE app.subscriptions (id SERIAL PRIMARY KEY, plan_id INTEGER NOT NULL);
+	`)
+	require.NoError(t, err)
+
+	t.Run("schema scope by default", func(t *testing.T) {
+		subs, err := postgres.NewExplorer(pool, nil).DescribeTable(ctx, "app", "subscriptions")
+		require.NoError(t, err)
+		assert.Empty(t, subs.InferredFKs)
+	})
+
+	t.Run("all schemas", func(t *testing.T) {
+		explorer := postgres.NewExplorer(pool, nil, postgres.WithFKInferenceScope(postgres.FKInferenceScopeAll))
+		subs, err := explorer.DescribeTable(ctx, "app", "subscriptions")
+		require.NoError(t, err)
+		require.Len(t, subs.InferredFKs, 1)
+		assert.Equal(t, "internal.plans", subs.InferredFKs[0].ReferencedTable)
+		assert.Equal(t, "medium", subs.InferredFKs[0].Confidence)
+	})
+
+	t.Run("all schemas, high confidence only", func(t *testing.T) {
+		explorer := postgres.NewExplorer(pool, nil,
+			postgres.WithFKInferenceScope(postgres.FKInferenceScopeAll),
+			postgres.WithFKInferenceHighConfidenceOnly(true),
+		)
+		subs, err := explorer.DescribeTable(ctx, "app", "subscriptions")
+		require.NoError(t, err)
+		assert.Empty(t, subs.InferredFKs)
+	})
+}
+
+func TestDescribeTable_Partitions(t *testing.T) {
+	pool := setupMultiSchemaDB(t)
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `
+		CREATE TABLE app.measurements (id INT NOT NULL, taken_on DATE NOT NULL) PARTITION BY RANGE (taken_on);
+		CREATE TABLE app.measurements_2025 PARTITION OF app.measurements FOR VALUES FROM ('2025-01-01') TO ('2026-01-01');
+		CREATE TABLE internal.measurements_2026 PARTITION OF app.measurements FOR VALUES FROM ('2026-01-01') TO ('2027-01-01');
+	`)
+	require.NoError(t, err)
+
+	t.Run("parent lists partitions across schemas", func(t *testing.T) {
+		detail, err := postgres.NewExplorer(pool, nil).DescribeTable(ctx, "app", "measurements")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"measurements_2025", "internal.measurements_2026"}, detail.Children)
+	})
+
+	t.Run("partition names its parent", func(t *testing.T) {
+		detail, err := postgres.NewExplorer(pool, nil).DescribeTable(ctx, "internal", "measurements_2026")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app.measurements"}, detail.Parents)
+	})
+
+	t.Run("schemas outside the filter are hidden", func(t *testing.T) {
+		detail, err := postgres.NewExplorer(pool, []string{"app"}).DescribeTable(ctx, "app", "measurements")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"measurements_2025"}, detail.Children)
+	})
+}
+
+func TestDescribeTable_RowEstimateSampling(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+
+	// Created after the setup ANALYZE, with autovacuum off so nothing
+	// analyzes them during the test.
+	_, err := pool.Exec(ctx, `
+		CREATE TABLE public.bulk_load (id INT, payload TEXT) WITH (autovacuum_enabled = false);
+		INSERT INTO public.bulk_load SELECT i, 'row ' || i FROM generate_series(1, 500000) AS i;
+		CREATE TABLE public.small_load (id INT) WITH (autovacuum_enabled = false);
+		INSERT INTO public.small_load SELECT i FROM generate_series(1, 250) AS i;
+		CREATE TABLE public.empty_load (id INT PRIMARY KEY) WITH (autovacuum_enabled = false);
+	`)
+	require.NoError(t, err)
+
+	t.Run("off by default", func(t *testing.T) {
+		detail, err := postgres.NewExplorer(pool, nil).DescribeTable(ctx, "public", "bulk_load")
+		require.NoError(t, err)
+		assert.LessOrEqual(t, detail.RowEstimate, int64(0), "never analyzed, so no planner estimate")
+		assert.Empty(t, detail.RowEstimateNote)
+	})
+
+	explorer := postgres.NewExplorer(pool, nil, postgres.WithRowEstimateSampling(true))
+
+	t.Run("large table is sampled", func(t *testing.T) {
+		detail, err := explorer.DescribeTable(ctx, "public", "bulk_load")
+		require.NoError(t, err)
+		assert.InDelta(t, 500000, detail.RowEstimate, 250000)
+		assert.Contains(t, detail.RowEstimateNote, "TABLESAMPLE")
+	})
+
+	t.Run("small table is counted", func(t *testing.T) {
+		detail, err := explorer.DescribeTable(ctx, "public", "small_load")
+		require.NoError(t, err)
+		assert.Equal(t, int64(250), detail.RowEstimate)
+		assert.Contains(t, detail.RowEstimateNote, "Counted")
+	})
+
+	t.Run("empty table has no note", func(t *testing.T) {
+		for _, table := range []string{"empty_load", "users"} {
+			detail, err := explorer.DescribeTable(ctx, "public", table)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, detail.RowEstimate, int64(0), table)
+			assert.Empty(t, detail.RowEstimateNote, table)
+		}
+	})
+}
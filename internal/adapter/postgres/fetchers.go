@@ -6,11 +6,11 @@ import (
 	"fmt"
 	"math"
 	"strings"
-	"time"
 
 	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"github.com/guillermoBallester/isthmus/internal/core/port"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 func (e *Explorer) fetchTableComment(ctx context.Context, schema, tableName string) (string, error) {
@@ -26,23 +26,44 @@ func (e *Explorer) fetchTableComment(ctx context.Context, schema, tableName stri
 }
 
 func (e *Explorer) fetchTableMeta(ctx context.Context, tableName string) (schema, comment string, err error) {
-	filter, filterArgs := schemaFilter(e.schemas, "t.table_schema", 2) // $1 is tableName
+	filter, filterArgs := schemaFilter(e.schemas, e.hidden, "t.table_schema", 2) // $1 is tableName
 	query := fmt.Sprintf(queryTableMeta, filter)
 
 	args := make([]any, 0, 1+len(filterArgs))
 	args = append(args, tableName)
 	args = append(args, filterArgs...)
 
-	err = e.pool.QueryRow(ctx, query, args...).Scan(&schema, &comment)
+	rows, err := e.pool.Query(ctx, query, args...)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			if len(e.schemas) > 0 {
-				return "", "", fmt.Errorf("table %q %w in schemas %v", tableName, domain.ErrNotFound, e.schemas)
-			}
-			return "", "", fmt.Errorf("table %q %w", tableName, domain.ErrNotFound)
+		return "", "", fmt.Errorf("querying table metadata for %q: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var candidates []string
+	for rows.Next() {
+		var s, c string
+		if err := rows.Scan(&s, &c); err != nil {
+			return "", "", fmt.Errorf("scanning table metadata for %q: %w", tableName, err)
 		}
+		if len(candidates) == 0 {
+			schema, comment = s, c
+		}
+		candidates = append(candidates, s)
+	}
+	if err := rows.Err(); err != nil {
 		return "", "", fmt.Errorf("querying table metadata for %q: %w", tableName, err)
 	}
+
+	switch {
+	case len(candidates) == 0:
+		if len(e.schemas) > 0 {
+			return "", "", fmt.Errorf("table %q %w in schemas %v", tableName, domain.ErrNotFound, e.schemas)
+		}
+		return "", "", fmt.Errorf("table %q %w", tableName, domain.ErrNotFound)
+	case len(candidates) > 1 && !e.resolveAmbiguous:
+		return "", "", fmt.Errorf("%w: %q exists in schemas %s; pass schema to choose one",
+			domain.ErrAmbiguousTable, tableName, strings.Join(candidates, ", "))
+	}
 	return schema, comment, nil
 }
 
@@ -56,7 +77,7 @@ func (e *Explorer) fetchColumns(ctx context.Context, schema, tableName string) (
 	var cols []port.ColumnInfo
 	for rows.Next() {
 		var col port.ColumnInfo
-		if err := rows.Scan(&col.Name, &col.DataType, &col.IsNullable, &col.DefaultValue, &col.Comment); err != nil {
+		if err := rows.Scan(&col.Name, &col.DataType, &col.IsNullable, &col.DefaultValue, &col.Comment, &col.Collation); err != nil {
 			return nil, fmt.Errorf("scanning column: %w", err)
 		}
 		cols = append(cols, col)
@@ -91,6 +112,52 @@ func (e *Explorer) markPrimaryKeys(ctx context.Context, detail *port.TableDetail
 	return nil
 }
 
+// markColumnStorage sets the storage mode and compression method of the
+// columns whose values can be moved out of line to TOAST. Fixed-width columns
+// are stored plain and left without either.
+func (e *Explorer) markColumnStorage(ctx context.Context, detail *port.TableDetail) error {
+	storage, err := e.queryColumnStorage(ctx, queryColumnStorage, detail.Schema, detail.Name)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42703" { // undefined_column
+		// Before PostgreSQL 14: no per-column compression.
+		storage, err = e.queryColumnStorage(ctx, queryColumnStorageNoCompression, detail.Schema, detail.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range detail.Columns {
+		if s, ok := storage[detail.Columns[i].Name]; ok {
+			detail.Columns[i].Storage = s[0]
+			detail.Columns[i].Compression = s[1]
+		}
+	}
+	return nil
+}
+
+// queryColumnStorage runs one of the column storage queries and returns the
+// storage mode and compression method by column name.
+func (e *Explorer) queryColumnStorage(ctx context.Context, query, schema, tableName string) (map[string][2]string, error) {
+	rows, err := e.pool.Query(ctx, query, schema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("querying column storage: %w", err)
+	}
+	defer rows.Close()
+
+	storage := make(map[string][2]string)
+	for rows.Next() {
+		var name, mode, compression string
+		if err := rows.Scan(&name, &mode, &compression); err != nil {
+			return nil, fmt.Errorf("scanning column storage: %w", err)
+		}
+		storage[name] = [2]string{mode, compression}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying column storage: %w", err)
+	}
+	return storage, nil
+}
+
 func (e *Explorer) fetchForeignKeys(ctx context.Context, schema, tableName string) ([]port.ForeignKey, error) {
 	rows, err := e.pool.Query(ctx, queryForeignKeys, schema, tableName)
 	if err != nil {
@@ -101,7 +168,7 @@ func (e *Explorer) fetchForeignKeys(ctx context.Context, schema, tableName strin
 	var fks []port.ForeignKey
 	for rows.Next() {
 		var fk port.ForeignKey
-		if err := rows.Scan(&fk.ConstraintName, &fk.ColumnName, &fk.ReferencedTable, &fk.ReferencedColumn); err != nil {
+		if err := rows.Scan(&fk.ConstraintName, &fk.ColumnName, &fk.ReferencedTable, &fk.ReferencedColumn, &fk.OnDelete, &fk.OnUpdate, &fk.Comment); err != nil {
 			return nil, fmt.Errorf("scanning fk: %w", err)
 		}
 		fks = append(fks, fk)
@@ -109,6 +176,36 @@ func (e *Explorer) fetchForeignKeys(ctx context.Context, schema, tableName strin
 	return fks, rows.Err()
 }
 
+// fetchInheritance returns the direct parents and children of a table.
+// Related tables in the same schema are reported by bare name, others as
+// "schema.table"; tables in schemas outside the allowed list are left out.
+func (e *Explorer) fetchInheritance(ctx context.Context, schema, tableName string) (parents, children []string, err error) {
+	filter, filterArgs := schemaFilter(e.schemas, e.hidden, "rn.nspname", 3)
+	args := append([]any{schema, tableName}, filterArgs...)
+	rows, err := e.pool.Query(ctx, fmt.Sprintf(queryInheritance, filter, filter), args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying inheritance: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var relation, relSchema, relName string
+		var seq int32
+		if err := rows.Scan(&relation, &relSchema, &relName, &seq); err != nil {
+			return nil, nil, fmt.Errorf("scanning inheritance: %w", err)
+		}
+		if relSchema != schema {
+			relName = relSchema + "." + relName
+		}
+		if relation == "parent" {
+			parents = append(parents, relName)
+		} else {
+			children = append(children, relName)
+		}
+	}
+	return parents, children, rows.Err()
+}
+
 func (e *Explorer) fetchIndexes(ctx context.Context, schema, tableName string) ([]port.IndexInfo, error) {
 	rows, err := e.pool.Query(ctx, queryIndexes, schema, tableName)
 	if err != nil {
@@ -119,7 +216,7 @@ func (e *Explorer) fetchIndexes(ctx context.Context, schema, tableName string) (
 	var idxs []port.IndexInfo
 	for rows.Next() {
 		var idx port.IndexInfo
-		if err := rows.Scan(&idx.Name, &idx.Definition, &idx.IsUnique); err != nil {
+		if err := rows.Scan(&idx.Name, &idx.Definition, &idx.IsUnique, &idx.Columns, &idx.Comment); err != nil {
 			return nil, fmt.Errorf("scanning index: %w", err)
 		}
 		idxs = append(idxs, idx)
@@ -199,7 +296,7 @@ func (e *Explorer) fetchCheckConstraints(ctx context.Context, schema, tableName
 	var checks []port.CheckConstraint
 	for rows.Next() {
 		var ck port.CheckConstraint
-		if err := rows.Scan(&ck.Name, &ck.Expression); err != nil {
+		if err := rows.Scan(&ck.Name, &ck.Expression, &ck.Columns, &ck.Comment); err != nil {
 			return nil, fmt.Errorf("scanning check constraint: %w", err)
 		}
 		checks = append(checks, ck)
@@ -217,11 +314,37 @@ func (e *Explorer) fetchTableSize(ctx context.Context, schema, tableName string)
 	return rowEstimate, totalBytes, sizeHuman, nil
 }
 
+// exactCountMaxBytes is the size up to which an unanalyzed table is counted
+// exactly: a 1% block sample of so few pages is mostly empty or noise.
+const exactCountMaxBytes = 1 << 20
+
+// sampleRowEstimate estimates the row count of a table that the planner has
+// no estimate for, as reltuples is unset (-1) or zero until the first
+// ANALYZE or VACUUM. Callers should ignore a zero result, which means the
+// table is really empty. Tables up to exactCountMaxBytes are counted; larger ones
+// extrapolate the rows in a 1% TABLESAMPLE SYSTEM block sample. The note says
+// which was done.
+func (e *Explorer) sampleRowEstimate(ctx context.Context, schema, tableName string, totalBytes int64) (int64, string, error) {
+	fqn := domain.QualifiedName(schema, tableName)
+	var rows int64
+	if totalBytes <= exactCountMaxBytes {
+		if err := e.pool.QueryRow(ctx, "SELECT count(*) FROM "+fqn).Scan(&rows); err != nil {
+			return 0, "", fmt.Errorf("counting rows: %w", err)
+		}
+		return rows, "Counted directly: the planner has no row estimate for this table. Run ANALYZE to get one.", nil
+	}
+	if err := e.pool.QueryRow(ctx, "SELECT count(*) * 100 FROM "+fqn+" TABLESAMPLE SYSTEM (1)").Scan(&rows); err != nil {
+		return 0, "", fmt.Errorf("sampling rows: %w", err)
+	}
+	return rows, "Approximate: extrapolated from a 1% TABLESAMPLE SYSTEM sample because the planner has no row estimate for this table. Run ANALYZE to get one.", nil
+}
+
 // fetchSampleRows retrieves a handful of representative rows from a table.
+// Array and composite values are normalized for JSON output.
 func fetchSampleRows(ctx context.Context, pool interface {
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 }, schema, tableName string) ([]map[string]any, error) {
-	fqn := fmt.Sprintf("%s.%s", quoteIdent(schema), quoteIdent(tableName))
+	fqn := domain.QualifiedName(schema, tableName)
 	query := fmt.Sprintf("SELECT * FROM %s TABLESAMPLE BERNOULLI(50) LIMIT 5", fqn)
 
 	rows, err := pool.Query(ctx, query)
@@ -235,7 +358,26 @@ func fetchSampleRows(ctx context.Context, pool interface {
 	}
 	defer rows.Close()
 
-	return rowsToMaps(rows)
+	cols := unknownColumns(rows.FieldDescriptions())
+	result, err := rowsToMaps(rows, DuplicateColumnsSuffix, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve array/composite shapes only when pgx left values undecoded.
+	shapes := map[uint32]typeShape{}
+	if len(cols) > 0 && len(result) > 0 {
+		oids := make([]uint32, len(cols))
+		for i, c := range cols {
+			oids[i] = c.oid
+		}
+		if shapes, err = fetchTypeShapes(ctx, pool, oids); err != nil {
+			// Non-fatal: fall back to the raw text representation.
+			shapes = map[uint32]typeShape{}
+		}
+	}
+	normalizeSampleRows(result, cols, shapes)
+	return result, nil
 }
 
 // fetchIndexUsage retrieves usage statistics for all indexes on a table.
@@ -259,15 +401,45 @@ func fetchIndexUsage(ctx context.Context, pool interface {
 	return usage, rows.Err()
 }
 
-// fetchStatsAge reads the last ANALYZE timestamp for a table.
-func (e *Explorer) fetchStatsAge(ctx context.Context, schema, tableName string) (*time.Time, error) {
-	var ts *time.Time
-	err := e.pool.QueryRow(ctx, queryStatsAge, schema, tableName).Scan(&ts)
+// indexBloat is the estimated bloat of one index.
+type indexBloat struct {
+	bytes int64
+	ratio float64
+}
+
+// fetchIndexBloat estimates the bloat of each btree index on a table, keyed
+// by index name.
+func fetchIndexBloat(ctx context.Context, pool interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}, schema, tableName string) (map[string]indexBloat, error) {
+	rows, err := pool.Query(ctx, queryIndexBloat, schema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("querying index bloat: %w", err)
+	}
+	defer rows.Close()
+
+	bloat := make(map[string]indexBloat)
+	for rows.Next() {
+		var name string
+		var b indexBloat
+		if err := rows.Scan(&name, &b.bytes, &b.ratio); err != nil {
+			return nil, fmt.Errorf("scanning index bloat: %w", err)
+		}
+		bloat[name] = b
+	}
+	return bloat, rows.Err()
+}
+
+// fetchStatsActivity reads the last ANALYZE and VACUUM timestamps and the
+// modifications since the last ANALYZE for a table.
+func (e *Explorer) fetchStatsActivity(ctx context.Context, schema, tableName string) (domain.RowEstimateActivity, error) {
+	var a domain.RowEstimateActivity
+	err := e.pool.QueryRow(ctx, queryStatsActivity, schema, tableName).Scan(&a.LastAnalyze, &a.LastVacuum, &a.ModsSinceAnalyze)
 	if err != nil {
 		// No stats is not an error — could be a fresh table.
-		return nil, nil //nolint:nilerr
+		return domain.RowEstimateActivity{}, nil //nolint:nilerr
 	}
-	return ts, nil
+	return a, nil
 }
 
 // pgDistinctToAbsolute converts pg_stats n_distinct to an absolute distinct count.
@@ -8,22 +8,34 @@ import (
 // schemaFilter returns a SQL WHERE clause fragment and args for filtering by schema.
 // paramOffset is the starting $N parameter index (1-based).
 // When schemas is empty, it excludes system schemas (pg_catalog, information_schema).
-func schemaFilter(schemas []string, column string, paramOffset int) (clause string, args []any) {
+// Schemas in hidden are excluded in either case, so they are subtracted from
+// the allowed schemas.
+func schemaFilter(schemas, hidden []string, column string, paramOffset int) (clause string, args []any) {
 	if len(schemas) == 0 {
-		return fmt.Sprintf("%s NOT IN ('pg_catalog', 'information_schema')", column), nil
+		clause = fmt.Sprintf("%s NOT IN ('pg_catalog', 'information_schema')", column)
+	} else {
+		clause = fmt.Sprintf("%s IN (%s)", column, placeholders(paramOffset, len(schemas)))
+		args = make([]any, 0, len(schemas)+len(hidden))
+		for _, s := range schemas {
+			args = append(args, s)
+		}
 	}
-	placeholders := make([]string, len(schemas))
-	args = make([]any, len(schemas))
-	for i, s := range schemas {
-		placeholders[i] = fmt.Sprintf("$%d", paramOffset+i)
-		args[i] = s
+	if len(hidden) > 0 {
+		clause += fmt.Sprintf(" AND %s NOT IN (%s)", column, placeholders(paramOffset+len(args), len(hidden)))
+		for _, s := range hidden {
+			args = append(args, s)
+		}
 	}
-	return fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")), args
+	return clause, args
 }
 
-// quoteIdent quotes a SQL identifier to prevent injection.
-func quoteIdent(name string) string {
-	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+// placeholders returns n comma-separated parameters starting at $from.
+func placeholders(from, n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = fmt.Sprintf("$%d", from+i)
+	}
+	return strings.Join(ps, ", ")
 }
 
 // isTypeCompatible checks if two column types are compatible for FK inference.
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+)
+
+// DefaultPKIndexTTL is how long the primary-key index used for FK inference
+// is reused before it is rebuilt from the catalog.
+const DefaultPKIndexTTL = 5 * time.Minute
+
+// FKInferenceScope selects which tables inferred foreign keys may point at.
+type FKInferenceScope string
+
+const (
+	// FKInferenceScopeSchema only matches tables in the described table's
+	// own schema.
+	FKInferenceScopeSchema FKInferenceScope = "schema"
+	// FKInferenceScopeAll also matches tables in other schemas, with
+	// "medium" confidence.
+	FKInferenceScopeAll FKInferenceScope = "all"
+)
+
+// fkInferenceOptions limits which candidates inferFromPKIndex reports.
+type fkInferenceOptions struct {
+	scope              FKInferenceScope
+	highConfidenceOnly bool
+	max                int // keep at most this many candidates; 0 keeps all
+}
+
+// pkColumn describes the single-column primary key of a table.
+type pkColumn struct {
+	Schema   string
+	Column   string
+	DataType string
+}
+
+// pkIndex maps "schema.table" to the table's single-column primary key, so
+// same-named tables in different schemas do not collide.
+type pkIndex map[string]pkColumn
+
+// pkIndexCache holds the most recently built pkIndex.
+type pkIndexCache = ttlCache[pkIndex]
+
+func newPKIndexCache(ttl time.Duration) *pkIndexCache {
+	return newTTLCache[pkIndex](ttl)
+}
+
+// buildPKIndex scans single-column primary keys across all schemas in scope.
+func (e *Explorer) buildPKIndex(ctx context.Context) (pkIndex, error) {
+	filter, args := schemaFilter(e.schemas, e.hidden, "n.nspname", 1)
+	query := fmt.Sprintf(queryPrimaryKeyIndex, filter)
+
+	rows, err := e.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("building primary key index: %w", err)
+	}
+	defer rows.Close()
+
+	idx := make(pkIndex)
+	for rows.Next() {
+		var table string
+		var pk pkColumn
+		if err := rows.Scan(&pk.Schema, &table, &pk.Column, &pk.DataType); err != nil {
+			return nil, fmt.Errorf("scanning primary key row: %w", err)
+		}
+		idx[pk.Schema+"."+table] = pk
+	}
+	return idx, rows.Err()
+}
+
+// inferForeignKeys suggests relationships for *_id columns that have no
+// FOREIGN KEY constraint, using the cached primary-key index.
+func (e *Explorer) inferForeignKeys(ctx context.Context, detail *port.TableDetail) ([]port.InferredForeignKey, error) {
+	idx, err := e.pkIndex.get(ctx, e.buildPKIndex)
+	if err != nil {
+		return nil, err
+	}
+	return inferFromPKIndex(detail, idx, e.fkInference), nil
+}
+
+// inferFromPKIndex matches the table's columns against idx. Columns already
+// covered by a declared FK, and primary key columns, are skipped. Targets in
+// the table's own schema are reported by bare name, like declared FKs;
+// targets in another schema are qualified as "schema.table". opts narrows
+// the match to the table's schema and/or to high-confidence candidates.
+//
+// Candidates are ordered by confidence, high first, keeping column order
+// within each level, and truncated to opts.max when it is set.
+func inferFromPKIndex(detail *port.TableDetail, idx pkIndex, opts fkInferenceOptions) []port.InferredForeignKey {
+	declared := make(map[string]bool, len(detail.ForeignKeys))
+	for _, fk := range detail.ForeignKeys {
+		declared[fk.ColumnName] = true
+	}
+
+	tables := make(map[string]bool, len(idx))
+	for key, pk := range idx {
+		if opts.scope != FKInferenceScopeAll && pk.Schema != detail.Schema {
+			continue
+		}
+		tables[key] = true
+	}
+
+	var inferred []port.InferredForeignKey
+	for _, col := range detail.Columns {
+		if col.IsPrimaryKey || declared[col.Name] {
+			continue
+		}
+		candidate, ok := domain.MatchFKNamingPattern(col.Name, detail.Schema, tables)
+		if !ok || (opts.highConfidenceOnly && candidate.Confidence != "high") {
+			continue
+		}
+		pk := idx[candidate.ReferencedSchema+"."+candidate.ReferencedTable]
+		if !isTypeCompatible(col.DataType, pk.DataType) {
+			continue
+		}
+		refTable := candidate.ReferencedTable
+		if candidate.ReferencedSchema != detail.Schema {
+			refTable = candidate.ReferencedSchema + "." + refTable
+		}
+		inferred = append(inferred, port.InferredForeignKey{
+			ColumnName:       col.Name,
+			ReferencedTable:  refTable,
+			ReferencedColumn: pk.Column,
+			Confidence:       candidate.Confidence,
+			Reason:           candidate.Reason,
+		})
+	}
+
+	slices.SortStableFunc(inferred, func(a, b port.InferredForeignKey) int {
+		return confidenceRank(a.Confidence) - confidenceRank(b.Confidence)
+	})
+	if opts.max > 0 && len(inferred) > opts.max {
+		inferred = inferred[:opts.max]
+	}
+	return inferred
+}
+
+// confidenceRank orders confidence levels from strongest to weakest.
+func confidenceRank(confidence string) int {
+	switch confidence {
+	case "high":
+		return 0
+	case "medium":
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,236 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns a controllable time source for pkIndexCache.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func countingBuild(calls *int) func(context.Context) (pkIndex, error) {
+	return func(context.Context) (pkIndex, error) {
+		*calls++
+		return pkIndex{"public.users": {Schema: "public", Column: "id", DataType: "int4"}}, nil
+	}
+}
+
+func TestPKIndexCache_ReusedWithinTTL(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	cache := newPKIndexCache(time.Minute)
+	cache.now = clock.now
+
+	calls := 0
+	build := countingBuild(&calls)
+
+	_, err := cache.get(context.Background(), build)
+	require.NoError(t, err)
+	clock.t = clock.t.Add(30 * time.Second)
+	idx, err := cache.get(context.Background(), build)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, calls)
+	assert.Contains(t, idx, "public.users")
+}
+
+func TestPKIndexCache_RefreshedAfterTTL(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	cache := newPKIndexCache(time.Minute)
+	cache.now = clock.now
+
+	calls := 0
+	build := countingBuild(&calls)
+
+	_, err := cache.get(context.Background(), build)
+	require.NoError(t, err)
+	clock.t = clock.t.Add(time.Minute)
+	_, err = cache.get(context.Background(), build)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestPKIndexCache_ZeroTTLDisablesCaching(t *testing.T) {
+	t.Parallel()
+	cache := newPKIndexCache(0)
+
+	calls := 0
+	build := countingBuild(&calls)
+	for range 3 {
+		_, err := cache.get(context.Background(), build)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 3, calls)
+}
+
+func TestPKIndexCache_BuildErrorNotCached(t *testing.T) {
+	t.Parallel()
+	cache := newPKIndexCache(time.Minute)
+
+	_, err := cache.get(context.Background(), func(context.Context) (pkIndex, error) {
+		return nil, errors.New("boom")
+	})
+	require.Error(t, err)
+
+	calls := 0
+	_, err = cache.get(context.Background(), countingBuild(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls, "failed build must not populate the cache")
+}
+
+func TestInferFromPKIndex(t *testing.T) {
+	t.Parallel()
+	idx := pkIndex{
+		"public.users":    {Schema: "public", Column: "id", DataType: "int4"},
+		"public.accounts": {Schema: "public", Column: "account_uuid", DataType: "uuid"},
+		"public.orders":   {Schema: "public", Column: "id", DataType: "int4"},
+	}
+	detail := &port.TableDetail{
+		Schema: "public",
+		Name:   "reviews",
+		Columns: []port.ColumnInfo{
+			{Name: "id", DataType: "integer", IsPrimaryKey: true},
+			{Name: "user_id", DataType: "integer"},
+			{Name: "account_id", DataType: "integer"}, // type mismatch with uuid PK
+			{Name: "order_id", DataType: "integer"},   // already has a declared FK
+			{Name: "ticket_id", DataType: "integer"},  // no matching table
+		},
+		ForeignKeys: []port.ForeignKey{{ColumnName: "order_id", ReferencedTable: "public.orders"}},
+	}
+
+	got := inferFromPKIndex(detail, idx, fkInferenceOptions{scope: FKInferenceScopeSchema})
+
+	require.Len(t, got, 1)
+	assert.Equal(t, "user_id", got[0].ColumnName)
+	assert.Equal(t, "users", got[0].ReferencedTable)
+	assert.Equal(t, "id", got[0].ReferencedColumn)
+	assert.Equal(t, "high", got[0].Confidence)
+}
+
+func TestInferFromPKIndex_SameNameAcrossSchemas(t *testing.T) {
+	t.Parallel()
+	idx := pkIndex{
+		"sales.customers":   {Schema: "sales", Column: "id", DataType: "int4"},
+		"support.customers": {Schema: "support", Column: "customer_uuid", DataType: "uuid"},
+		"billing.plans":     {Schema: "billing", Column: "id", DataType: "int8"},
+	}
+	orders := &port.TableDetail{
+		Schema: "sales",
+		Name:   "orders",
+		Columns: []port.ColumnInfo{
+			{Name: "customer_id", DataType: "integer"},
+			{Name: "plan_id", DataType: "bigint"},
+		},
+	}
+	tickets := &port.TableDetail{
+		Schema: "support",
+		Name:   "tickets",
+		Columns: []port.ColumnInfo{
+			{Name: "customer_id", DataType: "uuid"},
+		},
+	}
+
+	all := fkInferenceOptions{scope: FKInferenceScopeAll}
+	got := inferFromPKIndex(orders, idx, all)
+	require.Len(t, got, 2)
+	assert.Equal(t, "customers", got[0].ReferencedTable, "same-schema table is referenced by bare name")
+	assert.Equal(t, "id", got[0].ReferencedColumn)
+	assert.Equal(t, "high", got[0].Confidence)
+	assert.Equal(t, "billing.plans", got[1].ReferencedTable, "cross-schema table is qualified")
+	assert.Equal(t, "medium", got[1].Confidence)
+
+	got = inferFromPKIndex(tickets, idx, all)
+	require.Len(t, got, 1)
+	assert.Equal(t, "customers", got[0].ReferencedTable)
+	assert.Equal(t, "customer_uuid", got[0].ReferencedColumn, "must use the support schema's PK, not sales'")
+}
+
+func TestInferFromPKIndex_ScopeAndConfidence(t *testing.T) {
+	t.Parallel()
+	idx := pkIndex{
+		"sales.customers": {Schema: "sales", Column: "id", DataType: "int4"},
+		"sales.addresses": {Schema: "sales", Column: "id", DataType: "int4"},
+		"billing.plans":   {Schema: "billing", Column: "id", DataType: "int8"},
+	}
+	orders := &port.TableDetail{
+		Schema: "sales",
+		Name:   "orders",
+		Columns: []port.ColumnInfo{
+			{Name: "customer_id", DataType: "integer"}, // high: sales.customers
+			{Name: "address_id", DataType: "integer"},  // medium: "es" plural
+			{Name: "plan_id", DataType: "bigint"},      // medium: billing.plans
+		},
+	}
+	referenced := func(fks []port.InferredForeignKey) []string {
+		var out []string
+		for _, fk := range fks {
+			out = append(out, fk.ReferencedTable)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		opts fkInferenceOptions
+		want []string
+	}{
+		{"schema scope", fkInferenceOptions{scope: FKInferenceScopeSchema}, []string{"customers", "addresses"}},
+		{"all schemas", fkInferenceOptions{scope: FKInferenceScopeAll}, []string{"customers", "addresses", "billing.plans"}},
+		{"schema scope, high only", fkInferenceOptions{scope: FKInferenceScopeSchema, highConfidenceOnly: true}, []string{"customers"}},
+		{"all schemas, high only", fkInferenceOptions{scope: FKInferenceScopeAll, highConfidenceOnly: true}, []string{"customers"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, referenced(inferFromPKIndex(orders, idx, tt.opts)))
+		})
+	}
+}
+
+func TestInferFromPKIndex_OrderAndMax(t *testing.T) {
+	t.Parallel()
+	idx := pkIndex{
+		"public.addresses": {Schema: "public", Column: "id", DataType: "int4"},
+		"public.users":     {Schema: "public", Column: "id", DataType: "int4"},
+		"public.boxes":     {Schema: "public", Column: "id", DataType: "int4"},
+		"public.orders":    {Schema: "public", Column: "id", DataType: "int4"},
+	}
+	detail := &port.TableDetail{
+		Schema: "public",
+		Name:   "shipments",
+		Columns: []port.ColumnInfo{
+			{Name: "address_id", DataType: "integer"}, // medium
+			{Name: "user_id", DataType: "integer"},    // high
+			{Name: "box_id", DataType: "integer"},     // medium
+			{Name: "order_id", DataType: "integer"},   // high
+		},
+	}
+	columns := func(fks []port.InferredForeignKey) []string {
+		var out []string
+		for _, fk := range fks {
+			out = append(out, fk.ColumnName)
+		}
+		return out
+	}
+
+	got := inferFromPKIndex(detail, idx, fkInferenceOptions{scope: FKInferenceScopeSchema})
+	assert.Equal(t, []string{"user_id", "order_id", "address_id", "box_id"}, columns(got),
+		"high confidence first, column order within a level")
+
+	got = inferFromPKIndex(detail, idx, fkInferenceOptions{scope: FKInferenceScopeSchema, max: 3})
+	assert.Equal(t, []string{"user_id", "order_id", "address_id"}, columns(got))
+
+	got = inferFromPKIndex(detail, idx, fkInferenceOptions{scope: FKInferenceScopeSchema, max: 10})
+	assert.Len(t, got, 4, "a cap above the count keeps everything")
+}
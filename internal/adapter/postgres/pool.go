@@ -2,30 +2,38 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"time"
 
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/net/proxy"
 )
 
 // PoolOptions configures the connection pool.
 type PoolOptions struct {
-	MaxConns        int32
-	MinConns        int32
-	MaxConnLifetime time.Duration
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	HealthCheckPeriod time.Duration // how often idle connections are checked; 0 means 30s
+	DialerProxy       string        // optional SOCKS5 proxy URL, e.g. socks5://bastion:1080
+	SetupSQL          string        // optional SET statements run on every new connection
 }
 
+// defaultHealthCheckPeriod is used when PoolOptions.HealthCheckPeriod is zero.
+const defaultHealthCheckPeriod = 30 * time.Second
+
 func NewPool(ctx context.Context, databaseURL string, opts PoolOptions) (*pgxpool.Pool, error) {
-	config, err := pgxpool.ParseConfig(databaseURL)
+	config, err := newPoolConfig(databaseURL, opts)
 	if err != nil {
-		return nil, fmt.Errorf("parsing database URL: %w", err)
+		return nil, err
 	}
 
-	config.MaxConns = opts.MaxConns
-	config.MinConns = opts.MinConns
-	config.MaxConnLifetime = opts.MaxConnLifetime
-	config.HealthCheckPeriod = 30 * time.Second
-
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("creating connection pool: %w", err)
@@ -41,3 +49,84 @@ func NewPool(ctx context.Context, databaseURL string, opts PoolOptions) (*pgxpoo
 
 	return pool, nil
 }
+
+// newPoolConfig parses the database URL and applies the pool options.
+func newPoolConfig(databaseURL string, opts PoolOptions) (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing database URL: %w", err)
+	}
+
+	config.MaxConns = opts.MaxConns
+	config.MinConns = opts.MinConns
+	config.MaxConnLifetime = opts.MaxConnLifetime
+	config.HealthCheckPeriod = defaultHealthCheckPeriod
+	if opts.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = opts.HealthCheckPeriod
+	}
+
+	if opts.DialerProxy != "" {
+		dialFn, err := proxyDialFunc(opts.DialerProxy)
+		if err != nil {
+			return nil, err
+		}
+		config.ConnConfig.DialFunc = dialFn
+	}
+
+	if opts.SetupSQL != "" {
+		stmts, err := domain.ParseSetupSQL(opts.SetupSQL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid connection setup SQL: %w", err)
+		}
+		config.AfterConnect = afterConnect(stmts)
+	}
+
+	return config, nil
+}
+
+// afterConnect returns a pgxpool.Config.AfterConnect hook that runs the
+// setup statements on each new connection, before the pool hands it out. A
+// failing statement discards the connection.
+func afterConnect(stmts []string) func(context.Context, *pgx.Conn) error {
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		return runSetup(ctx, conn.Exec, stmts)
+	}
+}
+
+// execFunc is the signature of pgx.Conn.Exec.
+type execFunc func(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+
+func runSetup(ctx context.Context, exec execFunc, stmts []string) error {
+	for _, stmt := range stmts {
+		if _, err := exec(ctx, stmt); err != nil {
+			return fmt.Errorf("running connection setup %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// proxyDialFunc returns a pgconn.DialFunc that tunnels TCP connections through
+// a SOCKS5 proxy. TLS (sslmode) is negotiated by pgx on top of the tunneled
+// connection, so the proxy only ever sees encrypted traffic when TLS is on.
+func proxyDialFunc(rawURL string) (pgconn.DialFunc, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err // the wrapper quotes the URL, credentials included
+		}
+		return nil, fmt.Errorf("parsing dialer proxy URL: %w", err)
+	}
+
+	dialer, err := proxy.FromURL(u, proxy.Direct)
+	if err != nil {
+		return nil, fmt.Errorf("creating proxy dialer: %w", err)
+	}
+
+	if cd, ok := dialer.(proxy.ContextDialer); ok {
+		return cd.DialContext, nil
+	}
+	return func(_ context.Context, network, addr string) (net.Conn, error) {
+		return dialer.Dial(network, addr)
+	}, nil
+}
@@ -2,6 +2,7 @@ package postgres_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -49,6 +50,16 @@ const testSchemaProfiler = `
 		body       TEXT
 	);
 
+	-- Array and composite columns for sample row rendering.
+	CREATE TYPE dimensions AS (width NUMERIC, height NUMERIC, unit TEXT);
+	CREATE TYPE mood AS ENUM ('happy', 'sad');
+	CREATE TABLE listings (
+		id      SERIAL PRIMARY KEY,
+		tags    TEXT[] NOT NULL,
+		size    dimensions,
+		moods   mood[]
+	);
+
 	-- Seed data for stats.
 	INSERT INTO categories (name) VALUES ('Electronics'), ('Books'), ('Clothing');
 
@@ -181,6 +192,53 @@ func TestDescribeTable_CheckConstraints(t *testing.T) {
 	assert.True(t, found, "should find at least one named check constraint with expression")
 }
 
+func TestDescribeTable_CheckAllowedValues(t *testing.T) {
+	pool := setupProfilerDB(t)
+	explorer := postgres.NewExplorer(pool, nil)
+	ctx := context.Background()
+
+	// Basic detail skips pg_stats, so the values come from the constraint alone.
+	ctx = port.WithDetailLevel(ctx, port.DetailBasic)
+	detail, err := explorer.DescribeTable(ctx, "", "products")
+	require.NoError(t, err)
+
+	for _, col := range detail.Columns {
+		if col.Name == "status" {
+			assert.Equal(t, []string{"active", "inactive", "discontinued"}, col.AllowedValues)
+			assert.Nil(t, col.Stats)
+		} else {
+			assert.Nil(t, col.AllowedValues, col.Name)
+		}
+	}
+}
+
+func TestDescribeTable_ColumnConstraints(t *testing.T) {
+	pool := setupProfilerDB(t)
+	explorer := postgres.NewExplorer(pool, nil)
+	ctx := context.Background()
+
+	detail, err := explorer.DescribeTable(ctx, "", "products")
+	require.NoError(t, err)
+
+	constraints := make(map[string][]string)
+	for _, col := range detail.Columns {
+		constraints[col.Name] = col.Constraints
+	}
+	assert.Equal(t, []string{"primary_key", "not_null"}, constraints["id"])
+	assert.Equal(t, []string{"foreign_key", "not_null"}, constraints["category_id"])
+	assert.Equal(t, []string{"check", "not_null"}, constraints["status"])
+	assert.Equal(t, []string{"not_null"}, constraints["price"])
+	assert.Empty(t, constraints["deleted_at"])
+
+	detail, err = explorer.DescribeTable(ctx, "", "categories")
+	require.NoError(t, err)
+	for _, col := range detail.Columns {
+		if col.Name == "name" {
+			assert.Equal(t, []string{"unique", "not_null"}, col.Constraints)
+		}
+	}
+}
+
 func TestDescribeTable_StatsAge(t *testing.T) {
 	pool := setupProfilerDB(t)
 	explorer := postgres.NewExplorer(pool, nil)
@@ -194,6 +252,27 @@ func TestDescribeTable_StatsAge(t *testing.T) {
 	assert.True(t, detail.StatsAge.Before(time.Now()), "stats_age should be in the past")
 }
 
+func TestDescribeTable_InferredForeignKeys(t *testing.T) {
+	pool := setupProfilerDB(t)
+	explorer := postgres.NewExplorer(pool, nil)
+	ctx := context.Background()
+
+	detail, err := explorer.DescribeTable(ctx, "", "reviews")
+	require.NoError(t, err)
+
+	require.Len(t, detail.InferredFKs, 1, "only product_id has a matching table")
+	fk := detail.InferredFKs[0]
+	assert.Equal(t, "product_id", fk.ColumnName)
+	assert.Equal(t, "products", fk.ReferencedTable)
+	assert.Equal(t, "id", fk.ReferencedColumn)
+	assert.Equal(t, "high", fk.Confidence)
+
+	// Declared FKs are not repeated as inferred ones.
+	detail, err = explorer.DescribeTable(ctx, "", "products")
+	require.NoError(t, err)
+	assert.Empty(t, detail.InferredFKs)
+}
+
 func TestListTables_Enhanced(t *testing.T) {
 	pool := setupProfilerDB(t)
 	explorer := postgres.NewExplorer(pool, nil)
@@ -237,6 +316,146 @@ func TestDescribeTable_SampleRows(t *testing.T) {
 	}
 }
 
+func TestDescribeTable_SampleRows_ArrayAndComposite(t *testing.T) {
+	pool := setupProfilerDB(t)
+	explorer := postgres.NewExplorer(pool, nil)
+	ctx := context.Background()
+
+	detail, err := explorer.DescribeTable(ctx, "", "listings")
+	require.NoError(t, err)
+	require.NotEmpty(t, detail.SampleRows)
+
+	row := detail.SampleRows[0]
+	assert.Equal(t, []any{"new", "sale"}, row["tags"])
+	assert.Equal(t, []any{"happy", "sad"}, row["moods"])
+	assert.Equal(t, map[string]any{"width": "10", "height": "20", "unit": "cm"}, row["size"])
+}
+
+func TestDescribeTable_ConcurrentFetchersConsistent(t *testing.T) {
+	pool := setupProfilerDB(t)
+	explorer := postgres.NewExplorer(pool, nil)
+	ctx := context.Background()
+
+	// Fetchers run concurrently inside DescribeTable; run several calls at once
+	// too and check every result carries the same structure, including the
+	// steps that depend on earlier fetchers (PK marks, stats, inference).
+	const calls = 4
+	details := make([]*port.TableDetail, calls)
+	errs := make([]error, calls)
+	var wg sync.WaitGroup
+	for i := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			details[i], errs[i] = explorer.DescribeTable(ctx, "", "products")
+		}()
+	}
+	wg.Wait()
+
+	for i := range calls {
+		require.NoError(t, errs[i])
+		d := details[i]
+		require.Len(t, d.Columns, 8)
+		assert.True(t, d.Columns[0].IsPrimaryKey, "PK marks are applied after columns are fetched")
+		assert.Greater(t, d.RowEstimate, int64(0))
+		assert.NotEmpty(t, d.IndexUsage)
+		assert.NotNil(t, d.StatsAge)
+
+		assert.Equal(t, details[0].Columns, d.Columns)
+		assert.Equal(t, details[0].ForeignKeys, d.ForeignKeys)
+		assert.Equal(t, details[0].Indexes, d.Indexes)
+		assert.Equal(t, details[0].CheckConstraints, d.CheckConstraints)
+	}
+
+	var statusCol *port.ColumnInfo
+	for i, col := range details[0].Columns {
+		if col.Name == "status" {
+			statusCol = &details[0].Columns[i]
+		}
+	}
+	require.NotNil(t, statusCol)
+	require.NotNil(t, statusCol.Stats, "column stats run after the row estimate is known")
+	assert.Equal(t, domain.CardinalityEnumLike, statusCol.Stats.Cardinality)
+}
+
+func TestDescribeTable_SamplesAndIndexUsageDisabled(t *testing.T) {
+	pool := setupProfilerDB(t)
+	explorer := postgres.NewExplorer(pool, nil,
+		postgres.WithSampleRows(false),
+		postgres.WithIndexUsage(false),
+	)
+
+	detail, err := explorer.DescribeTable(context.Background(), "", "products")
+	require.NoError(t, err)
+
+	assert.Empty(t, detail.SampleRows)
+	assert.Empty(t, detail.IndexUsage)
+
+	// The rest of the full detail level is unaffected.
+	assert.Len(t, detail.Columns, 8)
+	assert.NotNil(t, detail.StatsAge)
+}
+
+func TestDescribeTable_IndexBloat(t *testing.T) {
+	pool := setupProfilerDB(t)
+	ctx := context.Background()
+
+	// Deleting 90% of the rows leaves the index pages in place: VACUUM
+	// marks them reusable but never shrinks a btree.
+	_, err := pool.Exec(ctx, `
+		CREATE TABLE bloated (id int PRIMARY KEY) WITH (autovacuum_enabled = false);
+		INSERT INTO bloated SELECT generate_series(1, 500000);
+		DELETE FROM bloated WHERE id % 10 <> 0`)
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, "VACUUM ANALYZE bloated")
+	require.NoError(t, err)
+
+	explorer := postgres.NewExplorer(pool, nil)
+	detail, err := explorer.DescribeTable(ctx, "", "bloated")
+	require.NoError(t, err)
+
+	require.Len(t, detail.IndexUsage, 1)
+	u := detail.IndexUsage[0]
+	assert.Equal(t, "bloated_pkey", u.Name)
+	assert.Greater(t, u.BloatRatio, 0.8)
+	assert.Greater(t, u.BloatBytes, int64(8<<20))
+	assert.Less(t, u.BloatBytes, u.SizeBytes)
+	require.Len(t, detail.Recommendations, 1)
+	assert.Contains(t, detail.Recommendations[0], `REINDEX INDEX CONCURRENTLY "public"."bloated_pkey"`)
+
+	// Freshly built indexes of the shared schema carry little or no bloat.
+	detail, err = explorer.DescribeTable(ctx, "", "products")
+	require.NoError(t, err)
+	require.NotEmpty(t, detail.IndexUsage)
+	for _, u := range detail.IndexUsage {
+		assert.Less(t, u.BloatRatio, 0.5, u.Name)
+	}
+}
+
+func TestDescribeTable_DetailLevelBasic(t *testing.T) {
+	pool := setupProfilerDB(t)
+	explorer := postgres.NewExplorer(pool, nil)
+	ctx := port.WithDetailLevel(context.Background(), port.DetailBasic)
+
+	detail, err := explorer.DescribeTable(ctx, "", "products")
+	require.NoError(t, err)
+
+	// Structure is still present.
+	assert.Len(t, detail.Columns, 8)
+	assert.NotEmpty(t, detail.ForeignKeys)
+	assert.NotEmpty(t, detail.Indexes)
+	assert.NotEmpty(t, detail.CheckConstraints)
+
+	// Heavy enrichment is skipped.
+	assert.Empty(t, detail.SampleRows)
+	assert.Empty(t, detail.IndexUsage)
+	assert.Nil(t, detail.StatsAge)
+	assert.Empty(t, detail.StatsAgeWarning)
+	for _, col := range detail.Columns {
+		assert.Nil(t, col.Stats, "column %s should have no stats in basic mode", col.Name)
+	}
+}
+
 func TestDescribeTable_IndexUsage(t *testing.T) {
 	pool := setupProfilerDB(t)
 	explorer := postgres.NewExplorer(pool, nil)
@@ -270,6 +489,39 @@ func TestDescribeTable_StatsAgeWarning(t *testing.T) {
 	assert.Empty(t, detail.StatsAgeWarning, "should not warn about fresh stats")
 }
 
+func TestDescribeTable_RowEstimateConfidence(t *testing.T) {
+	pool := setupProfilerDB(t)
+	explorer := postgres.NewExplorer(pool, nil)
+	ctx := context.Background()
+
+	// Analyzed but never vacuumed.
+	detail, err := explorer.DescribeTable(ctx, "", "products")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RowEstimateMedium, detail.RowEstimateConfidence)
+
+	_, err = pool.Exec(ctx, "VACUUM ANALYZE products")
+	require.NoError(t, err)
+	detail, err = explorer.DescribeTable(ctx, "", "products")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RowEstimateHigh, detail.RowEstimateConfidence)
+
+	// A bulk load after the last ANALYZE makes reltuples unreliable.
+	conn, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, `
+		INSERT INTO products (category_id, name, status)
+		SELECT 1, 'Bulk ' || i, 'active' FROM generate_series(1, 1000) AS i`)
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, "SELECT pg_stat_force_next_flush()")
+	require.NoError(t, err)
+	conn.Release()
+
+	require.Eventually(t, func() bool {
+		detail, err := explorer.DescribeTable(ctx, "", "products")
+		return err == nil && detail.RowEstimateConfidence == domain.RowEstimateLow
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
 func TestDiscover(t *testing.T) {
 	pool := setupProfilerDB(t)
 	explorer := postgres.NewExplorer(pool, nil)
@@ -3,6 +3,7 @@ package postgres
 import (
 	"testing"
 
+	"github.com/guillermoBallester/isthmus/internal/core/port"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -108,44 +109,48 @@ func TestIsTypeCompatible(t *testing.T) {
 	}
 }
 
-func TestQuoteIdent(t *testing.T) {
-	t.Parallel()
-	assert.Equal(t, `"users"`, quoteIdent("users"))
-	assert.Equal(t, `"my table"`, quoteIdent("my table"))
-	assert.Equal(t, `"test""quote"`, quoteIdent(`test"quote`))
-	assert.Equal(t, `""`, quoteIdent(""))
-	assert.Equal(t, `"café"`, quoteIdent("café"))
-	assert.Equal(t, `""""""""`, quoteIdent(`"""`))
-}
-
 func TestSchemaFilter_Empty(t *testing.T) {
 	t.Parallel()
-	clause, args := schemaFilter(nil, "n.nspname", 1)
+	clause, args := schemaFilter(nil, nil, "n.nspname", 1)
 	assert.Equal(t, "n.nspname NOT IN ('pg_catalog', 'information_schema')", clause)
 	assert.Nil(t, args)
 }
 
 func TestSchemaFilter_Single(t *testing.T) {
 	t.Parallel()
-	clause, args := schemaFilter([]string{"public"}, "n.nspname", 1)
+	clause, args := schemaFilter([]string{"public"}, nil, "n.nspname", 1)
 	assert.Equal(t, "n.nspname IN ($1)", clause)
 	assert.Equal(t, []any{"public"}, args)
 }
 
 func TestSchemaFilter_Multiple(t *testing.T) {
 	t.Parallel()
-	clause, args := schemaFilter([]string{"public", "app", "sales"}, "s.schema_name", 1)
+	clause, args := schemaFilter([]string{"public", "app", "sales"}, nil, "s.schema_name", 1)
 	assert.Equal(t, "s.schema_name IN ($1, $2, $3)", clause)
 	assert.Equal(t, []any{"public", "app", "sales"}, args)
 }
 
 func TestSchemaFilter_ParamOffset(t *testing.T) {
 	t.Parallel()
-	clause, args := schemaFilter([]string{"public", "app"}, "t.table_schema", 3)
+	clause, args := schemaFilter([]string{"public", "app"}, nil, "t.table_schema", 3)
 	assert.Equal(t, "t.table_schema IN ($3, $4)", clause)
 	assert.Equal(t, []any{"public", "app"}, args)
 }
 
+func TestSchemaFilter_HiddenOnly(t *testing.T) {
+	t.Parallel()
+	clause, args := schemaFilter(nil, []string{"audit", "internal"}, "n.nspname", 1)
+	assert.Equal(t, "n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT IN ($1, $2)", clause)
+	assert.Equal(t, []any{"audit", "internal"}, args)
+}
+
+func TestSchemaFilter_AllowedAndHidden(t *testing.T) {
+	t.Parallel()
+	clause, args := schemaFilter([]string{"public", "audit"}, []string{"audit"}, "t.table_schema", 2)
+	assert.Equal(t, "t.table_schema IN ($2, $3) AND t.table_schema NOT IN ($4)", clause)
+	assert.Equal(t, []any{"public", "audit", "audit"}, args)
+}
+
 func TestParsePgArray_EscapedBrace(t *testing.T) {
 	t.Parallel()
 	got := parsePgArray(`{hello\}`)
@@ -209,3 +214,134 @@ func TestIsTypeCompatible_UnknownTypes(t *testing.T) {
 	assert.True(t, isTypeCompatible("jsonb", "jsonb"))
 	assert.False(t, isTypeCompatible("jsonb", "json"))
 }
+
+func TestFKIndexRecommendations(t *testing.T) {
+	t.Parallel()
+	fks := []port.ForeignKey{
+		{ConstraintName: "orders_customer_id_fkey", ColumnName: "customer_id"},
+		{ConstraintName: "orders_product_id_fkey", ColumnName: "product_id"},
+	}
+	idxs := []port.IndexInfo{
+		{Name: "orders_pkey", Columns: []string{"id"}},
+		{Name: "idx_orders_customer", Columns: []string{"customer_id"}},
+	}
+
+	recs := fkIndexRecommendations(fks, idxs)
+	require.Len(t, recs, 1)
+	assert.Contains(t, recs[0], "orders_product_id_fkey")
+	assert.Contains(t, recs[0], "product_id")
+
+	assert.Nil(t, fkIndexRecommendations(nil, idxs))
+}
+
+func TestIndexBloatRecommendations(t *testing.T) {
+	t.Parallel()
+	usage := []port.IndexUsage{
+		{Name: "orders_pkey", SizeBytes: 100 << 20, BloatBytes: 10 << 20, BloatRatio: 0.1},
+		{Name: "orders_status_idx", SizeBytes: 64 << 20, BloatBytes: 32 << 20, BloatRatio: 0.5},
+		{Name: "orders_note_idx", SizeBytes: 4 << 20, BloatBytes: 3 << 20, BloatRatio: 0.75},
+	}
+
+	recs := indexBloatRecommendations("sales", usage)
+	require.Len(t, recs, 1, "only large, mostly bloated indexes are flagged")
+	assert.Contains(t, recs[0], `"orders_status_idx" is about 50% bloat`)
+	assert.Contains(t, recs[0], `REINDEX INDEX CONCURRENTLY "sales"."orders_status_idx"`)
+
+	assert.Nil(t, indexBloatRecommendations("sales", nil))
+}
+
+func TestSummarizeColumnConstraints(t *testing.T) {
+	t.Parallel()
+	detail := &port.TableDetail{
+		Columns: []port.ColumnInfo{
+			{Name: "id", IsPrimaryKey: true},
+			{Name: "category_id"},
+			{Name: "sku"},
+			{Name: "status"},
+			{Name: "tenant_id", IsNullable: true},
+			{Name: "notes", IsNullable: true},
+		},
+		ForeignKeys: []port.ForeignKey{{ColumnName: "category_id"}},
+		Indexes: []port.IndexInfo{
+			{Name: "products_pkey", IsUnique: true, Columns: []string{"id"}},
+			{Name: "products_sku_key", IsUnique: true, Columns: []string{"sku"}},
+			{Name: "products_tenant_sku_key", IsUnique: true, Columns: []string{"tenant_id", "sku"}},
+		},
+		CheckConstraints: []port.CheckConstraint{{Name: "products_status_check", Columns: []string{"status"}}},
+	}
+
+	summarizeColumnConstraints(detail)
+
+	got := make(map[string][]string)
+	for _, c := range detail.Columns {
+		got[c.Name] = c.Constraints
+	}
+	assert.Equal(t, []string{"primary_key", "not_null"}, got["id"])
+	assert.Equal(t, []string{"foreign_key", "not_null"}, got["category_id"])
+	assert.Equal(t, []string{"unique", "not_null"}, got["sku"])
+	assert.Equal(t, []string{"check", "not_null"}, got["status"])
+	assert.Nil(t, got["tenant_id"], "composite unique index does not make a column unique")
+	assert.Nil(t, got["notes"])
+}
+
+func TestParseCheckInList(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		column string
+		values []string
+	}{
+		{"text array", "CHECK ((status = ANY (ARRAY['active'::text, 'inactive'::text, 'discontinued'::text])))",
+			"status", []string{"active", "inactive", "discontinued"}},
+		{"varchar column", "CHECK (((kind)::text = ANY ((ARRAY['a'::character varying, 'b'::character varying])::text[])))",
+			"kind", []string{"a", "b"}},
+		{"integer array", "CHECK ((priority = ANY (ARRAY[1, 2, 3])))", "priority", []string{"1", "2", "3"}},
+		{"IN list", "CHECK (status IN ('on', 'off'))", "status", []string{"on", "off"}},
+		{"quoted column and escaped quote", `CHECK (("Mood" = ANY (ARRAY['it''s fine'::text, 'meh'::text])))`,
+			"Mood", []string{"it's fine", "meh"}},
+		{"comma inside value", "CHECK ((label = ANY (ARRAY['a, b'::text, 'c'::text])))", "label", []string{"a, b", "c"}},
+		{"not valid", "CHECK ((status = ANY (ARRAY['x'::text]))) NOT VALID", "status", []string{"x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			column, values, ok := parseCheckInList(tt.expr)
+			require.True(t, ok)
+			assert.Equal(t, tt.column, column)
+			assert.Equal(t, tt.values, values)
+		})
+	}
+}
+
+func TestParseCheckInList_Unsupported(t *testing.T) {
+	for _, expr := range []string{
+		"CHECK ((price > (0)::numeric))",
+		"CHECK ((status <> ALL (ARRAY['gone'::text])))",
+		"CHECK (((status = ANY (ARRAY['a'::text])) AND (qty > 0)))",
+		"CHECK ((status = ANY (ARRAY[lower(name)])))",
+		"CHECK ((a = b))",
+		"",
+	} {
+		_, _, ok := parseCheckInList(expr)
+		assert.False(t, ok, expr)
+	}
+}
+
+func TestAttachCheckValues(t *testing.T) {
+	detail := &port.TableDetail{
+		Columns: []port.ColumnInfo{{Name: "status"}, {Name: "kind"}, {Name: "price"}},
+		CheckConstraints: []port.CheckConstraint{
+			{Name: "products_status_check", Columns: []string{"status"},
+				Expression: "CHECK ((status = ANY (ARRAY['active'::text, 'inactive'::text])))"},
+			{Name: "products_price_check", Columns: []string{"price"}, Expression: "CHECK ((price > (0)::numeric))"},
+			// Spans two columns, so it does not restrict kind on its own.
+			{Name: "products_kind_check", Columns: []string{"kind", "price"},
+				Expression: "CHECK ((kind = ANY (ARRAY['a'::text])))"},
+		},
+	}
+
+	attachCheckValues(detail)
+
+	assert.Equal(t, []string{"active", "inactive"}, detail.Columns[0].AllowedValues)
+	assert.Nil(t, detail.Columns[1].AllowedValues)
+	assert.Nil(t, detail.Columns[2].AllowedValues)
+}
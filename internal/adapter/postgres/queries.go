@@ -8,7 +8,8 @@ const queryListSchemas = `
 	ORDER BY s.schema_name`
 
 // queryListTables has one %s placeholder for the schema filter clause.
-// Returns enhanced info: total_bytes, column_count, has_indexes.
+// Returns enhanced info: total_bytes with its index and TOAST parts,
+// column_count, has_indexes.
 const queryListTables = `
 	SELECT
 		t.table_schema,
@@ -31,6 +32,18 @@ const queryListTables = `
 			), 0))
 		ELSE '0 bytes'
 		END AS size_human,
+		CASE WHEN t.table_type = 'BASE TABLE' THEN
+			COALESCE(pg_indexes_size(
+				(quote_ident(t.table_schema) || '.' || quote_ident(t.table_name))::regclass
+			), 0)
+		ELSE 0
+		END AS index_bytes,
+		COALESCE((
+			SELECT pg_total_relation_size(c.reltoastrelid)
+			FROM pg_class c
+			WHERE c.oid = (quote_ident(t.table_schema) || '.' || quote_ident(t.table_name))::regclass
+				AND c.reltoastrelid <> 0
+		), 0) AS toast_bytes,
 		(SELECT count(*)::int FROM information_schema.columns c
 		 WHERE c.table_schema = t.table_schema AND c.table_name = t.table_name
 		) AS column_count,
@@ -49,7 +62,8 @@ const queryListTables = `
 	ORDER BY t.table_schema, t.table_name`
 
 // queryTableMeta has one %s placeholder for the schema filter clause.
-// $1 is always table_name; schema filter params start at $2.
+// $1 is always table_name; schema filter params start at $2. It returns one
+// row per schema holding a table of that name.
 const queryTableMeta = `
 	SELECT t.table_schema,
 		   COALESCE(pg_catalog.obj_description(
@@ -58,7 +72,7 @@ const queryTableMeta = `
 	FROM information_schema.tables t
 	WHERE t.table_name = $1
 		AND %s
-	LIMIT 1`
+	ORDER BY t.table_schema`
 
 // queryTableComment fetches the comment for a table with a known schema.
 // $1 is schema_name, $2 is table_name.
@@ -67,6 +81,9 @@ const queryTableComment = `
 		(quote_ident($1) || '.' || quote_ident($2))::regclass, 'pg_class'
 	), '')`
 
+// collation_name is only set for a collation other than the database
+// default, which is the only one worth reporting; collations outside
+// pg_catalog are schema-qualified.
 const queryColumns = `
 	SELECT
 		c.column_name,
@@ -76,11 +93,56 @@ const queryColumns = `
 		COALESCE(pg_catalog.col_description(
 			(quote_ident(c.table_schema) || '.' || quote_ident(c.table_name))::regclass,
 			c.ordinal_position
-		), '')
+		), ''),
+		CASE WHEN c.collation_schema IS NULL OR c.collation_schema = 'pg_catalog'
+			THEN COALESCE(c.collation_name, '')
+			ELSE c.collation_schema || '.' || c.collation_name
+		END
 	FROM information_schema.columns c
 	WHERE c.table_schema = $1 AND c.table_name = $2
 	ORDER BY c.ordinal_position`
 
+// queryColumnStorage returns the storage mode of every column that can be
+// TOASTed (attstorage other than plain) and, for the modes that compress,
+// its compression method: the one set with ALTER COLUMN ... SET COMPRESSION,
+// or else the server's default_toast_compression. attcompression is new in PostgreSQL 14; older
+// servers use queryColumnStorageNoCompression.
+const queryColumnStorage = `
+	SELECT
+		a.attname,
+		CASE a.attstorage
+			WHEN 'x' THEN 'extended'
+			WHEN 'e' THEN 'external'
+			WHEN 'm' THEN 'main'
+			ELSE 'plain'
+		END,
+		CASE WHEN a.attstorage IN ('x', 'm') THEN COALESCE(
+			CASE a.attcompression WHEN 'p' THEN 'pglz' WHEN 'l' THEN 'lz4' END,
+			current_setting('default_toast_compression', true),
+			''
+		) ELSE '' END
+	FROM pg_attribute a
+	WHERE a.attrelid = (quote_ident($1) || '.' || quote_ident($2))::regclass
+		AND a.attnum > 0
+		AND NOT a.attisdropped
+		AND a.attstorage <> 'p'`
+
+const queryColumnStorageNoCompression = `
+	SELECT
+		a.attname,
+		CASE a.attstorage
+			WHEN 'x' THEN 'extended'
+			WHEN 'e' THEN 'external'
+			WHEN 'm' THEN 'main'
+			ELSE 'plain'
+		END,
+		''
+	FROM pg_attribute a
+	WHERE a.attrelid = (quote_ident($1) || '.' || quote_ident($2))::regclass
+		AND a.attnum > 0
+		AND NOT a.attisdropped
+		AND a.attstorage <> 'p'`
+
 const queryPrimaryKeys = `
 	SELECT a.attname
 	FROM pg_index i
@@ -88,26 +150,80 @@ const queryPrimaryKeys = `
 	WHERE i.indrelid = (quote_ident($1) || '.' || quote_ident($2))::regclass
 		AND i.indisprimary`
 
+// queryForeignKeys reads the referential actions from
+// referential_constraints, which spells out pg_constraint's
+// confdeltype/confupdtype codes, e.g. CASCADE or SET NULL.
 const queryForeignKeys = `
 	SELECT
 		tc.constraint_name,
 		kcu.column_name,
 		ccu.table_name AS referenced_table,
-		ccu.column_name AS referenced_column
+		ccu.column_name AS referenced_column,
+		rc.delete_rule,
+		rc.update_rule,
+		COALESCE((
+			SELECT pg_catalog.obj_description(con.oid, 'pg_constraint')
+			FROM pg_constraint con
+			WHERE con.conname = tc.constraint_name
+				AND con.conrelid = (quote_ident(tc.table_schema) || '.' || quote_ident(tc.table_name))::regclass
+		), '') AS comment
 	FROM information_schema.table_constraints tc
 	JOIN information_schema.key_column_usage kcu
 		ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema
 	JOIN information_schema.constraint_column_usage ccu
 		ON tc.constraint_name = ccu.constraint_name AND tc.table_schema = ccu.table_schema
+	JOIN information_schema.referential_constraints rc
+		ON tc.constraint_name = rc.constraint_name AND tc.table_schema = rc.constraint_schema
 	WHERE tc.constraint_type = 'FOREIGN KEY'
 		AND tc.table_schema = $1
 		AND tc.table_name = $2`
 
+// queryAllForeignKeys lists the foreign keys of every table in one pass over
+// pg_constraint, one row per key column in key order, with the same columns
+// as queryForeignKeys after the table's schema and name. It has one %s
+// placeholder for the schema filter clause.
+const queryAllForeignKeys = `
+	SELECT
+		n.nspname,
+		r.relname,
+		c.conname,
+		a.attname,
+		fr.relname AS referenced_table,
+		fa.attname AS referenced_column,
+		CASE c.confdeltype
+			WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE'
+			WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT'
+			ELSE 'NO ACTION'
+		END AS delete_rule,
+		CASE c.confupdtype
+			WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE'
+			WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT'
+			ELSE 'NO ACTION'
+		END AS update_rule,
+		COALESCE(pg_catalog.obj_description(c.oid, 'pg_constraint'), '') AS comment
+	FROM pg_constraint c
+	JOIN pg_class r ON r.oid = c.conrelid
+	JOIN pg_namespace n ON n.oid = r.relnamespace
+	JOIN pg_class fr ON fr.oid = c.confrelid
+	CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, fattnum, ord)
+	JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+	JOIN pg_attribute fa ON fa.attrelid = c.confrelid AND fa.attnum = k.fattnum
+	WHERE c.contype = 'f' AND %s
+	ORDER BY n.nspname, r.relname, c.conname, k.ord`
+
+// queryIndexes returns key columns in index order; expression keys are omitted.
 const queryIndexes = `
 	SELECT
 		indexname,
 		indexdef,
-		i.indisunique
+		i.indisunique,
+		ARRAY(
+			SELECT a.attname
+			FROM unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord)
+			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+			ORDER BY k.ord
+		) AS columns,
+		COALESCE(pg_catalog.obj_description(c.oid, 'pg_class'), '') AS comment
 	FROM pg_indexes pgi
 	JOIN pg_class c ON c.relname = pgi.indexname
 	JOIN pg_index i ON i.indexrelid = c.oid
@@ -134,7 +250,13 @@ const queryColumnStats = `
 const queryCheckConstraints = `
 	SELECT
 		c.conname,
-		pg_get_constraintdef(c.oid)
+		pg_get_constraintdef(c.oid),
+		ARRAY(
+			SELECT a.attname
+			FROM unnest(c.conkey) AS k(attnum)
+			JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+		) AS columns,
+		COALESCE(pg_catalog.obj_description(c.oid, 'pg_constraint'), '') AS comment
 	FROM pg_constraint c
 	JOIN pg_class r ON r.oid = c.conrelid
 	JOIN pg_namespace n ON n.oid = r.relnamespace
@@ -142,20 +264,69 @@ const queryCheckConstraints = `
 	ORDER BY c.conname`
 
 // queryTableSize fetches row estimate, total relation size, and human-readable size.
+// A partitioned table holds no data itself, so for one the row estimate and
+// size are summed over its whole partition tree (pg_partition_tree, PG 12+).
 // $1 = schema, $2 = table_name.
 const queryTableSize = `
 	SELECT
-		COALESCE(c.reltuples::bigint, 0),
-		COALESCE(pg_total_relation_size(c.oid), 0),
-		pg_size_pretty(COALESCE(pg_total_relation_size(c.oid), 0))
+		CASE WHEN c.relkind = 'p' THEN (
+			SELECT COALESCE(sum(GREATEST(pc.reltuples, 0)), 0)::bigint
+			FROM pg_partition_tree(c.oid) pt
+			JOIN pg_class pc ON pc.oid = pt.relid
+			WHERE pt.isleaf
+		) ELSE COALESCE(c.reltuples::bigint, 0)
+		END,
+		s.total_bytes,
+		pg_size_pretty(s.total_bytes)
 	FROM pg_class c
 	JOIN pg_namespace n ON n.oid = c.relnamespace
+	CROSS JOIN LATERAL (
+		SELECT CASE WHEN c.relkind = 'p' THEN (
+			SELECT COALESCE(sum(pg_total_relation_size(pt.relid)), 0)::bigint
+			FROM pg_partition_tree(c.oid) pt
+		) ELSE COALESCE(pg_total_relation_size(c.oid), 0)
+		END AS total_bytes
+	) s
 	WHERE n.nspname = $1 AND c.relname = $2`
 
-// queryStatsAge fetches the timestamp of the last ANALYZE for a table.
+// queryInheritance lists a table's direct parents and children from
+// pg_inherits, which covers both INHERITS and declarative partitioning.
+// Both %s placeholders take the same schema filter clause, applied to the
+// related table's schema. $1 = schema, $2 = table_name; schema filter params
+// start at $3.
+const queryInheritance = `
+	SELECT 'parent' AS relation, rn.nspname, r.relname, i.inhseqno
+	FROM pg_inherits i
+	JOIN pg_class t ON t.oid = i.inhrelid
+	JOIN pg_namespace tn ON tn.oid = t.relnamespace
+	JOIN pg_class r ON r.oid = i.inhparent
+	JOIN pg_namespace rn ON rn.oid = r.relnamespace
+	WHERE tn.nspname = $1 AND t.relname = $2 AND %s
+	UNION ALL
+	SELECT 'child', rn.nspname, r.relname, 0
+	FROM pg_inherits i
+	JOIN pg_class t ON t.oid = i.inhparent
+	JOIN pg_namespace tn ON tn.oid = t.relnamespace
+	JOIN pg_class r ON r.oid = i.inhrelid
+	JOIN pg_namespace rn ON rn.oid = r.relnamespace
+	WHERE tn.nspname = $1 AND t.relname = $2 AND %s
+	ORDER BY 1, 4, 2, 3`
+
+// queryFormatTypes renders type names for parallel arrays of type OIDs and
+// modifiers, in input order. $1 = oids, $2 = type modifiers (-1 for none).
+const queryFormatTypes = `
+	SELECT COALESCE(format_type(f.oid, NULLIF(f.mod, -1)), '')
+	FROM unnest($1::oid[], $2::int4[]) WITH ORDINALITY AS f(oid, mod, n)
+	ORDER BY f.n`
+
+// queryStatsActivity fetches the last ANALYZE and VACUUM timestamps for a
+// table, manual or automatic, plus the rows modified since the last ANALYZE.
 // $1 = schema, $2 = table_name.
-const queryStatsAge = `
-	SELECT COALESCE(last_autoanalyze, last_analyze)
+const queryStatsActivity = `
+	SELECT
+		GREATEST(last_autoanalyze, last_analyze),
+		GREATEST(last_autovacuum, last_vacuum),
+		n_mod_since_analyze
 	FROM pg_stat_user_tables
 	WHERE schemaname = $1 AND relname = $2`
 
@@ -170,3 +341,154 @@ const queryIndexUsage = `
 	FROM pg_stat_user_indexes s
 	WHERE s.schemaname = $1 AND s.relname = $2
 	ORDER BY s.indexrelname`
+
+// queryIndexBloat estimates the bloat of each btree index on a table, after
+// the widely used pgsql-bloat-estimation query: the expected page count
+// follows from reltuples, the average key width in pg_stats and the index
+// fillfactor, and anything above it is bloat. A page holds its size minus
+// the page header (24) and btree special space (16), divided by the line
+// pointer (4) plus the aligned tuple header and key data of each entry.
+// Expression columns take their
+// stats from the index itself. Indexes with a key column missing from
+// pg_stats report 0. Assumes 8-byte alignment, as on 64-bit platforms.
+// $1 = schema, $2 = table_name.
+const queryIndexBloat = `
+	WITH idx AS (
+		SELECT ci.oid AS idxoid, ci.relname AS index_name, i.indrelid, ct.relname AS table_name,
+			GREATEST(ci.reltuples, 0) AS reltuples, ci.relpages,
+			COALESCE(substring(array_to_string(ci.reloptions, ' ') FROM 'fillfactor=([0-9]+)')::int, 90) AS fillfactor,
+			string_to_array(i.indkey::text, ' ')::int[] AS indkey
+		FROM pg_index i
+		JOIN pg_class ci ON ci.oid = i.indexrelid
+		JOIN pg_class ct ON ct.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = ct.relnamespace
+		JOIN pg_am am ON am.oid = ci.relam
+		WHERE n.nspname = $1 AND ct.relname = $2 AND am.amname = 'btree' AND ci.relpages > 0
+	),
+	widths AS (
+		SELECT idx.idxoid,
+			bool_and(s.attname IS NOT NULL) AS has_stats,
+			max(COALESCE(s.null_frac, 0)) > 0 AS has_nulls,
+			sum((1 - COALESCE(s.null_frac, 0)) * COALESCE(s.avg_width, 1024)) AS data_width
+		FROM idx
+		CROSS JOIN LATERAL generate_series(1, array_length(idx.indkey, 1)) AS k(pos)
+		LEFT JOIN pg_attribute a ON idx.indkey[k.pos] <> 0
+			AND a.attrelid = idx.indrelid AND a.attnum = idx.indkey[k.pos]
+		LEFT JOIN pg_attribute ea ON idx.indkey[k.pos] = 0
+			AND ea.attrelid = idx.idxoid AND ea.attnum = k.pos
+		LEFT JOIN pg_stats s ON s.schemaname = $1
+			AND s.tablename = CASE WHEN a.attnum IS NULL THEN idx.index_name ELSE idx.table_name END
+			AND s.attname = COALESCE(a.attname, ea.attname)
+		GROUP BY idx.idxoid
+	),
+	est AS (
+		SELECT idx.index_name, idx.relpages, w.has_stats,
+			current_setting('block_size')::numeric AS bs,
+			1 + ceil(idx.reltuples / floor(
+				(current_setting('block_size')::numeric - 24 - 16) * idx.fillfactor
+				/ (100 * (4 + ceil(CASE WHEN w.has_nulls THEN 12 ELSE 8 END / 8.0) * 8
+					+ ceil(w.data_width / 8.0) * 8))
+			)) AS est_pages
+		FROM idx
+		JOIN widths w ON w.idxoid = idx.idxoid
+	)
+	SELECT index_name,
+		CASE WHEN has_stats AND relpages > est_pages
+			THEN (bs * (relpages - est_pages))::bigint ELSE 0 END AS bloat_bytes,
+		CASE WHEN has_stats AND relpages > est_pages
+			THEN ((relpages - est_pages) / relpages)::float8 ELSE 0 END AS bloat_ratio
+	FROM est`
+
+// queryPrimaryKeyIndex lists single-column primary keys for FK inference.
+// Has one %s placeholder for the schema filter clause on n.nspname.
+const queryPrimaryKeyIndex = `
+	SELECT n.nspname, c.relname, a.attname, t.typname
+	FROM pg_index i
+	JOIN pg_class c ON c.oid = i.indrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = i.indkey[0]
+	JOIN pg_type t ON t.oid = a.atttypid
+	WHERE i.indisprimary AND i.indnatts = 1
+		AND %s
+	ORDER BY n.nspname, c.relname`
+
+// queryTypeShapes describes types pgx cannot decode natively. For each OID it
+// reports whether the type is an array and, when the type (or its element
+// type) is composite, the attribute names in declaration order.
+const queryTypeShapes = `
+	SELECT t.oid, t.typcategory = 'A' AS is_array,
+		ARRAY(
+			SELECT a.attname
+			FROM pg_attribute a
+			WHERE a.attrelid = e.typrelid AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attnum
+		) AS fields
+	FROM pg_type t
+	JOIN pg_type e ON e.oid = CASE WHEN t.typcategory = 'A' THEN t.typelem ELSE t.oid END
+	WHERE t.oid = ANY($1)`
+
+// queryTableActivity has one %s placeholder for the schema filter clause.
+// Busiest tables come first.
+const queryTableActivity = `
+	SELECT
+		s.schemaname,
+		s.relname,
+		s.n_tup_ins,
+		s.n_tup_upd,
+		s.n_tup_del,
+		(SELECT d.stats_reset FROM pg_stat_database d WHERE d.datname = current_database())
+	FROM pg_stat_user_tables s
+	WHERE %s
+	ORDER BY s.n_tup_ins + s.n_tup_upd + s.n_tup_del DESC, s.schemaname, s.relname`
+
+// queryReplicationStatus reports recovery state and, on a standby, replay lag.
+// A standby that has replayed everything it received is not behind, however
+// old its last replayed transaction is.
+const queryReplicationStatus = `
+	SELECT
+		pg_is_in_recovery(),
+		CASE
+			WHEN NOT pg_is_in_recovery() THEN NULL
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())::float8
+		END,
+		pg_last_xact_replay_timestamp()`
+
+// queryTablespaces lists tablespaces with their sizes. pg_tablespace_size
+// raises an error unless the role has CREATE on the tablespace, is a member
+// of pg_read_all_stats, or the tablespace is the database's default, so the
+// size is only computed in those cases and is NULL otherwise.
+const queryTablespaces = `
+	SELECT
+		t.spcname,
+		pg_tablespace_location(t.oid),
+		size_bytes,
+		pg_size_pretty(size_bytes)
+	FROM pg_catalog.pg_tablespace t
+	CROSS JOIN LATERAL (
+		SELECT CASE
+			WHEN t.oid = (SELECT dattablespace FROM pg_catalog.pg_database WHERE datname = current_database())
+				OR has_tablespace_privilege(t.oid, 'CREATE')
+				OR pg_has_role('pg_read_all_stats', 'USAGE')
+			THEN pg_tablespace_size(t.oid)
+		END AS size_bytes
+	) s
+	ORDER BY t.spcname`
+
+// queryColumnNames lists the columns of every table, view or foreign table
+// in the schemas $1 plus the "schema.table" names in $2.
+const queryColumnNames = `
+	SELECT n.nspname, c.relname, a.attname
+	FROM pg_catalog.pg_attribute a
+	JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+	JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+	WHERE a.attnum > 0
+	  AND NOT a.attisdropped
+	  AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+	  AND (n.nspname = ANY($1::text[]) OR n.nspname || '.' || c.relname = ANY($2::text[]))
+	ORDER BY n.nspname, c.relname, a.attnum`
+
+// queryPlanHintsLoaded checks for a setting pg_hint_plan defines when its
+// library is loaded; current_setting returns NULL for unknown settings.
+const queryPlanHintsLoaded = `
+	SELECT current_setting('pg_hint_plan.enable_hint', true) IS NOT NULL`
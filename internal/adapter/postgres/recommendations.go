@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+)
+
+// An index is a REINDEX candidate when at least this share of it, and at
+// least this many bytes, is estimated bloat. Small indexes are not worth it.
+const (
+	indexBloatMinRatio = 0.3
+	indexBloatMinBytes = 8 << 20
+)
+
+// indexBloatRecommendations flags indexes whose estimated bloat is worth a
+// REINDEX.
+func indexBloatRecommendations(schema string, usage []port.IndexUsage) []string {
+	var recs []string
+	for _, u := range usage {
+		if u.BloatRatio < indexBloatMinRatio || u.BloatBytes < indexBloatMinBytes {
+			continue
+		}
+		recs = append(recs, fmt.Sprintf(
+			"Index %q is about %.0f%% bloat (%d bytes of %d); scans read more pages than needed. Consider REINDEX INDEX CONCURRENTLY %s.",
+			u.Name, u.BloatRatio*100, u.BloatBytes, u.SizeBytes, domain.QualifiedName(schema, u.Name),
+		))
+	}
+	return recs
+}
+
+// fkIndexRecommendations flags explicit foreign keys without a supporting index.
+func fkIndexRecommendations(fks []port.ForeignKey, idxs []port.IndexInfo) []string {
+	if len(fks) == 0 {
+		return nil
+	}
+
+	fkColumns := make(map[string][]string)
+	for _, fk := range fks {
+		fkColumns[fk.ConstraintName] = append(fkColumns[fk.ConstraintName], fk.ColumnName)
+	}
+	indexColumns := make([][]string, 0, len(idxs))
+	for _, idx := range idxs {
+		indexColumns = append(indexColumns, idx.Columns)
+	}
+
+	var recs []string
+	for _, name := range domain.UncoveredForeignKeys(fkColumns, indexColumns) {
+		recs = append(recs, fmt.Sprintf(
+			"Foreign key %q on (%s) has no supporting index; joins and deletes on the referenced table will scan this table. Consider CREATE INDEX on (%s).",
+			name, strings.Join(fkColumns[name], ", "), strings.Join(fkColumns[name], ", "),
+		))
+	}
+	return recs
+}
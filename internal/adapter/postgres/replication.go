@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReplicationReporter reads primary/standby status from the server the pool
+// is connected to.
+type ReplicationReporter struct {
+	pool *pgxpool.Pool
+}
+
+func NewReplicationReporter(pool *pgxpool.Pool) *ReplicationReporter {
+	return &ReplicationReporter{pool: pool}
+}
+
+func (r *ReplicationReporter) ReplicationStatus(ctx context.Context) (*port.ReplicationStatus, error) {
+	status := &port.ReplicationStatus{Role: "primary"}
+	if err := r.pool.QueryRow(ctx, queryReplicationStatus).Scan(
+		&status.InRecovery, &status.LagSeconds, &status.LastReplayAt,
+	); err != nil {
+		return nil, fmt.Errorf("querying replication status: %w", err)
+	}
+	if status.InRecovery {
+		status.Role = "standby"
+	}
+	return status, nil
+}
@@ -0,0 +1,21 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guillermoBallester/isthmus/internal/adapter/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicationStatus_Primary(t *testing.T) {
+	pool := setupTestDB(t)
+
+	status, err := postgres.NewReplicationReporter(pool).ReplicationStatus(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "primary", status.Role)
+	assert.False(t, status.InRecovery)
+	assert.Nil(t, status.LagSeconds, "lag is only reported on a standby")
+	assert.Nil(t, status.LastReplayAt)
+}
@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+)
+
+// ReplicaRoutingExecutor sends calls whose context carries
+// port.WithPreferReplica to a read replica and every other call to the
+// primary.
+type ReplicaRoutingExecutor struct {
+	primary port.QueryExecutor
+	replica port.QueryExecutor
+}
+
+func NewReplicaRoutingExecutor(primary, replica port.QueryExecutor) *ReplicaRoutingExecutor {
+	return &ReplicaRoutingExecutor{primary: primary, replica: replica}
+}
+
+func (e *ReplicaRoutingExecutor) Execute(ctx context.Context, sql string) ([]map[string]any, error) {
+	return e.route(ctx).Execute(ctx, sql)
+}
+
+// route picks the executor for a call.
+func (e *ReplicaRoutingExecutor) route(ctx context.Context) port.QueryExecutor {
+	if port.PreferReplicaFromContext(ctx) {
+		return e.replica
+	}
+	return e.primary
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicaRoutingExecutor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		wantReplica bool
+	}{
+		{"default goes to the primary", context.Background(), false},
+		{"prefer_replica goes to the replica", port.WithPreferReplica(context.Background()), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			primary, replica := &capturingExecutor{}, &capturingExecutor{}
+			e := NewReplicaRoutingExecutor(primary, replica)
+
+			_, _ = e.Execute(tt.ctx, "SELECT 1")
+			if tt.wantReplica {
+				assert.Equal(t, "SELECT 1", replica.lastSQL)
+				assert.Empty(t, primary.lastSQL)
+			} else {
+				assert.Equal(t, "SELECT 1", primary.lastSQL)
+				assert.Empty(t, replica.lastSQL)
+			}
+		})
+	}
+}
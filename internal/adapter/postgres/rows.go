@@ -1,23 +1,102 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/guillermoBallester/isthmus/internal/core/port"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-// rowsToMaps converts pgx.Rows into a slice of maps keyed by column name.
-func rowsToMaps(rows pgx.Rows) ([]map[string]any, error) {
+// moneyOID is the type OID of money. pgx has no codec for it and returns the
+// locale-formatted text, e.g. "$1,234.56".
+const moneyOID = 790
+
+// DuplicateColumns selects what happens when a result has several columns
+// with the same name, e.g. a join selecting a.id and b.id. Rows are maps
+// keyed by column name, so without it all but the last would be lost.
+type DuplicateColumns string
+
+const (
+	// DuplicateColumnsError fails the query, asking for aliases.
+	DuplicateColumnsError DuplicateColumns = "error"
+	// DuplicateColumnsSuffix keeps the first column's name and numbers the
+	// others: id, id_2, id_3.
+	DuplicateColumnsSuffix DuplicateColumns = "suffix"
+)
+
+// resultKeys returns the row map key of each field, handling repeated names
+// as mode says. Numbered names skip any the result already has.
+func resultKeys(fields []pgconn.FieldDescription, mode DuplicateColumns) ([]string, error) {
+	keys := make([]string, len(fields))
+	taken := make(map[string]bool, len(fields))
+	for _, fd := range fields {
+		taken[fd.Name] = true
+	}
+	count := make(map[string]int, len(fields))
+	for i, fd := range fields {
+		count[fd.Name]++
+		if count[fd.Name] == 1 {
+			keys[i] = fd.Name
+			continue
+		}
+		if mode != DuplicateColumnsSuffix {
+			return nil, fmt.Errorf("%w %q: the result has more than one column with this name; give each a distinct alias, e.g. SELECT a.%s AS a_%s, b.%s AS b_%s",
+				domain.ErrAmbiguousName, fd.Name, fd.Name, fd.Name, fd.Name, fd.Name)
+		}
+		n := count[fd.Name]
+		key := domain.DuplicateColumnName(fd.Name, n)
+		for taken[key] {
+			n++
+			key = domain.DuplicateColumnName(fd.Name, n)
+		}
+		count[fd.Name] = n
+		taken[key] = true
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+// rowOverhead and entryOverhead approximate what a map and each of its
+// entries cost on top of their keys and values.
+const (
+	rowOverhead   = 48
+	entryOverhead = 16
+)
+
+// rowsToMaps converts pgx.Rows into a slice of maps keyed by column name,
+// with repeated names handled as mode says. When maxBytes is positive it
+// keeps a running estimate of the memory the rows take (see valueSize) and
+// stops with domain.ErrResultTooLarge as soon as it goes over, so a few huge
+// values cannot exhaust memory before the row limit is reached.
+func rowsToMaps(rows pgx.Rows, mode DuplicateColumns, maxBytes int64) ([]map[string]any, error) {
 	fields := rows.FieldDescriptions()
+	keys, err := resultKeys(fields, mode)
+	if err != nil {
+		return nil, err
+	}
 	var result []map[string]any
+	var size int64
 	for rows.Next() {
 		vals, err := rows.Values()
 		if err != nil {
 			return nil, fmt.Errorf("reading row values: %w", err)
 		}
 		row := make(map[string]any, len(fields))
+		size += rowOverhead
 		for i, fd := range fields {
-			row[fd.Name] = vals[i]
+			v := normalizeResultValue(vals[i], fd.DataTypeOID)
+			row[keys[i]] = v
+			size += entryOverhead + int64(len(keys[i])) + valueSize(v)
+		}
+		if maxBytes > 0 && size > maxBytes {
+			return nil, fmt.Errorf("%w: the first %d rows already take about %d bytes in memory, over the limit of %d; select fewer or narrower columns, or fewer rows",
+				domain.ErrResultTooLarge, len(result)+1, size, maxBytes)
 		}
 		result = append(result, row)
 	}
@@ -26,3 +105,178 @@ func rowsToMaps(rows pgx.Rows) ([]map[string]any, error) {
 	}
 	return result, nil
 }
+
+// valueSize estimates the memory held by a result value: the bytes of strings
+// and byte slices, recursively for arrays and JSON objects, and a flat
+// amount for numbers, booleans, timestamps and other fixed-size values.
+func valueSize(v any) int64 {
+	switch val := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(val))
+	case []byte:
+		return int64(len(val))
+	case []any:
+		n := int64(24)
+		for _, e := range val {
+			n += entryOverhead + valueSize(e)
+		}
+		return n
+	case map[string]any:
+		n := int64(rowOverhead)
+		for k, e := range val {
+			n += entryOverhead + int64(len(k)) + valueSize(e)
+		}
+		return n
+	default:
+		return entryOverhead
+	}
+}
+
+// describeResultColumns names the type of each result field, including its
+// modifier (e.g. "numeric(10,2)"), with format_type in the same transaction.
+func describeResultColumns(ctx context.Context, tx pgx.Tx, fields []pgconn.FieldDescription, mode DuplicateColumns) ([]port.ResultColumn, error) {
+	cols := make([]port.ResultColumn, len(fields))
+	if len(fields) == 0 {
+		return cols, nil
+	}
+	keys, err := resultKeys(fields, mode)
+	if err != nil {
+		return nil, err
+	}
+	oids := make([]uint32, len(fields))
+	mods := make([]int32, len(fields))
+	for i, fd := range fields {
+		cols[i] = port.ResultColumn{Name: keys[i], TypeOID: fd.DataTypeOID}
+		oids[i], mods[i] = fd.DataTypeOID, fd.TypeModifier
+	}
+
+	rows, err := tx.Query(ctx, queryFormatTypes, oids, mods)
+	if err != nil {
+		return nil, fmt.Errorf("describing result columns: %w", err)
+	}
+	defer rows.Close()
+	for i := 0; rows.Next() && i < len(cols); i++ {
+		if err := rows.Scan(&cols[i].Type); err != nil {
+			return nil, fmt.Errorf("scanning result column type: %w", err)
+		}
+	}
+	return cols, rows.Err()
+}
+
+// normalizeResultValue converts driver-specific values into JSON-friendly
+// ones: numeric becomes a string so no precision is lost to float64, interval
+// a PostgreSQL-style string such as "1 year 2 mons 3 days 04:05:06", and
+// money a plain decimal string. Other values are returned unchanged.
+func normalizeResultValue(v any, oid uint32) any {
+	switch val := v.(type) {
+	case pgtype.Numeric:
+		return formatNumeric(val)
+	case pgtype.Interval:
+		return formatInterval(val)
+	case []any:
+		for i := range val {
+			val[i] = normalizeResultValue(val[i], 0)
+		}
+		return val
+	case string:
+		if oid == moneyOID {
+			return moneyToDecimal(val)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// formatNumeric renders n exactly, including NaN and ±Infinity.
+func formatNumeric(n pgtype.Numeric) any {
+	if !n.Valid {
+		return nil
+	}
+	v, err := n.Value()
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// formatInterval renders iv the way PostgreSQL's default "postgres"
+// IntervalStyle does.
+func formatInterval(iv pgtype.Interval) any {
+	if !iv.Valid {
+		return nil
+	}
+
+	var parts []string
+	plural := func(n int64, unit, units string) {
+		if n == 0 {
+			return
+		}
+		if n == 1 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, unit))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %s", n, units))
+		}
+	}
+	plural(int64(iv.Months/12), "year", "years")
+	plural(int64(iv.Months%12), "mon", "mons")
+	plural(int64(iv.Days), "day", "days")
+
+	if us := iv.Microseconds; us != 0 || len(parts) == 0 {
+		sign := ""
+		switch {
+		case us < 0:
+			sign, us = "-", -us
+		case iv.Months < 0 || iv.Days < 0:
+			// Mixed signs: PostgreSQL marks the positive time part explicitly.
+			sign = "+"
+		}
+		clock := fmt.Sprintf("%s%02d:%02d:%02d", sign, us/3_600_000_000, us/60_000_000%60, us/1_000_000%60)
+		if frac := us % 1_000_000; frac != 0 {
+			clock += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+		}
+		parts = append(parts, clock)
+	}
+	return strings.Join(parts, " ")
+}
+
+// moneyToDecimal strips currency symbols and digit grouping from a
+// locale-formatted money value, e.g. "-$1,234.56" or "1.234,56 €" become
+// "-1234.56" and "1234.56". The last '.' or ',' is the decimal separator
+// unless exactly three digits follow it, in which case it groups thousands.
+// Text it cannot make sense of is returned unchanged.
+func moneyToDecimal(s string) string {
+	negative := strings.ContainsAny(s, "-(")
+
+	var digits strings.Builder
+	sepAt := -1 // position in digits where the decimal separator goes
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '.' || r == ',':
+			sepAt = digits.Len()
+		}
+	}
+	d := digits.String()
+	if d == "" {
+		return s
+	}
+	if sepAt >= 0 && len(d)-sepAt == 3 {
+		sepAt = -1
+	}
+
+	out := d
+	if sepAt >= 0 {
+		out = d[:sepAt] + "." + d[sepAt:]
+	}
+	if negative {
+		out = "-" + out
+	}
+	if _, err := strconv.ParseFloat(out, 64); err != nil {
+		return s
+	}
+	return out
+}
@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/guillermoBallester/isthmus/internal/core/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeResultValue_Numeric(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		in   pgtype.Numeric
+		want any
+	}{
+		{"keeps precision", pgtype.Numeric{Int: big.NewInt(12345678901234567), Exp: -4, Valid: true}, "1234567890123.4567"},
+		{"trailing zeros", pgtype.Numeric{Int: big.NewInt(1050), Exp: -2, Valid: true}, "10.50"},
+		{"negative", pgtype.Numeric{Int: big.NewInt(-5), Exp: -1, Valid: true}, "-0.5"},
+		{"NaN", pgtype.Numeric{NaN: true, Valid: true}, "NaN"},
+		{"infinity", pgtype.Numeric{InfinityModifier: pgtype.Infinity, Valid: true}, "Infinity"},
+		{"null", pgtype.Numeric{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, normalizeResultValue(tt.in, pgtype.NumericOID))
+		})
+	}
+}
+
+func TestNormalizeResultValue_Interval(t *testing.T) {
+	t.Parallel()
+	const hour = 3_600_000_000
+	tests := []struct {
+		name string
+		in   pgtype.Interval
+		want any
+	}{
+		{"full", pgtype.Interval{Months: 14, Days: 3, Microseconds: 4*hour + 5*60_000_000 + 6_000_000, Valid: true}, "1 year 2 mons 3 days 04:05:06"},
+		{"singular", pgtype.Interval{Months: 1, Days: 1, Valid: true}, "1 mon 1 day"},
+		{"fractional seconds", pgtype.Interval{Microseconds: 1_500_000, Valid: true}, "00:00:01.5"},
+		{"negative time", pgtype.Interval{Microseconds: -hour, Valid: true}, "-01:00:00"},
+		{"mixed signs", pgtype.Interval{Days: -1, Microseconds: hour, Valid: true}, "-1 days +01:00:00"},
+		{"zero", pgtype.Interval{Valid: true}, "00:00:00"},
+		{"null", pgtype.Interval{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, normalizeResultValue(tt.in, pgtype.IntervalOID))
+		})
+	}
+}
+
+func TestNormalizeResultValue_Money(t *testing.T) {
+	t.Parallel()
+	tests := []struct{ in, want string }{
+		{"$1,234.56", "1234.56"},
+		{"-$1,234.56", "-1234.56"},
+		{"($12.00)", "-12.00"},
+		{"1.234,56 €", "1234.56"},
+		{"¥1,234,567", "1234567"},
+		{"$0.05", "0.05"},
+		{"n/a", "n/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, normalizeResultValue(tt.in, moneyOID))
+		})
+	}
+}
+
+func TestNormalizeResultValue_Passthrough(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "$5", normalizeResultValue("$5", pgtype.TextOID), "only money columns are rewritten")
+	assert.Equal(t, int32(7), normalizeResultValue(int32(7), pgtype.Int4OID))
+	assert.Equal(t,
+		[]any{"1.5", nil},
+		normalizeResultValue([]any{pgtype.Numeric{Int: big.NewInt(15), Exp: -1, Valid: true}, nil}, 1231),
+		"array elements are normalized")
+}
+
+func TestResultKeys(t *testing.T) {
+	t.Parallel()
+	fields := func(names ...string) []pgconn.FieldDescription {
+		fds := make([]pgconn.FieldDescription, len(names))
+		for i, n := range names {
+			fds[i].Name = n
+		}
+		return fds
+	}
+
+	keys, err := resultKeys(fields("id", "name"), DuplicateColumnsError)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "name"}, keys)
+
+	_, err = resultKeys(fields("id", "name", "id"), DuplicateColumnsError)
+	require.ErrorIs(t, err, domain.ErrAmbiguousName)
+	assert.Contains(t, err.Error(), `"id"`)
+
+	keys, err = resultKeys(fields("id", "name", "id", "id"), DuplicateColumnsSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "id_2", "id_3"}, keys)
+
+	keys, err = resultKeys(fields("id", "id", "id_2"), DuplicateColumnsSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "id_3", "id_2"}, keys, "a numbered name never shadows a real column")
+}
+
+// fakeRows serves fixed values through the pgx.Rows interface and counts
+// how many rows were read.
+type fakeRows struct {
+	fields []pgconn.FieldDescription
+	values [][]any
+	read   int
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return r.fields }
+func (r *fakeRows) Scan(...any) error                            { return nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	if r.read == len(r.values) {
+		return false
+	}
+	r.read++
+	return true
+}
+
+func (r *fakeRows) Values() ([]any, error) { return r.values[r.read-1], nil }
+
+func TestRowsToMaps_MemoryGuard(t *testing.T) {
+	t.Parallel()
+	big := strings.Repeat("x", 1<<20)
+	newRows := func() *fakeRows {
+		rows := &fakeRows{fields: []pgconn.FieldDescription{{Name: "id"}, {Name: "body"}}}
+		for i := range 10 {
+			rows.values = append(rows.values, []any{int64(i), big})
+		}
+		return rows
+	}
+
+	rows := newRows()
+	_, err := rowsToMaps(rows, DuplicateColumnsError, 3<<20)
+	require.ErrorIs(t, err, domain.ErrResultTooLarge)
+	assert.Contains(t, err.Error(), "first 3 rows")
+	assert.Equal(t, 3, rows.read, "scanning stops at the row that crosses the limit")
+
+	result, err := rowsToMaps(newRows(), DuplicateColumnsError, 0)
+	require.NoError(t, err, "zero disables the guard")
+	assert.Len(t, result, 10)
+
+	result, err = rowsToMaps(newRows(), DuplicateColumnsError, 16<<20)
+	require.NoError(t, err)
+	assert.Len(t, result, 10)
+}
+
+func TestValueSize(t *testing.T) {
+	t.Parallel()
+	assert.Zero(t, valueSize(nil))
+	assert.Equal(t, int64(5), valueSize("hello"))
+	assert.Equal(t, int64(3), valueSize([]byte{1, 2, 3}))
+	assert.Equal(t, int64(entryOverhead), valueSize(int64(42)))
+	nested := map[string]any{"tags": []any{"a", "bc"}}
+	assert.Greater(t, valueSize(nested), valueSize([]any{"a", "bc"}), "objects count their keys and values")
+}
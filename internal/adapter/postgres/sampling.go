@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// typeShape describes how to render a value of a type pgx has no codec for.
+type typeShape struct {
+	isArray bool
+	fields  []string // composite attribute names; empty for non-composite types
+}
+
+// sampleColumn pairs a result column with the OID of its type.
+type sampleColumn struct {
+	name string
+	oid  uint32
+}
+
+// unknownColumns returns the columns whose types pgx's default type map does
+// not know. pgx returns such values as raw text, e.g. "(1,foo)" or "{a,b}".
+func unknownColumns(fields []pgconn.FieldDescription) []sampleColumn {
+	known := pgtype.NewMap()
+	var cols []sampleColumn
+	for _, fd := range fields {
+		if _, ok := known.TypeForOID(fd.DataTypeOID); !ok {
+			cols = append(cols, sampleColumn{name: fd.Name, oid: fd.DataTypeOID})
+		}
+	}
+	return cols
+}
+
+// fetchTypeShapes looks up array and composite metadata for the given OIDs.
+func fetchTypeShapes(ctx context.Context, pool interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}, oids []uint32) (map[uint32]typeShape, error) {
+	rows, err := pool.Query(ctx, queryTypeShapes, oids)
+	if err != nil {
+		return nil, fmt.Errorf("querying type shapes: %w", err)
+	}
+	defer rows.Close()
+
+	shapes := make(map[uint32]typeShape, len(oids))
+	for rows.Next() {
+		var oid uint32
+		var s typeShape
+		if err := rows.Scan(&oid, &s.isArray, &s.fields); err != nil {
+			return nil, fmt.Errorf("scanning type shape: %w", err)
+		}
+		shapes[oid] = s
+	}
+	return shapes, rows.Err()
+}
+
+// normalizeSampleRows rewrites values in place so they marshal readably:
+// UUIDs become strings, text-encoded arrays become JSON arrays and composite
+// values become objects keyed by attribute name.
+func normalizeSampleRows(rows []map[string]any, cols []sampleColumn, shapes map[uint32]typeShape) {
+	for _, row := range rows {
+		for k, v := range row {
+			row[k] = normalizeValue(v)
+		}
+		for _, c := range cols {
+			shape, ok := shapes[c.oid]
+			if !ok {
+				continue
+			}
+			row[c.name] = renderShaped(row[c.name], shape)
+		}
+	}
+}
+
+// normalizeValue converts decoded values that marshal awkwardly to JSON.
+func normalizeValue(v any) any {
+	switch val := v.(type) {
+	case [16]byte:
+		return formatUUID(val)
+	case []any:
+		for i := range val {
+			val[i] = normalizeValue(val[i])
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// renderShaped parses a text-encoded array or composite value.
+func renderShaped(v any, shape typeShape) any {
+	var raw string
+	switch val := v.(type) {
+	case string:
+		raw = val
+	case []byte:
+		raw = string(val)
+	default:
+		return v
+	}
+
+	if !shape.isArray {
+		if len(shape.fields) == 0 {
+			return raw
+		}
+		return parseComposite(raw, shape.fields)
+	}
+
+	elems := parsePgArray(raw)
+	out := make([]any, len(elems))
+	for i, e := range elems {
+		if len(shape.fields) > 0 {
+			out[i] = parseComposite(e, shape.fields)
+		} else {
+			out[i] = e
+		}
+	}
+	return out
+}
+
+// parseComposite parses a PostgreSQL composite literal like (1,"a b",) into
+// an object keyed by field name. Unquoted empty fields are NULL. Values stay
+// as text; nested composites are not expanded.
+func parseComposite(raw string, fields []string) map[string]any {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimPrefix(raw, "(")
+	raw = strings.TrimSuffix(raw, ")")
+
+	var values []any
+	var current strings.Builder
+	inQuote, quoted, escaped := false, false, false
+
+	flush := func() {
+		if current.Len() == 0 && !quoted {
+			values = append(values, nil)
+		} else {
+			values = append(values, current.String())
+		}
+		current.Reset()
+		quoted = false
+	}
+
+	runes := []rune(raw)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		if escaped {
+			current.WriteRune(ch)
+			escaped = false
+			continue
+		}
+		switch {
+		case ch == '\\':
+			escaped = true
+		case ch == '"' && inQuote && i+1 < len(runes) && runes[i+1] == '"':
+			current.WriteRune('"')
+			i++
+		case ch == '"':
+			inQuote = !inQuote
+			quoted = true
+		case ch == ',' && !inQuote:
+			flush()
+		default:
+			current.WriteRune(ch)
+		}
+	}
+	flush()
+
+	obj := make(map[string]any, len(fields))
+	for i, name := range fields {
+		if i < len(values) {
+			obj[name] = values[i]
+		} else {
+			obj[name] = nil
+		}
+	}
+	return obj
+}
+
+func formatUUID(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseComposite(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		raw    string
+		fields []string
+		want   map[string]any
+	}{
+		{"simple", "(10,20,cm)", []string{"w", "h", "unit"}, map[string]any{"w": "10", "h": "20", "unit": "cm"}},
+		{"null field", "(10,,cm)", []string{"w", "h", "unit"}, map[string]any{"w": "10", "h": nil, "unit": "cm"}},
+		{"quoted empty string", `(10,"",cm)`, []string{"w", "h", "unit"}, map[string]any{"w": "10", "h": "", "unit": "cm"}},
+		{"quoted with comma", `(1,"a, b")`, []string{"id", "label"}, map[string]any{"id": "1", "label": "a, b"}},
+		{"doubled quote", `(1,"say ""hi""")`, []string{"id", "label"}, map[string]any{"id": "1", "label": `say "hi"`}},
+		{"backslash escape", `(1,"a\\b")`, []string{"id", "label"}, map[string]any{"id": "1", "label": `a\b`}},
+		{"fewer values than fields", "(1)", []string{"id", "label"}, map[string]any{"id": "1", "label": nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, parseComposite(tt.raw, tt.fields))
+		})
+	}
+}
+
+func TestRenderShaped(t *testing.T) {
+	t.Parallel()
+
+	enumArray := renderShaped("{happy,sad}", typeShape{isArray: true})
+	assert.Equal(t, []any{"happy", "sad"}, enumArray)
+
+	compositeArray := renderShaped(`{"(1,a)","(2,b)"}`, typeShape{isArray: true, fields: []string{"id", "label"}})
+	assert.Equal(t, []any{
+		map[string]any{"id": "1", "label": "a"},
+		map[string]any{"id": "2", "label": "b"},
+	}, compositeArray)
+
+	composite := renderShaped([]byte("(1,a)"), typeShape{fields: []string{"id", "label"}})
+	assert.Equal(t, map[string]any{"id": "1", "label": "a"}, composite)
+
+	// Non-composite scalars (e.g. enums) are left as text.
+	assert.Equal(t, "happy", renderShaped("happy", typeShape{}))
+
+	// Already-decoded values pass through.
+	assert.Equal(t, 42, renderShaped(42, typeShape{isArray: true}))
+}
+
+func TestNormalizeValue(t *testing.T) {
+	t.Parallel()
+	id := [16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}
+
+	assert.Equal(t, "12345678-9abc-def0-1234-56789abcdef0", normalizeValue(id))
+	assert.Equal(t, []any{"12345678-9abc-def0-1234-56789abcdef0", nil}, normalizeValue([]any{id, nil}))
+	assert.Equal(t, "text", normalizeValue("text"))
+}
+
+func TestNormalizeSampleRows(t *testing.T) {
+	t.Parallel()
+	rows := []map[string]any{
+		{"id": int32(1), "size": "(10,20,cm)", "tags": []any{"a", "b"}},
+		{"id": int32(2), "size": nil, "tags": nil},
+	}
+	cols := []sampleColumn{{name: "size", oid: 90001}}
+	shapes := map[uint32]typeShape{90001: {fields: []string{"width", "height", "unit"}}}
+
+	normalizeSampleRows(rows, cols, shapes)
+
+	assert.Equal(t, map[string]any{"width": "10", "height": "20", "unit": "cm"}, rows[0]["size"])
+	assert.Equal(t, []any{"a", "b"}, rows[0]["tags"])
+	assert.Nil(t, rows[1]["size"])
+}
@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TablespaceLister reads tablespaces and their sizes from the server the
+// pool is connected to.
+type TablespaceLister struct {
+	pool *pgxpool.Pool
+}
+
+func NewTablespaceLister(pool *pgxpool.Pool) *TablespaceLister {
+	return &TablespaceLister{pool: pool}
+}
+
+func (l *TablespaceLister) Tablespaces(ctx context.Context) ([]port.Tablespace, error) {
+	rows, err := l.pool.Query(ctx, queryTablespaces)
+	if err != nil {
+		return nil, fmt.Errorf("querying tablespaces: %w", err)
+	}
+	defer rows.Close()
+
+	var tablespaces []port.Tablespace
+	for rows.Next() {
+		var ts port.Tablespace
+		var sizeHuman *string
+		if err := rows.Scan(&ts.Name, &ts.Location, &ts.SizeBytes, &sizeHuman); err != nil {
+			return nil, fmt.Errorf("scanning tablespace: %w", err)
+		}
+		if sizeHuman != nil {
+			ts.SizeHuman = *sizeHuman
+		}
+		tablespaces = append(tablespaces, ts)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating tablespaces: %w", err)
+	}
+	return tablespaces, nil
+}
@@ -0,0 +1,60 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guillermoBallester/isthmus/internal/adapter/postgres"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTablespaces_Builtin(t *testing.T) {
+	pool := setupTestDB(t)
+
+	tablespaces, err := postgres.NewTablespaceLister(pool).Tablespaces(context.Background())
+	require.NoError(t, err)
+
+	names := make([]string, len(tablespaces))
+	for i, ts := range tablespaces {
+		names[i] = ts.Name
+	}
+	require.Contains(t, names, "pg_default")
+	for _, ts := range tablespaces {
+		if ts.Name == "pg_default" {
+			assert.Empty(t, ts.Location, "built-in tablespaces have no location")
+			require.NotNil(t, ts.SizeBytes)
+			assert.Positive(t, *ts.SizeBytes)
+			assert.NotEmpty(t, ts.SizeHuman)
+		}
+	}
+}
+
+func TestTablespaces_WithoutPrivilege(t *testing.T) {
+	pool := setupTestDB(t)
+	ctx := context.Background()
+
+	// pg_global is not the database's default tablespace and only superusers
+	// have CREATE on it, so an unprivileged role must get no size, not an error.
+	_, err := pool.Exec(ctx, `CREATE ROLE ts_reader LOGIN PASSWORD 'ts_reader'`)
+	require.NoError(t, err)
+
+	cfg := pool.Config().Copy()
+	cfg.ConnConfig.User, cfg.ConnConfig.Password = "ts_reader", "ts_reader"
+	reader, err := pgxpool.NewWithConfig(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(reader.Close)
+
+	tablespaces, err := postgres.NewTablespaceLister(reader).Tablespaces(ctx)
+	require.NoError(t, err)
+	for _, ts := range tablespaces {
+		switch ts.Name {
+		case "pg_default":
+			assert.NotNil(t, ts.SizeBytes, "the database's default tablespace is always readable")
+		case "pg_global":
+			assert.Nil(t, ts.SizeBytes)
+			assert.Empty(t, ts.SizeHuman)
+		}
+	}
+}
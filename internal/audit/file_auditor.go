@@ -1,60 +1,52 @@
 package audit
 
 import (
-	"context"
-	"encoding/json"
 	"os"
 	"sync"
-	"time"
-
-	"github.com/guillermoBallester/isthmus/internal/core/port"
 )
 
-// fileEntry is the NDJSON-serializable form of an audit record.
-type fileEntry struct {
-	Timestamp    string  `json:"ts"`
-	Tool         string  `json:"tool"`
-	SQL          string  `json:"sql"`
-	RowsReturned int     `json:"rows_returned"`
-	DurationMS   int64   `json:"duration_ms"`
-	Error        *string `json:"error"`
-}
-
 // FileAuditor writes audit entries as NDJSON (one JSON object per line) to a file.
 type FileAuditor struct {
-	mu   sync.Mutex
+	*WriterAuditor
+
+	mu   sync.Mutex // guards file
+	path string
 	file *os.File
-	enc  *json.Encoder
 }
 
 // NewFileAuditor opens (or creates) the file at path for append-only writing.
 func NewFileAuditor(path string) (*FileAuditor, error) {
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	f, err := openAuditFile(path)
 	if err != nil {
 		return nil, err
 	}
 	return &FileAuditor{
-		file: f,
-		enc:  json.NewEncoder(f),
+		WriterAuditor: NewWriterAuditor(f),
+		path:          path,
+		file:          f,
 	}, nil
 }
 
-func (a *FileAuditor) Record(_ context.Context, entry port.AuditEntry) {
-	fe := fileEntry{
-		Timestamp:    time.Now().UTC().Format(time.RFC3339),
-		Tool:         entry.Tool,
-		SQL:          entry.SQL,
-		RowsReturned: entry.RowsReturned,
-		DurationMS:   entry.DurationMS,
-	}
-	if entry.Err != nil {
-		s := entry.Err.Error()
-		fe.Error = &s
+func openAuditFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// Reopen closes the current file and opens the configured path again, so
+// entries written after an external rotation (e.g. logrotate) land in the
+// new file instead of the renamed one. If the path cannot be opened the
+// current file is kept.
+func (a *FileAuditor) Reopen() error {
+	f, err := openAuditFile(a.path)
+	if err != nil {
+		return err
 	}
 
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	_ = a.enc.Encode(fe) // best-effort; don't fail the request for audit I/O
+	a.WriterAuditor.reset(f)
+	old := a.file
+	a.file = f
+	return old.Close()
 }
 
 func (a *FileAuditor) Close() error {
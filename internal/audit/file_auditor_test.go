@@ -174,6 +174,57 @@ func TestFileAuditor_Append(t *testing.T) {
 	assert.Equal(t, 2, count)
 }
 
+func TestFileAuditor_Reopen_AfterRotation(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+	rotated := filepath.Join(dir, "audit.jsonl.1")
+
+	fa, err := NewFileAuditor(path)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, fa.Close()) }()
+
+	fa.Record(context.Background(), port.AuditEntry{Tool: "query", SQL: "SELECT 1"})
+
+	// Simulate logrotate: move the file aside, then signal a reopen.
+	require.NoError(t, os.Rename(path, rotated))
+	require.NoError(t, fa.Reopen())
+
+	fa.Record(context.Background(), port.AuditEntry{Tool: "query", SQL: "SELECT 2"})
+
+	var old, current fileEntry
+	data, err := os.ReadFile(rotated)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &old))
+	assert.Equal(t, "SELECT 1", old.SQL)
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &current))
+	assert.Equal(t, "SELECT 2", current.SQL)
+}
+
+func TestFileAuditor_Reopen_FailureKeepsCurrentFile(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), "logs")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	path := filepath.Join(dir, "audit.jsonl")
+
+	fa, err := NewFileAuditor(path)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, fa.Close()) }()
+
+	// Remove the directory so the path can no longer be opened.
+	require.NoError(t, os.Rename(dir, dir+".old"))
+	require.Error(t, fa.Reopen())
+
+	fa.Record(context.Background(), port.AuditEntry{Tool: "query", SQL: "SELECT 1"})
+
+	data, err := os.ReadFile(filepath.Join(dir+".old", "audit.jsonl"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "SELECT 1")
+}
+
 func TestNoopAuditor(t *testing.T) {
 	t.Parallel()
 	a := port.NoopAuditor{}
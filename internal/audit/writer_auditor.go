@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+)
+
+// fileEntry is the NDJSON-serializable form of an audit record.
+type fileEntry struct {
+	Timestamp    string   `json:"ts"`
+	Tool         string   `json:"tool"`
+	SQL          string   `json:"sql"`
+	Tables       []string `json:"tables"`
+	Schemas      int      `json:"schemas"`
+	RowsReturned int      `json:"rows_returned"`
+	DurationMS   int64    `json:"duration_ms"`
+	Error        *string  `json:"error"`
+}
+
+// WriterAuditor writes audit entries as NDJSON (one JSON object per line) to
+// an io.Writer. Each entry is written with a single Write call, so lines stay
+// whole when the writer is shared, e.g. with the logger on stderr.
+type WriterAuditor struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriterAuditor returns an auditor that writes to w. The caller owns w.
+func NewWriterAuditor(w io.Writer) *WriterAuditor {
+	return &WriterAuditor{enc: json.NewEncoder(w)}
+}
+
+func (a *WriterAuditor) Record(_ context.Context, entry port.AuditEntry) {
+	fe := fileEntry{
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Tool:         entry.Tool,
+		SQL:          entry.SQL,
+		Tables:       entry.ReferencedTables,
+		Schemas:      distinctSchemas(entry.ReferencedTables),
+		RowsReturned: entry.RowsReturned,
+		DurationMS:   entry.DurationMS,
+	}
+	if fe.Tables == nil {
+		fe.Tables = []string{}
+	}
+	if entry.Err != nil {
+		s := entry.Err.Error()
+		fe.Error = &s
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_ = a.enc.Encode(fe) // best-effort; don't fail the request for audit I/O
+}
+
+// Close is a no-op: the writer belongs to the caller.
+func (a *WriterAuditor) Close() error { return nil }
+
+// reset makes later entries go to w. Once it returns, no entry is being
+// written to the previous writer.
+func (a *WriterAuditor) reset(w io.Writer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.enc = json.NewEncoder(w)
+}
+
+// distinctSchemas counts the schemas among "schema.table" names. Unqualified
+// names together count as one more, the schema the search_path resolves
+// them to.
+func distinctSchemas(tables []string) int {
+	schemas := make(map[string]bool)
+	for _, t := range tables {
+		schema, _, ok := strings.Cut(t, ".")
+		if !ok {
+			schema = ""
+		}
+		schemas[schema] = true
+	}
+	return len(schemas)
+}
@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterAuditor_Record_WritesNDJSON(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	wa := NewWriterAuditor(&buf)
+
+	wa.Record(context.Background(), port.AuditEntry{Tool: "query", SQL: "SELECT 1", RowsReturned: 1, DurationMS: 7})
+	wa.Record(context.Background(), port.AuditEntry{Tool: "query", SQL: "SELECT bad", Err: errors.New("syntax error")})
+
+	scanner := bufio.NewScanner(&buf)
+	var entries []fileEntry
+	for scanner.Scan() {
+		var e fileEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e), "line %q", scanner.Text())
+		entries = append(entries, e)
+	}
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "SELECT 1", entries[0].SQL)
+	assert.Equal(t, 1, entries[0].RowsReturned)
+	assert.Equal(t, int64(7), entries[0].DurationMS)
+	assert.Nil(t, entries[0].Error)
+	assert.NotEmpty(t, entries[0].Timestamp)
+
+	require.NotNil(t, entries[1].Error)
+	assert.Equal(t, "syntax error", *entries[1].Error)
+}
+
+func TestWriterAuditor_Record_Tables(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	wa := NewWriterAuditor(&buf)
+
+	wa.Record(context.Background(), port.AuditEntry{
+		Tool: "query", SQL: "SELECT ...",
+		ReferencedTables: []string{"billing.plans", "orders", "public.users", "users"},
+	})
+	wa.Record(context.Background(), port.AuditEntry{Tool: "query", SQL: "SELECT 1"})
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	var e fileEntry
+	require.NoError(t, json.Unmarshal(lines[0], &e))
+	assert.Equal(t, []string{"billing.plans", "orders", "public.users", "users"}, e.Tables)
+	assert.Equal(t, 3, e.Schemas, "billing, public, and the search_path schema of the bare names")
+
+	assert.Contains(t, string(lines[1]), `"tables":[],"schemas":0`)
+}
+
+func TestWriterAuditor_Record_ConcurrentWrites(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	wa := NewWriterAuditor(&buf)
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wa.Record(context.Background(), port.AuditEntry{Tool: "query", SQL: fmt.Sprintf("SELECT %d", i)})
+		}()
+	}
+	wg.Wait()
+
+	scanner := bufio.NewScanner(&buf)
+	var count int
+	for scanner.Scan() {
+		var e fileEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e), "interleaved line %q", scanner.Text())
+		count++
+	}
+	assert.Equal(t, n, count)
+}
+
+// countingWriter records how many Write calls it receives.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestWriterAuditor_Record_SingleWritePerEntry(t *testing.T) {
+	t.Parallel()
+	var w countingWriter
+	wa := NewWriterAuditor(&w)
+
+	wa.Record(context.Background(), port.AuditEntry{Tool: "query", SQL: "SELECT 1"})
+
+	assert.Equal(t, 1, w.writes, "an entry must be one write so it isn't split when the writer is shared")
+}
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -11,33 +14,92 @@ import (
 
 type Config struct {
 	// Database connection.
-	DatabaseURL  string
-	ReadOnly     bool
-	MaxRows      int
-	QueryTimeout time.Duration
+	DatabaseURL     string
+	ReadOnly        bool
+	MaxRows         int
+	MaxRowsCeiling  int   // hard cap for per-call limits; 0 means MaxRows
+	MaxResultMemory int64 // estimated bytes a result may take in memory while it is read; 0 disables the guard
+	QueryTimeout    time.Duration
+	QueryTimeoutMin time.Duration // floor for any statement timeout
+	QueryTimeoutMax time.Duration // ceiling for any statement timeout; 0 means QueryTimeout
+	DialerProxy     string        // optional SOCKS5 proxy URL for reaching the database
+
+	// Read scaling.
+	ReplicaDatabaseURL string // optional read replica for query calls with prefer_replica
+
+	// Query guards.
+	AnalyzeMaxCost        float64       // refuse EXPLAIN ANALYZE above this planner cost; 0 disables the guard
+	ExplainRedactLiterals bool          // replace quoted literals in EXPLAIN output with '***'
+	LogPgErrorDetail      bool          // keep DETAIL/HINT/WHERE of PostgreSQL errors for the server log
+	AllowMultiStatement   bool          // accept batches of SELECT and SET LOCAL statements in one transaction
+	FunctionCheck         bool          // reject queries calling known side-effecting functions such as pg_terminate_backend
+	BlockedFunctions      []string      // further functions a query may not call, optionally schema-qualified
+	RollbackAlways        bool          // end query transactions with ROLLBACK instead of COMMIT
+	LimitInjection        bool          // apply the row limit on the query's own LIMIT clause instead of a wrapping subquery
+	StrictExplain         bool          // refuse EXPLAIN of write statements even without ANALYZE
+	PlanHints             bool          // keep leading pg_hint_plan comments at the start of executed queries
+	ToolCallTimeout       time.Duration // bound on a whole tool call, across all its statements; 0 (default) disables it
+	MaxArgBytes           int           // longest string argument a tool call may carry; 0 (default) disables the check
+
+	// Result formatting.
+	ResultKeyCase    string // "original" (default), "snake", or "camel"
+	DuplicateColumns string // "error" (default) or "suffix": how repeated result column names are handled
+	IncludeTiming    bool   // add validation, execution and masking times to query and custom tool responses
+	IncludeSQL       bool   // add the SQL the executor ran, after limit rewriting, to query responses
 
 	// Schema filtering.
-	Schemas    []string // empty means all non-system schemas
-	PolicyFile string   // optional path to policy YAML
+	Schemas        []string // empty means all non-system schemas
+	HiddenSchemas  []string // never exposed, even when listed in Schemas
+	EnforceSchemas bool     // reject queries naming a table in a schema outside Schemas
+	PolicyFile     string   // optional path, or comma-separated paths, to policy YAML
+	PolicyDir      string   // optional directory whose *.yaml/*.yml files are merged after PolicyFile
+	ContextFile    string   // optional dbt manifest.json or JSON description map merged into the policy descriptions
+	PolicyStrict   string   // "off" (default), "warn" or "error": how masks on nonexistent columns are reported at startup
+
+	// Schema exploration.
+	SchemaCacheTTL                time.Duration // how long schema and table listings are reused; 0 (default) disables caching
+	FKInferenceCacheTTL           time.Duration // how long the PK index for FK inference is reused; 0 disables caching
+	FKInferenceScope              string        // "schema" (default) or "all": where inferred FK targets may live
+	FKInferenceHighConfidenceOnly bool          // drop "medium" confidence inferred FKs
+	FKInferenceMax                int           // most inferred FKs reported per table, high confidence first; 0 (default) is unlimited
+	TableGrowthSnapshot           string        // file holding the table_growth snapshot; empty disables the tool
+	DescribeIncludeSamples        bool          // fetch sample rows in describe_table (default true)
+	DescribeIncludeIndexUsage     bool          // fetch index usage statistics in describe_table (default true)
+	DescribeMaxConcurrency        int           // most describe_table and lint_schema calls, and schema_graph describes, at once; 0 (default) is unlimited
+	RowEstimateSampling           bool          // estimate rows of never-analyzed tables with TABLESAMPLE in describe_table
+	ResolveAmbiguous              bool          // describe the first schema's table when a name without a schema matches several
+	ServerInfoTablespaces         bool          // list tablespaces and their sizes in server_info
+
+	// MCP resources.
+	TableResources          bool // expose each table as an MCP resource
+	ResourcesFromPolicyOnly bool // only expose tables listed in the policy's context.tables (implies TableResources)
 
 	// Logging.
 	LogLevel slog.Level
 
 	// Transport.
-	Transport       string // "stdio" (default) or "http"
-	HTTPAddr        string // listen address for HTTP transport (default ":8080")
-	HTTPBearerToken string // required when transport=http
+	Transport       string        // "stdio" (default) or "http"
+	HTTPAddr        string        // listen address for HTTP transport (default ":8080")
+	HTTPBearerToken string        // required when transport=http
+	PageTokenKey    string        // signs query page tokens; a random key per process when empty
+	IdleTimeout     time.Duration // stdio only: shut down after this long without a tool call; 0 (default) disables it
 
 	// Connection pool.
-	PoolMaxConns        int32         // default: 5
-	PoolMinConns        int32         // default: 1
-	PoolMaxConnLifetime time.Duration // default: 30m
+	PoolMaxConns          int32         // default: 5
+	PoolMinConns          int32         // default: 1
+	PoolMaxConnLifetime   time.Duration // default: 30m
+	PoolHealthCheckPeriod time.Duration // default: 30s
+	ConnectSetupSQL       string        // SET statements run on every new connection
 
 	// Observability.
-	OTelEnabled bool // enable OpenTelemetry tracing and metrics
+	OTelEnabled  bool   // enable OpenTelemetry tracing and metrics
+	OTelCAFile   string // PEM CA bundle to verify the OTLP collector with; empty uses the system roots
+	OTelInsecure bool   // talk to the OTLP collector without TLS, e.g. a local one
+	AuditSink    string // "file" (default, needs --audit-log) or "stderr"
 
 	// CLI-only fields (not settable via env vars).
 	DryRun      bool
+	DumpConfig  bool // print the resolved config as JSON and exit
 	ExplainOnly bool
 	AuditLog    string // path to NDJSON audit log file
 }
@@ -55,6 +117,7 @@ type Overrides struct {
 	HTTPBearerToken *string
 	OTelEnabled     bool
 	DryRun          bool
+	DumpConfig      bool
 	ExplainOnly     bool
 	AuditLog        string
 
@@ -75,6 +138,23 @@ func Load(overrides Overrides) (*Config, error) {
 	if err := applyOverrides(cfg, overrides); err != nil {
 		return nil, err
 	}
+	// DATABASE_URL (or --database-url) wins; otherwise fall back to the
+	// discrete libpq variables some orchestrators provide.
+	if cfg.DatabaseURL == "" {
+		dsn, err := dsnFromPGEnv()
+		if err != nil {
+			return nil, err
+		}
+		cfg.DatabaseURL = dsn
+	}
+	// The ceiling defaults to the (possibly overridden) MaxRows.
+	if cfg.MaxRowsCeiling == 0 {
+		cfg.MaxRowsCeiling = cfg.MaxRows
+	}
+	// Likewise for the timeout ceiling and the (possibly overridden) QueryTimeout.
+	if cfg.QueryTimeoutMax == 0 {
+		cfg.QueryTimeoutMax = cfg.QueryTimeout
+	}
 	if err := validate(cfg); err != nil {
 		return nil, err
 	}
@@ -85,15 +165,25 @@ func Load(overrides Overrides) (*Config, error) {
 // defaults returns a Config populated with default values.
 func defaults() *Config {
 	return &Config{
-		DatabaseURL:         os.Getenv("DATABASE_URL"),
-		ReadOnly:            true,
-		MaxRows:             100,
-		QueryTimeout:        10 * time.Second,
-		Transport:           "stdio",
-		HTTPAddr:            ":8080",
-		PoolMaxConns:        5,
-		PoolMinConns:        1,
-		PoolMaxConnLifetime: 30 * time.Minute,
+		DatabaseURL:               os.Getenv("DATABASE_URL"),
+		ReadOnly:                  true,
+		MaxRows:                   100,
+		QueryTimeout:              10 * time.Second,
+		QueryTimeoutMin:           100 * time.Millisecond,
+		ResultKeyCase:             "original",
+		DuplicateColumns:          "error",
+		FKInferenceCacheTTL:       5 * time.Minute,
+		FKInferenceScope:          "schema",
+		PolicyStrict:              "off",
+		DescribeIncludeSamples:    true,
+		DescribeIncludeIndexUsage: true,
+		Transport:                 "stdio",
+		HTTPAddr:                  ":8080",
+		PoolMaxConns:              5,
+		PoolMinConns:              1,
+		PoolMaxConnLifetime:       30 * time.Minute,
+		PoolHealthCheckPeriod:     30 * time.Second,
+		AuditSink:                 "file",
 	}
 }
 
@@ -115,6 +205,22 @@ func loadEnvVars(cfg *Config) error {
 		cfg.MaxRows = n
 	}
 
+	if v := os.Getenv("MAX_ROWS_CEILING"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid MAX_ROWS_CEILING value %q: must be a positive integer", v)
+		}
+		cfg.MaxRowsCeiling = n
+	}
+
+	if v := os.Getenv("MAX_RESULT_MEMORY"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid MAX_RESULT_MEMORY value %q: must be a non-negative integer", v)
+		}
+		cfg.MaxResultMemory = n
+	}
+
 	if v := os.Getenv("QUERY_TIMEOUT"); v != "" {
 		d, err := time.ParseDuration(v)
 		if err != nil {
@@ -123,6 +229,103 @@ func loadEnvVars(cfg *Config) error {
 		cfg.QueryTimeout = d
 	}
 
+	if v := os.Getenv("QUERY_TIMEOUT_MIN"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid QUERY_TIMEOUT_MIN value %q: must be a non-negative duration", v)
+		}
+		cfg.QueryTimeoutMin = d
+	}
+
+	if v := os.Getenv("QUERY_TIMEOUT_MAX"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid QUERY_TIMEOUT_MAX value %q: must be a positive duration", v)
+		}
+		cfg.QueryTimeoutMax = d
+	}
+
+	if v := os.Getenv("LOG_PG_ERROR_DETAIL"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid LOG_PG_ERROR_DETAIL value %q: %w", v, err)
+		}
+		cfg.LogPgErrorDetail = b
+	}
+
+	if v := os.Getenv("EXPLAIN_REDACT_LITERALS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid EXPLAIN_REDACT_LITERALS value %q: %w", v, err)
+		}
+		cfg.ExplainRedactLiterals = b
+	}
+
+	if v := os.Getenv("ALLOW_MULTI_STATEMENT"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ALLOW_MULTI_STATEMENT value %q: %w", v, err)
+		}
+		cfg.AllowMultiStatement = b
+	}
+
+	if v := os.Getenv("FUNCTION_CHECK"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid FUNCTION_CHECK value %q: %w", v, err)
+		}
+		cfg.FunctionCheck = b
+	}
+
+	if v := os.Getenv("BLOCKED_FUNCTIONS"); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				cfg.BlockedFunctions = append(cfg.BlockedFunctions, name)
+			}
+		}
+	}
+
+	if v := os.Getenv("ROLLBACK_ALWAYS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ROLLBACK_ALWAYS value %q: %w", v, err)
+		}
+		cfg.RollbackAlways = b
+	}
+
+	if v := os.Getenv("LIMIT_INJECTION"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid LIMIT_INJECTION value %q: %w", v, err)
+		}
+		cfg.LimitInjection = b
+	}
+
+	if v := os.Getenv("STRICT_EXPLAIN"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid STRICT_EXPLAIN value %q: %w", v, err)
+		}
+		cfg.StrictExplain = b
+	}
+
+	if v := os.Getenv("PLAN_HINTS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid PLAN_HINTS value %q: %w", v, err)
+		}
+		cfg.PlanHints = b
+	}
+
+	if v := os.Getenv("ANALYZE_MAX_COST"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return fmt.Errorf("invalid ANALYZE_MAX_COST value %q: must be a non-negative number", v)
+		}
+		cfg.AnalyzeMaxCost = f
+	}
+
 	if v := os.Getenv("LOG_LEVEL"); v != "" {
 		level, err := parseLogLevel(v)
 		if err != nil {
@@ -140,7 +343,182 @@ func loadEnvVars(cfg *Config) error {
 		}
 	}
 
+	if v := os.Getenv("HIDDEN_SCHEMAS"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			s = strings.TrimSpace(s)
+			if s != "" {
+				cfg.HiddenSchemas = append(cfg.HiddenSchemas, s)
+			}
+		}
+	}
+
+	if v := os.Getenv("ENFORCE_SCHEMAS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ENFORCE_SCHEMAS value %q: %w", v, err)
+		}
+		cfg.EnforceSchemas = b
+	}
+
+	if v := os.Getenv("RESULT_KEY_CASE"); v != "" {
+		cfg.ResultKeyCase = strings.ToLower(strings.TrimSpace(v))
+	}
+
+	if v := os.Getenv("DUPLICATE_COLUMNS"); v != "" {
+		cfg.DuplicateColumns = strings.ToLower(strings.TrimSpace(v))
+	}
+
+	if v := os.Getenv("INCLUDE_TIMING"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid INCLUDE_TIMING value %q: %w", v, err)
+		}
+		cfg.IncludeTiming = b
+	}
+
+	if v := os.Getenv("INCLUDE_EXECUTED_SQL"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid INCLUDE_EXECUTED_SQL value %q: %w", v, err)
+		}
+		cfg.IncludeSQL = b
+	}
+
+	if v := os.Getenv("TOOL_CALL_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid TOOL_CALL_TIMEOUT value %q: must be a non-negative duration", v)
+		}
+		cfg.ToolCallTimeout = d
+	}
+
+	if v := os.Getenv("MAX_ARG_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid MAX_ARG_BYTES value %q: must be a non-negative integer", v)
+		}
+		cfg.MaxArgBytes = n
+	}
+
+	if v := os.Getenv("IDLE_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid IDLE_TIMEOUT value %q: must be a non-negative duration", v)
+		}
+		cfg.IdleTimeout = d
+	}
+
+	if v := os.Getenv("SCHEMA_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid SCHEMA_CACHE_TTL value %q: must be a non-negative duration", v)
+		}
+		cfg.SchemaCacheTTL = d
+	}
+
+	if v := os.Getenv("FK_INFERENCE_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid FK_INFERENCE_CACHE_TTL value %q: must be a non-negative duration", v)
+		}
+		cfg.FKInferenceCacheTTL = d
+	}
+
+	if v := os.Getenv("FK_INFERENCE_SCOPE"); v != "" {
+		cfg.FKInferenceScope = strings.ToLower(strings.TrimSpace(v))
+	}
+
+	if v := os.Getenv("FK_INFERENCE_HIGH_CONFIDENCE_ONLY"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid FK_INFERENCE_HIGH_CONFIDENCE_ONLY value %q: %w", v, err)
+		}
+		cfg.FKInferenceHighConfidenceOnly = b
+	}
+
+	if v := os.Getenv("FK_INFERENCE_MAX"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid FK_INFERENCE_MAX value %q: must be a non-negative integer", v)
+		}
+		cfg.FKInferenceMax = n
+	}
+
+	if v := os.Getenv("DESCRIBE_INCLUDE_SAMPLES"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid DESCRIBE_INCLUDE_SAMPLES value %q: %w", v, err)
+		}
+		cfg.DescribeIncludeSamples = b
+	}
+
+	if v := os.Getenv("DESCRIBE_INCLUDE_INDEX_USAGE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid DESCRIBE_INCLUDE_INDEX_USAGE value %q: %w", v, err)
+		}
+		cfg.DescribeIncludeIndexUsage = b
+	}
+
+	if v := os.Getenv("DESCRIBE_MAX_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid DESCRIBE_MAX_CONCURRENCY value %q: must be a non-negative integer", v)
+		}
+		cfg.DescribeMaxConcurrency = n
+	}
+
+	if v := os.Getenv("ROW_ESTIMATE_SAMPLING"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ROW_ESTIMATE_SAMPLING value %q: %w", v, err)
+		}
+		cfg.RowEstimateSampling = b
+	}
+
+	if v := os.Getenv("RESOLVE_AMBIGUOUS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid RESOLVE_AMBIGUOUS value %q: %w", v, err)
+		}
+		cfg.ResolveAmbiguous = b
+	}
+
+	if v := os.Getenv("SERVER_INFO_TABLESPACES"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid SERVER_INFO_TABLESPACES value %q: %w", v, err)
+		}
+		cfg.ServerInfoTablespaces = b
+	}
+
+	if v := os.Getenv("TABLE_RESOURCES"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid TABLE_RESOURCES value %q: %w", v, err)
+		}
+		cfg.TableResources = b
+	}
+
+	if v := os.Getenv("RESOURCES_FROM_POLICY_ONLY"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid RESOURCES_FROM_POLICY_ONLY value %q: %w", v, err)
+		}
+		cfg.ResourcesFromPolicyOnly = b
+	}
+
 	cfg.PolicyFile = os.Getenv("POLICY_FILE")
+	cfg.PolicyDir = os.Getenv("POLICY_DIR")
+	cfg.ContextFile = os.Getenv("CONTEXT_FILE")
+
+	if v := os.Getenv("POLICY_STRICT"); v != "" {
+		cfg.PolicyStrict = strings.ToLower(strings.TrimSpace(v))
+	}
+
+	cfg.TableGrowthSnapshot = os.Getenv("TABLE_GROWTH_SNAPSHOT")
+	cfg.DialerProxy = os.Getenv("DB_DIALER_PROXY")
+	cfg.ReplicaDatabaseURL = os.Getenv("REPLICA_DATABASE_URL")
 
 	if v := os.Getenv("TRANSPORT"); v != "" {
 		cfg.Transport = v
@@ -149,6 +527,7 @@ func loadEnvVars(cfg *Config) error {
 		cfg.HTTPAddr = v
 	}
 	cfg.HTTPBearerToken = os.Getenv("HTTP_BEARER_TOKEN")
+	cfg.PageTokenKey = os.Getenv("PAGE_TOKEN_KEY")
 
 	if v := os.Getenv("OTEL_ENABLED"); v != "" {
 		b, err := strconv.ParseBool(v)
@@ -157,6 +536,18 @@ func loadEnvVars(cfg *Config) error {
 		}
 		cfg.OTelEnabled = b
 	}
+	cfg.OTelCAFile = os.Getenv("OTEL_EXPORTER_OTLP_CERTIFICATE")
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_INSECURE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid OTEL_EXPORTER_OTLP_INSECURE value %q: %w", v, err)
+		}
+		cfg.OTelInsecure = b
+	}
+
+	if v := os.Getenv("AUDIT_SINK"); v != "" {
+		cfg.AuditSink = strings.ToLower(strings.TrimSpace(v))
+	}
 
 	if err := loadPoolEnvVars(cfg); err != nil {
 		return err
@@ -188,6 +579,14 @@ func loadPoolEnvVars(cfg *Config) error {
 		}
 		cfg.PoolMaxConnLifetime = d
 	}
+	if v := os.Getenv("POOL_HEALTH_CHECK_PERIOD"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid POOL_HEALTH_CHECK_PERIOD value %q: must be a positive duration", v)
+		}
+		cfg.PoolHealthCheckPeriod = d
+	}
+	cfg.ConnectSetupSQL = strings.TrimSpace(os.Getenv("CONNECT_SETUP_SQL"))
 	return nil
 }
 
@@ -230,6 +629,7 @@ func applyOverrides(cfg *Config, o Overrides) error {
 	}
 
 	cfg.DryRun = o.DryRun
+	cfg.DumpConfig = o.DumpConfig
 	cfg.ExplainOnly = o.ExplainOnly
 	cfg.AuditLog = o.AuditLog
 	cfg.OTelEnabled = cfg.OTelEnabled || o.OTelEnabled
@@ -260,7 +660,7 @@ func applyPoolOverrides(cfg *Config, o Overrides) error {
 // validate checks cross-field constraints on the final config.
 func validate(cfg *Config) error {
 	if cfg.DatabaseURL == "" {
-		return fmt.Errorf("DATABASE_URL is required (set via env var or --database-url flag)")
+		return fmt.Errorf("DATABASE_URL is required (set via env var or --database-url flag, or set PGHOST/PGUSER/PGDATABASE)")
 	}
 
 	switch cfg.Transport {
@@ -269,10 +669,95 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("invalid TRANSPORT value %q: must be \"stdio\" or \"http\"", cfg.Transport)
 	}
 
+	if cfg.EnforceSchemas {
+		visible := 0
+		for _, s := range cfg.Schemas {
+			if !slices.Contains(cfg.HiddenSchemas, s) {
+				visible++
+			}
+		}
+		if visible == 0 {
+			return fmt.Errorf("ENFORCE_SCHEMAS requires SCHEMAS to list the schemas queries may use, outside HIDDEN_SCHEMAS")
+		}
+	}
+
 	if cfg.Transport == "http" && cfg.HTTPBearerToken == "" {
 		return fmt.Errorf("HTTP_BEARER_TOKEN is required when transport is \"http\" (set via env var or --http-bearer-token flag)")
 	}
 
+	if cfg.Transport == "http" && cfg.IdleTimeout > 0 {
+		return fmt.Errorf("IDLE_TIMEOUT only applies to the stdio transport")
+	}
+
+	if cfg.OTelEnabled && cfg.OTelCAFile != "" {
+		if cfg.OTelInsecure {
+			return fmt.Errorf("OTEL_EXPORTER_OTLP_CERTIFICATE cannot be combined with OTEL_EXPORTER_OTLP_INSECURE")
+		}
+		if _, err := os.Stat(cfg.OTelCAFile); err != nil {
+			return fmt.Errorf("invalid OTEL_EXPORTER_OTLP_CERTIFICATE value: %w", err)
+		}
+	}
+
+	if cfg.MaxRowsCeiling < cfg.MaxRows {
+		return fmt.Errorf("MAX_ROWS_CEILING (%d) must not be lower than MAX_ROWS (%d)", cfg.MaxRowsCeiling, cfg.MaxRows)
+	}
+
+	if cfg.QueryTimeout <= 0 {
+		return fmt.Errorf("QUERY_TIMEOUT (%s) must be positive", cfg.QueryTimeout)
+	}
+	if cfg.QueryTimeout > cfg.QueryTimeoutMax {
+		return fmt.Errorf("QUERY_TIMEOUT (%s) must not exceed QUERY_TIMEOUT_MAX (%s)", cfg.QueryTimeout, cfg.QueryTimeoutMax)
+	}
+	if cfg.QueryTimeout < cfg.QueryTimeoutMin {
+		return fmt.Errorf("QUERY_TIMEOUT (%s) must not be lower than QUERY_TIMEOUT_MIN (%s)", cfg.QueryTimeout, cfg.QueryTimeoutMin)
+	}
+
+	switch cfg.ResultKeyCase {
+	case "original", "snake", "camel":
+	default:
+		return fmt.Errorf("invalid RESULT_KEY_CASE value %q: must be \"original\", \"snake\", or \"camel\"", cfg.ResultKeyCase)
+	}
+
+	switch cfg.DuplicateColumns {
+	case "error", "suffix":
+	default:
+		return fmt.Errorf("invalid DUPLICATE_COLUMNS value %q: must be \"error\" or \"suffix\"", cfg.DuplicateColumns)
+	}
+
+	switch cfg.AuditSink {
+	case "file":
+	case "stderr":
+		if cfg.AuditLog != "" {
+			return fmt.Errorf("AUDIT_SINK=stderr cannot be combined with --audit-log")
+		}
+	case "stdout":
+		return fmt.Errorf("invalid AUDIT_SINK value %q: stdout is reserved for the stdio transport, use \"stderr\"", cfg.AuditSink)
+	default:
+		return fmt.Errorf("invalid AUDIT_SINK value %q: must be \"file\" or \"stderr\"", cfg.AuditSink)
+	}
+
+	switch cfg.FKInferenceScope {
+	case "schema", "all":
+	default:
+		return fmt.Errorf("invalid FK_INFERENCE_SCOPE value %q: must be \"schema\" or \"all\"", cfg.FKInferenceScope)
+	}
+
+	switch cfg.PolicyStrict {
+	case "off", "warn", "error":
+	default:
+		return fmt.Errorf("invalid POLICY_STRICT value %q: must be \"off\", \"warn\" or \"error\"", cfg.PolicyStrict)
+	}
+
+	if cfg.DialerProxy != "" {
+		if err := validateDialerProxy(cfg.DialerProxy); err != nil {
+			return err
+		}
+	}
+
+	if cfg.ResourcesFromPolicyOnly && cfg.PolicyFile == "" && cfg.PolicyDir == "" {
+		return fmt.Errorf("RESOURCES_FROM_POLICY_ONLY requires POLICY_FILE or POLICY_DIR")
+	}
+
 	if cfg.PoolMinConns > cfg.PoolMaxConns {
 		return fmt.Errorf("POOL_MIN_CONNS (%d) must not exceed POOL_MAX_CONNS (%d)", cfg.PoolMinConns, cfg.PoolMaxConns)
 	}
@@ -280,6 +765,27 @@ func validate(cfg *Config) error {
 	return nil
 }
 
+// validateDialerProxy checks that the proxy URL is a usable SOCKS5 endpoint.
+func validateDialerProxy(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		// *url.Error quotes the whole URL, credentials included; keep only
+		// the reason.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("invalid DB_DIALER_PROXY value: %w", err)
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return fmt.Errorf("invalid DB_DIALER_PROXY scheme %q in %s: must be \"socks5\" or \"socks5h\"", u.Scheme, u.Redacted())
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid DB_DIALER_PROXY value %s: missing host", u.Redacted())
+	}
+	return nil
+}
+
 func parseLogLevel(s string) (slog.Level, error) {
 	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "debug":
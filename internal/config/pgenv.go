@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgEnvVars are the libpq connection variables that trigger building a DSN
+// when DATABASE_URL is not set.
+var pgEnvVars = []string{"PGHOST", "PGPORT", "PGUSER", "PGPASSWORD", "PGDATABASE"}
+
+// pgEnvParams maps optional libpq variables to their connection-string
+// parameter names. They are only read alongside pgEnvVars.
+var pgEnvParams = []struct{ env, param string }{
+	{"PGSSLMODE", "sslmode"},
+	{"PGCONNECT_TIMEOUT", "connect_timeout"},
+	{"PGAPPNAME", "application_name"},
+}
+
+// dsnFromPGEnv builds a postgres:// URL from the discrete libpq environment
+// variables. It returns "" when none of PGHOST, PGPORT, PGUSER, PGPASSWORD
+// or PGDATABASE is set. Unset variables are left out so the driver applies
+// the usual libpq defaults (localhost, port 5432, the OS user, and a
+// database named after the user).
+func dsnFromPGEnv() (string, error) {
+	set := false
+	for _, name := range pgEnvVars {
+		if os.Getenv(name) != "" {
+			set = true
+			break
+		}
+	}
+	if !set {
+		return "", nil
+	}
+
+	u := &url.URL{Scheme: "postgres"}
+	q := url.Values{}
+
+	host := os.Getenv("PGHOST")
+	port := os.Getenv("PGPORT")
+	if port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("invalid PGPORT value %q: must be a port number", port)
+		}
+	}
+	switch {
+	case strings.HasPrefix(host, "/"):
+		// A leading slash names a Unix-socket directory, which cannot be
+		// expressed in the URL authority.
+		q.Set("host", host)
+		if port != "" {
+			q.Set("port", port)
+		}
+	case strings.Contains(host, ","):
+		// Multiple hosts: libpq pairs PGPORT with every host.
+		hosts := strings.Split(host, ",")
+		ipv6 := false
+		for i, h := range hosts {
+			h = strings.TrimSpace(h)
+			if h == "" {
+				return "", fmt.Errorf("invalid PGHOST value %q: empty host in list", host)
+			}
+			hosts[i] = h
+			ipv6 = ipv6 || strings.Contains(h, ":")
+		}
+		if ipv6 {
+			// The driver cannot parse a bracketed address in a host list,
+			// so pass the list as parameters.
+			q.Set("host", strings.Join(hosts, ","))
+			if port != "" {
+				q.Set("port", port)
+			}
+			break
+		}
+		for i, h := range hosts {
+			hosts[i] = urlHost(h, port)
+		}
+		u.Host = strings.Join(hosts, ",")
+	default:
+		u.Host = urlHost(host, port)
+	}
+
+	user, password := os.Getenv("PGUSER"), os.Getenv("PGPASSWORD")
+	switch {
+	case password != "":
+		u.User = url.UserPassword(user, password)
+	case user != "":
+		u.User = url.User(user)
+	}
+
+	if db := os.Getenv("PGDATABASE"); db != "" {
+		u.Path = "/" + db
+	} else if u.Host == "" && u.User == nil {
+		// Keep the "//" that marks a URL to the driver, not key=value pairs.
+		u.Path = "/"
+	}
+
+	for _, p := range pgEnvParams {
+		if v := os.Getenv(p.env); v != "" {
+			q.Set(p.param, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	dsn := u.String()
+	if _, err := pgconn.ParseConfig(dsn); err != nil {
+		return "", fmt.Errorf("invalid connection string built from PG* env vars: %w", err)
+	}
+	return dsn, nil
+}
+
+// urlHost returns host, with port if set, as it goes in a URL authority:
+// an IPv6 address is bracketed, as in [::1]:5432.
+func urlHost(host, port string) string {
+	if port != "" {
+		return net.JoinHostPort(host, port)
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
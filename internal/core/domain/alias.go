@@ -1,6 +1,9 @@
 package domain
 
 import (
+	"slices"
+	"strings"
+
 	pg_query "github.com/pganalyze/pg_query_go/v6"
 )
 
@@ -8,66 +11,245 @@ import (
 // original column name → alias for every column that uses an AS clause.
 // Only simple column references are considered (e.g. "Email" AS email,
 // c."Email" AS email). Expressions are skipped because they won't match
-// any mask key. Returns an empty map on parse error (fail-open to
+// any mask key. For a batch, the last statement is used, since its rows
+// are the result. Returns an empty map on parse error (fail-open to
 // preserve current behavior).
 func ExtractAliasMap(sql string) map[string]string {
-	aliases := make(map[string]string)
-
-	tree, err := pg_query.Parse(sql)
-	if err != nil || len(tree.Stmts) == 0 {
-		return aliases
+	q, err := ParseQuery(sql)
+	if err != nil {
+		return make(map[string]string)
 	}
+	return q.AliasMap()
+}
 
-	stmt := tree.Stmts[0].Stmt
-	if stmt == nil {
-		return aliases
-	}
+// AliasMap returns the aliases of the query's result columns, as described
+// at ExtractAliasMap.
+func (q *ParsedQuery) AliasMap() map[string]string {
+	aliases := make(map[string]string)
 
-	sel, ok := stmt.Node.(*pg_query.Node_SelectStmt)
-	if !ok {
+	sel := q.lastStmt().GetSelectStmt()
+	if sel == nil {
 		return aliases
 	}
 
-	for _, target := range sel.SelectStmt.TargetList {
-		rt, ok := target.Node.(*pg_query.Node_ResTarget)
-		if !ok || rt.ResTarget == nil {
+	for _, target := range sel.TargetList {
+		rt := target.GetResTarget()
+		if rt == nil {
 			continue
 		}
 
-		alias := rt.ResTarget.Name
+		alias := rt.Name
 		if alias == "" {
 			continue // no alias on this target
 		}
 
-		val := rt.ResTarget.Val
-		if val == nil {
-			continue
-		}
-
-		cr, ok := val.Node.(*pg_query.Node_ColumnRef)
-		if !ok || cr.ColumnRef == nil {
+		cr := rt.Val.GetColumnRef()
+		if cr == nil {
 			continue // not a simple column reference (expression, etc.)
 		}
 
 		// Extract the bare column name from the last field of the ColumnRef.
 		// For "Email" → Fields = [String{Sval:"Email"}]
 		// For c."Email" → Fields = [String{Sval:"c"}, String{Sval:"Email"}]
-		fields := cr.ColumnRef.Fields
-		if len(fields) == 0 {
-			continue
+		colName := columnRefName(cr)
+		if colName != "" && colName != alias {
+			aliases[colName] = alias
 		}
+	}
+
+	return aliases
+}
+
+// ExtractDerivedColumns parses a SQL SELECT statement and returns, for every
+// result column computed from other columns, the result name and the columns
+// it reads, e.g. "max" → ["ssn"] for SELECT max(ssn). Such columns can reveal
+// the values of a masked column: min and max give away the boundary values,
+// lower(email), ssn || '-', CASE WHEN ... THEN ssn END or a scalar subquery
+// over ssn the values themselves. Every target other than a bare column
+// reference counts, except a top-level count, which reveals no values. The
+// result name is the alias, or the name PostgreSQL gives an unaliased column
+// ("?column?" for an operator). Columns sharing a result name have their
+// sources merged. For a batch, the last statement is used. Returns an empty
+// map on parse error.
+//
+// Sources are traced through subqueries in FROM and CTEs to the columns they
+// rename, so SELECT max(s) FROM (SELECT ssn AS s FROM users) q reads ssn, and
+// so does a bare s selected from there. A whole-row reference, such as
+// to_jsonb(u.*) or row_to_json(u), reads every column of its table, which is
+// not known here; it is given the source WholeRow.
+func ExtractDerivedColumns(sql string) map[string][]string {
+	q, err := ParseQuery(sql)
+	if err != nil {
+		return make(map[string][]string)
+	}
+	return q.DerivedColumns()
+}
 
-		lastField := fields[len(fields)-1]
-		str, ok := lastField.Node.(*pg_query.Node_String_)
-		if !ok || str.String_ == nil {
+// DerivedColumns returns the query's computed result columns and the
+// columns each reads, as described at ExtractDerivedColumns.
+func (q *ParsedQuery) DerivedColumns() map[string][]string {
+	derived := make(map[string][]string)
+	add := func(name string, sources []string) {
+		sources = append(sources, derived[name]...)
+		slices.Sort(sources)
+		derived[name] = slices.Compact(sources)
+	}
+
+	branches, scopes := branchScopes(q.lastStmt().GetSelectStmt(), nil)
+	if len(branches) == 0 {
+		return derived
+	}
+	for i, rt := range resTargets(branches[0]) {
+		if rt.Val == nil {
 			continue
 		}
+		// A star passes the columns of its relations through under their
+		// own names; those of a subquery or CTE may rename other columns.
+		if cr := rt.Val.GetColumnRef(); cr != nil && isStar(cr) {
+			for _, rel := range scopes[0].starRelations(cr) {
+				for name, sources := range rel.columns {
+					if !slices.Equal(sources, []string{name}) {
+						add(name, slices.Clone(sources))
+					}
+				}
+			}
+			continue
+		}
+		name := rt.Name
+		if name == "" {
+			name, _ = figureColname(rt.Val)
+		}
+		// A bare column is masked by its own name or alias. In a set
+		// operation the result carries every branch's values under the first
+		// branch's name, so a later branch reading another column counts too.
+		var column string
+		if rt.Val.GetColumnRef() != nil {
+			column, _ = figureColname(rt.Val)
+		}
+		var sources []string
+		isDerived := false
+		for b, branch := range branches {
+			targets := resTargets(branch)
+			if i >= len(targets) {
+				continue
+			}
+			v := targets[i].Val
+			if v == nil || isCount(v) {
+				continue
+			}
+			refs := scopes[b].sources(v)
+			if v.GetColumnRef() != nil && slices.Equal(refs, []string{column}) {
+				continue
+			}
+			isDerived = true
+			sources = append(sources, refs...)
+		}
+		if !isDerived || len(sources) == 0 {
+			continue
+		}
+		add(name, sources)
+	}
+	return derived
+}
 
-		colName := str.String_.Sval
-		if colName != "" && colName != alias {
-			aliases[colName] = alias
+// resTargets returns the ResTarget nodes of a SELECT's target list. A set
+// operation has none of its own; its columns are named by its first branch.
+func resTargets(sel *pg_query.SelectStmt) []*pg_query.ResTarget {
+	for sel != nil && sel.Larg != nil {
+		sel = sel.Larg
+	}
+	if sel == nil {
+		return nil
+	}
+	targets := make([]*pg_query.ResTarget, 0, len(sel.TargetList))
+	for _, node := range sel.TargetList {
+		if rt := node.GetResTarget(); rt != nil {
+			targets = append(targets, rt)
 		}
 	}
+	return targets
+}
 
-	return aliases
+// isCount reports whether expr is a count call, under any casts.
+func isCount(expr *pg_query.Node) bool {
+	for expr.GetTypeCast() != nil {
+		expr = expr.GetTypeCast().Arg
+	}
+	fc := expr.GetFuncCall()
+	if fc == nil {
+		return false
+	}
+	name := funcCallName(fc)
+	return name[strings.LastIndex(name, ".")+1:] == "count"
+}
+
+// figureColname returns the name PostgreSQL gives an unaliased result
+// column, following FigureColname in its parser: a column reference or
+// function is named after itself, a cast after its argument when that has a
+// name of its own and otherwise after the type, and anything without a
+// name is "?column?". strength ranks how definite the name is, as there.
+func figureColname(expr *pg_query.Node) (name string, strength int) {
+	switch n := expr.GetNode().(type) {
+	case *pg_query.Node_ColumnRef:
+		if name := columnRefName(n.ColumnRef); name != "" {
+			return name, 2
+		}
+	case *pg_query.Node_TypeCast:
+		if name, strength := figureColname(n.TypeCast.Arg); strength > 1 {
+			return name, strength
+		}
+		if names := n.TypeCast.GetTypeName().GetNames(); len(names) > 0 {
+			if sval := names[len(names)-1].GetString_().GetSval(); sval != "" {
+				return sval, 1
+			}
+		}
+	case *pg_query.Node_FuncCall:
+		name := funcCallName(n.FuncCall)
+		return name[strings.LastIndex(name, ".")+1:], 2
+	case *pg_query.Node_AExpr:
+		if n.AExpr.Kind == pg_query.A_Expr_Kind_AEXPR_NULLIF {
+			return "nullif", 2
+		}
+	case *pg_query.Node_SubLink:
+		switch n.SubLink.SubLinkType {
+		case pg_query.SubLinkType_EXISTS_SUBLINK:
+			return "exists", 2
+		case pg_query.SubLinkType_ARRAY_SUBLINK:
+			return "array", 2
+		case pg_query.SubLinkType_EXPR_SUBLINK:
+			if targets := resTargets(n.SubLink.Subselect.GetSelectStmt()); len(targets) > 0 {
+				if targets[0].Name != "" {
+					return targets[0].Name, 2
+				}
+				if targets[0].Val != nil {
+					if name, strength := figureColname(targets[0].Val); strength > 0 {
+						return name, strength
+					}
+				}
+			}
+		}
+	case *pg_query.Node_CaseExpr:
+		return "case", 1
+	case *pg_query.Node_CoalesceExpr:
+		return "coalesce", 2
+	case *pg_query.Node_MinMaxExpr:
+		if n.MinMaxExpr.Op == pg_query.MinMaxOp_IS_LEAST {
+			return "least", 2
+		}
+		return "greatest", 2
+	case *pg_query.Node_AArrayExpr:
+		return "array", 2
+	case *pg_query.Node_RowExpr:
+		return "row", 2
+	}
+	return "?column?", 0
+}
+
+// columnRefName returns the last field of a column reference: the column's
+// bare name, or "" for a star.
+func columnRefName(cr *pg_query.ColumnRef) string {
+	if len(cr.Fields) == 0 {
+		return ""
+	}
+	return cr.Fields[len(cr.Fields)-1].GetString_().GetSval()
 }
@@ -18,6 +18,12 @@ func TestExtractAliasMap_TableQualified(t *testing.T) {
 	assert.Equal(t, map[string]string{"Email": "email"}, aliases)
 }
 
+func TestExtractAliasMap_BatchUsesLastStatement(t *testing.T) {
+	t.Parallel()
+	aliases := ExtractAliasMap(`SELECT 1 AS one; SELECT "Email" AS email FROM "Customer"`)
+	assert.Equal(t, map[string]string{"Email": "email"}, aliases)
+}
+
 func TestExtractAliasMap_NoAlias(t *testing.T) {
 	t.Parallel()
 	aliases := ExtractAliasMap(`SELECT "Email" FROM "Customer"`)
@@ -80,3 +86,59 @@ func TestExtractAliasMap_MultipleTableQualified(t *testing.T) {
 		"LastName":  "last_name",
 	}, aliases)
 }
+
+func TestExtractDerivedColumns(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		sql  string
+		want map[string][]string
+	}{
+		{"aggregate", "SELECT min(ssn) FROM users", map[string][]string{"min": {"ssn"}}},
+		{"aggregate over a subquery alias", "SELECT max(s) FROM (SELECT ssn AS s FROM users) q", map[string][]string{"max": {"ssn"}}},
+		{"qualified subquery alias", "SELECT lower(q.s) AS l FROM (SELECT email AS s FROM users) q", map[string][]string{"l": {"email"}}},
+		{"bare subquery alias", "SELECT s FROM (SELECT ssn AS s FROM users) q", map[string][]string{"s": {"ssn"}}},
+		{"subquery column list", "SELECT s FROM (SELECT ssn FROM users) q(s)", map[string][]string{"s": {"ssn"}}},
+		{"star over a subquery", "SELECT * FROM (SELECT id, ssn AS s FROM users) q", map[string][]string{"s": {"ssn"}}},
+		{"cte alias", "WITH c AS (SELECT ssn AS s FROM users) SELECT max(s) FROM c", map[string][]string{"max": {"ssn"}}},
+		{"cte column list", "WITH c(s) AS (SELECT ssn FROM users) SELECT s FROM c", map[string][]string{"s": {"ssn"}}},
+		{"nested subqueries", "SELECT upper(t) FROM (SELECT s AS t FROM (SELECT ssn AS s FROM users) a) b", map[string][]string{"upper": {"ssn"}}},
+		{"union in a subquery", "SELECT max(s) FROM (SELECT id AS s FROM a UNION ALL SELECT ssn FROM b) q", map[string][]string{"max": {"id", "ssn"}}},
+		{"whole row star", "SELECT to_jsonb(u.*) AS j FROM users u", map[string][]string{"j": {WholeRow}}},
+		{"whole row reference", "SELECT row_to_json(u) FROM users u", map[string][]string{"row_to_json": {WholeRow, "u"}}},
+		{"whole row of a subquery", "SELECT row_to_json(q) FROM (SELECT ssn AS s FROM users) q", map[string][]string{"row_to_json": {"q", "ssn"}}},
+		{"bare whole row", "SELECT u FROM users u", map[string][]string{"u": {WholeRow, "u"}}},
+		{"table column list", "SELECT x FROM users u(x)", map[string][]string{"x": {WholeRow}}},
+		{"aliased aggregate", `SELECT max(u."Email") AS last_email FROM users u`, map[string][]string{"last_email": {"Email"}}},
+		{"nested functions", "SELECT upper(trim(email)) FROM users", map[string][]string{"upper": {"email"}}},
+		{"cast", "SELECT max(ssn)::text FROM users", map[string][]string{"max": {"ssn"}}},
+		{"coalesce", "SELECT coalesce(phone, email) FROM users", map[string][]string{"coalesce": {"email", "phone"}}},
+		{"greatest", "SELECT least(a, b), greatest(c, 1) FROM t", map[string][]string{"least": {"a", "b"}, "greatest": {"c"}}},
+		{"window function", "SELECT first_value(ssn) OVER (ORDER BY id) FROM users", map[string][]string{"first_value": {"id", "ssn"}}},
+		{"schema-qualified function", "SELECT pg_catalog.lower(email) FROM users", map[string][]string{"lower": {"email"}}},
+		{"count is not derived", "SELECT count(ssn), count(DISTINCT email) AS n FROM users", map[string][]string{}},
+		{"plain columns are not derived", "SELECT ssn, email AS e, u.id FROM users u", map[string][]string{}},
+		{"operator", "SELECT id + 1 FROM users", map[string][]string{"?column?": {"id"}}},
+		{"operator over an aggregate", "SELECT max(ssn) || '' AS x FROM users", map[string][]string{"x": {"ssn"}}},
+		{"unaliased operators merge", "SELECT ssn || '', email || '' FROM users", map[string][]string{"?column?": {"email", "ssn"}}},
+		{"case", "SELECT CASE WHEN true THEN ssn END FROM users", map[string][]string{"case": {"ssn"}}},
+		{"cast of case is named after the type", "SELECT CASE WHEN true THEN ssn END::text FROM users", map[string][]string{"text": {"ssn"}}},
+		{"cast of a column", "SELECT ssn::text AS s FROM users", map[string][]string{"s": {"ssn"}}},
+		{"scalar subquery", "SELECT (SELECT min(ssn) FROM t) FROM users", map[string][]string{"min": {"ssn"}}},
+		{"aliased scalar subquery", "SELECT (SELECT ssn FROM t LIMIT 1) AS s FROM users", map[string][]string{"s": {"ssn"}}},
+		{"array subquery", "SELECT ARRAY(SELECT ssn FROM t) FROM users", map[string][]string{"array": {"ssn"}}},
+		{"nullif", "SELECT nullif(ssn, '') FROM users", map[string][]string{"nullif": {"ssn"}}},
+		{"set operation named by the first branch", "SELECT upper(ssn) FROM a UNION SELECT email FROM b", map[string][]string{"upper": {"email", "ssn"}}},
+		{"set operation with another column in a later branch", "SELECT id FROM a UNION ALL SELECT ssn FROM b", map[string][]string{"id": {"ssn"}}},
+		{"set operation of the same column", "SELECT ssn FROM a UNION SELECT ssn FROM b", map[string][]string{}},
+		{"no column references", "SELECT now(), random()", map[string][]string{}},
+		{"batch uses last statement", "SELECT min(a) FROM t; SELECT max(ssn) FROM users", map[string][]string{"max": {"ssn"}}},
+		{"invalid SQL", "SELEC min(ssn)", map[string][]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, ExtractDerivedColumns(tt.sql))
+		})
+	}
+}
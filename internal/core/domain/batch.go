@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// SplitStatements returns the statements of sql as PostgreSQL's parser
+// separates them, trimmed and without the separating semicolons. Empty
+// statements are dropped.
+func SplitStatements(sql string) ([]string, error) {
+	return pg_query.SplitWithParser(sql, true)
+}
+
+// PrefixLastStatement inserts prefix before the last statement of sql, so
+// that e.g. "EXPLAIN " applies to the statement whose rows a batch returns
+// rather than to its first. For a single statement, or SQL that does not
+// parse, it returns prefix + sql.
+func PrefixLastStatement(sql, prefix string) string {
+	tree, err := pg_query.Parse(sql)
+	if err != nil || len(tree.Stmts) < 2 {
+		return prefix + sql
+	}
+	loc := int(tree.Stmts[len(tree.Stmts)-1].StmtLocation)
+	if loc <= 0 || loc > len(sql) {
+		return prefix + sql
+	}
+	// The location points just past the previous semicolon.
+	loc += len(sql[loc:]) - len(strings.TrimLeft(sql[loc:], " \t\r\n"))
+	return sql[:loc] + prefix + sql[loc:]
+}
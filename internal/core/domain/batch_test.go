@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{"single", "SELECT 1", []string{"SELECT 1"}},
+		{"trailing semicolon", "SELECT 1;", []string{"SELECT 1"}},
+		{"batch", "SET LOCAL work_mem = '64MB';\n  SELECT 1 ;", []string{"SET LOCAL work_mem = '64MB'", "SELECT 1"}},
+		{"semicolon in literal", "SELECT ';'; SELECT 2", []string{"SELECT ';'", "SELECT 2"}},
+		{"empty statements", ";;SELECT 1;;", []string{"SELECT 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := SplitStatements(tt.sql)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPrefixLastStatement(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{"single", "SELECT 1", "EXPLAIN SELECT 1"},
+		{"batch", "SET LOCAL work_mem = '64MB'; SELECT 1", "SET LOCAL work_mem = '64MB'; EXPLAIN SELECT 1"},
+		{"trailing semicolon", "SELECT 1; SELECT 2;", "SELECT 1; EXPLAIN SELECT 2;"},
+		{"unparseable", "SELEC 1; SELECT 2", "EXPLAIN SELEC 1; SELECT 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, PrefixLastStatement(tt.sql, "EXPLAIN "))
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// CanonicalizeForCache returns a cache key for sql under which semantically
+// identical queries collide. It is deliberately lighter than literal
+// normalization: keywords and unquoted identifiers are lowercased (Postgres
+// folds them anyway), comments and a trailing semicolon are dropped, and all
+// tokens are joined by a single space. String literals, numbers and quoted
+// identifiers are kept verbatim, so queries that differ only in their
+// constants still get distinct keys.
+func CanonicalizeForCache(sql string) (string, error) {
+	scan, err := pg_query.Scan(sql)
+	if err != nil {
+		return "", fmt.Errorf("scanning SQL: %w", err)
+	}
+
+	tokens := scan.GetTokens()
+	for len(tokens) > 0 && tokens[len(tokens)-1].Token == pg_query.Token_ASCII_59 {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	parts := make([]string, 0, len(tokens))
+	for _, tok := range tokens {
+		switch tok.Token {
+		case pg_query.Token_SQL_COMMENT, pg_query.Token_C_COMMENT:
+			continue
+		}
+		text := sql[tok.Start:tok.End]
+		if tok.KeywordKind != pg_query.KeywordKind_NO_KEYWORD ||
+			(tok.Token == pg_query.Token_IDENT && !strings.HasPrefix(text, `"`)) {
+			text = strings.ToLower(text)
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, " "), nil
+}
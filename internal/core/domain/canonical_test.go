@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalizeForCache_Equivalent(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"keyword case", "SELECT id FROM users", "select id from users"},
+		{"whitespace", "SELECT id\n  FROM   users\tWHERE id = 1", "SELECT id FROM users WHERE id = 1"},
+		{"unquoted identifier case", "SELECT ID FROM Users", "SELECT id FROM users"},
+		{"trailing semicolon", "SELECT 1;", "SELECT 1"},
+		{"comments", "SELECT 1 -- one\n/* block */", "SELECT 1"},
+		{"punctuation spacing", "SELECT count(*) FROM users", "SELECT count ( * ) FROM users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a, err := CanonicalizeForCache(tt.a)
+			require.NoError(t, err)
+			b, err := CanonicalizeForCache(tt.b)
+			require.NoError(t, err)
+			assert.Equal(t, a, b)
+		})
+	}
+}
+
+func TestCanonicalizeForCache_Distinct(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"string literal case", "SELECT * FROM users WHERE name = 'Alice'", "SELECT * FROM users WHERE name = 'alice'"},
+		{"numeric literal", "SELECT * FROM users WHERE id = 1", "SELECT * FROM users WHERE id = 2"},
+		{"quoted identifier case", `SELECT "Email" FROM users`, `SELECT "email" FROM users`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a, err := CanonicalizeForCache(tt.a)
+			require.NoError(t, err)
+			b, err := CanonicalizeForCache(tt.b)
+			require.NoError(t, err)
+			assert.NotEqual(t, a, b)
+		})
+	}
+}
+
+func TestCanonicalizeForCache_Output(t *testing.T) {
+	t.Parallel()
+	got, err := CanonicalizeForCache("SELECT \"Email\", Name\nFROM Public.Users WHERE note = 'Hi  There';")
+	require.NoError(t, err)
+	assert.Equal(t, `select "Email" , name from public . users where note = 'Hi  There'`, got)
+}
+
+func TestCanonicalizeForCache_Empty(t *testing.T) {
+	t.Parallel()
+	got, err := CanonicalizeForCache("   ")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// ExplainedWrite reports whether sql is a single EXPLAIN of a statement that
+// writes: anything but a plain SELECT, a SELECT INTO, or a SELECT with a
+// data-modifying WITH clause. kind names the explained statement, e.g.
+// "DELETE" or "WITH ... DELETE", and is empty when sql is not such an
+// EXPLAIN or does not parse. analyze reports whether the EXPLAIN runs the
+// statement; an ANALYZE option counts unless it is explicitly turned off.
+func ExplainedWrite(sql string) (kind string, analyze bool) {
+	tree, err := pg_query.Parse(sql)
+	if err != nil || len(tree.Stmts) != 1 {
+		return "", false
+	}
+	explain := tree.Stmts[0].Stmt.GetExplainStmt()
+	if explain == nil {
+		return "", false
+	}
+	for _, opt := range explain.Options {
+		if def := opt.GetDefElem(); def != nil && strings.EqualFold(def.Defname, "analyze") {
+			analyze = !defElemFalse(def)
+		}
+	}
+	return writeKind(explain.Query), analyze
+}
+
+// writeKind names the write stmt performs, or returns "" for a read.
+func writeKind(stmt *pg_query.Node) string {
+	if stmt == nil {
+		return ""
+	}
+	sel := stmt.GetSelectStmt()
+	if sel == nil {
+		return rejectStatement(stmt).Kind
+	}
+	if sel.IntoClause != nil {
+		return "SELECT INTO"
+	}
+	if sel.WithClause != nil {
+		for _, cte := range sel.WithClause.Ctes {
+			if kind := writeKind(cte.GetCommonTableExpr().GetCtequery()); kind != "" {
+				return "WITH ... " + kind
+			}
+		}
+	}
+	return ""
+}
+
+// defElemFalse reports whether an option is explicitly turned off, as in
+// (ANALYZE false), (ANALYZE off) or (ANALYZE 0).
+func defElemFalse(def *pg_query.DefElem) bool {
+	switch {
+	case def.Arg == nil:
+		return false
+	case def.Arg.GetString_() != nil:
+		switch strings.ToLower(def.Arg.GetString_().Sval) {
+		case "false", "off", "no", "0":
+			return true
+		}
+	case def.Arg.GetInteger() != nil:
+		return def.Arg.GetInteger().Ival == 0
+	case def.Arg.GetBoolean() != nil:
+		return !def.Arg.GetBoolean().Boolval
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainedWrite(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		sql     string
+		kind    string
+		analyze bool
+	}{
+		{"EXPLAIN SELECT id FROM t", "", false},
+		{"EXPLAIN ANALYZE SELECT id FROM t", "", true},
+		{"EXPLAIN (ANALYZE, FORMAT JSON) WITH x AS (SELECT 1) SELECT * FROM x", "", true},
+		{"EXPLAIN DELETE FROM t", "DELETE", false},
+		{"EXPLAIN ANALYZE UPDATE t SET a = 1", "UPDATE", true},
+		{"explain analyze insert into t values (1)", "INSERT", true},
+		{"EXPLAIN (ANALYZE false) DELETE FROM t", "DELETE", false},
+		{"EXPLAIN (ANALYZE off, COSTS) DELETE FROM t", "DELETE", false},
+		{"EXPLAIN (ANALYZE 0) DELETE FROM t", "DELETE", false},
+		{"EXPLAIN (ANALYZE true) DELETE FROM t", "DELETE", true},
+		{"EXPLAIN ANALYZE WITH d AS (DELETE FROM t RETURNING id) SELECT id FROM d", "WITH ... DELETE", true},
+		{"EXPLAIN ANALYZE SELECT id INTO new_t FROM t", "SELECT INTO", true},
+		{"EXPLAIN ANALYZE CREATE TABLE new_t AS SELECT id FROM t", "CREATE TABLE AS", true},
+	}
+	for _, tt := range tests {
+		kind, analyze := ExplainedWrite(tt.sql)
+		assert.Equal(t, tt.kind, kind, tt.sql)
+		assert.Equal(t, tt.analyze, analyze, tt.sql)
+	}
+}
+
+func TestExplainedWrite_NotExplain(t *testing.T) {
+	t.Parallel()
+	for _, sql := range []string{
+		"DELETE FROM t",
+		"WITH d AS (DELETE FROM t RETURNING id) SELECT id FROM d",
+		"EXPLAIN SELECT 1; EXPLAIN DELETE FROM t",
+		"not sql",
+	} {
+		kind, analyze := ExplainedWrite(sql)
+		assert.Empty(t, kind, sql)
+		assert.False(t, analyze, sql)
+	}
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sideEffectFunctions are built-in and common extension functions that
+// change server state even when called from a SELECT in a read-only
+// transaction: they signal or reconfigure backends, take locks, reset
+// statistics, touch replication or large objects, or open connections that
+// the read-only setting does not reach. Volatility alone is not a usable
+// test, since random() and now()-like functions are volatile too; telling
+// harmless volatile functions from harmful ones needs a pg_proc lookup,
+// which the validator does not do.
+var sideEffectFunctions = []string{
+	// Backends and server configuration.
+	"pg_terminate_backend", "pg_cancel_backend", "pg_reload_conf", "pg_rotate_logfile",
+	"pg_log_backend_memory_contexts", "set_config", "pg_promote",
+	// Advisory locks outlive the query when taken at session level.
+	"pg_advisory_lock", "pg_advisory_lock_shared", "pg_try_advisory_lock", "pg_try_advisory_lock_shared",
+	"pg_advisory_xact_lock", "pg_advisory_xact_lock_shared", "pg_try_advisory_xact_lock", "pg_try_advisory_xact_lock_shared",
+	"pg_advisory_unlock", "pg_advisory_unlock_shared", "pg_advisory_unlock_all",
+	// Statistics.
+	"pg_stat_reset", "pg_stat_reset_shared", "pg_stat_reset_single_table_counters",
+	"pg_stat_reset_single_function_counters", "pg_stat_reset_slru", "pg_stat_reset_replication_slot",
+	"pg_stat_statements_reset",
+	// WAL, backups and replication.
+	"pg_switch_wal", "pg_create_restore_point", "pg_backup_start", "pg_backup_stop",
+	"pg_wal_replay_pause", "pg_wal_replay_resume",
+	"pg_create_physical_replication_slot", "pg_create_logical_replication_slot", "pg_drop_replication_slot",
+	"pg_replication_slot_advance", "pg_logical_slot_get_changes", "pg_logical_slot_get_binary_changes",
+	// Large objects and files on the server.
+	"lo_import", "lo_export", "lo_create", "lo_creat", "lo_unlink", "lo_from_bytea", "lo_put",
+	// Sequences and notifications.
+	"nextval", "setval", "pg_notify",
+	// dblink runs its statements on a separate, writable connection.
+	"dblink", "dblink_exec", "dblink_connect", "dblink_connect_u", "dblink_send_query",
+}
+
+// BlockedFunctionError reports a call to a function on the validator's
+// blocklist. It wraps ErrNotAllowed, so errors.Is(err, ErrNotAllowed) still
+// holds.
+type BlockedFunctionError struct {
+	Name string // the function as called, e.g. "pg_catalog.pg_terminate_backend"
+}
+
+func (e *BlockedFunctionError) Error() string {
+	return fmt.Sprintf("%v: function %s has side effects even inside a SELECT and is blocked", ErrNotAllowed, e.Name)
+}
+
+func (e *BlockedFunctionError) Unwrap() error {
+	return ErrNotAllowed
+}
+
+// CalledFunctions parses sql and lists the functions it calls; see
+// ParsedQuery.Functions.
+func CalledFunctions(sql string) ([]string, error) {
+	q, err := ParseQuery(sql)
+	if err != nil {
+		return nil, err
+	}
+	return q.Functions(), nil
+}
+
+// blockedFunction returns the first call in q that matches blocked, keyed
+// by lowercased function name, or "" when there is none. An unqualified
+// entry matches the function in any schema; a qualified entry also matches
+// unqualified calls, since the search_path that resolves them is not known
+// here.
+func blockedFunction(q *ParsedQuery, blocked map[string]bool) string {
+	for _, call := range q.Functions() {
+		name := call[strings.LastIndex(call, ".")+1:]
+		if blocked[call] || blocked[name] {
+			return call
+		}
+		for entry := range blocked {
+			if strings.HasSuffix(entry, "."+name) && !strings.Contains(call, ".") {
+				return call
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalledFunctions(t *testing.T) {
+	t.Parallel()
+	got, err := CalledFunctions(`SELECT count(*), Public.Score(u.id) FROM users u
+		WHERE u.id IN (SELECT max(id) FROM orders) AND count(*) > 0`)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"count", "public.score", "max"}, got)
+
+	got, err = CalledFunctions("SELECT 1 FROM users")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = CalledFunctions("SELEC")
+	assert.ErrorIs(t, err, ErrParseFailed)
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// SplitPlanHint removes the pg_hint_plan hint comment ("/*+ ... */") from
+// sql and returns it with the remaining statement. pg_hint_plan only reads a
+// hint at the start of the query text, so the hint must be before the query
+// itself: at the very start, or after an EXPLAIN prefix as added by the
+// query tool. A "/*+" comment anywhere later is not a hint and is left in
+// place. Without a hint, or when sql does not scan, it returns "", sql.
+func SplitPlanHint(sql string) (hint, rest string) {
+	scan, err := pg_query.Scan(sql)
+	if err != nil {
+		return "", sql
+	}
+	for _, tok := range scan.Tokens {
+		switch tok.Token {
+		case pg_query.Token_C_COMMENT:
+			text := sql[tok.Start:tok.End]
+			if strings.HasPrefix(text, "/*+") {
+				return text, sql[:tok.Start] + strings.TrimLeft(sql[tok.End:], " \t\r\n")
+			}
+		case pg_query.Token_SELECT, pg_query.Token_WITH, pg_query.Token_VALUES, pg_query.Token_TABLE:
+			return "", sql
+		}
+	}
+	return "", sql
+}
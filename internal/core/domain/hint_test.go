@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitPlanHint(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, sql, hint, rest string
+	}{
+		{"leading", "/*+ SeqScan(t) */ SELECT * FROM t", "/*+ SeqScan(t) */", "SELECT * FROM t"},
+		{"leading whitespace", "\n  /*+ IndexScan(t t_pkey) */\nSELECT 1", "/*+ IndexScan(t t_pkey) */", "\n  SELECT 1"},
+		{"after explain", "EXPLAIN (FORMAT JSON) /*+ SeqScan(t) */ SELECT * FROM t", "/*+ SeqScan(t) */", "EXPLAIN (FORMAT JSON) SELECT * FROM t"},
+		{"multiline", "/*+\n  HashJoin(a b)\n  SeqScan(a)\n*/ SELECT 1", "/*+\n  HashJoin(a b)\n  SeqScan(a)\n*/", "SELECT 1"},
+		{"after a plain comment", "/* report */ /*+ SeqScan(t) */ SELECT 1", "/*+ SeqScan(t) */", "/* report */ SELECT 1"},
+		{"with query", "/*+ SeqScan(t) */ WITH x AS (SELECT 1) SELECT * FROM x", "/*+ SeqScan(t) */", "WITH x AS (SELECT 1) SELECT * FROM x"},
+		{"plain comment", "/* not a hint */ SELECT 1", "", "/* not a hint */ SELECT 1"},
+		{"inside the query", "SELECT /*+ SeqScan(t) */ * FROM t", "", "SELECT /*+ SeqScan(t) */ * FROM t"},
+		{"none", "SELECT 1", "", "SELECT 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			hint, rest := SplitPlanHint(tt.sql)
+			assert.Equal(t, tt.hint, hint)
+			assert.Equal(t, tt.rest, rest)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxIdentifierLength is PostgreSQL's NAMEDATALEN - 1. Longer identifiers
+// are silently truncated by the server, so they could name another object.
+const maxIdentifierLength = 63
+
+// maxNameParts is the deepest qualified name SplitQualifiedName accepts:
+// schema.table. Database-qualified names are refused rather than guessed at.
+const maxNameParts = 2
+
+// QuoteIdent quotes name as a PostgreSQL identifier, doubling any embedded
+// double quotes, so it is safe to interpolate into SQL.
+func QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QualifiedName returns the quoted schema.table name, or just the quoted
+// table when schema is empty.
+func QualifiedName(schema, table string) string {
+	if schema == "" {
+		return QuoteIdent(table)
+	}
+	return QuoteIdent(schema) + "." + QuoteIdent(table)
+}
+
+// ValidIdentifier checks a schema or table name received from a client
+// before it is used to build SQL. Quoting already makes any name safe; this
+// refuses names no real object has (control characters such as newlines,
+// semicolons, invalid UTF-8, or more than 63 bytes) as defense in depth.
+func ValidIdentifier(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: empty name", ErrBadIdentifier)
+	case len(name) > maxIdentifierLength:
+		return fmt.Errorf("%w %q: longer than %d bytes", ErrBadIdentifier, name, maxIdentifierLength)
+	case !utf8.ValidString(name):
+		return fmt.Errorf("%w %q: not valid UTF-8", ErrBadIdentifier, name)
+	case strings.ContainsRune(name, ';'):
+		return fmt.Errorf("%w %q: contains a semicolon", ErrBadIdentifier, name)
+	case strings.ContainsFunc(name, unicode.IsControl):
+		return fmt.Errorf("%w %q: contains a control character", ErrBadIdentifier, name)
+	}
+	return nil
+}
+
+// SplitQualifiedName splits a table name a client may have written
+// schema-qualified, such as sales.orders or "My Schema"."My Table", into its
+// schema and table. Double-quoted parts are unquoted, with "" read as one
+// quote; unquoted parts are used as written, like a plain table name, so
+// they are not folded to lower case. A name that neither contains a dot nor
+// starts with a double quote is returned unchanged as the table, which keeps
+// names such as weird"name working. schema is empty for an unqualified name.
+func SplitQualifiedName(name string) (schema, table string, err error) {
+	if !strings.ContainsRune(name, '.') && !strings.HasPrefix(name, `"`) {
+		return "", name, nil
+	}
+
+	var parts []string
+	rest := name
+	for {
+		var part string
+		if strings.HasPrefix(rest, `"`) {
+			end := 1
+			for {
+				i := strings.IndexByte(rest[end:], '"')
+				if i < 0 {
+					return "", "", fmt.Errorf("%w %q: unterminated quoted identifier", ErrBadIdentifier, name)
+				}
+				end += i + 1
+				if !strings.HasPrefix(rest[end:], `"`) {
+					break
+				}
+				end++ // doubled quote inside the name
+			}
+			part = strings.ReplaceAll(rest[1:end-1], `""`, `"`)
+			rest = rest[end:]
+			if rest != "" && rest[0] != '.' {
+				return "", "", fmt.Errorf("%w %q: unexpected text after quoted identifier", ErrBadIdentifier, name)
+			}
+		} else {
+			end := strings.IndexByte(rest, '.')
+			if end < 0 {
+				end = len(rest)
+			}
+			part = rest[:end]
+			if strings.ContainsRune(part, '"') {
+				return "", "", fmt.Errorf("%w %q: quote inside unquoted identifier", ErrBadIdentifier, name)
+			}
+			rest = rest[end:]
+		}
+		if part == "" {
+			return "", "", fmt.Errorf("%w %q: empty name part", ErrBadIdentifier, name)
+		}
+		parts = append(parts, part)
+		if len(parts) > maxNameParts {
+			return "", "", fmt.Errorf("%w %q: more than %d name parts; expected schema.table", ErrBadIdentifier, name, maxNameParts)
+		}
+		if rest == "" {
+			break
+		}
+		rest = rest[1:] // the dot
+	}
+
+	if len(parts) == 1 {
+		return "", parts[0], nil
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIdent(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, `"users"`, QuoteIdent("users"))
+	assert.Equal(t, `"my table"`, QuoteIdent("my table"))
+	assert.Equal(t, `"test""quote"`, QuoteIdent(`test"quote`))
+	assert.Equal(t, `""`, QuoteIdent(""))
+	assert.Equal(t, `"café"`, QuoteIdent("café"))
+	assert.Equal(t, `""""""""`, QuoteIdent(`"""`))
+}
+
+func TestQualifiedName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, `"public"."users"`, QualifiedName("public", "users"))
+	assert.Equal(t, `"users"`, QualifiedName("", "users"))
+	assert.Equal(t, `"a.b"."c""d"`, QualifiedName("a.b", `c"d`))
+}
+
+func TestValidIdentifier(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		input string
+		valid bool
+	}{
+		{"plain", "users", true},
+		{"mixed case", "OrderItems", true},
+		{"space", "order items", true},
+		{"dot", "v1.2", true},
+		{"double quote", `weird"name`, true},
+		{"non-ascii", "café", true},
+		{"max length", strings.Repeat("a", 63), true},
+
+		{"empty", "", false},
+		{"too long", strings.Repeat("a", 64), false},
+		{"semicolon", "users; DROP TABLE users", false},
+		{"quote then semicolon", `users"; DROP TABLE users; --`, false},
+		{"newline", "users\nDROP TABLE users", false},
+		{"carriage return", "users\r", false},
+		{"tab", "users\t", false},
+		{"nul byte", "users\x00", false},
+		{"invalid utf-8", "users\xff", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := ValidIdentifier(tt.input)
+			if tt.valid {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, ErrBadIdentifier), "got %v", err)
+		})
+	}
+}
+
+func TestSplitQualifiedName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		input  string
+		schema string
+		table  string
+	}{
+		{"plain", "users", "", "users"},
+		{"quote inside plain name", `weird"name`, "", `weird"name`},
+		{"unquoted", "sales.orders", "sales", "orders"},
+		{"unquoted keeps case", "Sales.Orders", "Sales", "Orders"},
+		{"quoted", `"My Schema"."My Table"`, "My Schema", "My Table"},
+		{"quoted dot", `"v1.2"`, "", "v1.2"},
+		{"quoted table only", `"Orders"`, "", "Orders"},
+		{"mixed", `sales."Order Items"`, "sales", "Order Items"},
+		{"mixed reversed", `"Sales".orders`, "Sales", "orders"},
+		{"doubled quote", `"a""b"."c"`, `a"b`, "c"},
+		{"dot in quoted schema", `"x.y".z`, "x.y", "z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			schema, table, err := SplitQualifiedName(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.schema, schema)
+			assert.Equal(t, tt.table, table)
+		})
+	}
+}
+
+func TestSplitQualifiedName_Invalid(t *testing.T) {
+	t.Parallel()
+	for _, input := range []string{
+		"db.sales.orders",
+		`"a"."b"."c"`,
+		".orders",
+		"sales.",
+		"sales..orders",
+		`""`,
+		`"unterminated`,
+		`"a"b`,
+		`sales.ord"ers`,
+	} {
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+			_, _, err := SplitQualifiedName(input)
+			assert.True(t, errors.Is(err, ErrBadIdentifier), "got %v", err)
+		})
+	}
+}
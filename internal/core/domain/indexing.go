@@ -0,0 +1,48 @@
+package domain
+
+import "sort"
+
+// UncoveredForeignKeys returns the names of foreign keys whose columns are not
+// the leading columns of any index. fkColumns maps constraint name → columns;
+// indexColumns lists each index's key columns in order. Without a supporting
+// index, joins on the FK and deletes/updates of the referenced row must scan
+// the whole referencing table. Results are sorted by constraint name.
+func UncoveredForeignKeys(fkColumns map[string][]string, indexColumns [][]string) []string {
+	var uncovered []string
+	for name, cols := range fkColumns {
+		if len(cols) == 0 {
+			continue
+		}
+		covered := false
+		for _, idx := range indexColumns {
+			if hasLeadingColumns(idx, cols) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			uncovered = append(uncovered, name)
+		}
+	}
+	sort.Strings(uncovered)
+	return uncovered
+}
+
+// hasLeadingColumns reports whether the first len(cols) entries of index are
+// exactly the set cols, in any order.
+func hasLeadingColumns(index, cols []string) bool {
+	if len(index) < len(cols) {
+		return false
+	}
+	want := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		want[c] = true
+	}
+	for _, c := range index[:len(cols)] {
+		if !want[c] {
+			return false
+		}
+		delete(want, c)
+	}
+	return len(want) == 0
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUncoveredForeignKeys(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		fks     map[string][]string
+		indexes [][]string
+		want    []string
+	}{
+		{
+			name:    "single column covered",
+			fks:     map[string][]string{"orders_customer_fk": {"customer_id"}},
+			indexes: [][]string{{"id"}, {"customer_id"}},
+			want:    nil,
+		},
+		{
+			name:    "single column uncovered",
+			fks:     map[string][]string{"orders_customer_fk": {"customer_id"}},
+			indexes: [][]string{{"id"}},
+			want:    []string{"orders_customer_fk"},
+		},
+		{
+			name:    "leading column of composite index covers",
+			fks:     map[string][]string{"fk": {"customer_id"}},
+			indexes: [][]string{{"customer_id", "created_at"}},
+			want:    nil,
+		},
+		{
+			name:    "trailing column of composite index does not cover",
+			fks:     map[string][]string{"fk": {"customer_id"}},
+			indexes: [][]string{{"created_at", "customer_id"}},
+			want:    []string{"fk"},
+		},
+		{
+			name:    "multi-column fk covered in any order",
+			fks:     map[string][]string{"fk": {"a", "b"}},
+			indexes: [][]string{{"b", "a", "c"}},
+			want:    nil,
+		},
+		{
+			name:    "multi-column fk partially covered",
+			fks:     map[string][]string{"fk": {"a", "b"}},
+			indexes: [][]string{{"a"}},
+			want:    []string{"fk"},
+		},
+		{
+			name:    "results sorted",
+			fks:     map[string][]string{"z_fk": {"z"}, "a_fk": {"a"}},
+			indexes: nil,
+			want:    []string{"a_fk", "z_fk"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, UncoveredForeignKeys(tt.fks, tt.indexes))
+		})
+	}
+}
@@ -9,40 +9,73 @@ import (
 // column naming patterns. Type compatibility checking is left to the adapter
 // because type names are database-specific.
 type FKCandidate struct {
-	ColumnName      string // e.g. "user_id"
-	ReferencedTable string // e.g. "users"
-	ReferencedPK    string // e.g. "id" (assumed PK column name)
-	Confidence      string // "high" or "medium"
-	Reason          string
+	ColumnName       string // e.g. "user_id"
+	ReferencedSchema string // e.g. "public"
+	ReferencedTable  string // e.g. "users"
+	ReferencedPK     string // e.g. "id" (assumed PK column name)
+	Confidence       string // "high" or "medium"
+	Reason           string
 }
 
 // MatchFKNamingPattern checks whether columnName follows the *_id naming
 // convention and matches a known table name (plural or singular form).
-// tableNames is the set of all table names in scope. Returns a candidate
-// and true if a match is found, or zero value and false otherwise.
-func MatchFKNamingPattern(columnName string, tableNames map[string]bool) (FKCandidate, bool) {
+// tables is the set of all tables in scope, keyed by "schema.table"; schema
+// is the schema of the table that owns the column.
+//
+// A table in the same schema always wins. Otherwise a table in another schema
+// is accepted only when exactly one schema has it, with confidence lowered to
+// "medium"; same-named tables in several other schemas are ambiguous and
+// produce no match.
+func MatchFKNamingPattern(columnName, schema string, tables map[string]bool) (FKCandidate, bool) {
 	if !strings.HasSuffix(columnName, "_id") {
 		return FKCandidate{}, false
 	}
 	prefix := strings.TrimSuffix(columnName, "_id")
 
 	// Try plural and singular forms of the table name.
-	candidates := []string{prefix + "s", prefix, prefix + "es"}
-	for _, candidate := range candidates {
-		if !tableNames[candidate] {
+	names := []string{prefix + "s", prefix, prefix + "es"}
+	for _, name := range names {
+		if !tables[schema+"."+name] {
 			continue
 		}
 		confidence := "high"
-		if candidate != prefix+"s" && candidate != prefix {
+		if name != prefix+"s" && name != prefix {
 			confidence = "medium"
 		}
 		return FKCandidate{
-			ColumnName:      columnName,
-			ReferencedTable: candidate,
-			ReferencedPK:    "id",
-			Confidence:      confidence,
-			Reason:          fmt.Sprintf("column %q matches naming pattern for table %q", columnName, candidate),
+			ColumnName:       columnName,
+			ReferencedSchema: schema,
+			ReferencedTable:  name,
+			ReferencedPK:     "id",
+			Confidence:       confidence,
+			Reason:           fmt.Sprintf("column %q matches naming pattern for table %q", columnName, name),
 		}, true
 	}
+
+	for _, name := range names {
+		var found []string
+		for key := range tables {
+			s, t, ok := strings.Cut(key, ".")
+			if ok && t == name && s != schema {
+				found = append(found, s)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return FKCandidate{
+				ColumnName:       columnName,
+				ReferencedSchema: found[0],
+				ReferencedTable:  name,
+				ReferencedPK:     "id",
+				Confidence:       "medium",
+				Reason: fmt.Sprintf("column %q matches naming pattern for table %q in schema %q",
+					columnName, name, found[0]),
+			}, true
+		default:
+			return FKCandidate{}, false
+		}
+	}
 	return FKCandidate{}, false
 }
@@ -9,10 +9,10 @@ import (
 func TestMatchFKNamingPattern(t *testing.T) {
 	t.Parallel()
 	tables := map[string]bool{
-		"users":      true,
-		"products":   true,
-		"categories": true,
-		"status":     true, // singular table name
+		"public.users":      true,
+		"public.products":   true,
+		"public.categories": true,
+		"public.status":     true, // singular table name
 	}
 
 	tests := []struct {
@@ -35,9 +35,10 @@ func TestMatchFKNamingPattern(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			candidate, ok := MatchFKNamingPattern(tt.column, tables)
+			candidate, ok := MatchFKNamingPattern(tt.column, "public", tables)
 			assert.Equal(t, tt.wantMatch, ok)
 			if ok {
+				assert.Equal(t, "public", candidate.ReferencedSchema)
 				assert.Equal(t, tt.wantTable, candidate.ReferencedTable)
 				assert.Equal(t, tt.wantConf, candidate.Confidence)
 				assert.Equal(t, tt.column, candidate.ColumnName)
@@ -47,3 +48,41 @@ func TestMatchFKNamingPattern(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchFKNamingPattern_Schemas(t *testing.T) {
+	t.Parallel()
+	tables := map[string]bool{
+		"sales.users":     true,
+		"billing.users":   true,
+		"billing.plans":   true,
+		"catalog.items":   true,
+		"warehouse.items": true,
+	}
+
+	tests := []struct {
+		name       string
+		schema     string
+		column     string
+		wantMatch  bool
+		wantSchema string
+		wantConf   string
+	}{
+		{"same schema wins over other schema", "sales", "user_id", true, "sales", "high"},
+		{"same schema wins (other side)", "billing", "user_id", true, "billing", "high"},
+		{"unique table in other schema", "sales", "plan_id", true, "billing", "medium"},
+		{"ambiguous across other schemas", "sales", "item_id", false, "", ""},
+		{"ambiguous but one is local", "catalog", "item_id", true, "catalog", "high"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			candidate, ok := MatchFKNamingPattern(tt.column, tt.schema, tables)
+			assert.Equal(t, tt.wantMatch, ok)
+			if ok {
+				assert.Equal(t, tt.wantSchema, candidate.ReferencedSchema)
+				assert.Equal(t, tt.wantConf, candidate.Confidence)
+			}
+		})
+	}
+}
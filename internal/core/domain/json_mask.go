@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSONPathMask masks one field inside a json or jsonb column. Path is a
+// dot-separated list of object keys, e.g. "contact.phone"; arrays along the
+// way are walked element by element, so "addresses.street" masks the street
+// of every address.
+type JSONPathMask struct {
+	Path string
+	Mask MaskType
+}
+
+// ValidJSONPath checks a JSONPathMask path: one or more non-empty keys
+// separated by dots.
+func ValidJSONPath(path string) error {
+	if path == "" {
+		return fmt.Errorf("empty path")
+	}
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			return fmt.Errorf("path %q has an empty key", path)
+		}
+	}
+	return nil
+}
+
+// MaskJSON applies masks to a decoded JSON value and returns the result.
+// Maps and slices are masked in place. A string holding a JSON object or
+// array, e.g. from a jsonb column cast to text, is decoded, masked and
+// encoded again. Paths that do not exist in the value are skipped.
+func MaskJSON(value any, masks []JSONPathMask) any {
+	if len(masks) == 0 || value == nil {
+		return value
+	}
+	if s, ok := value.(string); ok {
+		var decoded any
+		if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+			return value
+		}
+		switch decoded.(type) {
+		case map[string]any, []any:
+		default:
+			return value
+		}
+		for _, m := range masks {
+			decoded = maskJSONPath(decoded, strings.Split(m.Path, "."), m.Mask)
+		}
+		out, err := json.Marshal(decoded)
+		if err != nil {
+			return value
+		}
+		return string(out)
+	}
+	for _, m := range masks {
+		value = maskJSONPath(value, strings.Split(m.Path, "."), m.Mask)
+	}
+	return value
+}
+
+// maskJSONPath masks the value at keys below v.
+func maskJSONPath(v any, keys []string, mask MaskType) any {
+	if len(keys) == 0 {
+		return ApplyMask(v, mask)
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		if child, ok := t[keys[0]]; ok {
+			t[keys[0]] = maskJSONPath(child, keys[1:], mask)
+		}
+	case []any:
+		for i := range t {
+			t[i] = maskJSONPath(t[i], keys, mask)
+		}
+	}
+	return v
+}
+
+// MaskRowsJSON applies JSON path masks to query result rows in place. The
+// masks map is column-name -> paths; aliased columns are resolved like in
+// MaskRowsWithAliases.
+func MaskRowsJSON(rows []map[string]any, masks map[string][]JSONPathMask, aliases map[string]string) {
+	if len(masks) == 0 {
+		return
+	}
+	for _, row := range rows {
+		for col, paths := range masks {
+			if val, exists := row[col]; exists {
+				row[col] = MaskJSON(val, paths)
+			} else if alias, hasAlias := aliases[col]; hasAlias {
+				if val, exists := row[alias]; exists {
+					row[alias] = MaskJSON(val, paths)
+				}
+			}
+		}
+	}
+}
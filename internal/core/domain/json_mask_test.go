@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidJSONPath(t *testing.T) {
+	t.Parallel()
+	for _, path := range []string{"ssn", "contact.phone", "a.b.c"} {
+		assert.NoError(t, ValidJSONPath(path), path)
+	}
+	for _, path := range []string{"", ".ssn", "ssn.", "contact..phone"} {
+		assert.Error(t, ValidJSONPath(path), path)
+	}
+}
+
+func TestMaskJSON_Nested(t *testing.T) {
+	t.Parallel()
+	value := map[string]any{
+		"ssn":  "123-45-6789",
+		"name": "Alice",
+		"contact": map[string]any{
+			"phone": "555-123-4567",
+			"city":  "Madrid",
+		},
+	}
+
+	got := MaskJSON(value, []JSONPathMask{
+		{Path: "ssn", Mask: MaskRedact},
+		{Path: "contact.phone", Mask: MaskPartial},
+	})
+
+	assert.Equal(t, map[string]any{
+		"ssn":  "***",
+		"name": "Alice",
+		"contact": map[string]any{
+			"phone": "********4567",
+			"city":  "Madrid",
+		},
+	}, got)
+}
+
+func TestMaskJSON_Arrays(t *testing.T) {
+	t.Parallel()
+	value := map[string]any{
+		"addresses": []any{
+			map[string]any{"street": "1 Main St", "zip": "28001"},
+			map[string]any{"street": "2 High St", "zip": "28002"},
+			"not an object",
+		},
+	}
+
+	got := MaskJSON(value, []JSONPathMask{{Path: "addresses.street", Mask: MaskNull}})
+
+	assert.Equal(t, map[string]any{
+		"addresses": []any{
+			map[string]any{"street": nil, "zip": "28001"},
+			map[string]any{"street": nil, "zip": "28002"},
+			"not an object",
+		},
+	}, got)
+
+	// A top-level array is walked the same way.
+	rows := []any{map[string]any{"ssn": "1"}, map[string]any{"ssn": "2"}}
+	assert.Equal(t, []any{map[string]any{"ssn": "***"}, map[string]any{"ssn": "***"}},
+		MaskJSON(rows, []JSONPathMask{{Path: "ssn", Mask: MaskRedact}}))
+}
+
+func TestMaskJSON_MasksWholeSubtree(t *testing.T) {
+	t.Parallel()
+	value := map[string]any{"contact": map[string]any{"phone": "555"}, "id": float64(7)}
+	got := MaskJSON(value, []JSONPathMask{{Path: "contact", Mask: MaskRedact}})
+	assert.Equal(t, map[string]any{"contact": "***", "id": float64(7)}, got)
+}
+
+func TestMaskJSON_MissingPathsAndScalars(t *testing.T) {
+	t.Parallel()
+	masks := []JSONPathMask{{Path: "contact.phone", Mask: MaskRedact}}
+
+	value := map[string]any{"contact": "inline", "other": map[string]any{"phone": "555"}}
+	assert.Equal(t, map[string]any{"contact": "inline", "other": map[string]any{"phone": "555"}}, MaskJSON(value, masks))
+
+	assert.Nil(t, MaskJSON(nil, masks))
+	assert.Equal(t, float64(3), MaskJSON(float64(3), masks))
+	assert.Equal(t, "plain text", MaskJSON("plain text", masks))
+	assert.Equal(t, `"quoted"`, MaskJSON(`"quoted"`, masks), "JSON scalars in text are left alone")
+}
+
+func TestMaskJSON_Text(t *testing.T) {
+	t.Parallel()
+	got := MaskJSON(`{"ssn": "123-45-6789", "name": "Alice"}`, []JSONPathMask{{Path: "ssn", Mask: MaskRedact}})
+	assert.JSONEq(t, `{"ssn": "***", "name": "Alice"}`, got.(string))
+}
+
+func TestMaskRowsJSON(t *testing.T) {
+	t.Parallel()
+	rows := []map[string]any{
+		{"id": 1, "metadata": map[string]any{"ssn": "111", "plan": "pro"}},
+		{"id": 2, "metadata": nil},
+		{"id": 3, "info": map[string]any{"ssn": "333"}},
+	}
+	masks := map[string][]JSONPathMask{"metadata": {{Path: "ssn", Mask: MaskRedact}}}
+
+	MaskRowsJSON(rows, masks, map[string]string{"metadata": "info"})
+
+	assert.Equal(t, map[string]any{"ssn": "***", "plan": "pro"}, rows[0]["metadata"])
+	assert.Nil(t, rows[1]["metadata"])
+	assert.Equal(t, map[string]any{"ssn": "***"}, rows[2]["info"], "aliased column is masked")
+	assert.Equal(t, 1, rows[0]["id"])
+}
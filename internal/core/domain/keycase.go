@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// KeyCase selects how result column names are rendered to clients.
+type KeyCase string
+
+const (
+	KeyCaseOriginal KeyCase = "original"
+	KeyCaseSnake    KeyCase = "snake"
+	KeyCaseCamel    KeyCase = "camel"
+)
+
+// Valid returns true if the KeyCase is a recognised casing strategy
+// (including the zero value "", which means "original").
+func (k KeyCase) Valid() bool {
+	switch k {
+	case KeyCaseOriginal, KeyCaseSnake, KeyCaseCamel, "":
+		return true
+	}
+	return false
+}
+
+// TransformKeys rewrites the keys of each row in place according to the casing
+// strategy and returns the new name of every key. It must run after masking,
+// since masks are keyed on the original column names. columns gives the
+// result's column order; keys it does not list follow in sorted order. If
+// two columns convert to the same key, the first keeps it and the others are
+// numbered like repeated column names (user_id, user_id_2).
+func TransformKeys(rows []map[string]any, keyCase KeyCase, columns []string) map[string]string {
+	if keyCase == "" || keyCase == KeyCaseOriginal {
+		return nil
+	}
+	keys := slices.Clone(columns)
+	if len(rows) > 0 {
+		var rest []string
+		for k := range rows[0] {
+			if !slices.Contains(columns, k) {
+				rest = append(rest, k)
+			}
+		}
+		slices.Sort(rest)
+		keys = append(keys, rest...)
+	}
+
+	names := make(map[string]string, len(keys))
+	taken := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if _, ok := names[k]; ok {
+			continue
+		}
+		name := ConvertKey(k, keyCase)
+		for n := 2; taken[name]; n++ {
+			name = DuplicateColumnName(ConvertKey(k, keyCase), n)
+		}
+		names[k] = name
+		taken[name] = true
+	}
+
+	for i, row := range rows {
+		out := make(map[string]any, len(row))
+		for k, v := range row {
+			out[names[k]] = v
+		}
+		rows[i] = out
+	}
+	return names
+}
+
+// ConvertKey converts a single column name to the given casing.
+func ConvertKey(key string, keyCase KeyCase) string {
+	words := splitWords(key)
+	if len(words) == 0 {
+		return key
+	}
+	switch keyCase {
+	case KeyCaseSnake:
+		for i, w := range words {
+			words[i] = strings.ToLower(w)
+		}
+		return strings.Join(words, "_")
+	case KeyCaseCamel:
+		var b strings.Builder
+		for i, w := range words {
+			w = strings.ToLower(w)
+			if i > 0 {
+				r := []rune(w)
+				r[0] = unicode.ToUpper(r[0])
+				w = string(r)
+			}
+			b.WriteString(w)
+		}
+		return b.String()
+	default:
+		return key
+	}
+}
+
+// splitWords breaks an identifier into words on separators and case changes,
+// keeping acronyms together (e.g. "HTTPStatus" → ["HTTP", "Status"]).
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(s)
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyCase_Valid(t *testing.T) {
+	t.Parallel()
+	assert.True(t, KeyCaseOriginal.Valid())
+	assert.True(t, KeyCaseSnake.Valid())
+	assert.True(t, KeyCaseCamel.Valid())
+	assert.True(t, KeyCase("").Valid())
+	assert.False(t, KeyCase("kebab").Valid())
+}
+
+func TestConvertKey(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in    string
+		snake string
+		camel string
+	}{
+		{"user_id", "user_id", "userId"},
+		{"userId", "user_id", "userId"},
+		{"UserID", "user_id", "userId"},
+		{"HTTPStatus", "http_status", "httpStatus"},
+		{"QUERY PLAN", "query_plan", "queryPlan"},
+		{"created_at_2", "created_at_2", "createdAt2"},
+		{"id", "id", "id"},
+		{"?column?", "column", "column"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.snake, ConvertKey(tt.in, KeyCaseSnake))
+			assert.Equal(t, tt.camel, ConvertKey(tt.in, KeyCaseCamel))
+			assert.Equal(t, tt.in, ConvertKey(tt.in, KeyCaseOriginal))
+		})
+	}
+}
+
+func TestConvertKey_NoWords(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "___", ConvertKey("___", KeyCaseCamel))
+}
+
+func TestTransformKeys(t *testing.T) {
+	t.Parallel()
+	rows := []map[string]any{
+		{"user_id": 1, "first_name": "alice"},
+		{"user_id": 2, "first_name": "bob"},
+	}
+	TransformKeys(rows, KeyCaseCamel, nil)
+
+	assert.Equal(t, map[string]any{"userId": 1, "firstName": "alice"}, rows[0])
+	assert.Equal(t, map[string]any{"userId": 2, "firstName": "bob"}, rows[1])
+}
+
+func TestTransformKeys_OriginalIsNoop(t *testing.T) {
+	t.Parallel()
+	rows := []map[string]any{{"user_id": 1}}
+	TransformKeys(rows, KeyCaseOriginal, nil)
+	assert.Equal(t, map[string]any{"user_id": 1}, rows[0])
+
+	TransformKeys(rows, "", nil)
+	assert.Equal(t, map[string]any{"user_id": 1}, rows[0])
+}
+
+func TestTransformKeys_Collisions(t *testing.T) {
+	t.Parallel()
+
+	// Run repeatedly: map iteration order must not decide which column
+	// keeps the name.
+	for range 20 {
+		rows := []map[string]any{{"userId": 1, "user_id": 2, "name": "a"}}
+		names := TransformKeys(rows, KeyCaseSnake, []string{"user_id", "name", "userId"})
+		assert.Equal(t, map[string]any{"user_id": 2, "name": "a", "user_id_2": 1}, rows[0])
+		assert.Equal(t, map[string]string{"user_id": "user_id", "name": "name", "userId": "user_id_2"}, names)
+
+		rows = []map[string]any{{"userId": 1, "user_id": 2}}
+		TransformKeys(rows, KeyCaseCamel, nil)
+		assert.Equal(t, map[string]any{"userId": 1, "userId_2": 2}, rows[0], "without a column order keys are taken in sorted order")
+	}
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// RewriteLimit applies a row limit to a single SELECT by rewriting its own
+// LIMIT clause, for queries whose meaning depends on the top-level ORDER BY
+// or DISTINCT: wrapping those in "SELECT * FROM (...) LIMIT n" leaves the
+// row order of the outer query unspecified. An existing constant LIMIT is
+// kept when it is lower. OFFSET is left as is.
+//
+// It reports ok=false when the statement should be wrapped instead: it does
+// not parse to a single SELECT, needs no rewrite, or has a LIMIT that cannot
+// be tightened safely (a non-constant expression, or FETCH ... WITH TIES,
+// which may return more rows than the count).
+func RewriteLimit(sql string, limit int) (rewritten string, ok bool) {
+	return rewriteLimit(sql, limit, false)
+}
+
+// InjectLimit is RewriteLimit for every single SELECT, ordered or not, so
+// the limit reaches the planner directly instead of through a subquery
+// wrapper. Set operations without ORDER BY or DISTINCT, such as a bare
+// UNION, report ok=false and are left to the wrapper, like everything else
+// RewriteLimit refuses.
+func InjectLimit(sql string, limit int) (rewritten string, ok bool) {
+	return rewriteLimit(sql, limit, true)
+}
+
+func rewriteLimit(sql string, limit int, unordered bool) (string, bool) {
+	tree, err := pg_query.Parse(sql)
+	if err != nil || len(tree.Stmts) != 1 {
+		return "", false
+	}
+	sel := tree.Stmts[0].Stmt.GetSelectStmt()
+	if sel == nil || sel.IntoClause != nil {
+		return "", false
+	}
+	if len(sel.SortClause) == 0 && len(sel.DistinctClause) == 0 {
+		if !unordered || sel.Op != pg_query.SetOperation_SETOP_NONE {
+			return "", false
+		}
+	}
+	if sel.LimitOption == pg_query.LimitOption_LIMIT_OPTION_WITH_TIES {
+		return "", false
+	}
+
+	if sel.LimitCount != nil {
+		c := sel.LimitCount.GetAConst()
+		switch {
+		case c == nil:
+			return "", false
+		case c.Isnull: // LIMIT ALL / LIMIT NULL
+		case c.GetIval() == nil:
+			return "", false
+		case int(c.GetIval().Ival) <= limit:
+			return sql, true
+		}
+	}
+	sel.LimitCount = pg_query.MakeAConstIntNode(int64(limit), -1)
+	sel.LimitOption = pg_query.LimitOption_LIMIT_OPTION_COUNT
+
+	out, err := pg_query.Deparse(tree)
+	if err != nil {
+		return "", false
+	}
+	return out, true
+}
+
+// PageQuery adds OFFSET offset to a single SELECT so it returns a later page
+// of its rows; the row limit is left to the executor. It reports ok=false
+// for anything else, and for a SELECT that already has a LIMIT or OFFSET,
+// whose pages could not be combined with the query's own.
+func PageQuery(sql string, offset int) (paged string, ok bool) {
+	tree, err := pg_query.Parse(sql)
+	if err != nil || len(tree.Stmts) != 1 {
+		return "", false
+	}
+	sel := tree.Stmts[0].Stmt.GetSelectStmt()
+	if sel == nil || sel.IntoClause != nil || sel.LimitCount != nil || sel.LimitOffset != nil {
+		return "", false
+	}
+	if offset <= 0 {
+		return sql, true
+	}
+	sel.LimitOffset = pg_query.MakeAConstIntNode(int64(offset), -1)
+
+	out, err := pg_query.Deparse(tree)
+	if err != nil {
+		return "", false
+	}
+	return out, true
+}
@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteLimit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{"order by", "SELECT id FROM t ORDER BY id DESC", "SELECT id FROM t ORDER BY id DESC LIMIT 10"},
+		{"distinct on", "SELECT DISTINCT ON (a) a, b FROM t ORDER BY a, b", "SELECT DISTINCT ON (a) a, b FROM t ORDER BY a, b LIMIT 10"},
+		{"distinct", "SELECT DISTINCT a FROM t", "SELECT DISTINCT a FROM t LIMIT 10"},
+		{"window function", "SELECT id, rank() OVER (ORDER BY score) FROM t ORDER BY 2",
+			"SELECT id, rank() OVER (ORDER BY score) FROM t ORDER BY 2 LIMIT 10"},
+		{"larger limit is lowered", "SELECT id FROM t ORDER BY id LIMIT 500", "SELECT id FROM t ORDER BY id LIMIT 10"},
+		{"smaller limit is kept", "SELECT id FROM t ORDER BY id LIMIT 3", "SELECT id FROM t ORDER BY id LIMIT 3"},
+		{"limit all", "SELECT id FROM t ORDER BY id LIMIT ALL", "SELECT id FROM t ORDER BY id LIMIT 10"},
+		{"offset kept", "SELECT id FROM t ORDER BY id OFFSET 5", "SELECT id FROM t ORDER BY id LIMIT 10 OFFSET 5"},
+		{"fetch first", "SELECT id FROM t ORDER BY id FETCH FIRST 50 ROWS ONLY", "SELECT id FROM t ORDER BY id LIMIT 10"},
+		{"union", "SELECT a FROM t UNION SELECT a FROM u ORDER BY 1", "SELECT a FROM t UNION SELECT a FROM u ORDER BY 1 LIMIT 10"},
+		{"cte", "WITH x AS (SELECT 1 AS a) SELECT a FROM x ORDER BY a", "WITH x AS (SELECT 1 AS a) SELECT a FROM x ORDER BY a LIMIT 10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := RewriteLimit(tt.sql, 10)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRewriteLimit_Wrapped(t *testing.T) {
+	t.Parallel()
+	for _, sql := range []string{
+		"SELECT id FROM t",                              // no ordering to preserve
+		"SELECT id FROM t ORDER BY id LIMIT $1",         // not a constant
+		"SELECT id FROM t ORDER BY id LIMIT (SELECT 5)", // not a constant
+		"SELECT id FROM t ORDER BY id FETCH FIRST 5 ROWS WITH TIES",
+		"EXPLAIN SELECT id FROM t ORDER BY id",
+		"SELECT 1; SELECT 2 ORDER BY 1",
+		"not sql",
+	} {
+		_, ok := RewriteLimit(sql, 10)
+		assert.False(t, ok, sql)
+	}
+}
+
+func TestInjectLimit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{"plain select", "SELECT id FROM t", "SELECT id FROM t LIMIT 10"},
+		{"where and join", "SELECT t.id FROM t JOIN u ON u.t_id = t.id WHERE u.x > 1",
+			"SELECT t.id FROM t JOIN u ON u.t_id = t.id WHERE u.x > 1 LIMIT 10"},
+		{"cte", "WITH x AS (SELECT a FROM t) SELECT a FROM x", "WITH x AS (SELECT a FROM t) SELECT a FROM x LIMIT 10"},
+		{"larger limit is lowered", "SELECT id FROM t LIMIT 500", "SELECT id FROM t LIMIT 10"},
+		{"smaller limit is untouched", "SELECT id FROM t LIMIT 3", "SELECT id FROM t LIMIT 3"},
+		{"equal limit is untouched", "select id from t limit 10", "select id from t limit 10"},
+		{"offset kept", "SELECT id FROM t OFFSET 5", "SELECT id FROM t LIMIT 10 OFFSET 5"},
+		{"ordered union", "SELECT a FROM t UNION SELECT a FROM u ORDER BY 1", "SELECT a FROM t UNION SELECT a FROM u ORDER BY 1 LIMIT 10"},
+		{"ordered as before", "SELECT id FROM t ORDER BY id DESC", "SELECT id FROM t ORDER BY id DESC LIMIT 10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := InjectLimit(tt.sql, 10)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInjectLimit_Wrapped(t *testing.T) {
+	t.Parallel()
+	for _, sql := range []string{
+		"SELECT a FROM t UNION SELECT a FROM u", // set operation
+		"SELECT a FROM t INTERSECT SELECT a FROM u",
+		"WITH x AS (SELECT a FROM t) SELECT a FROM x UNION ALL SELECT a FROM u",
+		"SELECT id FROM t LIMIT $1",
+		"SELECT id INTO new_t FROM t",
+		"EXPLAIN SELECT id FROM t",
+		"SELECT 1; SELECT 2",
+		"not sql",
+	} {
+		_, ok := InjectLimit(sql, 10)
+		assert.False(t, ok, sql)
+	}
+}
+
+func TestPageQuery(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		sql    string
+		offset int
+		want   string
+	}{
+		{"first page is untouched", "select id from t order by id", 0, "select id from t order by id"},
+		{"later page", "SELECT id FROM t ORDER BY id", 50, "SELECT id FROM t ORDER BY id OFFSET 50"},
+		{"unordered", "SELECT id FROM t WHERE x > 1", 10, "SELECT id FROM t WHERE x > 1 OFFSET 10"},
+		{"union", "SELECT a FROM t UNION SELECT a FROM u", 5, "SELECT a FROM t UNION SELECT a FROM u OFFSET 5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := PageQuery(tt.sql, tt.offset)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPageQuery_Refused(t *testing.T) {
+	t.Parallel()
+	for _, sql := range []string{
+		"SELECT id FROM t LIMIT 10",
+		"SELECT id FROM t OFFSET 10",
+		"SELECT id FROM t FETCH FIRST 5 ROWS ONLY",
+		"SELECT id INTO new_t FROM t",
+		"EXPLAIN SELECT id FROM t",
+		"SELECT 1; SELECT 2",
+		"not sql",
+	} {
+		_, ok := PageQuery(sql, 10)
+		assert.False(t, ok, sql)
+	}
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaskPattern masks every column whose name matches Name and, when DataType
+// is set, whose data type equals it (case-insensitive). Patterns complement
+// explicit per-column masks, which always take precedence.
+type MaskPattern struct {
+	Name     *regexp.Regexp
+	DataType string
+	Mask     MaskType
+}
+
+// Matches reports whether the pattern applies to a column. An empty dataType
+// means the type is unknown (e.g. query results); the name alone decides, so
+// masking fails closed.
+func (p MaskPattern) Matches(column, dataType string) bool {
+	if !p.Name.MatchString(column) {
+		return false
+	}
+	if p.DataType == "" || dataType == "" {
+		return true
+	}
+	return strings.EqualFold(p.DataType, dataType)
+}
+
+// MatchMaskPattern returns the mask of the first pattern matching the column.
+func MatchMaskPattern(patterns []MaskPattern, column, dataType string) (MaskType, bool) {
+	for _, p := range patterns {
+		if p.Matches(column, dataType) {
+			return p.Mask, true
+		}
+	}
+	return "", false
+}
+
+// ResolveColumnMasks returns the effective masks for a table's columns
+// (column name -> data type). Explicit masks win over patterns.
+func ResolveColumnMasks(columns map[string]string, explicit map[string]MaskType, patterns []MaskPattern) map[string]MaskType {
+	resolved := make(map[string]MaskType)
+	for col, dataType := range columns {
+		if m, ok := explicit[col]; ok {
+			resolved[col] = m
+			continue
+		}
+		if m, ok := MatchMaskPattern(patterns, col, dataType); ok {
+			resolved[col] = m
+		}
+	}
+	return resolved
+}
+
+// MaskRowsByPattern applies patterns to result columns not covered by an
+// explicit mask. Aliased columns are matched by both their result name and
+// their source column name; with numbered set, a numbered repeat of a column
+// (see DuplicateColumnName) is also matched by the name it repeats. Run
+// after MaskRowsWithAliases, passing the same explicit masks, so explicit
+// masks are applied first and never masked twice.
+func MaskRowsByPattern(rows []map[string]any, explicit map[string]MaskType, patterns []MaskPattern, aliases map[string]string, numbered bool) {
+	if len(patterns) == 0 || len(rows) == 0 {
+		return
+	}
+
+	// aliases maps source -> alias; invert it to find a result key's source.
+	sources := make(map[string]string, len(aliases))
+	for src, alias := range aliases {
+		sources[alias] = src
+	}
+
+	masks := make(map[string]MaskType)
+	for key := range rows[0] {
+		if _, ok := explicit[key]; ok {
+			continue
+		}
+		src, aliased := sources[key]
+		if aliased {
+			if _, ok := explicit[src]; ok {
+				continue
+			}
+		}
+		if m, ok := MatchMaskPattern(patterns, key, ""); ok {
+			masks[key] = m
+		} else if aliased {
+			if m, ok := MatchMaskPattern(patterns, src, ""); ok {
+				masks[key] = m
+			}
+		} else if base, ok := DuplicateColumnBase(key); ok && numbered {
+			if _, ok := explicit[base]; ok {
+				continue
+			}
+			if m, ok := MatchMaskPattern(patterns, base, ""); ok {
+				masks[key] = m
+			}
+		}
+	}
+	MaskRows(rows, masks)
+}
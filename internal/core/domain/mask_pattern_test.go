@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func emailPattern() MaskPattern {
+	return MaskPattern{Name: regexp.MustCompile("email"), DataType: "character varying", Mask: MaskRedact}
+}
+
+func TestMaskPattern_Matches(t *testing.T) {
+	t.Parallel()
+	p := emailPattern()
+
+	assert.True(t, p.Matches("contact_email", "character varying"))
+	assert.True(t, p.Matches("email", "CHARACTER VARYING"))
+	assert.False(t, p.Matches("email", "text"), "data type must match when known")
+	assert.True(t, p.Matches("email", ""), "unknown type matches by name (fail closed)")
+	assert.False(t, p.Matches("phone", "character varying"))
+}
+
+func TestMatchMaskPattern_FirstWins(t *testing.T) {
+	t.Parallel()
+	patterns := []MaskPattern{
+		{Name: regexp.MustCompile("^work_email$"), Mask: MaskHash},
+		{Name: regexp.MustCompile("email"), Mask: MaskRedact},
+	}
+
+	m, ok := MatchMaskPattern(patterns, "work_email", "")
+	assert.True(t, ok)
+	assert.Equal(t, MaskHash, m)
+
+	m, ok = MatchMaskPattern(patterns, "home_email", "")
+	assert.True(t, ok)
+	assert.Equal(t, MaskRedact, m)
+
+	_, ok = MatchMaskPattern(patterns, "name", "")
+	assert.False(t, ok)
+}
+
+func TestResolveColumnMasks_ExplicitTakesPrecedence(t *testing.T) {
+	t.Parallel()
+	columns := map[string]string{
+		"email":        "character varying",
+		"backup_email": "character varying",
+		"email_hash":   "text",
+		"id":           "integer",
+	}
+	explicit := map[string]MaskType{"email": MaskHash}
+
+	got := ResolveColumnMasks(columns, explicit, []MaskPattern{emailPattern()})
+
+	assert.Equal(t, map[string]MaskType{
+		"email":        MaskHash,   // explicit mask wins over the pattern
+		"backup_email": MaskRedact, // pattern
+	}, got)
+}
+
+func TestMaskRowsByPattern(t *testing.T) {
+	t.Parallel()
+	rows := []map[string]any{
+		{"id": 1, "email": "a@example.com", "backup_email": "b@example.com", "contact": "c@example.com"},
+	}
+	explicit := map[string]MaskType{"email": MaskNull}
+	aliases := map[string]string{"work_email": "contact"}
+
+	// Explicit masks run first, as in the query service.
+	MaskRowsWithAliases(rows, explicit, aliases)
+	MaskRowsByPattern(rows, explicit, []MaskPattern{emailPattern()}, aliases, false)
+
+	assert.Nil(t, rows[0]["email"], "explicit mask applied once, not overridden")
+	assert.Equal(t, "***", rows[0]["backup_email"])
+	assert.Equal(t, "***", rows[0]["contact"], "alias source matched the pattern")
+	assert.Equal(t, 1, rows[0]["id"])
+}
+
+func TestMaskRowsByPattern_AliasOfExplicitSkipped(t *testing.T) {
+	t.Parallel()
+	rows := []map[string]any{{"contact": "a@example.com"}}
+	explicit := map[string]MaskType{"email": MaskHash}
+	aliases := map[string]string{"email": "contact"}
+
+	MaskRowsWithAliases(rows, explicit, aliases)
+	hashed := rows[0]["contact"]
+	MaskRowsByPattern(rows, explicit, []MaskPattern{{Name: regexp.MustCompile("contact"), Mask: MaskRedact}}, aliases, false)
+
+	assert.Equal(t, hashed, rows[0]["contact"])
+}
+
+func TestMaskRowsByPattern_NoPatterns(t *testing.T) {
+	t.Parallel()
+	rows := []map[string]any{{"email": "a@example.com"}}
+	MaskRowsByPattern(rows, nil, nil, nil, false)
+	assert.Equal(t, "a@example.com", rows[0]["email"])
+}
+
+func TestMaskRowsByPattern_NumberedDuplicates(t *testing.T) {
+	t.Parallel()
+	ssn := []MaskPattern{{Name: regexp.MustCompile("^ssn$"), Mask: MaskRedact}}
+
+	rows := []map[string]any{{"ssn": "123", "ssn_2": "456", "address_2": "x"}}
+	MaskRowsByPattern(rows, nil, ssn, nil, true)
+	assert.Equal(t, map[string]any{"ssn": "***", "ssn_2": "***", "address_2": "x"}, rows[0])
+
+	rows = []map[string]any{{"ssn_2": "456"}}
+	MaskRowsByPattern(rows, nil, ssn, nil, false)
+	assert.Equal(t, "456", rows[0]["ssn_2"], "only numbered results are matched by base name")
+}
@@ -3,6 +3,8 @@ package domain
 import (
 	"crypto/sha256"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 // MaskType represents a column masking strategy.
@@ -25,6 +27,14 @@ func (m MaskType) Valid() bool {
 	return false
 }
 
+// ToolMasking overrides how masking applies to the output of one tool.
+// The zero value keeps the defaults: column masks apply, and EXPLAIN literals
+// are redacted only when the server redacts them for every tool.
+type ToolMasking struct {
+	SkipColumns        bool // leave result and sample row columns unmasked
+	RedactPlanLiterals bool // replace quoted literals in EXPLAIN output with '***'
+}
+
 // ApplyMask transforms a value according to the mask type.
 // Masked values may change type (e.g. int -> string for hash/partial).
 // MaskNull returns nil, which is indistinguishable from SQL NULL.
@@ -108,3 +118,134 @@ func MaskRowsWithAliases(rows []map[string]any, masks map[string]MaskType, alias
 		}
 	}
 }
+
+// MaskDerivedColumns masks result columns computed from a masked column,
+// as found by ExtractDerivedColumns (result name -> source columns), so
+// that SELECT min(ssn) does not reveal the smallest SSN. A column takes the
+// mask of its first masked source, by explicit mask, then pattern. A column
+// reading a WholeRow source, whose columns are unknown, is redacted unless
+// another source gives it a mask. Columns whose own name is masked are left
+// to MaskRowsWithAliases and MaskRowsByPattern.
+func MaskDerivedColumns(rows []map[string]any, derived map[string][]string, explicit map[string]MaskType, patterns []MaskPattern) {
+	if len(derived) == 0 || (len(explicit) == 0 && len(patterns) == 0) {
+		return
+	}
+	masks := make(map[string]MaskType)
+	for key, sources := range derived {
+		if _, ok := explicit[key]; ok {
+			continue
+		}
+		if _, ok := MatchMaskPattern(patterns, key, ""); ok {
+			continue
+		}
+		wholeRow := false
+		for _, src := range sources {
+			if src == WholeRow {
+				wholeRow = true
+				continue
+			}
+			if m, ok := explicit[src]; ok {
+				masks[key] = m
+				break
+			}
+			if m, ok := MatchMaskPattern(patterns, src, ""); ok {
+				masks[key] = m
+				break
+			}
+		}
+		if _, ok := masks[key]; !ok && wholeRow {
+			masks[key] = MaskRedact
+		}
+	}
+	MaskRows(rows, masks)
+}
+
+// DuplicateColumnName is the result key of the nth column named name when
+// repeated column names are numbered: id, id_2, id_3.
+func DuplicateColumnName(name string, n int) string {
+	return name + "_" + strconv.Itoa(n)
+}
+
+// DuplicateColumnBase returns the name a numbered result key repeats
+// ("id" for "id_2"); ok is false for keys not shaped like DuplicateColumnName.
+func DuplicateColumnBase(key string) (base string, ok bool) {
+	i := strings.LastIndexByte(key, '_')
+	if i <= 0 {
+		return "", false
+	}
+	if n, err := strconv.Atoi(key[i+1:]); err != nil || n < 2 {
+		return "", false
+	}
+	return key[:i], true
+}
+
+// DuplicateColumnMasks returns masks plus an entry for every numbered
+// result key in rows (see DuplicateColumnName) whose base name is masked, so
+// a repeated column is masked like the first one. Keys with a mask of their
+// own keep it. masks is returned unchanged when nothing is added.
+func DuplicateColumnMasks[M any](rows []map[string]any, masks map[string]M) map[string]M {
+	if len(masks) == 0 || len(rows) == 0 {
+		return masks
+	}
+	var extended map[string]M
+	for key := range rows[0] {
+		if _, ok := masks[key]; ok {
+			continue
+		}
+		base, ok := DuplicateColumnBase(key)
+		if !ok {
+			continue
+		}
+		m, ok := masks[base]
+		if !ok {
+			continue
+		}
+		if extended == nil {
+			extended = make(map[string]M, len(masks)+1)
+			for k, v := range masks {
+				extended[k] = v
+			}
+		}
+		extended[key] = m
+	}
+	if extended == nil {
+		return masks
+	}
+	return extended
+}
+
+// DefaultMaskedTables returns the tables among refs that fall under a
+// default mask, each with its own default from tables or else its schema's
+// from schemas. A reference without a schema may resolve to a table in any
+// schema, so it matches every covered table of that name.
+func DefaultMaskedTables(refs []TableRef, tables map[TableRef]MaskType, schemas map[string]MaskType) map[TableRef]MaskType {
+	if len(tables) == 0 && len(schemas) == 0 {
+		return nil
+	}
+	covered := make(map[TableRef]MaskType)
+	add := func(t TableRef) {
+		if m, ok := tables[t]; ok {
+			covered[t] = m
+		} else if m, ok := schemas[t.Schema]; ok {
+			covered[t] = m
+		}
+	}
+	for _, ref := range refs {
+		if ref.Schema != "" {
+			add(ref)
+			continue
+		}
+		for t := range tables {
+			if t.Name == ref.Name {
+				add(t)
+			}
+		}
+		for schema := range schemas {
+			add(TableRef{Schema: schema, Name: ref.Name})
+		}
+	}
+	if len(covered) == 0 {
+		return nil
+	}
+	return covered
+}
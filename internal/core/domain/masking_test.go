@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"regexp"
 	"strings"
 	"testing"
 
@@ -273,3 +274,75 @@ func TestMaskRowsWithAliases_DirectMatchTakesPrecedence(t *testing.T) {
 	assert.Equal(t, "***", rows[0]["Email"])
 	assert.Equal(t, "aliased@example.com", rows[0]["email"]) // alias not touched when direct match exists
 }
+
+func TestMaskDerivedColumns(t *testing.T) {
+	t.Parallel()
+	rows := []map[string]any{{"min": "078-05-1120", "upper": "ALICE@EXAMPLE.COM", "max": 42, "total": 10, "j": `{"ssn": "078-05-1120"}`, "h": "078-05-1120"}}
+	derived := map[string][]string{
+		"min":   {"ssn"},
+		"upper": {"name", "work_email"},
+		"max":   {"id"},
+		"total": {"ssn"},
+		"j":     {WholeRow},
+		"h":     {WholeRow, "total"},
+	}
+	explicit := map[string]MaskType{"ssn": MaskRedact, "total": MaskNull}
+	patterns := []MaskPattern{{Name: regexp.MustCompile("email"), Mask: MaskPartial}}
+
+	MaskDerivedColumns(rows, derived, explicit, patterns)
+
+	assert.Equal(t, map[string]any{
+		"min":   "***",
+		"upper": "*************.COM",
+		"max":   42,
+		"total": 10, // masked by name elsewhere, not twice
+		"j":     "***",
+		"h":     nil, // a masked source wins over the whole-row fallback
+	}, rows[0])
+}
+
+func TestDuplicateColumnMasks(t *testing.T) {
+	t.Parallel()
+	masks := map[string]MaskType{"email": MaskRedact, "email_3": MaskNull}
+	rows := []map[string]any{
+		{"email": "a", "email_2": "b", "email_3": "c", "email_x": "d", "email_1": "e", "name_2": "f"},
+	}
+
+	got := DuplicateColumnMasks(rows, masks)
+
+	assert.Equal(t, map[string]MaskType{"email": MaskRedact, "email_2": MaskRedact, "email_3": MaskNull}, got)
+	assert.Len(t, masks, 2, "the input map is not modified")
+	assert.Equal(t, masks, DuplicateColumnMasks([]map[string]any{{"email": "a"}}, masks))
+}
+
+func TestDefaultMaskedTables(t *testing.T) {
+	t.Parallel()
+	tables := map[TableRef]MaskType{
+		{Schema: "pii", Name: "cards"}:    MaskNull,
+		{Schema: "public", Name: "audit"}: MaskHash,
+	}
+	schemas := map[string]MaskType{"pii": MaskRedact}
+
+	tests := []struct {
+		name string
+		refs []TableRef
+		want map[TableRef]MaskType
+	}{
+		{"not covered", []TableRef{{Schema: "public", Name: "users"}}, nil},
+		{"schema default", []TableRef{{Schema: "pii", Name: "people"}}, map[TableRef]MaskType{{Schema: "pii", Name: "people"}: MaskRedact}},
+		{"table default beats schema", []TableRef{{Schema: "pii", Name: "cards"}}, map[TableRef]MaskType{{Schema: "pii", Name: "cards"}: MaskNull}},
+		{"unqualified matches every schema", []TableRef{{Name: "audit"}, {Name: "people"}}, map[TableRef]MaskType{
+			{Schema: "public", Name: "audit"}: MaskHash,
+			{Schema: "pii", Name: "audit"}:    MaskRedact,
+			{Schema: "pii", Name: "people"}:   MaskRedact,
+		}},
+		{"other schema", []TableRef{{Schema: "archive", Name: "cards"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, DefaultMaskedTables(tt.refs, tables, schemas))
+		})
+	}
+	assert.Nil(t, DefaultMaskedTables([]TableRef{{Name: "cards"}}, nil, nil))
+}
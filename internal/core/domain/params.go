@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// ErrBadParam is returned when a value cannot be bound to a query parameter.
+var ErrBadParam = errors.New("invalid parameter")
+
+// ParamType is the declared type of a $N parameter in an operator-defined
+// query.
+type ParamType string
+
+const (
+	ParamText      ParamType = "text"
+	ParamInteger   ParamType = "integer"
+	ParamNumeric   ParamType = "numeric"
+	ParamBoolean   ParamType = "boolean"
+	ParamTimestamp ParamType = "timestamp"
+	ParamDate      ParamType = "date"
+	ParamUUID      ParamType = "uuid"
+)
+
+// paramPGTypes maps each ParamType to the PostgreSQL type its literal is
+// cast to.
+var paramPGTypes = map[ParamType]string{
+	ParamText:      "text",
+	ParamInteger:   "bigint",
+	ParamNumeric:   "numeric",
+	ParamBoolean:   "boolean",
+	ParamTimestamp: "timestamptz",
+	ParamDate:      "date",
+	ParamUUID:      "uuid",
+}
+
+// Valid reports whether t is a known parameter type.
+func (t ParamType) Valid() bool {
+	_, ok := paramPGTypes[t]
+	return ok
+}
+
+// ParamCount returns the highest $N parameter number used in sql, or 0 when
+// it has none.
+func ParamCount(sql string) (int, error) {
+	scan, err := pg_query.Scan(sql)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrParseFailed, err)
+	}
+	n := 0
+	for _, tok := range scan.Tokens {
+		if tok.Token != pg_query.Token_PARAM {
+			continue
+		}
+		num, err := strconv.Atoi(sql[tok.Start+1 : tok.End])
+		if err != nil {
+			return 0, fmt.Errorf("%w: bad parameter %q", ErrParseFailed, sql[tok.Start:tok.End])
+		}
+		n = max(n, num)
+	}
+	return n, nil
+}
+
+// BindParams replaces every $N in sql with values[N-1] as a typed literal,
+// e.g. (E'2024-01-01'::timestamptz), so the statement can run without
+// placeholders. values hold decoded JSON: strings, float64 numbers or
+// booleans. Each value is checked against types[N-1]; PostgreSQL checks
+// the literal again when it is cast.
+func BindParams(sql string, types []ParamType, values []any) (string, error) {
+	if len(values) != len(types) {
+		return "", fmt.Errorf("%w: got %d values for %d parameters", ErrBadParam, len(values), len(types))
+	}
+	literals := make([]string, len(values))
+	for i, v := range values {
+		text, err := paramText(types[i], v)
+		if err != nil {
+			return "", fmt.Errorf("%w: $%d: %v", ErrBadParam, i+1, err)
+		}
+		literals[i] = fmt.Sprintf("(%s::%s)", quoteLiteral(text), paramPGTypes[types[i]])
+	}
+
+	scan, err := pg_query.Scan(sql)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrParseFailed, err)
+	}
+	var b strings.Builder
+	last := 0
+	for _, tok := range scan.Tokens {
+		if tok.Token != pg_query.Token_PARAM {
+			continue
+		}
+		num, err := strconv.Atoi(sql[tok.Start+1 : tok.End])
+		if err != nil || num < 1 || num > len(literals) {
+			return "", fmt.Errorf("%w: %s has no value", ErrBadParam, sql[tok.Start:tok.End])
+		}
+		b.WriteString(sql[last:tok.Start])
+		b.WriteString(literals[num-1])
+		last = int(tok.End)
+	}
+	b.WriteString(sql[last:])
+	return b.String(), nil
+}
+
+// paramText converts a decoded JSON value to the text of a literal of type t.
+func paramText(t ParamType, v any) (string, error) {
+	switch t {
+	case ParamInteger:
+		switch n := v.(type) {
+		case float64:
+			if n != math.Trunc(n) || math.Abs(n) > 1<<53 {
+				return "", fmt.Errorf("%v is not an integer", n)
+			}
+			return strconv.FormatInt(int64(n), 10), nil
+		case string:
+			if _, err := strconv.ParseInt(n, 10, 64); err != nil {
+				return "", fmt.Errorf("%q is not an integer", n)
+			}
+			return n, nil
+		}
+		return "", fmt.Errorf("expected an integer")
+	case ParamNumeric:
+		switch n := v.(type) {
+		case float64:
+			return strconv.FormatFloat(n, 'f', -1, 64), nil
+		case string:
+			if _, err := strconv.ParseFloat(n, 64); err != nil {
+				return "", fmt.Errorf("%q is not a number", n)
+			}
+			return n, nil
+		}
+		return "", fmt.Errorf("expected a number")
+	case ParamBoolean:
+		switch b := v.(type) {
+		case bool:
+			return strconv.FormatBool(b), nil
+		case string:
+			if _, err := strconv.ParseBool(b); err != nil {
+				return "", fmt.Errorf("%q is not a boolean", b)
+			}
+			return b, nil
+		}
+		return "", fmt.Errorf("expected a boolean")
+	case ParamText, ParamTimestamp, ParamDate, ParamUUID:
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("expected a string")
+		}
+		if strings.ContainsRune(s, 0) {
+			return "", fmt.Errorf("contains a NUL byte")
+		}
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown type %q", t)
+}
+
+// quoteLiteral quotes s as an escape string constant (E'...'), which reads
+// the same whatever standard_conforming_strings is set to.
+func quoteLiteral(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `''`)
+	return "E'" + s + "'"
+}
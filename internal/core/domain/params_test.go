@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamCount(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		sql  string
+		want int
+	}{
+		{"SELECT 1", 0},
+		{"SELECT * FROM users WHERE created_at > $1", 1},
+		{"SELECT $2, $1, $2", 2},
+		{"SELECT '$1', \"$2\" FROM t -- $3", 0},
+	}
+	for _, tt := range tests {
+		got, err := ParamCount(tt.sql)
+		require.NoError(t, err, tt.sql)
+		assert.Equal(t, tt.want, got, tt.sql)
+	}
+}
+
+func TestBindParams(t *testing.T) {
+	t.Parallel()
+	sql := "SELECT * FROM users WHERE created_at > $1 AND name = $2 AND age >= $3 AND active = $4 OR id = $3"
+	got, err := BindParams(sql,
+		[]ParamType{ParamTimestamp, ParamText, ParamInteger, ParamBoolean},
+		[]any{"2024-01-01", `O'Brien \ co`, float64(21), true})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE created_at > (E'2024-01-01'::timestamptz) AND name = (E'O''Brien \\\\ co'::text)"+
+		" AND age >= (E'21'::bigint) AND active = (E'true'::boolean) OR id = (E'21'::bigint)", got)
+
+	// The result must still be a single valid SELECT.
+	require.NoError(t, NewPgQueryValidator().Validate(got))
+}
+
+func TestBindParams_Numeric(t *testing.T) {
+	t.Parallel()
+	got, err := BindParams("SELECT $1, $2", []ParamType{ParamNumeric, ParamInteger}, []any{"12.50", "-7"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT (E'12.50'::numeric), (E'-7'::bigint)", got)
+}
+
+func TestBindParams_Invalid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		types  []ParamType
+		values []any
+	}{
+		{"fraction for integer", []ParamType{ParamInteger}, []any{1.5}},
+		{"text for integer", []ParamType{ParamInteger}, []any{"1; DROP TABLE users"}},
+		{"number for text", []ParamType{ParamText}, []any{float64(3)}},
+		{"bad boolean", []ParamType{ParamBoolean}, []any{"maybe"}},
+		{"bad numeric", []ParamType{ParamNumeric}, []any{"12,5"}},
+		{"nul byte", []ParamType{ParamText}, []any{"a\x00b"}},
+		{"missing value", []ParamType{ParamText}, []any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := BindParams("SELECT $1", tt.types, tt.values)
+			require.ErrorIs(t, err, ErrBadParam)
+		})
+	}
+}
+
+func TestParamType_Valid(t *testing.T) {
+	t.Parallel()
+	assert.True(t, ParamTimestamp.Valid())
+	assert.False(t, ParamType("jsonb").Valid())
+	assert.False(t, ParamType("").Valid())
+}
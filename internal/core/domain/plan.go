@@ -0,0 +1,325 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// ErrBadPlan is returned when EXPLAIN (FORMAT JSON) output has an unexpected shape.
+var ErrBadPlan = errors.New("unexpected EXPLAIN output")
+
+// PlanSummary holds the planner's estimates for the root node of a plan.
+type PlanSummary struct {
+	NodeType    string  `json:"Node Type"`
+	StartupCost float64 `json:"Startup Cost"`
+	TotalCost   float64 `json:"Total Cost"`
+	PlanRows    float64 `json:"Plan Rows"`
+}
+
+// ParseExplainJSON extracts the root plan node from the value of the
+// "QUERY PLAN" column returned by EXPLAIN (FORMAT JSON). The driver may hand
+// it back already decoded ([]any) or as raw JSON (string or []byte).
+func ParseExplainJSON(v any) (*PlanSummary, error) {
+	var doc []struct {
+		Plan *PlanSummary `json:"Plan"`
+	}
+	if err := decodeExplainJSON(v, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc) == 0 || doc[0].Plan == nil {
+		return nil, ErrBadPlan
+	}
+	return doc[0].Plan, nil
+}
+
+// decodeExplainJSON unmarshals an EXPLAIN (FORMAT JSON) value, raw or
+// already decoded, into dst.
+func decodeExplainJSON(v any, dst any) error {
+	var raw []byte
+	switch val := v.(type) {
+	case string:
+		raw = []byte(val)
+	case []byte:
+		raw = val
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrBadPlan, err)
+		}
+		raw = b
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return fmt.Errorf("%w: %w", ErrBadPlan, err)
+	}
+	return nil
+}
+
+// DefaultLargeSeqScanRows is the row count from which a sequential scan is
+// reported as scanning a large relation.
+const DefaultLargeSeqScanRows = 10000
+
+// SeqScan is a sequential scan found in a plan.
+type SeqScan struct {
+	Relation string  `json:"relation"`
+	Rows     float64 `json:"rows"`
+	Large    bool    `json:"large,omitempty"`
+}
+
+// IndexUsage reports which indexes a plan reads and where it scans tables
+// sequentially instead.
+type IndexUsage struct {
+	IndexesUsed  []string  `json:"indexes_used"`
+	SeqScans     []SeqScan `json:"seq_scans,omitempty"`
+	LargeSeqScan bool      `json:"large_seq_scan"`
+}
+
+// PlanIndexUsage walks every node of an EXPLAIN (FORMAT JSON) plan and lists
+// the indexes named by index, index-only and bitmap index scans, in plan
+// order and without duplicates, and the sequential scans. A sequential scan
+// is large when it covers at least largeRows rows: the rows it read with
+// ANALYZE (including those removed by its filter), otherwise the planner's
+// estimate of the rows it returns.
+func PlanIndexUsage(v any, largeRows float64) (*IndexUsage, error) {
+	var doc []struct {
+		Plan map[string]any `json:"Plan"`
+	}
+	if err := decodeExplainJSON(v, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc) == 0 || doc[0].Plan == nil {
+		return nil, ErrBadPlan
+	}
+
+	usage := &IndexUsage{IndexesUsed: []string{}}
+	seen := make(map[string]bool)
+	var walk func(node map[string]any)
+	walk = func(node map[string]any) {
+		if name, ok := node["Index Name"].(string); ok && !seen[name] {
+			seen[name] = true
+			usage.IndexesUsed = append(usage.IndexesUsed, name)
+		}
+		if node["Node Type"] == "Seq Scan" {
+			scan := SeqScan{Relation: planRelation(node), Rows: seqScanRows(node)}
+			scan.Large = scan.Rows >= largeRows
+			usage.LargeSeqScan = usage.LargeSeqScan || scan.Large
+			usage.SeqScans = append(usage.SeqScans, scan)
+		}
+		children, _ := node["Plans"].([]any)
+		for _, child := range children {
+			if c, ok := child.(map[string]any); ok {
+				walk(c)
+			}
+		}
+	}
+	walk(doc[0].Plan)
+	return usage, nil
+}
+
+// planRelation returns the relation a scan node reads, schema-qualified when
+// the plan includes the schema (EXPLAIN VERBOSE).
+func planRelation(node map[string]any) string {
+	name, _ := node["Relation Name"].(string)
+	if schema, ok := node["Schema"].(string); ok && schema != "" {
+		return schema + "." + name
+	}
+	return name
+}
+
+// seqScanRows returns the rows a sequential scan read: per-loop actual rows
+// plus rows removed by the filter, times loops, when the plan was analyzed,
+// and the planner's row estimate otherwise.
+func seqScanRows(node map[string]any) float64 {
+	actual, ok := node["Actual Rows"].(float64)
+	if !ok {
+		rows, _ := node["Plan Rows"].(float64)
+		return rows
+	}
+	removed, _ := node["Rows Removed by Filter"].(float64)
+	loops, ok := node["Actual Loops"].(float64)
+	if !ok || loops < 1 {
+		loops = 1
+	}
+	return (actual + removed) * loops
+}
+
+// DefaultMisestimateRatio is how many times off, in either direction, the
+// planner's row estimate for a node must be before it is reported.
+const DefaultMisestimateRatio = 10
+
+// Misestimate is a plan node whose estimated rows were far from the rows it
+// actually returned. Both counts are per loop, as EXPLAIN reports them.
+type Misestimate struct {
+	NodeType      string  `json:"node_type"`
+	Relation      string  `json:"relation,omitempty"`
+	EstimatedRows float64 `json:"estimated_rows"`
+	ActualRows    float64 `json:"actual_rows"`
+	Ratio         float64 `json:"ratio"`     // how many times off, always >= 1
+	Direction     string  `json:"direction"` // "under" when more rows came back than estimated, else "over"
+}
+
+// RowEstimates reports the nodes of an analyzed plan whose row estimates
+// were badly off, a common cause of poor join orders and strategies.
+type RowEstimates struct {
+	Misestimates     []Misestimate `json:"misestimates"`
+	WorstMisestimate *Misestimate  `json:"worst_misestimate,omitempty"`
+	Suggestion       string        `json:"suggestion,omitempty"`
+}
+
+// PlanRowEstimates walks every node of an EXPLAIN (ANALYZE, FORMAT JSON)
+// plan and lists, in plan order, those whose estimated and actual rows
+// differ by a factor of minRatio or more. Counts below one row are treated
+// as one, so an estimate of 1 for a node that returned nothing is not a
+// misestimate. Nodes that never ran are skipped. It returns ErrBadPlan for
+// a plan that was not analyzed.
+func PlanRowEstimates(v any, minRatio float64) (*RowEstimates, error) {
+	var doc []struct {
+		Plan map[string]any `json:"Plan"`
+	}
+	if err := decodeExplainJSON(v, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc) == 0 || doc[0].Plan == nil {
+		return nil, ErrBadPlan
+	}
+	if _, ok := doc[0].Plan["Actual Rows"]; !ok {
+		return nil, fmt.Errorf("%w: the plan has no actual row counts, run it with ANALYZE", ErrBadPlan)
+	}
+
+	est := &RowEstimates{Misestimates: []Misestimate{}}
+	var walk func(node map[string]any)
+	walk = func(node map[string]any) {
+		if m, ok := nodeMisestimate(node); ok && m.Ratio >= minRatio {
+			est.Misestimates = append(est.Misestimates, m)
+		}
+		children, _ := node["Plans"].([]any)
+		for _, child := range children {
+			if c, ok := child.(map[string]any); ok {
+				walk(c)
+			}
+		}
+	}
+	walk(doc[0].Plan)
+
+	var relations []string
+	for i, m := range est.Misestimates {
+		if est.WorstMisestimate == nil || m.Ratio > est.WorstMisestimate.Ratio {
+			est.WorstMisestimate = &est.Misestimates[i]
+		}
+		if m.Relation != "" && !slices.Contains(relations, m.Relation) {
+			relations = append(relations, m.Relation)
+		}
+	}
+	switch {
+	case len(relations) > 0:
+		est.Suggestion = fmt.Sprintf("run ANALYZE on %s to refresh planner statistics; if estimates stay off, consider CREATE STATISTICS on correlated columns",
+			strings.Join(relations, ", "))
+	case len(est.Misestimates) > 0:
+		est.Suggestion = "run ANALYZE on the tables in the query to refresh planner statistics; if estimates stay off, consider CREATE STATISTICS on correlated columns"
+	}
+	return est, nil
+}
+
+// nodeMisestimate compares a node's estimated and actual rows. ok is false
+// for nodes without actual rows or that never ran.
+func nodeMisestimate(node map[string]any) (m Misestimate, ok bool) {
+	actual, ok := node["Actual Rows"].(float64)
+	if !ok {
+		return m, false
+	}
+	if loops, ok := node["Actual Loops"].(float64); ok && loops == 0 {
+		return m, false
+	}
+	planned, _ := node["Plan Rows"].(float64)
+	m = Misestimate{Relation: planRelation(node), EstimatedRows: planned, ActualRows: actual, Direction: "over"}
+	m.NodeType, _ = node["Node Type"].(string)
+
+	e, a := max(planned, 1), max(actual, 1)
+	ratio := e / a
+	if a > e {
+		ratio = a / e
+		m.Direction = "under"
+	}
+	m.Ratio = math.Round(ratio*10) / 10
+	return m, true
+}
+
+// planColumn is the column EXPLAIN returns its plan in.
+const planColumn = "QUERY PLAN"
+
+// IsPlanOutput reports whether rows look like EXPLAIN output: at least one
+// row, each with the single "QUERY PLAN" column. It also recognizes plans of
+// queries the executor turned into EXPLAIN, whose SQL does not show it.
+func IsPlanOutput(rows []map[string]any) bool {
+	for _, row := range rows {
+		if _, ok := row[planColumn]; !ok || len(row) != 1 {
+			return false
+		}
+	}
+	return len(rows) > 0
+}
+
+// redactedLiteral replaces the contents of quoted literals in plans.
+const redactedLiteral = "'***'"
+
+// RedactPlanLiterals replaces every single-quoted literal in EXPLAIN output
+// with '***', so plans don't expose filter constants or sample values. It
+// rewrites string values in place, descending into decoded FORMAT JSON plans.
+// Type casts (e.g. '***'::text) and numeric constants are kept.
+func RedactPlanLiterals(rows []map[string]any) {
+	for _, row := range rows {
+		for k, v := range row {
+			row[k] = redactPlanValue(v)
+		}
+	}
+}
+
+func redactPlanValue(v any) any {
+	switch val := v.(type) {
+	case string:
+		return RedactQuotedLiterals(val)
+	case []any:
+		for i := range val {
+			val[i] = redactPlanValue(val[i])
+		}
+		return val
+	case map[string]any:
+		for k := range val {
+			val[k] = redactPlanValue(val[k])
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// RedactQuotedLiterals replaces each SQL string literal in s, including
+// doubled-quote escapes inside it, with '***'. An unterminated literal is
+// redacted up to the end of s.
+func RedactQuotedLiterals(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\'' {
+			b.WriteByte(s[i])
+			continue
+		}
+		// Skip to the closing quote; '' inside a literal is an escaped quote.
+		j := i + 1
+		for j < len(s) {
+			if s[j] == '\'' {
+				if j+1 < len(s) && s[j+1] == '\'' {
+					j += 2
+					continue
+				}
+				break
+			}
+			j++
+		}
+		b.WriteString(redactedLiteral)
+		i = j
+	}
+	return b.String()
+}
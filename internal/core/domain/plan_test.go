@@ -0,0 +1,252 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const samplePlanJSON = `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders",
+	"Startup Cost": 0.00, "Total Cost": 35.50, "Plan Rows": 12, "Plan Width": 4}}]`
+
+func TestParseExplainJSON_Raw(t *testing.T) {
+	t.Parallel()
+	for _, v := range []any{samplePlanJSON, []byte(samplePlanJSON)} {
+		plan, err := ParseExplainJSON(v)
+		require.NoError(t, err)
+		assert.Equal(t, "Seq Scan", plan.NodeType)
+		assert.InDelta(t, 35.5, plan.TotalCost, 1e-9)
+		assert.InDelta(t, 12, plan.PlanRows, 1e-9)
+	}
+}
+
+func TestParseExplainJSON_Decoded(t *testing.T) {
+	t.Parallel()
+	decoded := []any{map[string]any{"Plan": map[string]any{
+		"Node Type": "Index Scan", "Total Cost": 8.29, "Plan Rows": float64(1),
+	}}}
+	plan, err := ParseExplainJSON(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, "Index Scan", plan.NodeType)
+	assert.InDelta(t, 1, plan.PlanRows, 1e-9)
+}
+
+func TestParseExplainJSON_Invalid(t *testing.T) {
+	t.Parallel()
+	for _, v := range []any{"not json", "[]", `[{"NoPlan": {}}]`, "Seq Scan on orders"} {
+		_, err := ParseExplainJSON(v)
+		assert.ErrorIs(t, err, ErrBadPlan, v)
+	}
+}
+
+// indexUsagePlanJSON is EXPLAIN (FORMAT JSON) output for a join of orders,
+// customers and line items, trimmed to the fields PlanIndexUsage reads.
+const indexUsagePlanJSON = `[
+  {
+    "Plan": {
+      "Node Type": "Hash Join",
+      "Plan Rows": 2400,
+      "Plans": [
+        {
+          "Node Type": "Nested Loop",
+          "Parent Relationship": "Outer",
+          "Plan Rows": 2400,
+          "Plans": [
+            {
+              "Node Type": "Bitmap Heap Scan",
+              "Relation Name": "orders",
+              "Plan Rows": 120,
+              "Plans": [
+                {"Node Type": "Bitmap Index Scan", "Index Name": "idx_orders_created_at", "Plan Rows": 120}
+              ]
+            },
+            {"Node Type": "Index Scan", "Relation Name": "line_items", "Index Name": "idx_line_items_order_id", "Plan Rows": 20}
+          ]
+        },
+        {
+          "Node Type": "Hash",
+          "Parent Relationship": "Inner",
+          "Plan Rows": 50000,
+          "Plans": [
+            {"Node Type": "Seq Scan", "Relation Name": "customers", "Plan Rows": 50000}
+          ]
+        }
+      ]
+    }
+  },
+  {"Planning Time": 0.4}
+]`
+
+func TestPlanIndexUsage(t *testing.T) {
+	t.Parallel()
+	usage, err := PlanIndexUsage(indexUsagePlanJSON, DefaultLargeSeqScanRows)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"idx_orders_created_at", "idx_line_items_order_id"}, usage.IndexesUsed)
+	assert.Equal(t, []SeqScan{{Relation: "customers", Rows: 50000, Large: true}}, usage.SeqScans)
+	assert.True(t, usage.LargeSeqScan)
+
+	usage, err = PlanIndexUsage(indexUsagePlanJSON, 100000)
+	require.NoError(t, err)
+	assert.False(t, usage.LargeSeqScan, "below the threshold")
+	assert.False(t, usage.SeqScans[0].Large)
+}
+
+func TestPlanIndexUsage_SeqScanOnly(t *testing.T) {
+	t.Parallel()
+	usage, err := PlanIndexUsage(samplePlanJSON, DefaultLargeSeqScanRows)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, usage.IndexesUsed)
+	assert.Equal(t, []SeqScan{{Relation: "orders", Rows: 12}}, usage.SeqScans)
+	assert.False(t, usage.LargeSeqScan)
+}
+
+func TestPlanIndexUsage_Analyzed(t *testing.T) {
+	t.Parallel()
+	// A selective filter returns few rows, but the scan still read the
+	// whole table.
+	decoded := []any{map[string]any{"Plan": map[string]any{
+		"Node Type": "Seq Scan", "Schema": "sales", "Relation Name": "orders",
+		"Plan Rows": float64(3), "Actual Rows": float64(2), "Actual Loops": float64(1),
+		"Rows Removed by Filter": float64(19998),
+	}}}
+	usage, err := PlanIndexUsage(decoded, DefaultLargeSeqScanRows)
+	require.NoError(t, err)
+	assert.Equal(t, []SeqScan{{Relation: "sales.orders", Rows: 20000, Large: true}}, usage.SeqScans)
+	assert.True(t, usage.LargeSeqScan)
+}
+
+func TestPlanIndexUsage_Invalid(t *testing.T) {
+	t.Parallel()
+	for _, v := range []any{"not json", "[]", `[{"NoPlan": {}}]`} {
+		_, err := PlanIndexUsage(v, DefaultLargeSeqScanRows)
+		assert.ErrorIs(t, err, ErrBadPlan, v)
+	}
+}
+
+// misestimatePlanJSON is EXPLAIN (ANALYZE, FORMAT JSON) output for a join
+// whose filter on customers matched far more rows than the planner expected,
+// trimmed to the fields PlanRowEstimates reads.
+const misestimatePlanJSON = `[
+  {
+    "Plan": {
+      "Node Type": "Hash Join",
+      "Plan Rows": 100, "Actual Rows": 48000, "Actual Loops": 1,
+      "Plans": [
+        {"Node Type": "Seq Scan", "Relation Name": "orders", "Plan Rows": 5000, "Actual Rows": 4800, "Actual Loops": 1},
+        {
+          "Node Type": "Hash",
+          "Plan Rows": 10, "Actual Rows": 900, "Actual Loops": 1,
+          "Plans": [
+            {"Node Type": "Seq Scan", "Relation Name": "customers", "Plan Rows": 10, "Actual Rows": 900, "Actual Loops": 1}
+          ]
+        },
+        {"Node Type": "Index Scan", "Relation Name": "refunds", "Plan Rows": 500, "Actual Rows": 0, "Actual Loops": 0},
+        {"Node Type": "Seq Scan", "Relation Name": "stores", "Plan Rows": 1, "Actual Rows": 0, "Actual Loops": 1}
+      ]
+    },
+    "Execution Time": 52.1
+  }
+]`
+
+func TestPlanRowEstimates(t *testing.T) {
+	t.Parallel()
+	est, err := PlanRowEstimates(misestimatePlanJSON, DefaultMisestimateRatio)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Misestimate{
+		{NodeType: "Hash Join", EstimatedRows: 100, ActualRows: 48000, Ratio: 480, Direction: "under"},
+		{NodeType: "Hash", EstimatedRows: 10, ActualRows: 900, Ratio: 90, Direction: "under"},
+		{NodeType: "Seq Scan", Relation: "customers", EstimatedRows: 10, ActualRows: 900, Ratio: 90, Direction: "under"},
+	}, est.Misestimates, "accurate, never-executed and empty nodes are not reported")
+	require.NotNil(t, est.WorstMisestimate)
+	assert.Equal(t, "Hash Join", est.WorstMisestimate.NodeType)
+	assert.Contains(t, est.Suggestion, "ANALYZE on customers")
+}
+
+func TestPlanRowEstimates_Overestimate(t *testing.T) {
+	t.Parallel()
+	decoded := []any{map[string]any{"Plan": map[string]any{
+		"Node Type": "Index Scan", "Schema": "sales", "Relation Name": "orders",
+		"Plan Rows": float64(2500), "Actual Rows": float64(3), "Actual Loops": float64(4),
+	}}}
+	est, err := PlanRowEstimates(decoded, DefaultMisestimateRatio)
+	require.NoError(t, err)
+	require.Len(t, est.Misestimates, 1)
+	assert.Equal(t, Misestimate{
+		NodeType: "Index Scan", Relation: "sales.orders", EstimatedRows: 2500, ActualRows: 3, Ratio: 833.3, Direction: "over",
+	}, est.Misestimates[0])
+}
+
+func TestPlanRowEstimates_Accurate(t *testing.T) {
+	t.Parallel()
+	decoded := []any{map[string]any{"Plan": map[string]any{
+		"Node Type": "Seq Scan", "Relation Name": "orders", "Plan Rows": float64(120), "Actual Rows": float64(100), "Actual Loops": float64(1),
+	}}}
+	est, err := PlanRowEstimates(decoded, DefaultMisestimateRatio)
+	require.NoError(t, err)
+	assert.Empty(t, est.Misestimates)
+	assert.Nil(t, est.WorstMisestimate)
+	assert.Empty(t, est.Suggestion)
+}
+
+func TestPlanRowEstimates_NotAnalyzed(t *testing.T) {
+	t.Parallel()
+	_, err := PlanRowEstimates(samplePlanJSON, DefaultMisestimateRatio)
+	require.ErrorIs(t, err, ErrBadPlan)
+	assert.Contains(t, err.Error(), "ANALYZE")
+}
+
+func TestIsPlanOutput(t *testing.T) {
+	t.Parallel()
+	assert.True(t, IsPlanOutput([]map[string]any{{"QUERY PLAN": "Seq Scan on users"}, {"QUERY PLAN": "  Filter: (id = 1)"}}))
+	assert.True(t, IsPlanOutput([]map[string]any{{"QUERY PLAN": []any{map[string]any{"Plan": map[string]any{}}}}}))
+	assert.False(t, IsPlanOutput(nil))
+	assert.False(t, IsPlanOutput([]map[string]any{{"id": 1}}))
+	assert.False(t, IsPlanOutput([]map[string]any{{"QUERY PLAN": "x", "id": 1}}))
+}
+
+func TestRedactQuotedLiterals(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, in, want string
+	}{
+		{"text filter", "Filter: (email = 'alice@example.com'::text)", "Filter: (email = '***'::text)"},
+		{"escaped quote", "Filter: (name = 'O''Brien'::text)", "Filter: (name = '***'::text)"},
+		{"several literals", "Index Cond: ((a = 'x'::text) AND (b = ANY ('{1,2}'::integer[])))", "Index Cond: ((a = '***'::text) AND (b = ANY ('***'::integer[])))"},
+		{"numeric constant kept", "Filter: (id = 42)", "Filter: (id = 42)"},
+		{"quoted identifier kept", `Seq Scan on "Users"`, `Seq Scan on "Users"`},
+		{"empty literal", "Filter: (note = ''::text)", "Filter: (note = '***'::text)"},
+		{"unterminated", "Filter: (note = 'abc", "Filter: (note = '***'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, RedactQuotedLiterals(tt.in))
+		})
+	}
+}
+
+func TestRedactPlanLiterals(t *testing.T) {
+	t.Parallel()
+	rows := []map[string]any{
+		{"QUERY PLAN": "Seq Scan on users  (cost=0.00..25.88 rows=6 width=36)"},
+		{"QUERY PLAN": "  Filter: (ssn = '123-45-6789'::text)"},
+	}
+	RedactPlanLiterals(rows)
+	assert.Equal(t, "Seq Scan on users  (cost=0.00..25.88 rows=6 width=36)", rows[0]["QUERY PLAN"])
+	assert.Equal(t, "  Filter: (ssn = '***'::text)", rows[1]["QUERY PLAN"])
+
+	// FORMAT JSON plans arrive decoded.
+	jsonRows := []map[string]any{{"QUERY PLAN": []any{map[string]any{
+		"Plan": map[string]any{
+			"Node Type": "Seq Scan",
+			"Filter":    "(ssn = '123-45-6789'::text)",
+			"Plan Rows": float64(6),
+		},
+	}}}}
+	RedactPlanLiterals(jsonRows)
+	plan := jsonRows[0]["QUERY PLAN"].([]any)[0].(map[string]any)["Plan"].(map[string]any)
+	assert.Equal(t, "(ssn = '***'::text)", plan["Filter"])
+	assert.Equal(t, float64(6), plan["Plan Rows"])
+}
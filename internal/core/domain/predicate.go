@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// ValidatePredicate checks that pred is a lone WHERE-clause expression, so it
+// can be spliced into "SELECT ... WHERE <pred>" without changing the shape of
+// the statement. Anything that would add clauses (UNION, ORDER BY, LIMIT,
+// FOR UPDATE, a second statement, ...) is rejected. Type checking is left to
+// the planner, except for bare numeric constants, which can never be boolean.
+func ValidatePredicate(pred string) error {
+	trimmed := strings.TrimSpace(pred)
+	if trimmed == "" {
+		return ErrEmptyQuery
+	}
+
+	tree, err := pg_query.Parse("SELECT 1 WHERE " + trimmed)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrParseFailed, err)
+	}
+	if len(tree.Stmts) != 1 {
+		return ErrMultiStatement
+	}
+
+	sel := tree.Stmts[0].GetStmt().GetSelectStmt()
+	if sel == nil || sel.WhereClause == nil {
+		return ErrBadPredicate
+	}
+	if sel.Op != pg_query.SetOperation_SETOP_NONE ||
+		len(sel.TargetList) != 1 ||
+		len(sel.FromClause) > 0 ||
+		len(sel.DistinctClause) > 0 ||
+		sel.IntoClause != nil ||
+		len(sel.GroupClause) > 0 ||
+		sel.HavingClause != nil ||
+		len(sel.WindowClause) > 0 ||
+		len(sel.SortClause) > 0 ||
+		sel.LimitCount != nil ||
+		sel.LimitOffset != nil ||
+		len(sel.LockingClause) > 0 ||
+		sel.WithClause != nil {
+		return ErrBadPredicate
+	}
+
+	if c := sel.WhereClause.GetAConst(); c != nil && (c.GetIval() != nil || c.GetFval() != nil) {
+		return ErrBadPredicate
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePredicate_Valid(t *testing.T) {
+	t.Parallel()
+	for _, pred := range []string{
+		"status = 'paid'",
+		"amount_cents > 1000 AND created_at >= now() - interval '7 days'",
+		"customer_id IN (SELECT id FROM customers WHERE country = 'ES')",
+		"email IS NULL",
+		"true",
+		"name ILIKE '%smith%' -- trailing comment",
+	} {
+		assert.NoError(t, ValidatePredicate(pred), pred)
+	}
+}
+
+func TestValidatePredicate_Invalid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		pred string
+		want error
+	}{
+		{"", ErrEmptyQuery},
+		{"   ", ErrEmptyQuery},
+		{"status = ", ErrParseFailed},
+		{"true; DROP TABLE users", ErrMultiStatement},
+		{"true UNION SELECT 1", ErrBadPredicate},
+		{"true ORDER BY 1", ErrBadPredicate},
+		{"true LIMIT 1", ErrBadPredicate},
+		{"true FOR UPDATE", ErrBadPredicate},
+		{"true GROUP BY 1", ErrBadPredicate},
+		{"42", ErrBadPredicate},
+	}
+	for _, tt := range tests {
+		assert.ErrorIs(t, ValidatePredicate(tt.pred), tt.want, tt.pred)
+	}
+}
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ParsedQuery is a statement or batch parsed once, with the tables, CTE
+// names and function calls found in a single walk of its tree. Validation,
+// per-table limits, masking and auditing all read from it, so a query is
+// not parsed again for each of them.
+type ParsedQuery struct {
+	tree      *pg_query.ParseResult
+	tables    []TableRef      // every relation read, once each, in tree order
+	ctes      map[string]bool // names the query defines in WITH clauses
+	functions []string        // called functions as written, lowercased, once each
+}
+
+// ParseQuery parses sql. A parse error wraps ErrParseFailed; empty SQL
+// parses to a query without statements.
+func ParseQuery(sql string) (*ParsedQuery, error) {
+	tree, err := pg_query.Parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrParseFailed, err)
+	}
+	q := &ParsedQuery{tree: tree, ctes: make(map[string]bool)}
+	seenTable := make(map[TableRef]bool)
+	seenFunc := make(map[string]bool)
+	walkTree(tree.ProtoReflect(), func(m protoreflect.Message) {
+		switch n := m.Interface().(type) {
+		case *pg_query.RangeVar:
+			ref := TableRef{Schema: n.Schemaname, Name: n.Relname}
+			if ref.Name != "" && !seenTable[ref] {
+				seenTable[ref] = true
+				q.tables = append(q.tables, ref)
+			}
+		case *pg_query.CommonTableExpr:
+			if n.Ctename != "" {
+				q.ctes[n.Ctename] = true
+			}
+		case *pg_query.FuncCall:
+			if name := funcCallName(n); name != "" && !seenFunc[name] {
+				seenFunc[name] = true
+				q.functions = append(q.functions, name)
+			}
+		}
+	})
+	return q, nil
+}
+
+// Stmts returns the raw statements of the query.
+func (q *ParsedQuery) Stmts() []*pg_query.RawStmt {
+	return q.tree.Stmts
+}
+
+// lastStmt returns the statement whose rows a batch returns, or nil.
+func (q *ParsedQuery) lastStmt() *pg_query.Node {
+	if len(q.tree.Stmts) == 0 {
+		return nil
+	}
+	return q.tree.Stmts[len(q.tree.Stmts)-1].Stmt
+}
+
+// Tables lists the relations the query reads from, anywhere in the tree:
+// FROM and JOIN clauses, subqueries, CTE bodies and the query under
+// EXPLAIN. Each table is listed once. References to CTEs by name are
+// included as unqualified tables, since the parser cannot tell the two
+// apart.
+func (q *ParsedQuery) Tables() []TableRef {
+	return q.tables
+}
+
+// TableNames lists the tables like Tables, as sorted "schema.table" names,
+// or bare names where the query does not qualify them. Unqualified
+// references to a CTE of the query are left out.
+func (q *ParsedQuery) TableNames() []string {
+	var names []string
+	for _, ref := range q.tables {
+		switch {
+		case ref.Schema != "":
+			names = append(names, ref.Schema+"."+ref.Name)
+		case !q.ctes[ref.Name]:
+			names = append(names, ref.Name)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// Functions lists the functions the query calls, anywhere in the tree, as
+// written: lowercased and schema-qualified only when the query qualifies
+// them. Each function is listed once.
+func (q *ParsedQuery) Functions() []string {
+	return q.functions
+}
+
+// walkTree calls visit for m and every message below it, depth first in
+// field order.
+func walkTree(m protoreflect.Message, visit func(protoreflect.Message)) {
+	visit(m)
+	walkFields(m, func(child protoreflect.Message) {
+		walkTree(child, visit)
+	})
+}
+
+// walkFields calls f for each message directly below m, in field order.
+func walkFields(m protoreflect.Message, f func(protoreflect.Message)) {
+	// Range visits fields in an undefined order, so go by the descriptor.
+	fields := m.Descriptor().Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		if fd.IsMap() || fd.Message() == nil || !m.Has(fd) {
+			continue
+		}
+		if fd.IsList() {
+			list := m.Get(fd).List()
+			for j := range list.Len() {
+				f(list.Get(j).Message())
+			}
+			continue
+		}
+		f(m.Get(fd).Message())
+	}
+}
+
+// funcCallName joins the parts of a FuncCall's funcname with dots,
+// lowercased.
+func funcCallName(fc *pg_query.FuncCall) string {
+	names := make([]string, 0, len(fc.Funcname))
+	for _, part := range fc.Funcname {
+		if s := part.GetString_().GetSval(); s != "" {
+			names = append(names, strings.ToLower(s))
+		}
+	}
+	return strings.Join(names, ".")
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Parallel()
+	q, err := ParseQuery(`WITH recent AS (SELECT * FROM public.orders WHERE created_at > now())
+		SELECT lower(u.email), r.total FROM users u JOIN recent r ON r.user_id = u.id
+		WHERE u.id IN (SELECT user_id FROM hr.salaries WHERE pg_catalog.abs(amount) > 0)`)
+	require.NoError(t, err)
+
+	assert.Len(t, q.Stmts(), 1)
+	assert.Equal(t, []TableRef{
+		{Name: "users"},
+		{Name: "recent"},
+		{Schema: "hr", Name: "salaries"},
+		{Schema: "public", Name: "orders"},
+	}, q.Tables(), "in tree order, the WITH clause last")
+	assert.Equal(t, []string{"hr.salaries", "public.orders", "users"}, q.TableNames(), "the CTE is left out")
+	assert.Equal(t, []string{"lower", "pg_catalog.abs", "now"}, q.Functions())
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	t.Parallel()
+	_, err := ParseQuery("SELECT * FROM")
+	require.ErrorIs(t, err, ErrParseFailed)
+
+	q, err := ParseQuery("")
+	require.NoError(t, err)
+	assert.Empty(t, q.Stmts())
+	assert.Empty(t, q.Tables())
+	assert.Empty(t, q.AliasMap())
+	assert.Empty(t, q.DerivedColumns())
+}
@@ -0,0 +1,49 @@
+package domain
+
+import "time"
+
+// RowEstimateConfidence says how far a planner row estimate (reltuples) can
+// be trusted.
+type RowEstimateConfidence string
+
+const (
+	RowEstimateHigh   RowEstimateConfidence = "high"
+	RowEstimateMedium RowEstimateConfidence = "medium"
+	RowEstimateLow    RowEstimateConfidence = "low"
+)
+
+// staleStatsAfter is how old the last ANALYZE or VACUUM may be before a row
+// estimate is no longer considered reliable.
+const staleStatsAfter = 7 * 24 * time.Hour
+
+// RowEstimateActivity is the maintenance and write activity used to grade a
+// row estimate. Nil timestamps mean the operation has never run.
+type RowEstimateActivity struct {
+	LastAnalyze      *time.Time
+	LastVacuum       *time.Time
+	ModsSinceAnalyze int64 // rows inserted, updated or deleted since the last ANALYZE
+}
+
+// ClassifyRowEstimate grades rowEstimate against the table's activity:
+//   - low: never analyzed, or more than 20% of the rows (any rows, for an
+//     estimate of zero) changed since the last ANALYZE, as after a bulk load
+//   - medium: ANALYZE or VACUUM is older than a week or VACUUM never ran
+//   - high: otherwise
+func ClassifyRowEstimate(rowEstimate int64, a RowEstimateActivity, now time.Time) RowEstimateConfidence {
+	if a.LastAnalyze == nil {
+		return RowEstimateLow
+	}
+	if rowEstimate <= 0 {
+		if a.ModsSinceAnalyze > 0 {
+			return RowEstimateLow
+		}
+	} else if float64(a.ModsSinceAnalyze) > 0.2*float64(rowEstimate) {
+		return RowEstimateLow
+	}
+
+	if now.Sub(*a.LastAnalyze) > staleStatsAfter ||
+		a.LastVacuum == nil || now.Sub(*a.LastVacuum) > staleStatsAfter {
+		return RowEstimateMedium
+	}
+	return RowEstimateHigh
+}
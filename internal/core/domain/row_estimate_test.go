@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyRowEstimate(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		ts := now.Add(-d)
+		return &ts
+	}
+	const day = 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		estimate int64
+		activity RowEstimateActivity
+		want     RowEstimateConfidence
+	}{
+		{"fresh stats", 1000, RowEstimateActivity{LastAnalyze: ago(time.Hour), LastVacuum: ago(time.Hour), ModsSinceAnalyze: 10}, RowEstimateHigh},
+		{"never analyzed", 1000, RowEstimateActivity{LastVacuum: ago(time.Hour)}, RowEstimateLow},
+		{"bulk load since analyze", 1000, RowEstimateActivity{LastAnalyze: ago(time.Hour), LastVacuum: ago(time.Hour), ModsSinceAnalyze: 5000}, RowEstimateLow},
+		{"rows written into empty estimate", 0, RowEstimateActivity{LastAnalyze: ago(time.Hour), LastVacuum: ago(time.Hour), ModsSinceAnalyze: 1}, RowEstimateLow},
+		{"empty and untouched", 0, RowEstimateActivity{LastAnalyze: ago(time.Hour), LastVacuum: ago(time.Hour)}, RowEstimateHigh},
+		{"modifications at threshold", 1000, RowEstimateActivity{LastAnalyze: ago(time.Hour), LastVacuum: ago(time.Hour), ModsSinceAnalyze: 200}, RowEstimateHigh},
+		{"old analyze", 1000, RowEstimateActivity{LastAnalyze: ago(10 * day), LastVacuum: ago(time.Hour)}, RowEstimateMedium},
+		{"never vacuumed", 1000, RowEstimateActivity{LastAnalyze: ago(time.Hour)}, RowEstimateMedium},
+		{"old vacuum", 1000, RowEstimateActivity{LastAnalyze: ago(time.Hour), LastVacuum: ago(30 * day)}, RowEstimateMedium},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, ClassifyRowEstimate(tt.estimate, tt.activity, now))
+		})
+	}
+}
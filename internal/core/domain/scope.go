@@ -0,0 +1,346 @@
+package domain
+
+import (
+	"slices"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// WholeRow is the source of a derived column that reads a whole row of a
+// table, e.g. to_jsonb(u.*), row_to_json(u), or a column of users u(x)
+// renamed by position. The table's columns are not known from the SQL, so
+// MaskDerivedColumns treats it as reading a masked column whenever any mask
+// is configured.
+const WholeRow = "*"
+
+// relation is a FROM item or CTE as far as the SQL shows what its columns
+// read. A table's columns read themselves; a subquery or CTE maps each of
+// its output columns to the columns it reads.
+type relation struct {
+	columns map[string][]string // known output columns and their sources
+	table   bool                // other columns are table columns of that name
+	rest    []string            // read by every column, e.g. a FROM function's arguments
+}
+
+// column returns the sources of the relation's column col.
+func (r *relation) column(col string) []string {
+	if sources, ok := r.columns[col]; ok {
+		return sources
+	}
+	if r.table || len(r.rest) == 0 {
+		return append([]string{col}, r.rest...)
+	}
+	return r.rest
+}
+
+// expand returns the sources of every column of the relation, as a star or
+// whole-row reference reads them.
+func (r *relation) expand() []string {
+	sources := slices.Clone(r.rest)
+	for _, c := range r.columns {
+		sources = append(sources, c...)
+	}
+	if r.table {
+		sources = append(sources, WholeRow)
+	}
+	return sources
+}
+
+// namedRelation is a FROM item under the name the query refers to it by:
+// its alias, or a table's own name. Unaliased subqueries have no name.
+type namedRelation struct {
+	name string
+	rel  *relation
+}
+
+// scope holds the relations a SELECT's expressions can refer to: the CTEs of
+// its WITH clause and the items of its FROM clause, then those of the
+// enclosing SELECTs.
+type scope struct {
+	parent *scope
+	ctes   map[string]*relation
+	from   []namedRelation
+}
+
+// newScope returns the scope of sel inside parent.
+func newScope(sel *pg_query.SelectStmt, parent *scope) *scope {
+	sc := &scope{parent: parent, ctes: make(map[string]*relation)}
+	for _, node := range sel.GetWithClause().GetCtes() {
+		cte := node.GetCommonTableExpr()
+		if cte == nil {
+			continue
+		}
+		sc.ctes[cte.Ctename] = queryRelation(cte.Ctequery.GetSelectStmt(), sc, cte.Aliascolnames)
+	}
+	for _, item := range sel.GetFromClause() {
+		sc.addFrom(item)
+	}
+	return sc
+}
+
+// branchScopes returns the SELECTs of a statement, itself or for a set
+// operation (UNION, INTERSECT, EXCEPT) each of its branches in order, with
+// the scope of each.
+func branchScopes(sel *pg_query.SelectStmt, parent *scope) ([]*pg_query.SelectStmt, []*scope) {
+	if sel == nil {
+		return nil, nil
+	}
+	if sel.Larg == nil && sel.Rarg == nil {
+		return []*pg_query.SelectStmt{sel}, []*scope{newScope(sel, parent)}
+	}
+	sc := newScope(sel, parent) // the set operation's own WITH clause
+	lb, ls := branchScopes(sel.Larg, sc)
+	rb, rs := branchScopes(sel.Rarg, sc)
+	return append(lb, rb...), append(ls, rs...)
+}
+
+// addFrom adds a FROM item, and for a join each of its sides.
+func (sc *scope) addFrom(item *pg_query.Node) {
+	switch n := item.GetNode().(type) {
+	case *pg_query.Node_RangeVar:
+		rv := n.RangeVar
+		var rel *relation
+		if rv.Schemaname == "" {
+			rel = sc.cte(rv.Relname)
+		}
+		if rel == nil {
+			rel = &relation{table: true}
+		}
+		name := rv.Relname
+		if alias := rv.GetAlias(); alias != nil {
+			name = alias.Aliasname
+			rel = renamed(rel, alias.Colnames)
+		}
+		sc.from = append(sc.from, namedRelation{name, rel})
+	case *pg_query.Node_RangeSubselect:
+		rs := n.RangeSubselect
+		rel := queryRelation(rs.Subquery.GetSelectStmt(), sc, rs.GetAlias().GetColnames())
+		sc.from = append(sc.from, namedRelation{rs.GetAlias().GetAliasname(), rel})
+	case *pg_query.Node_RangeFunction:
+		rel := &relation{rest: sc.sources(item)}
+		sc.from = append(sc.from, namedRelation{n.RangeFunction.GetAlias().GetAliasname(), rel})
+	case *pg_query.Node_JoinExpr:
+		sc.addFrom(n.JoinExpr.Larg)
+		sc.addFrom(n.JoinExpr.Rarg)
+	}
+}
+
+// cte returns the CTE named name in sc or an enclosing scope, or nil.
+func (sc *scope) cte(name string) *relation {
+	for s := sc; s != nil; s = s.parent {
+		if rel, ok := s.ctes[name]; ok {
+			return rel
+		}
+	}
+	return nil
+}
+
+// named returns the FROM item called name in sc or an enclosing scope, or
+// nil.
+func (sc *scope) named(name string) *relation {
+	for s := sc; s != nil; s = s.parent {
+		for _, f := range s.from {
+			if f.name == name {
+				return f.rel
+			}
+		}
+	}
+	return nil
+}
+
+// starRelations returns the relations a star column reference expands to:
+// the one it qualifies, or every FROM item of the nearest scope with any.
+func (sc *scope) starRelations(cr *pg_query.ColumnRef) []*relation {
+	if len(cr.Fields) >= 2 {
+		qual := cr.Fields[len(cr.Fields)-2].GetString_().GetSval()
+		if rel := sc.named(qual); rel != nil {
+			return []*relation{rel}
+		}
+		return []*relation{{table: true}}
+	}
+	for s := sc; s != nil; s = s.parent {
+		if len(s.from) > 0 {
+			rels := make([]*relation, len(s.from))
+			for i, f := range s.from {
+				rels[i] = f.rel
+			}
+			return rels
+		}
+	}
+	return nil
+}
+
+// columnSources returns the columns a column reference reads. A qualified
+// reference is resolved in the relation it names. An unqualified one could
+// come from any FROM item in scope, so it reads what a column of that name
+// reads in each of them; a reference naming a FROM item itself is a
+// whole-row reference to it.
+func (sc *scope) columnSources(cr *pg_query.ColumnRef) []string {
+	if isStar(cr) {
+		var sources []string
+		for _, rel := range sc.starRelations(cr) {
+			sources = append(sources, rel.expand()...)
+		}
+		return sources
+	}
+	col := columnRefName(cr)
+	if col == "" {
+		return nil
+	}
+	if len(cr.Fields) >= 2 {
+		qual := cr.Fields[len(cr.Fields)-2].GetString_().GetSval()
+		if rel := sc.named(qual); rel != nil {
+			return rel.column(col)
+		}
+		return []string{col}
+	}
+
+	var sources []string
+	known := false
+	for s := sc; s != nil; s = s.parent {
+		for _, f := range s.from {
+			if c, ok := f.rel.columns[col]; ok {
+				sources = append(sources, c...)
+				known = true
+				continue
+			}
+			if f.name == col {
+				sources = append(sources, f.rel.expand()...)
+			}
+			sources = append(sources, f.rel.rest...)
+		}
+	}
+	if !known {
+		sources = append(sources, col)
+	}
+	return sources
+}
+
+// sources lists the columns read anywhere under expr, each once, sorted.
+// Column references in a subquery are resolved in the subquery's scope.
+func (sc *scope) sources(expr *pg_query.Node) []string {
+	var sources []string
+	sc.walkSources(expr.ProtoReflect(), func(s []string) {
+		sources = append(sources, s...)
+	})
+	slices.Sort(sources)
+	return slices.Compact(sources)
+}
+
+func (sc *scope) walkSources(m protoreflect.Message, add func([]string)) {
+	switch n := m.Interface().(type) {
+	case *pg_query.ColumnRef:
+		add(sc.columnSources(n))
+		return
+	case *pg_query.SelectStmt:
+		inner := newScope(n, sc)
+		walkFields(m, func(child protoreflect.Message) {
+			inner.walkSources(child, add)
+		})
+		return
+	}
+	walkFields(m, func(child protoreflect.Message) {
+		sc.walkSources(child, add)
+	})
+}
+
+// queryRelation returns the relation a subquery or CTE body defines, with
+// its output columns renamed by colnames, as in AS q(a, b).
+func queryRelation(sel *pg_query.SelectStmt, parent *scope, colnames []*pg_query.Node) *relation {
+	rel := &relation{columns: make(map[string][]string)}
+	branches, scopes := branchScopes(sel, parent)
+	if len(branches) == 0 {
+		return rel
+	}
+
+	var names []string // output columns by position, unless a star hides them
+	positional := true
+	for i, rt := range resTargets(branches[0]) {
+		if rt.Val == nil {
+			continue
+		}
+		if cr := rt.Val.GetColumnRef(); cr != nil && isStar(cr) {
+			for _, inner := range scopes[0].starRelations(cr) {
+				for name, sources := range inner.columns {
+					rel.columns[name] = append(rel.columns[name], sources...)
+				}
+				rel.table = rel.table || inner.table
+				rel.rest = append(rel.rest, inner.rest...)
+			}
+			positional = false
+			continue
+		}
+		name := rt.Name
+		if name == "" {
+			name, _ = figureColname(rt.Val)
+		}
+		rel.columns[name] = append(rel.columns[name], scopes[0].sources(rt.Val)...)
+		if positional && len(names) == i {
+			names = append(names, name)
+		}
+	}
+
+	// Later branches of a set operation feed the first branch's columns by
+	// position. Without positions, every column reads all of them.
+	for b := 1; b < len(branches); b++ {
+		for i, rt := range resTargets(branches[b]) {
+			if rt.Val == nil {
+				continue
+			}
+			sources := scopes[b].sources(rt.Val)
+			if positional && i < len(names) {
+				rel.columns[names[i]] = append(rel.columns[names[i]], sources...)
+			} else {
+				rel.rest = append(rel.rest, sources...)
+			}
+		}
+	}
+
+	if len(colnames) > 0 {
+		if !positional {
+			// Renamed columns can't be matched to their sources: each of
+			// them reads anything the relation reads.
+			rel.rest = rel.expand()
+			rel.table = false
+			return rel
+		}
+		for i, node := range colnames {
+			alias := node.GetString_().GetSval()
+			if i >= len(names) || alias == "" {
+				break
+			}
+			sources := rel.columns[names[i]]
+			delete(rel.columns, names[i])
+			rel.columns[alias] = sources
+			names[i] = alias
+		}
+	}
+	return rel
+}
+
+// renamed returns rel with its first columns renamed by colnames, as in
+// FROM users u(a, b). A table's columns are not known, so a renamed column
+// of a table reads the whole row.
+func renamed(rel *relation, colnames []*pg_query.Node) *relation {
+	if len(colnames) == 0 {
+		return rel
+	}
+	if rel.columns == nil {
+		out := &relation{columns: make(map[string][]string), table: rel.table, rest: rel.rest}
+		for _, node := range colnames {
+			if alias := node.GetString_().GetSval(); alias != "" {
+				out.columns[alias] = []string{WholeRow}
+			}
+		}
+		return out
+	}
+	// A CTE: its columns are renamed in the order they appear in its body,
+	// which the map does not keep, so fall back to the whole relation.
+	return &relation{table: rel.table, rest: rel.expand()}
+}
+
+// isStar reports whether cr is * or a qualified star such as u.*.
+func isStar(cr *pg_query.ColumnRef) bool {
+	return len(cr.Fields) > 0 && cr.Fields[len(cr.Fields)-1].GetAStar() != nil
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// ErrBadSetupSQL is returned by ParseSetupSQL for anything other than plain
+// SET statements.
+var ErrBadSetupSQL = errors.New("connection setup SQL may only contain SET statements")
+
+// protectedSettings guard the read-only session and the identity queries
+// run as, so setup SQL cannot loosen them.
+var protectedSettings = map[string]bool{
+	"transaction_read_only":         true,
+	"default_transaction_read_only": true,
+	"role":                          true,
+	"session_authorization":         true,
+}
+
+// ParseSetupSQL splits SQL meant to run on every new connection into its
+// statements, accepting only SET name = value (or TO DEFAULT) for settings
+// that do not touch read-only mode or the session's role.
+//
+//	SET lock_timeout = '2s'; SET idle_in_transaction_session_timeout = '30s'
+func ParseSetupSQL(sql string) ([]string, error) {
+	tree, err := pg_query.Parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrParseFailed, err)
+	}
+	stmts, err := SplitStatements(sql)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrParseFailed, err)
+	}
+	if len(stmts) != len(tree.Stmts) {
+		return nil, fmt.Errorf("%w: could not split statements", ErrParseFailed)
+	}
+	for i, raw := range tree.Stmts {
+		if err := validateSetupStatement(raw.Stmt); err != nil {
+			return nil, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+	}
+	return stmts, nil
+}
+
+func validateSetupStatement(stmt *pg_query.Node) error {
+	set := stmt.GetVariableSetStmt()
+	if set == nil {
+		return ErrBadSetupSQL
+	}
+	switch set.GetKind() {
+	case pg_query.VariableSetKind_VAR_SET_VALUE, pg_query.VariableSetKind_VAR_SET_DEFAULT:
+	default:
+		return fmt.Errorf("%w: only SET name = value is allowed", ErrBadSetupSQL)
+	}
+	name := strings.ToLower(set.GetName())
+	if set.GetIsLocal() {
+		return fmt.Errorf("%w: SET LOCAL %s would only last until the end of a transaction", ErrBadSetupSQL, name)
+	}
+	if protectedSettings[name] {
+		return fmt.Errorf("%w: %s cannot be changed", ErrBadSetupSQL, name)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSetupSQL(t *testing.T) {
+	t.Parallel()
+
+	stmts, err := ParseSetupSQL("SET lock_timeout = '2s'; SET idle_in_transaction_session_timeout TO '30s';\nSET statement_timeout TO DEFAULT")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"SET lock_timeout = '2s'",
+		"SET idle_in_transaction_session_timeout TO '30s'",
+		"SET statement_timeout TO DEFAULT",
+	}, stmts)
+
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{"select", "SELECT 1"},
+		{"write after set", "SET lock_timeout = '2s'; DELETE FROM users"},
+		{"set local", "SET LOCAL lock_timeout = '2s'"},
+		{"reset", "RESET lock_timeout"},
+		{"read only off", "SET default_transaction_read_only = off"},
+		{"transaction read only", "SET transaction_read_only = off"},
+		{"role", "SET ROLE admin"},
+		{"session authorization", "SET SESSION AUTHORIZATION admin"},
+		{"set transaction", "SET TRANSACTION READ WRITE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseSetupSQL(tt.sql)
+			assert.ErrorIs(t, err, ErrBadSetupSQL)
+		})
+	}
+
+	_, err = ParseSetupSQL("SET lock_timeout =")
+	assert.ErrorIs(t, err, ErrParseFailed)
+}
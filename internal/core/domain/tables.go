@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TableRef is a table named in a query. Schema is empty when the name was
+// not schema-qualified.
+type TableRef struct {
+	Schema string
+	Name   string
+}
+
+// ReferencedTables parses sql and lists the relations it reads from; see
+// ParsedQuery.Tables.
+func ReferencedTables(sql string) ([]TableRef, error) {
+	q, err := ParseQuery(sql)
+	if err != nil {
+		return nil, err
+	}
+	return q.Tables(), nil
+}
+
+// SchemaNotAllowedError reports a table qualified with a schema outside the
+// validator's allowed schemas. It wraps ErrNotAllowed, so
+// errors.Is(err, ErrNotAllowed) still holds.
+type SchemaNotAllowedError struct {
+	Schema string
+	Table  string
+}
+
+func (e *SchemaNotAllowedError) Error() string {
+	return fmt.Sprintf("%v: table %s.%s is in schema %q, outside the configured schemas", ErrNotAllowed, e.Schema, e.Table, e.Schema)
+}
+
+func (e *SchemaNotAllowedError) Unwrap() error {
+	return ErrNotAllowed
+}
+
+// checkSchemas returns a SchemaNotAllowedError for the first table q
+// qualifies with a schema not in allowed. Unqualified names are resolved by
+// the search_path the executor pins to allowed, except that PostgreSQL
+// searches pg_catalog first when the path does not list it, so an
+// unqualified pg_* name (pg_roles, pg_settings) is taken to be a catalog
+// relation unless pg_catalog is allowed. A table of an allowed schema with
+// such a name can still be read schema-qualified.
+func checkSchemas(q *ParsedQuery, allowed map[string]bool) error {
+	for _, ref := range q.Tables() {
+		schema := ref.Schema
+		if schema == "" && strings.HasPrefix(ref.Name, "pg_") && !q.ctes[ref.Name] {
+			schema = "pg_catalog"
+		}
+		if schema != "" && !allowed[schema] {
+			return &SchemaNotAllowedError{Schema: schema, Table: ref.Name}
+		}
+	}
+	return nil
+}
+
+// ExtractTables parses sql and lists the tables it references as sorted
+// names; see ParsedQuery.TableNames. It returns nil when sql does not parse.
+func ExtractTables(sql string) []string {
+	q, err := ParseQuery(sql)
+	if err != nil {
+		return nil
+	}
+	return q.TableNames()
+}
+
+// TableRowLimit returns the strictest of limits, keyed by schema-qualified
+// table, that applies to the tables q reads; ok is false when none does. An
+// unqualified table matches the entry of that name in every schema, since
+// the search_path that resolves it is not known here. A nil q, for a query
+// that could not be parsed, gets the strictest limit of all.
+func TableRowLimit(q *ParsedQuery, limits map[TableRef]int) (n int, ok bool) {
+	return strictestForTables(q, limits)
+}
+
+// TableTimeout returns the shortest of timeouts, keyed by schema-qualified
+// table, that applies to the tables q reads, matched as in TableRowLimit;
+// ok is false when none does.
+func TableTimeout(q *ParsedQuery, timeouts map[TableRef]time.Duration) (d time.Duration, ok bool) {
+	return strictestForTables(q, timeouts)
+}
+
+// strictestForTables returns the smallest value in bounds whose table q
+// reads, or the smallest of all when q is nil.
+func strictestForTables[T int | time.Duration](q *ParsedQuery, bounds map[TableRef]T) (n T, ok bool) {
+	if len(bounds) == 0 {
+		return 0, false
+	}
+	if q == nil {
+		for _, bound := range bounds {
+			if !ok || bound < n {
+				n, ok = bound, true
+			}
+		}
+		return n, ok
+	}
+	for table, bound := range bounds {
+		for _, ref := range q.Tables() {
+			if ref.Name == table.Name && (ref.Schema == "" || ref.Schema == table.Schema) {
+				if !ok || bound < n {
+					n, ok = bound, true
+				}
+				break
+			}
+		}
+	}
+	return n, ok
+}
@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferencedTables(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		sql  string
+		want []TableRef
+	}{
+		{"single", "SELECT * FROM users", []TableRef{{Name: "users"}}},
+		{"qualified", "SELECT * FROM hr.salaries", []TableRef{{Schema: "hr", Name: "salaries"}}},
+		{"quoted", `SELECT * FROM "HR"."Pay Slips"`, []TableRef{{Schema: "HR", Name: "Pay Slips"}}},
+		{"join listed once", "SELECT * FROM users a JOIN users b ON a.id = b.manager_id", []TableRef{{Name: "users"}}},
+		{"subquery", "SELECT * FROM users WHERE id IN (SELECT user_id FROM orders)", []TableRef{{Name: "users"}, {Name: "orders"}}},
+		{"cte body", "WITH s AS (SELECT * FROM hr.salaries) SELECT * FROM s", []TableRef{{Schema: "hr", Name: "salaries"}, {Name: "s"}}},
+		{"explain", "EXPLAIN SELECT * FROM orders", []TableRef{{Name: "orders"}}},
+		{"no tables", "SELECT 1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ReferencedTables(tt.sql)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+
+	_, err := ReferencedTables("SELECT * FROM")
+	assert.ErrorIs(t, err, ErrParseFailed)
+}
+
+func TestExtractTables(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{"single", "SELECT * FROM users", []string{"users"}},
+		{"qualified", "SELECT * FROM hr.salaries", []string{"hr.salaries"}},
+		{"quoted", `SELECT * FROM "HR"."Pay Slips"`, []string{"HR.Pay Slips"}},
+		{"joins sorted and listed once",
+			"SELECT * FROM users u JOIN public.orders o ON o.user_id = u.id LEFT JOIN users m ON m.id = u.manager_id CROSS JOIN billing.plans",
+			[]string{"billing.plans", "public.orders", "users"}},
+		{"qualified and bare kept apart", "SELECT * FROM users JOIN public.users p ON p.id = users.id", []string{"public.users", "users"}},
+		{"subquery in where", "SELECT * FROM users WHERE id IN (SELECT user_id FROM sales.orders)", []string{"sales.orders", "users"}},
+		{"derived table", "SELECT t.n FROM (SELECT count(*) AS n FROM events) t", []string{"events"}},
+		{"lateral", "SELECT * FROM users u, LATERAL (SELECT * FROM orders o WHERE o.user_id = u.id LIMIT 1) last", []string{"orders", "users"}},
+		{"scalar subquery", "SELECT id, (SELECT max(total) FROM orders) FROM users", []string{"orders", "users"}},
+		{"exists", "SELECT 1 FROM users u WHERE EXISTS (SELECT 1 FROM audit.logins l WHERE l.user_id = u.id)", []string{"audit.logins", "users"}},
+		{"cte names dropped", "WITH s AS (SELECT * FROM hr.salaries) SELECT * FROM s", []string{"hr.salaries"}},
+		{"nested ctes", "WITH a AS (SELECT * FROM t1), b AS (WITH c AS (SELECT * FROM t2) SELECT * FROM c JOIN a ON true) SELECT * FROM b",
+			[]string{"t1", "t2"}},
+		{"recursive cte", "WITH RECURSIVE tree AS (SELECT id FROM nodes WHERE parent IS NULL UNION ALL SELECT n.id FROM nodes n JOIN tree ON n.parent = tree.id) SELECT * FROM tree",
+			[]string{"nodes"}},
+		{"qualified name matching a cte is a table", "WITH s AS (SELECT 1) SELECT * FROM s, app.s", []string{"app.s"}},
+		{"union", "SELECT id FROM a.users UNION SELECT id FROM b.users", []string{"a.users", "b.users"}},
+		{"explain", "EXPLAIN ANALYZE SELECT * FROM orders", []string{"orders"}},
+		{"batch", "SET LOCAL work_mem = '64MB'; SELECT * FROM orders", []string{"orders"}},
+		{"no tables", "SELECT 1", nil},
+		{"unparseable", "SELECT * FROM", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, ExtractTables(tt.sql))
+		})
+	}
+}
+
+// parseOrNil parses sql, returning nil for SQL that does not parse, as the
+// per-table limits expect.
+func parseOrNil(sql string) *ParsedQuery {
+	q, err := ParseQuery(sql)
+	if err != nil {
+		return nil
+	}
+	return q
+}
+
+func TestTableRowLimit(t *testing.T) {
+	t.Parallel()
+	limits := map[TableRef]int{
+		{Schema: "hr", Name: "salaries"}:   10,
+		{Schema: "public", Name: "orders"}: 50,
+	}
+	tests := []struct {
+		name   string
+		sql    string
+		want   int
+		wantOK bool
+	}{
+		{"no limited table", "SELECT * FROM users", 0, false},
+		{"single table", "SELECT * FROM hr.salaries", 10, true},
+		{"unqualified name matches", "SELECT * FROM orders", 50, true},
+		{"other schema does not match", "SELECT * FROM archive.orders", 0, false},
+		{"strictest of several", "SELECT * FROM orders o JOIN hr.salaries s ON s.id = o.id", 10, true},
+		{"nested", "SELECT count(*) FROM (SELECT * FROM public.orders) o", 50, true},
+		{"unparsable gets the strictest", "SELECT * FROM", 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			n, ok := TableRowLimit(parseOrNil(tt.sql), limits)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, n)
+		})
+	}
+
+	_, ok := TableRowLimit(parseOrNil("SELECT * FROM hr.salaries"), nil)
+	assert.False(t, ok)
+}
+
+func TestTableTimeout(t *testing.T) {
+	t.Parallel()
+	timeouts := map[TableRef]time.Duration{
+		{Schema: "hr", Name: "salaries"}:      2 * time.Second,
+		{Schema: "public", Name: "events"}:    10 * time.Second,
+		{Schema: "archive", Name: "salaries"}: time.Second,
+	}
+	tests := []struct {
+		name   string
+		sql    string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"no table with a timeout", "SELECT * FROM users", 0, false},
+		{"single table", "SELECT * FROM public.events", 10 * time.Second, true},
+		{"other schema does not match", "SELECT * FROM staging.events", 0, false},
+		{"shortest of several", "SELECT * FROM public.events e JOIN hr.salaries s ON s.user_id = e.user_id", 2 * time.Second, true},
+		{"subquery", "SELECT * FROM users WHERE id IN (SELECT user_id FROM public.events)", 10 * time.Second, true},
+		{"unqualified name matches every schema", "SELECT * FROM salaries", time.Second, true},
+		{"unparsable gets the shortest", "SELECT * FROM", time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d, ok := TableTimeout(parseOrNil(tt.sql), timeouts)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, d)
+		})
+	}
+
+	_, ok := TableTimeout(parseOrNil("SELECT * FROM hr.salaries"), nil)
+	assert.False(t, ok)
+}
@@ -14,37 +14,153 @@ var (
 	ErrMultiStatement = errors.New("multiple statements are not allowed")
 	ErrParseFailed    = errors.New("failed to parse SQL")
 	ErrNotFound       = errors.New("not found")
+	ErrBadPredicate   = errors.New("predicate must be a single boolean expression")
+	ErrBadIdentifier  = errors.New("invalid identifier")
+	ErrAmbiguousName  = errors.New("ambiguous column")
+	ErrAmbiguousTable = errors.New("ambiguous table")
+	ErrResultTooLarge = errors.New("result too large")
 )
 
 // PgQueryValidator validates SQL statements using PostgreSQL's actual parser.
 // Only SELECT statements are permitted (whitelist approach).
-type PgQueryValidator struct{}
+type PgQueryValidator struct {
+	multiStatement bool            // accept batches of SELECT and SET LOCAL statements
+	blocked        map[string]bool // lowercased names of functions a query may not call
+	schemas        map[string]bool // schemas a qualified table may be in; nil allows any
+}
+
+// ValidatorOption configures optional PgQueryValidator behavior.
+type ValidatorOption func(*PgQueryValidator)
+
+// WithMultiStatement accepts semicolon-separated batches when on is true.
+// Every statement but the last must be a SELECT or a SET LOCAL of a setting
+// the server does not enforce; the last must be a SELECT or EXPLAIN, since
+// its rows are the result. Each statement is checked on its own.
+func WithMultiStatement(on bool) ValidatorOption {
+	return func(v *PgQueryValidator) {
+		v.multiStatement = on
+	}
+}
+
+// WithFunctionCheck rejects queries that call a known side-effecting
+// function, such as pg_terminate_backend, set_config or dblink_exec, when on
+// is true. These pass the statement check because they are called from a
+// SELECT, and some of them act outside the read-only transaction.
+func WithFunctionCheck(on bool) ValidatorOption {
+	return func(v *PgQueryValidator) {
+		if on {
+			v.block(sideEffectFunctions)
+		}
+	}
+}
+
+// WithBlockedFunctions rejects queries that call any of names, e.g. an
+// application's own functions that write. A name may be schema-qualified.
+// It works with or without WithFunctionCheck.
+func WithBlockedFunctions(names []string) ValidatorOption {
+	return func(v *PgQueryValidator) {
+		v.block(names)
+	}
+}
+
+// WithAllowedSchemas rejects queries that qualify a table with a schema
+// outside schemas, e.g. other_schema.secrets, with a SchemaNotAllowedError.
+// Unqualified names resolve through the search_path, which the executor
+// pins to schemas (see postgres.WithSearchPath), so the search_path itself
+// is locked: SET LOCAL search_path in a batch and set_config calls are
+// rejected. Unqualified pg_* names resolve to pg_catalog first and are
+// refused unless pg_catalog is allowed. An empty list allows every schema.
+func WithAllowedSchemas(schemas []string) ValidatorOption {
+	return func(v *PgQueryValidator) {
+		if len(schemas) == 0 {
+			return
+		}
+		v.schemas = make(map[string]bool, len(schemas))
+		for _, s := range schemas {
+			v.schemas[s] = true
+		}
+	}
+}
 
-func NewPgQueryValidator() *PgQueryValidator {
-	return &PgQueryValidator{}
+func (v *PgQueryValidator) block(names []string) {
+	if v.blocked == nil {
+		v.blocked = make(map[string]bool, len(names))
+	}
+	for _, name := range names {
+		v.blocked[strings.ToLower(name)] = true
+	}
 }
 
-// Validate parses the SQL and rejects anything that isn't a single SELECT statement.
+func NewPgQueryValidator(opts ...ValidatorOption) *PgQueryValidator {
+	v := &PgQueryValidator{}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// enforcedSettings are set by the executor for every query and must not be
+// changed from inside a batch.
+var enforcedSettings = map[string]bool{
+	"statement_timeout":             true,
+	"transaction_read_only":         true,
+	"default_transaction_read_only": true,
+	"role":                          true,
+	"session_authorization":         true,
+}
+
+// Validate parses the SQL and checks it with ValidateParsed.
 func (v *PgQueryValidator) Validate(sql string) error {
 	trimmed := strings.TrimSpace(sql)
 	if trimmed == "" {
 		return ErrEmptyQuery
 	}
 
-	tree, err := pg_query.Parse(trimmed)
+	q, err := ParseQuery(trimmed)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrParseFailed, err)
+		return err
 	}
+	return v.ValidateParsed(q)
+}
 
-	if len(tree.Stmts) == 0 {
+// ValidateParsed rejects anything that isn't a single SELECT statement, or,
+// with WithMultiStatement, a batch as described there. With allowed schemas
+// configured, it then rejects tables qualified with any other schema, and
+// with blocked functions, calls to any of them.
+func (v *PgQueryValidator) ValidateParsed(q *ParsedQuery) error {
+	stmts := q.Stmts()
+	if len(stmts) == 0 {
 		return ErrEmptyQuery
 	}
 
-	if len(tree.Stmts) > 1 {
-		return ErrMultiStatement
+	var err error
+	if len(stmts) > 1 {
+		if !v.multiStatement {
+			return ErrMultiStatement
+		}
+		err = validateBatch(stmts)
+	} else {
+		err = validateStatement(stmts[0].Stmt)
+	}
+	if err != nil {
+		return err
+	}
+	if v.schemas != nil {
+		if err := checkSchemas(q, v.schemas); err != nil {
+			return err
+		}
+		if err := checkSearchPath(q); err != nil {
+			return err
+		}
+	}
+	if name := blockedFunction(q, v.blocked); name != "" {
+		return &BlockedFunctionError{Name: name}
 	}
+	return nil
+}
 
-	stmt := tree.Stmts[0].Stmt
+// validateStatement accepts a single SELECT or EXPLAIN.
+func validateStatement(stmt *pg_query.Node) error {
 	if stmt == nil {
 		return ErrEmptyQuery
 	}
@@ -55,6 +171,138 @@ func (v *PgQueryValidator) Validate(sql string) error {
 	case *pg_query.Node_ExplainStmt:
 		return nil
 	default:
-		return ErrNotAllowed
+		return rejectStatement(stmt)
+	}
+}
+
+// validateBatch checks each statement of a batch, naming the first one
+// refused by its 1-based position.
+func validateBatch(stmts []*pg_query.RawStmt) error {
+	last := len(stmts) - 1
+	for i, raw := range stmts {
+		var err error
+		switch set := raw.Stmt.GetVariableSetStmt(); {
+		case set != nil && i == last:
+			err = &RejectedStatementError{"SET", "a batch must end with the SELECT whose rows are returned"}
+		case set != nil:
+			err = validateSetLocal(set)
+		case i < last && raw.Stmt.GetExplainStmt() != nil:
+			err = &RejectedStatementError{"EXPLAIN", "only the last statement of a batch returns rows; put EXPLAIN there"}
+		default:
+			err = validateStatement(raw.Stmt)
+		}
+		if err != nil {
+			return fmt.Errorf("statement %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateSetLocal accepts SET LOCAL name = value (or TO DEFAULT) for any
+// setting the executor does not enforce itself.
+func validateSetLocal(set *pg_query.VariableSetStmt) error {
+	if !set.GetIsLocal() {
+		return &RejectedStatementError{"SET", "use SET LOCAL so the setting only lasts for this query's transaction"}
+	}
+	switch set.GetKind() {
+	case pg_query.VariableSetKind_VAR_SET_VALUE, pg_query.VariableSetKind_VAR_SET_DEFAULT:
+	default:
+		return &RejectedStatementError{"SET LOCAL", "only SET LOCAL name = value is allowed in a batch"}
+	}
+	if name := strings.ToLower(set.GetName()); enforcedSettings[name] {
+		return &RejectedStatementError{"SET LOCAL " + name, "this setting is enforced by the server and cannot be changed"}
+	}
+	return nil
+}
+
+// checkSearchPath rejects the ways a query could point unqualified names at
+// another schema: SET LOCAL search_path in a batch, and set_config, which
+// can change search_path, or any other setting, from inside a SELECT.
+func checkSearchPath(q *ParsedQuery) error {
+	for i, raw := range q.Stmts() {
+		if set := raw.Stmt.GetVariableSetStmt(); set != nil && strings.EqualFold(set.GetName(), "search_path") {
+			return fmt.Errorf("statement %d: %w", i+1, &RejectedStatementError{"SET LOCAL search_path", "schemas are enforced, so the search path is fixed; qualify tables with an allowed schema instead"})
+		}
+	}
+	if name := blockedFunction(q, map[string]bool{"set_config": true}); name != "" {
+		return &BlockedFunctionError{Name: name}
+	}
+	return nil
+}
+
+// RejectedStatementError reports a statement the validator refused, naming
+// what was detected and how to get the same answer with a SELECT. It wraps
+// ErrNotAllowed, so errors.Is(err, ErrNotAllowed) still holds.
+type RejectedStatementError struct {
+	Kind       string // detected statement, e.g. "UPDATE"
+	Suggestion string // how to rephrase it as a read-only query
+}
+
+func (e *RejectedStatementError) Error() string {
+	return fmt.Sprintf("%v: detected %s statement; %s", ErrNotAllowed, e.Kind, e.Suggestion)
+}
+
+func (e *RejectedStatementError) Unwrap() error {
+	return ErrNotAllowed
+}
+
+const (
+	suggestPreview = "wrap as a SELECT with the same FROM and WHERE clauses to preview the affected rows"
+	suggestDDL     = "schema changes are not allowed; use discover or describe_table to inspect the schema"
+	suggestSelect  = "rewrite it as a SELECT"
+)
+
+// rejectStatement classifies a refused statement by its parse node type.
+func rejectStatement(stmt *pg_query.Node) *RejectedStatementError {
+	switch n := stmt.Node.(type) {
+	case *pg_query.Node_InsertStmt:
+		return &RejectedStatementError{"INSERT", "run the SELECT that produces the rows on its own"}
+	case *pg_query.Node_UpdateStmt:
+		return &RejectedStatementError{"UPDATE", suggestPreview}
+	case *pg_query.Node_DeleteStmt:
+		return &RejectedStatementError{"DELETE", suggestPreview}
+	case *pg_query.Node_MergeStmt:
+		return &RejectedStatementError{"MERGE", suggestPreview}
+	case *pg_query.Node_CreateStmt:
+		return &RejectedStatementError{"CREATE TABLE", suggestDDL}
+	case *pg_query.Node_CreateTableAsStmt:
+		if n.CreateTableAsStmt.GetObjtype() == pg_query.ObjectType_OBJECT_MATVIEW {
+			return &RejectedStatementError{"CREATE MATERIALIZED VIEW", "run its SELECT directly"}
+		}
+		return &RejectedStatementError{"CREATE TABLE AS", "run its SELECT directly"}
+	case *pg_query.Node_AlterTableStmt:
+		return &RejectedStatementError{"ALTER TABLE", suggestDDL}
+	case *pg_query.Node_DropStmt:
+		return &RejectedStatementError{"DROP", suggestDDL}
+	case *pg_query.Node_IndexStmt:
+		return &RejectedStatementError{"CREATE INDEX", "use explain=true to see which indexes a query would use"}
+	case *pg_query.Node_TruncateStmt:
+		return &RejectedStatementError{"TRUNCATE", "use SELECT count(*) to see how many rows the table holds"}
+	case *pg_query.Node_GrantStmt:
+		if n.GrantStmt.GetIsGrant() {
+			return &RejectedStatementError{"GRANT", "privileges cannot be changed through this server"}
+		}
+		return &RejectedStatementError{"REVOKE", "privileges cannot be changed through this server"}
+	case *pg_query.Node_CopyStmt:
+		return &RejectedStatementError{"COPY", "wrap as a SELECT to read the rows"}
+	case *pg_query.Node_VacuumStmt:
+		if n.VacuumStmt.GetIsVacuumcmd() {
+			return &RejectedStatementError{"VACUUM", "maintenance commands are not allowed"}
+		}
+		return &RejectedStatementError{"ANALYZE", "describe_table already reports column statistics"}
+	case *pg_query.Node_DoStmt:
+		return &RejectedStatementError{"DO", "anonymous code blocks are not allowed; " + suggestSelect}
+	case *pg_query.Node_CallStmt:
+		return &RejectedStatementError{"CALL", "procedures are not allowed; functions can be called from a SELECT"}
+	case *pg_query.Node_TransactionStmt:
+		return &RejectedStatementError{"transaction control", "each query already runs in its own read-only transaction; send the SELECT on its own"}
+	case *pg_query.Node_VariableSetStmt:
+		return &RejectedStatementError{"SET", "session settings cannot be changed"}
+	case *pg_query.Node_VariableShowStmt:
+		return &RejectedStatementError{"SHOW", "use SELECT current_setting('name') instead"}
+	default:
+		// Node_FooStmt → "Foo".
+		kind := strings.TrimPrefix(fmt.Sprintf("%T", n), "*pg_query.Node_")
+		return &RejectedStatementError{strings.TrimSuffix(kind, "Stmt"), suggestSelect}
 	}
 }
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"errors"
+	"strings"
 	"testing"
 )
 
@@ -92,3 +93,289 @@ func TestQueryValidator_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryValidator_RejectedStatementKind(t *testing.T) {
+	t.Parallel()
+	v := NewPgQueryValidator()
+
+	tests := []struct {
+		sql  string
+		kind string
+	}{
+		{"INSERT INTO users (name) VALUES ('a')", "INSERT"},
+		{"UPDATE users SET name = 'a'", "UPDATE"},
+		{"DELETE FROM users", "DELETE"},
+		{"MERGE INTO users u USING staging s ON u.id = s.id WHEN MATCHED THEN DELETE", "MERGE"},
+		{"CREATE TABLE t (id int)", "CREATE TABLE"},
+		{"CREATE TABLE t AS SELECT 1", "CREATE TABLE AS"},
+		{"CREATE MATERIALIZED VIEW mv AS SELECT 1", "CREATE MATERIALIZED VIEW"},
+		{"ALTER TABLE users ADD COLUMN age int", "ALTER TABLE"},
+		{"DROP TABLE users", "DROP"},
+		{"CREATE INDEX idx ON users(id)", "CREATE INDEX"},
+		{"TRUNCATE users", "TRUNCATE"},
+		{"GRANT SELECT ON users TO public", "GRANT"},
+		{"REVOKE SELECT ON users FROM public", "REVOKE"},
+		{"COPY users TO '/tmp/out.csv'", "COPY"},
+		{"VACUUM users", "VACUUM"},
+		{"ANALYZE users", "ANALYZE"},
+		{"DO $$ BEGIN RAISE NOTICE 'hi'; END $$", "DO"},
+		{"CALL refresh_stats()", "CALL"},
+		{"BEGIN", "transaction control"},
+		{"SET search_path = public", "SET"},
+		{"SHOW work_mem", "SHOW"},
+		{"LOCK TABLE users", "Lock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			t.Parallel()
+			err := v.Validate(tt.sql)
+
+			var rejected *RejectedStatementError
+			if !errors.As(err, &rejected) {
+				t.Fatalf("expected *RejectedStatementError, got: %v", err)
+			}
+			if rejected.Kind != tt.kind {
+				t.Errorf("Kind = %q, want %q", rejected.Kind, tt.kind)
+			}
+			if rejected.Suggestion == "" {
+				t.Error("expected a suggestion")
+			}
+			if !errors.Is(err, ErrNotAllowed) {
+				t.Errorf("expected error to wrap ErrNotAllowed, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestRejectedStatementError_Message(t *testing.T) {
+	t.Parallel()
+	err := NewPgQueryValidator().Validate("UPDATE users SET name = 'a'")
+	want := "only SELECT queries are allowed: detected UPDATE statement; " + suggestPreview
+	if err == nil || err.Error() != want {
+		t.Errorf("Error() = %v, want %q", err, want)
+	}
+}
+
+func TestQueryValidator_MultiStatement(t *testing.T) {
+	t.Parallel()
+	v := NewPgQueryValidator(WithMultiStatement(true))
+
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr error
+		wantMsg string // substring of the error, when set
+	}{
+		{"single select", "SELECT 1", nil, ""},
+		{"set local then select", "SET LOCAL work_mem = '64MB'; SELECT 1", nil, ""},
+		{"set local to default", "SET LOCAL enable_seqscan TO DEFAULT; SELECT 1", nil, ""},
+		{"several selects", "SELECT 1; SELECT 2; SELECT 3", nil, ""},
+		{"ends with explain", "SET LOCAL enable_seqscan = off; EXPLAIN SELECT 1", nil, ""},
+		{"trailing semicolon", "SET LOCAL work_mem = '64MB'; SELECT 1;", nil, ""},
+
+		{"select then drop", "SELECT 1; DROP TABLE users", ErrNotAllowed, "statement 2: "},
+		{"update first", "UPDATE users SET name = 'a'; SELECT 1", ErrNotAllowed, "statement 1: "},
+		{"session set", "SET work_mem = '64MB'; SELECT 1", ErrNotAllowed, "SET LOCAL"},
+		{"ends with set local", "SELECT 1; SET LOCAL work_mem = '64MB'", ErrNotAllowed, "must end with the SELECT"},
+		{"statement timeout", "SET LOCAL statement_timeout = 0; SELECT pg_sleep(60)", ErrNotAllowed, "statement_timeout"},
+		{"read only", "SET LOCAL transaction_read_only = off; SELECT 1", ErrNotAllowed, "transaction_read_only"},
+		{"role", "SET LOCAL ROLE postgres; SELECT 1", ErrNotAllowed, "role"},
+		{"set local transaction", "SET LOCAL TRANSACTION READ WRITE; SELECT 1", ErrNotAllowed, "SET LOCAL name = value"},
+		{"explain not last", "EXPLAIN SELECT 1; SELECT 2", ErrNotAllowed, "put EXPLAIN there"},
+		{"begin commit", "BEGIN; SELECT 1; COMMIT", ErrNotAllowed, "statement 1: "},
+		{"parse error anywhere", "SELECT 1; SELEC 2", ErrParseFailed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := v.Validate(tt.sql)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got: %v", tt.wantErr, err)
+			}
+			if tt.wantMsg != "" && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("error %q does not contain %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestQueryValidator_MultiStatementOffByDefault(t *testing.T) {
+	t.Parallel()
+	err := NewPgQueryValidator().Validate("SET LOCAL work_mem = '64MB'; SELECT 1")
+	if !errors.Is(err, ErrMultiStatement) {
+		t.Errorf("expected ErrMultiStatement, got: %v", err)
+	}
+}
+
+func TestQueryValidator_FunctionCheck(t *testing.T) {
+	t.Parallel()
+	v := NewPgQueryValidator(
+		WithMultiStatement(true),
+		WithFunctionCheck(true),
+		WithBlockedFunctions([]string{"app.record_visit", "Purge_Cache"}),
+	)
+
+	tests := []struct {
+		name    string
+		sql     string
+		blocked string // "" means the query is accepted
+	}{
+		{"plain select", "SELECT id, lower(name), now() FROM users", ""},
+		{"volatile but harmless", "SELECT random()", ""},
+		{"select list", "SELECT pg_terminate_backend(42)", "pg_terminate_backend"},
+		{"qualified built-in", "SELECT pg_catalog.set_config('statement_timeout', '0', false)", "pg_catalog.set_config"},
+		{"where clause", "SELECT * FROM users WHERE pg_advisory_lock(id) IS NOT NULL", "pg_advisory_lock"},
+		{"subquery in CTE", "WITH x AS (SELECT nextval('seq')) SELECT * FROM x", "nextval"},
+		{"from clause", "SELECT * FROM dblink_exec('dbname=app', 'DELETE FROM users')", "dblink_exec"},
+		{"explain analyze", "EXPLAIN ANALYZE SELECT pg_reload_conf()", "pg_reload_conf"},
+		{"batch", "SET LOCAL work_mem = '64MB'; SELECT lo_unlink(1)", "lo_unlink"},
+		{"configured, qualified", "SELECT app.record_visit(1)", "app.record_visit"},
+		{"configured, unqualified call", "SELECT record_visit(1)", "record_visit"},
+		{"configured, other schema", "SELECT audit.record_visit(1)", ""},
+		{"configured, case-insensitive", "SELECT PURGE_CACHE()", "purge_cache"},
+		{"string literal", "SELECT 'pg_terminate_backend(1)'", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := v.Validate(tt.sql)
+			if tt.blocked == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			var bf *BlockedFunctionError
+			if !errors.As(err, &bf) {
+				t.Fatalf("expected BlockedFunctionError, got: %v", err)
+			}
+			if bf.Name != tt.blocked {
+				t.Errorf("blocked %q, want %q", bf.Name, tt.blocked)
+			}
+			if !errors.Is(err, ErrNotAllowed) {
+				t.Errorf("expected error to wrap ErrNotAllowed, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestQueryValidator_FunctionCheckOffByDefault(t *testing.T) {
+	t.Parallel()
+	if err := NewPgQueryValidator().Validate("SELECT pg_terminate_backend(42)"); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+	// Configured names apply without the built-in list.
+	v := NewPgQueryValidator(WithBlockedFunctions([]string{"purge_cache"}))
+	if err := v.Validate("SELECT set_config('work_mem', '1GB', false)"); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+	if err := v.Validate("SELECT purge_cache()"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected ErrNotAllowed, got: %v", err)
+	}
+}
+
+func TestQueryValidator_AllowedSchemas(t *testing.T) {
+	t.Parallel()
+	v := NewPgQueryValidator(
+		WithMultiStatement(true),
+		WithAllowedSchemas([]string{"public", "sales"}),
+	)
+
+	tests := []struct {
+		name    string
+		sql     string
+		outside string // "schema.table" refused, "" means the query is accepted
+	}{
+		{"unqualified", "SELECT * FROM users", ""},
+		{"allowed schema", "SELECT * FROM sales.orders o JOIN public.users u ON u.id = o.user_id", ""},
+		{"cte name", "WITH hr AS (SELECT 1) SELECT * FROM hr", ""},
+		{"qualified", "SELECT * FROM hr.salaries", "hr.salaries"},
+		{"join", "SELECT * FROM public.users u JOIN hr.salaries s ON s.user_id = u.id", "hr.salaries"},
+		{"subquery", "SELECT * FROM users WHERE id IN (SELECT user_id FROM hr.salaries)", "hr.salaries"},
+		{"cte body", "WITH s AS (SELECT * FROM hr.salaries) SELECT * FROM s", "hr.salaries"},
+		{"lateral", "SELECT * FROM users u, LATERAL (SELECT * FROM hr.salaries s WHERE s.user_id = u.id) x", "hr.salaries"},
+		{"explain", "EXPLAIN SELECT * FROM hr.salaries", "hr.salaries"},
+		{"batch", "SET LOCAL work_mem = '64MB'; SELECT * FROM hr.salaries", "hr.salaries"},
+		{"system catalog", "SELECT * FROM pg_catalog.pg_class", "pg_catalog.pg_class"},
+		{"unqualified system catalog", "SELECT * FROM pg_roles", "pg_catalog.pg_roles"},
+		{"unqualified catalog view in a subquery", "SELECT * FROM users WHERE id IN (SELECT pid FROM pg_stat_activity)", "pg_catalog.pg_stat_activity"},
+		{"pg_ name qualified with an allowed schema", "SELECT * FROM public.pg_notes", ""},
+		{"pg_ cte name", "WITH pg_x AS (SELECT 1) SELECT * FROM pg_x", ""},
+		{"quoted schema is case-sensitive", `SELECT * FROM "Sales".orders`, "Sales.orders"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := v.Validate(tt.sql)
+			if tt.outside == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			var se *SchemaNotAllowedError
+			if !errors.As(err, &se) {
+				t.Fatalf("expected SchemaNotAllowedError, got: %v", err)
+			}
+			if got := se.Schema + "." + se.Table; got != tt.outside {
+				t.Errorf("refused %q, want %q", got, tt.outside)
+			}
+			if !errors.Is(err, ErrNotAllowed) {
+				t.Errorf("expected error to wrap ErrNotAllowed, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestQueryValidator_AllowedSchemasLockSearchPath(t *testing.T) {
+	t.Parallel()
+	enforced := NewPgQueryValidator(
+		WithMultiStatement(true),
+		WithAllowedSchemas([]string{"public"}),
+	)
+	open := NewPgQueryValidator(WithMultiStatement(true))
+
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{"set local search_path", "SET LOCAL search_path = hr; SELECT * FROM salaries"},
+		{"set local search_path to default", "SET LOCAL search_path TO DEFAULT; SELECT * FROM salaries"},
+		{"set_config", "SELECT set_config('search_path', 'hr', true); SELECT * FROM salaries"},
+		{"qualified set_config", "SELECT pg_catalog.set_config('search_path', 'hr', true)"},
+		{"set_config in a subquery", "SELECT * FROM users WHERE (SELECT set_config('search_path', 'hr', true)) IS NOT NULL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := enforced.Validate(tt.sql)
+			if !errors.Is(err, ErrNotAllowed) {
+				t.Errorf("expected ErrNotAllowed, got: %v", err)
+			}
+			if err := open.Validate(tt.sql); err != nil {
+				t.Errorf("without enforced schemas, expected no error, got: %v", err)
+			}
+		})
+	}
+
+	if err := enforced.Validate("SET LOCAL work_mem = '64MB'; SELECT * FROM users"); err != nil {
+		t.Errorf("other settings stay allowed, got: %v", err)
+	}
+}
+
+func TestQueryValidator_AllowedSchemasEmpty(t *testing.T) {
+	t.Parallel()
+	if err := NewPgQueryValidator(WithAllowedSchemas(nil)).Validate("SELECT * FROM hr.salaries"); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
@@ -4,11 +4,12 @@ import "context"
 
 // AuditEntry represents a single auditable query event.
 type AuditEntry struct {
-	Tool         string
-	SQL          string
-	RowsReturned int
-	DurationMS   int64
-	Err          error
+	Tool             string
+	SQL              string
+	ReferencedTables []string // tables the query names, "schema.table" where qualified; see domain.ParsedQuery.TableNames
+	RowsReturned     int
+	DurationMS       int64
+	Err              error
 }
 
 // QueryAuditor records query audit events.
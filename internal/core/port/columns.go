@@ -0,0 +1,12 @@
+package port
+
+import "context"
+
+// ColumnLister lists column names straight from the catalog, regardless of
+// the exposed schemas, for policy rules that cover whole tables.
+type ColumnLister interface {
+	// ColumnNames returns the columns, in table order, of every table in
+	// schemas plus the tables named in tables ("schema.table"), keyed by
+	// "schema.table".
+	ColumnNames(ctx context.Context, schemas, tables []string) (map[string][]string, error)
+}
@@ -1,7 +1,173 @@
 package port
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type QueryExecutor interface {
 	Execute(ctx context.Context, sql string) ([]map[string]any, error)
 }
+
+type maxRowsKey struct{}
+
+// WithMaxRows returns a context requesting a per-call row limit. Executors
+// clamp it to their configured ceiling.
+func WithMaxRows(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxRowsKey{}, n)
+}
+
+// MaxRowsFromContext returns the per-call row limit, if one was requested.
+func MaxRowsFromContext(ctx context.Context) (int, bool) {
+	n, ok := ctx.Value(maxRowsKey{}).(int)
+	return n, ok && n > 0
+}
+
+type rowCapKey struct{}
+
+// WithRowCap returns a context capping the call's row limit at n, whatever
+// limit was requested or configured. Unlike WithMaxRows it can only lower
+// the limit; the lowest of several caps wins.
+func WithRowCap(ctx context.Context, n int) context.Context {
+	if cur, ok := RowCapFromContext(ctx); ok && cur <= n {
+		return ctx
+	}
+	return context.WithValue(ctx, rowCapKey{}, n)
+}
+
+// RowCapFromContext returns the row cap set by WithRowCap, if any.
+func RowCapFromContext(ctx context.Context) (int, bool) {
+	n, ok := ctx.Value(rowCapKey{}).(int)
+	return n, ok && n > 0
+}
+
+type appliedRowLimitKey struct{}
+
+// WithAppliedRowLimit returns a context asking the executor to store the row
+// limit it ran the query under in *n, after clamping and caps, so a caller
+// can tell whether a result was cut short.
+func WithAppliedRowLimit(ctx context.Context, n *int) context.Context {
+	return context.WithValue(ctx, appliedRowLimitKey{}, n)
+}
+
+// AppliedRowLimitFromContext returns the destination for the applied row
+// limit, or nil if the caller did not ask for it.
+func AppliedRowLimitFromContext(ctx context.Context) *int {
+	n, _ := ctx.Value(appliedRowLimitKey{}).(*int)
+	return n
+}
+
+type executedSQLKey struct{}
+
+// WithExecutedSQL returns a context asking the executor to store the SQL it
+// actually sent to the database in *sql, after limit rewriting and any other
+// changes, so a caller can show what ran.
+func WithExecutedSQL(ctx context.Context, sql *string) context.Context {
+	return context.WithValue(ctx, executedSQLKey{}, sql)
+}
+
+// ExecutedSQLFromContext returns the destination for the executed SQL, or
+// nil if the caller did not ask for it.
+func ExecutedSQLFromContext(ctx context.Context) *string {
+	sql, _ := ctx.Value(executedSQLKey{}).(*string)
+	return sql
+}
+
+type columnOrderKey struct{}
+
+// WithColumnOrder returns a context asking the executor to store the result
+// keys in *keys in the order the columns were selected, which the row maps
+// themselves do not keep.
+func WithColumnOrder(ctx context.Context, keys *[]string) context.Context {
+	return context.WithValue(ctx, columnOrderKey{}, keys)
+}
+
+// ColumnOrderFromContext returns the destination for the column order, or
+// nil if the caller did not ask for it.
+func ColumnOrderFromContext(ctx context.Context) *[]string {
+	keys, _ := ctx.Value(columnOrderKey{}).(*[]string)
+	return keys
+}
+
+type timeoutCapKey struct{}
+
+// WithTimeoutCap returns a context capping the call's statement timeout at
+// d, whatever timeout was requested or configured, floor included. Like
+// WithRowCap it can only shorten the timeout; the lowest of several caps wins.
+func WithTimeoutCap(ctx context.Context, d time.Duration) context.Context {
+	if cur, ok := TimeoutCapFromContext(ctx); ok && cur <= d {
+		return ctx
+	}
+	return context.WithValue(ctx, timeoutCapKey{}, d)
+}
+
+// TimeoutCapFromContext returns the timeout cap set by WithTimeoutCap, if any.
+func TimeoutCapFromContext(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Value(timeoutCapKey{}).(time.Duration)
+	return d, ok && d > 0
+}
+
+type queryTimeoutKey struct{}
+
+// WithQueryTimeout returns a context requesting a per-call statement
+// timeout. Executors clamp it to their configured floor and ceiling.
+func WithQueryTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, d)
+}
+
+// QueryTimeoutFromContext returns the per-call timeout, if one was requested.
+func QueryTimeoutFromContext(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Value(queryTimeoutKey{}).(time.Duration)
+	return d, ok && d > 0
+}
+
+// ResultColumn describes one column of a query result.
+type ResultColumn struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"` // e.g. "integer", "numeric(10,2)"
+	TypeOID uint32 `json:"type_oid"`
+}
+
+type resultColumnsKey struct{}
+
+// WithResultColumns returns a context asking the executor to store the
+// result's column descriptions in *cols, even when no rows are returned.
+func WithResultColumns(ctx context.Context, cols *[]ResultColumn) context.Context {
+	return context.WithValue(ctx, resultColumnsKey{}, cols)
+}
+
+// ResultColumnsFromContext returns the destination for column descriptions,
+// or nil if the caller did not ask for them.
+func ResultColumnsFromContext(ctx context.Context) *[]ResultColumn {
+	cols, _ := ctx.Value(resultColumnsKey{}).(*[]ResultColumn)
+	return cols
+}
+
+type explainOnlyKey struct{}
+
+// WithExplainOnly returns a context asking for EXPLAIN plans instead of
+// results. There is deliberately no way to turn it off again: a caller can
+// opt in to explain-only, never out of it.
+func WithExplainOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, explainOnlyKey{}, true)
+}
+
+// ExplainOnlyFromContext reports whether the call asked for explain-only.
+func ExplainOnlyFromContext(ctx context.Context) bool {
+	v, _ := ctx.Value(explainOnlyKey{}).(bool)
+	return v
+}
+
+type preferReplicaKey struct{}
+
+// WithPreferReplica returns a context marking the call as tolerant of
+// replication lag, so it may run on a read replica instead of the primary.
+func WithPreferReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, preferReplicaKey{}, true)
+}
+
+// PreferReplicaFromContext reports whether the call may run on a replica.
+func PreferReplicaFromContext(ctx context.Context) bool {
+	v, _ := ctx.Value(preferReplicaKey{}).(bool)
+	return v
+}
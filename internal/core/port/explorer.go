@@ -20,6 +20,7 @@ type ColumnStats struct {
 
 type TableInfo struct {
 	Schema      string `json:"schema"`
+	SchemaLabel string `json:"schema_label,omitempty"` // display label from the policy; queries use Schema
 	Name        string `json:"name"`
 	Type        string `json:"type"`
 	RowEstimate int64  `json:"row_estimate"`
@@ -28,16 +29,30 @@ type TableInfo struct {
 	ColumnCount int    `json:"column_count"`
 	HasIndexes  bool   `json:"has_indexes"`
 	Comment     string `json:"comment,omitempty"`
+
+	SizeBreakdown *TableSizes `json:"size_breakdown,omitempty"` // tables only; discover returns it when asked
+}
+
+// TableSizes splits a table's TotalBytes into its parts.
+type TableSizes struct {
+	TableBytes int64 `json:"table_bytes"` // main data plus free space and visibility maps
+	IndexBytes int64 `json:"index_bytes"` // all indexes
+	ToastBytes int64 `json:"toast_bytes"` // out-of-line values and the TOAST index
 }
 
 type ColumnInfo struct {
-	Name         string       `json:"name"`
-	DataType     string       `json:"data_type"`
-	IsNullable   bool         `json:"is_nullable"`
-	DefaultValue string       `json:"default_value,omitempty"`
-	IsPrimaryKey bool         `json:"is_primary_key"`
-	Comment      string       `json:"comment,omitempty"`
-	Stats        *ColumnStats `json:"stats,omitempty"`
+	Name          string       `json:"name"`
+	DataType      string       `json:"data_type"`
+	IsNullable    bool         `json:"is_nullable"`
+	DefaultValue  string       `json:"default_value,omitempty"`
+	IsPrimaryKey  bool         `json:"is_primary_key"`
+	Comment       string       `json:"comment,omitempty"`
+	Constraints   []string     `json:"constraints,omitempty"`    // e.g. primary_key, foreign_key, unique, check, not_null
+	AllowedValues []string     `json:"allowed_values,omitempty"` // from a CHECK (col IN (...)) constraint
+	Storage       string       `json:"storage,omitempty"`        // extended, external or main; omitted for plain columns
+	Compression   string       `json:"compression,omitempty"`    // pglz or lz4, for extended and main columns
+	Collation     string       `json:"collation,omitempty"`      // e.g. "C" or "und-x-icu"; omitted for the database default
+	Stats         *ColumnStats `json:"stats,omitempty"`
 }
 
 type ForeignKey struct {
@@ -45,34 +60,59 @@ type ForeignKey struct {
 	ColumnName       string `json:"column_name"`
 	ReferencedTable  string `json:"referenced_table"`
 	ReferencedColumn string `json:"referenced_column"`
+	OnDelete         string `json:"on_delete"`         // NO ACTION, RESTRICT, CASCADE, SET NULL or SET DEFAULT
+	OnUpdate         string `json:"on_update"`         // same values as OnDelete
+	Comment          string `json:"comment,omitempty"` // COMMENT ON CONSTRAINT
+}
+
+// InferredForeignKey is a likely relationship derived from column naming
+// conventions where no FOREIGN KEY constraint exists.
+type InferredForeignKey struct {
+	ColumnName       string `json:"column_name"`
+	ReferencedTable  string `json:"referenced_table"`
+	ReferencedColumn string `json:"referenced_column"`
+	Confidence       string `json:"confidence"`
+	Reason           string `json:"reason"`
 }
 
 type CheckConstraint struct {
-	Name       string `json:"name"`
-	Expression string `json:"expression"`
+	Name       string   `json:"name"`
+	Expression string   `json:"expression"`
+	Columns    []string `json:"columns,omitempty"`
+	Comment    string   `json:"comment,omitempty"` // COMMENT ON CONSTRAINT
 }
 
 type IndexInfo struct {
-	Name       string `json:"name"`
-	Definition string `json:"definition"`
-	IsUnique   bool   `json:"is_unique"`
+	Name       string   `json:"name"`
+	Definition string   `json:"definition"`
+	IsUnique   bool     `json:"is_unique"`
+	Columns    []string `json:"columns,omitempty"`
+	Comment    string   `json:"comment,omitempty"` // COMMENT ON INDEX
 }
 
 type TableDetail struct {
-	Schema           string            `json:"schema"`
-	Name             string            `json:"name"`
-	Comment          string            `json:"comment,omitempty"`
-	RowEstimate      int64             `json:"row_estimate"`
-	TotalBytes       int64             `json:"total_bytes,omitempty"`
-	SizeHuman        string            `json:"size_human,omitempty"`
-	Columns          []ColumnInfo      `json:"columns"`
-	ForeignKeys      []ForeignKey      `json:"foreign_keys,omitempty"`
-	Indexes          []IndexInfo       `json:"indexes,omitempty"`
-	CheckConstraints []CheckConstraint `json:"check_constraints,omitempty"`
-	StatsAge         *time.Time        `json:"stats_age,omitempty"`
-	StatsAgeWarning  string            `json:"stats_age_warning,omitempty"`
-	SampleRows       []map[string]any  `json:"sample_rows,omitempty"`
-	IndexUsage       []IndexUsage      `json:"index_usage,omitempty"`
+	Schema                string                       `json:"schema"`
+	SchemaLabel           string                       `json:"schema_label,omitempty"` // display label from the policy; queries use Schema
+	Name                  string                       `json:"name"`
+	Comment               string                       `json:"comment,omitempty"`
+	RowEstimate           int64                        `json:"row_estimate"`
+	RowEstimateConfidence domain.RowEstimateConfidence `json:"row_estimate_confidence,omitempty"` // from stats age and VACUUM/write activity; DetailFull only
+	RowEstimateNote       string                       `json:"row_estimate_note,omitempty"`       // how RowEstimate was obtained when not from reltuples
+	TotalBytes            int64                        `json:"total_bytes,omitempty"`
+	SizeHuman             string                       `json:"size_human,omitempty"`
+	Columns               []ColumnInfo                 `json:"columns"`
+	TotalColumns          int                          `json:"total_columns,omitempty"` // set when describe_table left columns out
+	ForeignKeys           []ForeignKey                 `json:"foreign_keys,omitempty"`
+	InferredFKs           []InferredForeignKey         `json:"inferred_foreign_keys,omitempty"`
+	Indexes               []IndexInfo                  `json:"indexes,omitempty"`
+	CheckConstraints      []CheckConstraint            `json:"check_constraints,omitempty"`
+	Parents               []string                     `json:"parents,omitempty"`  // direct INHERITS/partition parents; "schema.table" when in another schema
+	Children              []string                     `json:"children,omitempty"` // direct INHERITS children or partitions
+	StatsAge              *time.Time                   `json:"stats_age,omitempty"`
+	StatsAgeWarning       string                       `json:"stats_age_warning,omitempty"`
+	SampleRows            []map[string]any             `json:"sample_rows,omitempty"`
+	IndexUsage            []IndexUsage                 `json:"index_usage,omitempty"`
+	Recommendations       []string                     `json:"recommendations,omitempty"`
 }
 
 // IndexUsage holds usage statistics for a single index.
@@ -81,16 +121,35 @@ type IndexUsage struct {
 	Scans     int64  `json:"scans"`
 	SizeBytes int64  `json:"size_bytes"`
 	SizeHuman string `json:"size_human"`
+
+	// Estimated space a REINDEX would reclaim, for btree indexes on analyzed
+	// tables; 0 when there is none or it cannot be estimated.
+	BloatBytes int64   `json:"bloat_bytes"`
+	BloatRatio float64 `json:"bloat_ratio"` // BloatBytes / SizeBytes
+}
+
+// TableActivity holds the cumulative row-change counters of a table from
+// pg_stat_user_tables, counted since the statistics were last reset.
+type TableActivity struct {
+	Schema       string     `json:"schema"`
+	Name         string     `json:"name"`
+	Inserts      int64      `json:"inserts"`
+	Updates      int64      `json:"updates"`
+	Deletes      int64      `json:"deletes"`
+	TotalChanges int64      `json:"total_changes"`         // inserts + updates + deletes
+	StatsReset   *time.Time `json:"stats_reset,omitempty"` // when the database's counters were last reset, if ever
 }
 
 type SchemaInfo struct {
-	Name string `json:"name"`
+	Name        string `json:"name"`
+	SchemaLabel string `json:"schema_label,omitempty"` // display label from the policy
 }
 
 // SchemaOverview groups tables under their schema for discovery results.
 type SchemaOverview struct {
-	Name   string      `json:"name"`
-	Tables []TableInfo `json:"tables"`
+	Name        string      `json:"name"`
+	SchemaLabel string      `json:"schema_label,omitempty"` // display label from the policy
+	Tables      []TableInfo `json:"tables"`
 }
 
 // DiscoveryResult is the response from Discover — all schemas with nested tables.
@@ -98,9 +157,45 @@ type DiscoveryResult struct {
 	Schemas []SchemaOverview `json:"schemas"`
 }
 
+// DetailLevel controls how much enrichment DescribeTable performs.
+type DetailLevel string
+
+const (
+	// DetailFull runs every enrichment fetcher (the default).
+	DetailFull DetailLevel = "full"
+	// DetailBasic skips column stats, stats age, sample rows and index usage
+	// for a fast structural answer.
+	DetailBasic DetailLevel = "basic"
+)
+
+// Valid reports whether d is a recognised detail level.
+func (d DetailLevel) Valid() bool {
+	return d == DetailFull || d == DetailBasic
+}
+
+type detailLevelKey struct{}
+
+// WithDetailLevel returns a context asking DescribeTable for the given level.
+func WithDetailLevel(ctx context.Context, level DetailLevel) context.Context {
+	return context.WithValue(ctx, detailLevelKey{}, level)
+}
+
+// DetailLevelFromContext returns the requested detail level, defaulting to DetailFull.
+func DetailLevelFromContext(ctx context.Context) DetailLevel {
+	if v, ok := ctx.Value(detailLevelKey{}).(DetailLevel); ok && v.Valid() {
+		return v
+	}
+	return DetailFull
+}
+
 type SchemaExplorer interface {
 	ListSchemas(ctx context.Context) ([]SchemaInfo, error)
 	ListTables(ctx context.Context) ([]TableInfo, error)
 	DescribeTable(ctx context.Context, schema, tableName string) (*TableDetail, error)
 	Discover(ctx context.Context) (*DiscoveryResult, error)
+	TableActivity(ctx context.Context) ([]TableActivity, error)
+	// ForeignKeys returns the declared foreign keys of every table in the
+	// configured schemas, keyed by "schema.table", as DescribeTable lists
+	// them for each table.
+	ForeignKeys(ctx context.Context) (map[string][]ForeignKey, error)
 }
@@ -8,6 +8,7 @@ type Instrumentation interface {
 	IncrementQueryCount(ctx context.Context)
 	IncrementQueryErrors(ctx context.Context)
 	RecordToolDuration(ctx context.Context, ms float64)
+	RecordAudit(ctx context.Context, entry AuditEntry) // aggregate counters for each audited query
 }
 
 // NoopInstrumentation discards all metrics.
@@ -17,3 +18,4 @@ func (NoopInstrumentation) RecordQueryDuration(context.Context, float64) {}
 func (NoopInstrumentation) IncrementQueryCount(context.Context)          {}
 func (NoopInstrumentation) IncrementQueryErrors(context.Context)         {}
 func (NoopInstrumentation) RecordToolDuration(context.Context, float64)  {}
+func (NoopInstrumentation) RecordAudit(context.Context, AuditEntry)      {}
@@ -0,0 +1,20 @@
+package port
+
+import (
+	"context"
+	"time"
+)
+
+// ReplicationStatus tells whether the server is a primary or a standby and,
+// for a standby, how far behind its primary it is.
+type ReplicationStatus struct {
+	Role         string     `json:"role"` // "primary" or "standby"
+	InRecovery   bool       `json:"in_recovery"`
+	LagSeconds   *float64   `json:"lag_seconds,omitempty"`    // standby only; 0 when all received WAL is replayed
+	LastReplayAt *time.Time `json:"last_replay_at,omitempty"` // commit time of the last replayed transaction
+}
+
+// ReplicationReporter reads the replication status of the connected server.
+type ReplicationReporter interface {
+	ReplicationStatus(ctx context.Context) (*ReplicationStatus, error)
+}
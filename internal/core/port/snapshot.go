@@ -0,0 +1,26 @@
+package port
+
+import (
+	"context"
+	"time"
+)
+
+// TableSize is a table's size when a snapshot was taken.
+type TableSize struct {
+	RowEstimate int64 `json:"row_estimate"`
+	TotalBytes  int64 `json:"total_bytes"`
+}
+
+// SizeSnapshot records the size of every visible table at one point in time,
+// keyed by "schema.table".
+type SizeSnapshot struct {
+	TakenAt time.Time            `json:"taken_at"`
+	Tables  map[string]TableSize `json:"tables"`
+}
+
+// SnapshotStore persists table size snapshots between calls.
+type SnapshotStore interface {
+	// Swap stores cur and returns the snapshot it replaced, or nil if none
+	// was stored yet.
+	Swap(ctx context.Context, cur SizeSnapshot) (*SizeSnapshot, error)
+}
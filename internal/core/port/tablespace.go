@@ -0,0 +1,16 @@
+package port
+
+import "context"
+
+// Tablespace is a storage location of the database cluster.
+type Tablespace struct {
+	Name      string `json:"name"`
+	Location  string `json:"location,omitempty"`   // empty for the built-in pg_default and pg_global
+	SizeBytes *int64 `json:"size_bytes,omitempty"` // nil when the role may not read the size
+	SizeHuman string `json:"size_human,omitempty"`
+}
+
+// TablespaceLister lists the tablespaces of the connected server.
+type TablespaceLister interface {
+	Tablespaces(ctx context.Context) ([]Tablespace, error)
+}
@@ -1,6 +1,9 @@
 package port
 
-// QueryValidator validates SQL statements before execution.
+import "github.com/guillermoBallester/isthmus/internal/core/domain"
+
+// QueryValidator validates SQL statements before execution. It takes the
+// query already parsed, so the service parses each query once.
 type QueryValidator interface {
-	Validate(sql string) error
+	ValidateParsed(q *domain.ParsedQuery) error
 }
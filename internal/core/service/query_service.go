@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/guillermoBallester/isthmus/internal/core/domain"
@@ -21,32 +23,128 @@ func WithToolName(ctx context.Context, name string) context.Context {
 	return context.WithValue(ctx, toolNameKey{}, name)
 }
 
-func toolNameFromCtx(ctx context.Context) string {
+// ToolNameFromContext returns the tool name set by WithToolName, or "".
+func ToolNameFromContext(ctx context.Context) string {
 	if v, ok := ctx.Value(toolNameKey{}).(string); ok {
 		return v
 	}
 	return ""
 }
 
+type timingKey struct{}
+
+// Timing reports where the time of a tool call went, in milliseconds.
+// Execute adds to it, so a call that runs several queries reports their sum.
+type Timing struct {
+	ValidationMS float64 `json:"validation_ms"`
+	ExecutionMS  float64 `json:"execution_ms"`
+	MaskingMS    float64 `json:"masking_ms"`
+}
+
+// WithTiming returns a context asking Execute to add its phase timings to t.
+func WithTiming(ctx context.Context, t *Timing) context.Context {
+	return context.WithValue(ctx, timingKey{}, t)
+}
+
+// timingFromContext returns the Timing set by WithTiming, or nil.
+func timingFromContext(ctx context.Context) *Timing {
+	t, _ := ctx.Value(timingKey{}).(*Timing)
+	return t
+}
+
+// millis converts d to fractional milliseconds.
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
 // QueryService orchestrates SQL validation (domain) and execution (infrastructure).
 type QueryService struct {
 	validator port.QueryValidator
 	executor  port.QueryExecutor
 	auditor   port.QueryAuditor
 	logger    *slog.Logger
-	masks     map[string]domain.MaskType // column-name → mask-type (nil = no masking)
+	masks     map[string]domain.MaskType        // column-name → mask-type (nil = no masking)
+	patterns  []domain.MaskPattern              // name-pattern masks, applied where no explicit mask exists
+	jsonMasks map[string][]domain.JSONPathMask  // column-name → masks on fields inside a JSON value
+	numbered  bool                              // repeated result columns arrive numbered (id, id_2)
+	rowLimits map[domain.TableRef]int           // per-table row caps; the strictest referenced one applies
+	timeouts  map[domain.TableRef]time.Duration // per-table statement timeout caps; the shortest referenced one applies
 	tracer    trace.Tracer
 	inst      port.Instrumentation
+	keyCase   domain.KeyCase                // result column-name casing, applied after masking
+	tools     map[string]domain.ToolMasking // per-tool masking overrides, keyed by tool name
+
+	// Default masks cover every column of a table or schema, resolved per
+	// query from the tables it reads.
+	tableDefaults  map[domain.TableRef]domain.MaskType
+	schemaDefaults map[string]domain.MaskType
+	columns        port.ColumnLister
 }
 
-func NewQueryService(validator port.QueryValidator, executor port.QueryExecutor, auditor port.QueryAuditor, logger *slog.Logger, masks map[string]domain.MaskType, tracer trace.Tracer, inst port.Instrumentation) *QueryService {
+// Option configures optional QueryService behavior.
+type Option func(*QueryService)
+
+// WithMaskPatterns masks result columns whose names match a pattern and
+// have no explicit mask.
+func WithMaskPatterns(patterns []domain.MaskPattern) Option {
+	return func(s *QueryService) { s.patterns = patterns }
+}
+
+// WithJSONMasks masks fields inside json and jsonb result columns, keyed by
+// column name. Column masks still apply to the whole value afterwards.
+func WithJSONMasks(masks map[string][]domain.JSONPathMask) Option {
+	return func(s *QueryService) { s.jsonMasks = masks }
+}
+
+// WithNumberedDuplicates tells the service that the executor numbers repeated
+// result column names (see domain.DuplicateColumnName), so the numbered
+// columns get the mask of the column they repeat.
+func WithNumberedDuplicates(on bool) Option {
+	return func(s *QueryService) { s.numbered = on }
+}
+
+// WithTableRowLimits caps the rows of queries that read the given tables.
+// A query reading several of them gets the strictest cap.
+func WithTableRowLimits(limits map[domain.TableRef]int) Option {
+	return func(s *QueryService) { s.rowLimits = limits }
+}
+
+// WithTableTimeouts caps the statement timeout of queries that read the
+// given tables. A query reading several of them gets the shortest cap.
+func WithTableTimeouts(timeouts map[domain.TableRef]time.Duration) Option {
+	return func(s *QueryService) { s.timeouts = timeouts }
+}
+
+// WithDefaultMasks masks every column of the given tables, and of every
+// table in the given schemas, that has no explicit mask. A table's default
+// beats its schema's. Defaults only apply to queries that read the table;
+// its columns are listed by lister when such a query runs, so tables and
+// columns created later are covered too.
+func WithDefaultMasks(tables map[domain.TableRef]domain.MaskType, schemas map[string]domain.MaskType, lister port.ColumnLister) Option {
+	return func(s *QueryService) {
+		s.tableDefaults, s.schemaDefaults, s.columns = tables, schemas, lister
+	}
+}
+
+// WithToolMasking overrides masking for the results of the named tools, as
+// set on the context with WithToolName.
+func WithToolMasking(tools map[string]domain.ToolMasking) Option {
+	return func(s *QueryService) { s.tools = tools }
+}
+
+// WithKeyCase renders result column names in the given casing.
+func WithKeyCase(c domain.KeyCase) Option {
+	return func(s *QueryService) { s.keyCase = c }
+}
+
+func NewQueryService(validator port.QueryValidator, executor port.QueryExecutor, auditor port.QueryAuditor, logger *slog.Logger, masks map[string]domain.MaskType, tracer trace.Tracer, inst port.Instrumentation, opts ...Option) *QueryService {
 	if tracer == nil {
 		tracer = noop.NewTracerProvider().Tracer("noop")
 	}
 	if inst == nil {
 		inst = port.NoopInstrumentation{}
 	}
-	return &QueryService{
+	s := &QueryService{
 		validator: validator,
 		executor:  executor,
 		auditor:   auditor,
@@ -55,6 +153,10 @@ func NewQueryService(validator port.QueryValidator, executor port.QueryExecutor,
 		tracer:    tracer,
 		inst:      inst,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Execute validates the SQL statement and, if allowed, delegates to the executor.
@@ -68,7 +170,16 @@ func (s *QueryService) Execute(ctx context.Context, sql string) ([]map[string]an
 	)
 	defer span.End()
 
-	if err := s.validator.Validate(sql); err != nil {
+	timing := timingFromContext(ctx)
+	validateStart := time.Now()
+	q, err := domain.ParseQuery(strings.TrimSpace(sql))
+	if err == nil {
+		err = s.validator.ValidateParsed(q)
+	}
+	if timing != nil {
+		timing.ValidationMS += millis(time.Since(validateStart))
+	}
+	if err != nil {
 		s.logger.WarnContext(ctx, "query validation rejected",
 			slog.String("db.operation.name", "query"),
 			slog.String("db.statement", sql),
@@ -80,19 +191,38 @@ func (s *QueryService) Execute(ctx context.Context, sql string) ([]map[string]an
 		return nil, fmt.Errorf("validation: %w", err)
 	}
 
+	if n, ok := domain.TableRowLimit(q, s.rowLimits); ok {
+		ctx = port.WithRowCap(ctx, n)
+	}
+	if d, ok := domain.TableTimeout(q, s.timeouts); ok {
+		ctx = port.WithTimeoutCap(ctx, d)
+	}
+
+	var order []string
+	if s.keyCase != "" && s.keyCase != domain.KeyCaseOriginal {
+		ctx = port.WithColumnOrder(ctx, &order)
+	}
+
 	start := time.Now()
 	results, err := s.executor.Execute(ctx, sql)
-	durationMS := time.Since(start).Milliseconds()
+	elapsed := time.Since(start)
+	durationMS := elapsed.Milliseconds()
+	if timing != nil {
+		timing.ExecutionMS += millis(elapsed)
+	}
 
 	s.inst.RecordQueryDuration(ctx, float64(durationMS))
 
-	s.auditor.Record(ctx, port.AuditEntry{
-		Tool:         toolNameFromCtx(ctx),
-		SQL:          sql,
-		RowsReturned: len(results),
-		DurationMS:   durationMS,
-		Err:          err,
-	})
+	entry := port.AuditEntry{
+		Tool:             ToolNameFromContext(ctx),
+		SQL:              sql,
+		ReferencedTables: q.TableNames(),
+		RowsReturned:     len(results),
+		DurationMS:       durationMS,
+		Err:              err,
+	}
+	s.auditor.Record(ctx, entry)
+	s.inst.RecordAudit(ctx, entry)
 
 	if err != nil {
 		span.RecordError(err)
@@ -103,8 +233,83 @@ func (s *QueryService) Execute(ctx context.Context, sql string) ([]map[string]an
 
 	s.inst.IncrementQueryCount(ctx)
 	span.SetAttributes(attribute.Int("db.response.rows", len(results)))
-	aliases := domain.ExtractAliasMap(sql)
-	domain.MaskRowsWithAliases(results, s.masks, aliases)
+	maskStart := time.Now()
+	rule := s.tools[ToolNameFromContext(ctx)]
+	if !rule.SkipColumns {
+		aliases := q.AliasMap()
+		masks, err := s.withDefaultMasks(ctx, q)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			s.inst.IncrementQueryErrors(ctx)
+			return nil, err
+		}
+		jsonMasks := s.jsonMasks
+		if s.numbered {
+			masks = domain.DuplicateColumnMasks(results, masks)
+			jsonMasks = domain.DuplicateColumnMasks(results, jsonMasks)
+		}
+		domain.MaskRowsJSON(results, jsonMasks, aliases)
+		domain.MaskRowsWithAliases(results, masks, aliases)
+		domain.MaskRowsByPattern(results, masks, s.patterns, aliases, s.numbered)
+		domain.MaskDerivedColumns(results, q.DerivedColumns(), masks, s.patterns)
+	}
+	if rule.RedactPlanLiterals && domain.IsPlanOutput(results) {
+		domain.RedactPlanLiterals(results)
+	}
+	if names := domain.TransformKeys(results, s.keyCase, order); names != nil {
+		if cols := port.ResultColumnsFromContext(ctx); cols != nil {
+			for i := range *cols {
+				name, ok := names[(*cols)[i].Name]
+				if !ok {
+					name = domain.ConvertKey((*cols)[i].Name, s.keyCase)
+				}
+				(*cols)[i].Name = name
+			}
+		}
+	}
+	if timing != nil {
+		timing.MaskingMS += millis(time.Since(maskStart))
+	}
 
 	return results, nil
 }
+
+// withDefaultMasks returns the explicit masks plus the default mask of
+// every column of the covered tables q reads. Explicit masks win; where
+// two tables give a column name different defaults, the first table in name
+// order wins. The columns come from the catalog, so the query fails rather
+// than returning unmasked rows when they cannot be listed.
+func (s *QueryService) withDefaultMasks(ctx context.Context, q *domain.ParsedQuery) (map[string]domain.MaskType, error) {
+	if s.columns == nil || (len(s.tableDefaults) == 0 && len(s.schemaDefaults) == 0) {
+		return s.masks, nil
+	}
+	covered := domain.DefaultMaskedTables(q.Tables(), s.tableDefaults, s.schemaDefaults)
+	if len(covered) == 0 {
+		return s.masks, nil
+	}
+	keys := make([]string, 0, len(covered))
+	for t := range covered {
+		keys = append(keys, t.Schema+"."+t.Name)
+	}
+	slices.Sort(keys)
+	columns, err := s.columns.ColumnNames(ctx, nil, keys)
+	if err != nil {
+		return nil, fmt.Errorf("resolving default masks: %w", err)
+	}
+
+	masks := make(map[string]domain.MaskType, len(s.masks))
+	for k, v := range s.masks {
+		masks[k] = v
+	}
+	for _, key := range keys {
+		schema, table, _ := strings.Cut(key, ".")
+		mask := covered[domain.TableRef{Schema: schema, Name: table}]
+		for _, col := range columns[key] {
+			if _, ok := masks[col]; !ok {
+				masks[col] = mask
+			}
+		}
+	}
+	return masks, nil
+}
@@ -2,10 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/guillermoBallester/isthmus/internal/core/domain"
 	"github.com/guillermoBallester/isthmus/internal/core/port"
@@ -22,18 +25,110 @@ func testLogger() *slog.Logger {
 type mockExecutor struct {
 	executeCalled bool
 	lastSQL       string
+	lastCtx       context.Context
 	result        []map[string]any
+	columns       []port.ResultColumn
 	err           error
+	delay         time.Duration // how long Execute takes
 }
 
-func (m *mockExecutor) Execute(_ context.Context, sql string) ([]map[string]any, error) {
+func (m *mockExecutor) Execute(ctx context.Context, sql string) ([]map[string]any, error) {
 	m.executeCalled = true
 	m.lastSQL = sql
+	m.lastCtx = ctx
+	time.Sleep(m.delay)
+	if cols := port.ResultColumnsFromContext(ctx); cols != nil {
+		*cols = m.columns
+	}
+	if order := port.ColumnOrderFromContext(ctx); order != nil {
+		for _, c := range m.columns {
+			*order = append(*order, c.Name)
+		}
+	}
 	return m.result, m.err
 }
 
+// --- recording Instrumentation ---
+
+type recordingInstrumentation struct {
+	port.NoopInstrumentation
+	audits []port.AuditEntry
+}
+
+func (r *recordingInstrumentation) RecordAudit(_ context.Context, entry port.AuditEntry) {
+	r.audits = append(r.audits, entry)
+}
+
 // --- tests ---
 
+func TestQueryService_RecordsAuditMetrics(t *testing.T) {
+	t.Parallel()
+	inst := &recordingInstrumentation{}
+	exec := &mockExecutor{result: []map[string]any{{"id": 1}, {"id": 2}}}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), nil, nil, inst)
+
+	_, err := svc.Execute(WithToolName(context.Background(), "query"), "SELECT id FROM users")
+	require.NoError(t, err)
+
+	exec.result, exec.err = nil, fmt.Errorf("relation does not exist")
+	_, err = svc.Execute(WithToolName(context.Background(), "query"), "SELECT id FROM missing")
+	require.Error(t, err)
+
+	_, err = svc.Execute(context.Background(), "DELETE FROM users")
+	require.Error(t, err)
+
+	require.Len(t, inst.audits, 2, "rejected statements are not audited")
+	assert.Equal(t, "query", inst.audits[0].Tool)
+	assert.Equal(t, 2, inst.audits[0].RowsReturned)
+	assert.NoError(t, inst.audits[0].Err)
+	assert.Error(t, inst.audits[1].Err)
+	assert.Equal(t, []string{"users"}, inst.audits[0].ReferencedTables)
+	assert.Equal(t, []string{"missing"}, inst.audits[1].ReferencedTables, "failed queries list their tables too")
+}
+
+func TestQueryService_AuditsReferencedTables(t *testing.T) {
+	t.Parallel()
+	inst := &recordingInstrumentation{}
+	exec := &mockExecutor{}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), nil, nil, inst)
+
+	_, err := svc.Execute(context.Background(),
+		"WITH recent AS (SELECT * FROM sales.orders) SELECT * FROM recent r JOIN public.users u ON u.id = r.user_id")
+	require.NoError(t, err)
+
+	require.Len(t, inst.audits, 1)
+	assert.Equal(t, []string{"public.users", "sales.orders"}, inst.audits[0].ReferencedTables)
+}
+
+func TestQueryService_Timing(t *testing.T) {
+	t.Parallel()
+	exec := &mockExecutor{result: []map[string]any{{"email": "a@example.com"}}, delay: 5 * time.Millisecond}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(),
+		map[string]domain.MaskType{"email": domain.MaskRedact}, nil, nil)
+
+	var timing Timing
+	ctx := WithTiming(context.Background(), &timing)
+	_, err := svc.Execute(ctx, "SELECT email FROM users")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, timing.ExecutionMS, 5.0)
+	assert.Less(t, timing.ValidationMS, timing.ExecutionMS)
+	assert.Less(t, timing.MaskingMS, timing.ExecutionMS)
+
+	first := timing
+	_, err = svc.Execute(ctx, "SELECT email FROM users")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, timing.ExecutionMS, first.ExecutionMS+5, "a second query adds to the timing")
+
+	second := timing
+	_, err = svc.Execute(ctx, "DELETE FROM users")
+	require.Error(t, err)
+	assert.Equal(t, second.ExecutionMS, timing.ExecutionMS, "rejected queries are not executed")
+	assert.GreaterOrEqual(t, timing.ValidationMS, second.ValidationMS)
+
+	_, err = svc.Execute(context.Background(), "SELECT email FROM users")
+	require.NoError(t, err, "timing is optional")
+}
+
 func TestQueryService_ValidSelect(t *testing.T) {
 	t.Parallel()
 	exec := &mockExecutor{
@@ -141,6 +236,190 @@ func TestQueryService_WithMasks(t *testing.T) {
 	assert.Equal(t, "Alice", rows[0]["name"])
 }
 
+func TestQueryService_NumberedDuplicatesMasked(t *testing.T) {
+	t.Parallel()
+	exec := &mockExecutor{
+		result: []map[string]any{
+			{"email": "alice@example.com", "email_2": "bob@example.com"},
+		},
+	}
+	masks := map[string]domain.MaskType{"email": domain.MaskRedact}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), masks, nil, nil,
+		WithNumberedDuplicates(true))
+
+	rows, err := svc.Execute(context.Background(), "SELECT a.email, b.email FROM users a JOIN users b ON a.id = b.referrer_id")
+	require.NoError(t, err)
+	assert.Equal(t, "***", rows[0]["email"])
+	assert.Equal(t, "***", rows[0]["email_2"], "the repeated column is masked like the first")
+}
+
+func TestQueryService_NumberedDuplicatesMaskedByPattern(t *testing.T) {
+	t.Parallel()
+	const sql = "SELECT a.ssn, b.ssn FROM people a, people b"
+	patterns := []domain.MaskPattern{{Name: regexp.MustCompile("^ssn$"), Mask: domain.MaskRedact}}
+
+	exec := &mockExecutor{result: []map[string]any{{"ssn": "123-45-6789", "ssn_2": "987-65-4321"}}}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), nil, nil, nil,
+		WithMaskPatterns(patterns), WithNumberedDuplicates(true))
+	rows, err := svc.Execute(context.Background(), sql)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"ssn": "***", "ssn_2": "***"}, rows[0])
+
+	// An explicit mask on the base name covers the repeat, and the pattern
+	// must not mask the hashed value again.
+	exec = &mockExecutor{result: []map[string]any{{"ssn": "123-45-6789", "ssn_2": "123-45-6789"}}}
+	svc = NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(),
+		map[string]domain.MaskType{"ssn": domain.MaskHash}, nil, nil,
+		WithMaskPatterns(patterns), WithNumberedDuplicates(true))
+	rows, err = svc.Execute(context.Background(), sql)
+	require.NoError(t, err)
+	assert.NotEqual(t, "***", rows[0]["ssn_2"])
+	assert.Equal(t, rows[0]["ssn"], rows[0]["ssn_2"], "both copies hashed once")
+}
+
+func TestQueryService_TableRowLimits(t *testing.T) {
+	t.Parallel()
+	limits := map[domain.TableRef]int{
+		{Schema: "hr", Name: "salaries"}:   10,
+		{Schema: "public", Name: "orders"}: 50,
+	}
+	tests := []struct {
+		name    string
+		sql     string
+		wantCap int
+		wantOK  bool
+	}{
+		{"unlimited table", "SELECT * FROM public.users", 0, false},
+		{"single table", "SELECT * FROM hr.salaries", 10, true},
+		{"strictest of several", "SELECT * FROM orders o JOIN hr.salaries s ON s.id = o.id", 10, true},
+		{"subquery", "SELECT * FROM users WHERE id IN (SELECT user_id FROM public.orders)", 50, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			exec := &mockExecutor{}
+			svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), nil, nil, nil,
+				WithTableRowLimits(limits))
+
+			_, err := svc.Execute(context.Background(), tt.sql)
+			require.NoError(t, err)
+			n, ok := port.RowCapFromContext(exec.lastCtx)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantCap, n)
+		})
+	}
+}
+
+func TestQueryService_TableTimeouts(t *testing.T) {
+	t.Parallel()
+	timeouts := map[domain.TableRef]time.Duration{
+		{Schema: "hr", Name: "salaries"}:   2 * time.Second,
+		{Schema: "public", Name: "events"}: 10 * time.Second,
+	}
+	tests := []struct {
+		name    string
+		sql     string
+		wantCap time.Duration
+		wantOK  bool
+	}{
+		{"no timeout", "SELECT * FROM public.users", 0, false},
+		{"single table", "SELECT * FROM public.events", 10 * time.Second, true},
+		{"shortest of several", "SELECT * FROM events e JOIN hr.salaries s ON s.id = e.id", 2 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			exec := &mockExecutor{}
+			svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), nil, nil, nil,
+				WithTableTimeouts(timeouts))
+
+			_, err := svc.Execute(context.Background(), tt.sql)
+			require.NoError(t, err)
+			d, ok := port.TimeoutCapFromContext(exec.lastCtx)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantCap, d)
+		})
+	}
+}
+
+func TestQueryService_JSONMasks(t *testing.T) {
+	t.Parallel()
+	exec := &mockExecutor{
+		result: []map[string]any{
+			{"id": 1, "metadata": map[string]any{"ssn": "123-45-6789", "contact": map[string]any{"phone": "555-123-4567"}}},
+			{"id": 2, "profile": map[string]any{"ssn": "987-65-4321"}},
+		},
+	}
+	jsonMasks := map[string][]domain.JSONPathMask{
+		"metadata": {
+			{Path: "contact.phone", Mask: domain.MaskPartial},
+			{Path: "ssn", Mask: domain.MaskRedact},
+		},
+	}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), nil, nil, nil,
+		WithJSONMasks(jsonMasks))
+
+	rows, err := svc.Execute(context.Background(), "SELECT id, metadata, metadata AS profile FROM users")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"ssn": "***", "contact": map[string]any{"phone": "********4567"}}, rows[0]["metadata"])
+	assert.Equal(t, map[string]any{"ssn": "***"}, rows[1]["profile"], "aliased JSON column is masked")
+}
+
+func TestQueryService_ToolMasking(t *testing.T) {
+	t.Parallel()
+	masks := map[string]domain.MaskType{"email": domain.MaskRedact}
+	newService := func(exec *mockExecutor) *QueryService {
+		return NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), masks, nil, nil,
+			WithToolMasking(map[string]domain.ToolMasking{
+				"orders_report": {SkipColumns: true},
+				"query":         {RedactPlanLiterals: true},
+			}))
+	}
+
+	t.Run("skips column masks for the tool", func(t *testing.T) {
+		t.Parallel()
+		exec := &mockExecutor{result: []map[string]any{{"email": "alice@example.com"}}}
+		rows, err := newService(exec).Execute(WithToolName(context.Background(), "orders_report"), "SELECT email FROM users")
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", rows[0]["email"])
+	})
+
+	t.Run("other tools keep column masks", func(t *testing.T) {
+		t.Parallel()
+		for _, ctx := range []context.Context{WithToolName(context.Background(), "query"), context.Background()} {
+			exec := &mockExecutor{result: []map[string]any{{"email": "alice@example.com"}}}
+			rows, err := newService(exec).Execute(ctx, "SELECT email FROM users")
+			require.NoError(t, err)
+			assert.Equal(t, "***", rows[0]["email"])
+		}
+	})
+
+	t.Run("redacts plan literals for the tool", func(t *testing.T) {
+		t.Parallel()
+		plan := func() []map[string]any {
+			return []map[string]any{{"QUERY PLAN": "Seq Scan on users  (cost=0.00..1.05 rows=1 width=36)"},
+				{"QUERY PLAN": "  Filter: (email = 'alice@example.com'::text)"}}
+		}
+		sql := "EXPLAIN SELECT id FROM users WHERE email = 'alice@example.com'"
+
+		rows, err := newService(&mockExecutor{result: plan()}).Execute(WithToolName(context.Background(), "query"), sql)
+		require.NoError(t, err)
+		assert.Equal(t, "  Filter: (email = '***'::text)", rows[1]["QUERY PLAN"])
+
+		rows, err = newService(&mockExecutor{result: plan()}).Execute(WithToolName(context.Background(), "filter_selectivity"), sql)
+		require.NoError(t, err)
+		assert.Equal(t, "  Filter: (email = 'alice@example.com'::text)", rows[1]["QUERY PLAN"], "no rule, no redaction")
+	})
+
+	t.Run("leaves query results alone", func(t *testing.T) {
+		t.Parallel()
+		exec := &mockExecutor{result: []map[string]any{{"note": "it's 'quoted'"}}}
+		rows, err := newService(exec).Execute(WithToolName(context.Background(), "query"), "SELECT note FROM notes")
+		require.NoError(t, err)
+		assert.Equal(t, "it's 'quoted'", rows[0]["note"])
+	})
+}
+
 func TestQueryService_WithMasks_Aliases(t *testing.T) {
 	t.Parallel()
 	// Simulate what happens when an LLM generates: SELECT "Email" AS email, "Phone" AS phone
@@ -167,6 +446,63 @@ func TestQueryService_WithMasks_Aliases(t *testing.T) {
 	assert.Equal(t, 1, rows[0]["id"])
 }
 
+func TestQueryService_DerivedColumnsMasked(t *testing.T) {
+	t.Parallel()
+	// min(ssn) would otherwise leak the smallest SSN.
+	exec := &mockExecutor{
+		result: []map[string]any{{"min": "078-05-1120", "lowest": "078-05-1120", "count": 3, "max": 9}},
+	}
+	masks := map[string]domain.MaskType{"ssn": domain.MaskRedact}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), masks, nil, nil)
+
+	rows, err := svc.Execute(context.Background(),
+		"SELECT min(ssn), min(ssn) AS lowest, count(ssn), max(id) FROM users")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, map[string]any{"min": "***", "lowest": "***", "count": 3, "max": 9}, rows[0])
+}
+
+func TestQueryService_ComputedColumnsMasked(t *testing.T) {
+	t.Parallel()
+	exec := &mockExecutor{
+		result: []map[string]any{{"x": "078-05-1120", "case": "078-05-1120", "min": "078-05-1120", "id": "078-05-1120"}},
+	}
+	masks := map[string]domain.MaskType{"ssn": domain.MaskRedact}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), masks, nil, nil)
+
+	rows, err := svc.Execute(context.Background(), `SELECT max(ssn) || '' AS x, CASE WHEN true THEN ssn END, (SELECT min(ssn) FROM users), id FROM users
+		UNION ALL SELECT ssn, ssn, ssn, ssn FROM users`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"x": "***", "case": "***", "min": "***", "id": "***"}, rows[0])
+}
+
+func TestQueryService_TracedColumnsMasked(t *testing.T) {
+	t.Parallel()
+	masks := map[string]domain.MaskType{"ssn": domain.MaskRedact}
+	tests := []struct {
+		name string
+		sql  string
+		row  map[string]any
+	}{
+		{"subquery alias", "SELECT max(s) FROM (SELECT ssn AS s FROM users) q", map[string]any{"max": "078-05-1120"}},
+		{"whole row star", "SELECT to_jsonb(u.*) AS j FROM users u", map[string]any{"j": `{"ssn": "078-05-1120"}`}},
+		{"whole row reference", "SELECT row_to_json(u) FROM users u", map[string]any{"row_to_json": `{"ssn": "078-05-1120"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			exec := &mockExecutor{result: []map[string]any{tt.row}}
+			svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), masks, nil, nil)
+
+			rows, err := svc.Execute(context.Background(), tt.sql)
+			require.NoError(t, err)
+			for k, v := range rows[0] {
+				assert.Equal(t, "***", v, k)
+			}
+		})
+	}
+}
+
 func TestQueryService_NoMasks(t *testing.T) {
 	t.Parallel()
 	exec := &mockExecutor{
@@ -180,3 +516,171 @@ func TestQueryService_NoMasks(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "alice@example.com", rows[0]["email"])
 }
+
+func TestQueryService_KeyCase_MasksBeforeTransform(t *testing.T) {
+	t.Parallel()
+	exec := &mockExecutor{
+		result: []map[string]any{
+			{"user_id": 1, "email_address": "alice@example.com"},
+		},
+	}
+	// The mask is keyed on the original snake_case column name; the camelCase
+	// transform must not prevent it from applying.
+	masks := map[string]domain.MaskType{"email_address": domain.MaskRedact}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), masks, nil, nil,
+		WithKeyCase(domain.KeyCaseCamel),
+	)
+
+	rows, err := svc.Execute(context.Background(), "SELECT user_id, email_address FROM users")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, map[string]any{"userId": 1, "emailAddress": "***"}, rows[0])
+}
+
+func TestQueryService_KeyCase_DefaultOriginal(t *testing.T) {
+	t.Parallel()
+	exec := &mockExecutor{
+		result: []map[string]any{{"user_id": 1}},
+	}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), nil, nil, nil)
+
+	rows, err := svc.Execute(context.Background(), "SELECT user_id FROM users")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"user_id": 1}, rows[0])
+}
+
+func TestQueryService_KeyCase_AppliesToResultColumns(t *testing.T) {
+	t.Parallel()
+	exec := &mockExecutor{
+		columns: []port.ResultColumn{{Name: "user_id", Type: "integer", TypeOID: 23}},
+	}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), nil, nil, nil,
+		WithKeyCase(domain.KeyCaseCamel),
+	)
+
+	var cols []port.ResultColumn
+	rows, err := svc.Execute(port.WithResultColumns(context.Background(), &cols), "SELECT user_id FROM users WHERE false")
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+	assert.Equal(t, []port.ResultColumn{{Name: "userId", Type: "integer", TypeOID: 23}}, cols)
+}
+
+func TestQueryService_KeyCase_CollisionsFollowColumnOrder(t *testing.T) {
+	t.Parallel()
+	exec := &mockExecutor{
+		result:  []map[string]any{{"userId": 1, "user_id": 2}},
+		columns: []port.ResultColumn{{Name: "user_id", Type: "integer"}, {Name: "userId", Type: "integer"}},
+	}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), nil, nil, nil,
+		WithKeyCase(domain.KeyCaseCamel),
+	)
+
+	var cols []port.ResultColumn
+	rows, err := svc.Execute(port.WithResultColumns(context.Background(), &cols), `SELECT user_id, "userId" FROM users`)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"userId": 2, "userId_2": 1}}, rows)
+	assert.Equal(t, "userId", cols[0].Name)
+	assert.Equal(t, "userId_2", cols[1].Name)
+}
+
+func TestQueryService_MaskPatterns_ExplicitTakesPrecedence(t *testing.T) {
+	t.Parallel()
+	exec := &mockExecutor{
+		result: []map[string]any{
+			{"id": 1, "email": "alice@example.com", "backup_email": "alice@backup.example"},
+		},
+	}
+	masks := map[string]domain.MaskType{"email": domain.MaskNull}
+	patterns := []domain.MaskPattern{{Name: regexp.MustCompile("email"), Mask: domain.MaskRedact}}
+	svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), masks, nil, nil,
+		WithMaskPatterns(patterns),
+	)
+
+	rows, err := svc.Execute(context.Background(), "SELECT id, email, backup_email FROM users")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, map[string]any{"id": 1, "email": nil, "backup_email": "***"}, rows[0])
+}
+
+// stubColumnLister returns fixed columns and records each lookup.
+type stubColumnLister struct {
+	columns map[string][]string
+	err     error
+	calls   [][]string
+}
+
+func (s *stubColumnLister) ColumnNames(_ context.Context, _, tables []string) (map[string][]string, error) {
+	s.calls = append(s.calls, tables)
+	out := make(map[string][]string)
+	for _, t := range tables {
+		if cols, ok := s.columns[t]; ok {
+			out[t] = cols
+		}
+	}
+	return out, s.err
+}
+
+func TestQueryService_DefaultMasks(t *testing.T) {
+	t.Parallel()
+	tables := map[domain.TableRef]domain.MaskType{{Schema: "pii", Name: "cards"}: domain.MaskNull}
+	schemas := map[string]domain.MaskType{"pii": domain.MaskRedact}
+	explicit := map[string]domain.MaskType{"email": domain.MaskHash}
+
+	run := func(t *testing.T, lister *stubColumnLister, sql string, row map[string]any) []map[string]any {
+		t.Helper()
+		exec := &mockExecutor{result: []map[string]any{row}}
+		svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), explicit, nil, nil,
+			WithDefaultMasks(tables, schemas, lister),
+		)
+		rows, err := svc.Execute(context.Background(), sql)
+		require.NoError(t, err)
+		return rows
+	}
+
+	t.Run("other tables keep their values", func(t *testing.T) {
+		t.Parallel()
+		lister := &stubColumnLister{columns: map[string][]string{"pii.people": {"id", "name"}}}
+		rows := run(t, lister, "SELECT id, name FROM public.users", map[string]any{"id": 1, "name": "alice"})
+		assert.Equal(t, map[string]any{"id": 1, "name": "alice"}, rows[0])
+		assert.Empty(t, lister.calls, "the catalog is not queried for uncovered tables")
+	})
+
+	t.Run("schema default covers the table read", func(t *testing.T) {
+		t.Parallel()
+		lister := &stubColumnLister{columns: map[string][]string{"pii.people": {"id", "name", "email"}}}
+		rows := run(t, lister, "SELECT id, name AS n, email FROM pii.people", map[string]any{"id": 1, "n": "alice", "email": "a@example.com"})
+		assert.Equal(t, "***", rows[0]["id"])
+		assert.Equal(t, "***", rows[0]["n"], "aliases resolve to the masked column")
+		assert.NotEqual(t, "***", rows[0]["email"], "explicit masks win")
+		assert.NotEqual(t, "a@example.com", rows[0]["email"])
+	})
+
+	t.Run("table default beats schema default", func(t *testing.T) {
+		t.Parallel()
+		lister := &stubColumnLister{columns: map[string][]string{"pii.cards": {"number"}}}
+		rows := run(t, lister, "SELECT number FROM pii.cards", map[string]any{"number": "4111"})
+		assert.Equal(t, map[string]any{"number": nil}, rows[0])
+	})
+
+	t.Run("columns are listed per query", func(t *testing.T) {
+		t.Parallel()
+		lister := &stubColumnLister{columns: map[string][]string{"pii.people": {"id"}}}
+		run(t, lister, "SELECT id FROM people", map[string]any{"id": 1})
+		lister.columns["pii.people"] = append(lister.columns["pii.people"], "added_later")
+		rows := run(t, lister, "SELECT added_later FROM people", map[string]any{"added_later": "x"})
+		assert.Equal(t, "***", rows[0]["added_later"])
+		assert.Equal(t, []string{"pii.people"}, lister.calls[1], "an unqualified name is looked up in the covered schema")
+	})
+
+	t.Run("lister failure fails the query", func(t *testing.T) {
+		t.Parallel()
+		exec := &mockExecutor{result: []map[string]any{{"id": 1}}}
+		svc := NewQueryService(domain.NewPgQueryValidator(), exec, port.NoopAuditor{}, testLogger(), nil, nil, nil,
+			WithDefaultMasks(nil, schemas, &stubColumnLister{err: errors.New("connection reset")}),
+		)
+		rows, err := svc.Execute(context.Background(), "SELECT id FROM pii.people")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "default masks")
+		assert.Nil(t, rows)
+	})
+}
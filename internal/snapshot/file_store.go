@@ -0,0 +1,82 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+)
+
+// FileStore keeps the latest table size snapshot in a single JSON file.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStore returns a store backed by the file at path. The file is
+// created on the first Swap.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Swap reads the stored snapshot, replaces it with cur and returns the old
+// one. The new file is written next to the old and renamed into place, so a
+// crash never leaves a truncated snapshot behind.
+func (s *FileStore) Swap(_ context.Context, cur port.SizeSnapshot) (*port.SizeSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.write(cur); err != nil {
+		return nil, err
+	}
+	return prev, nil
+}
+
+func (s *FileStore) read() (*port.SizeSnapshot, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	var snap port.SizeSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("decoding snapshot %s: %w", s.path, err)
+	}
+	return &snap, nil
+}
+
+func (s *FileStore) write(snap port.SizeSnapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return nil
+}
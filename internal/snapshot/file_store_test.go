@@ -0,0 +1,56 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/guillermoBallester/isthmus/internal/core/port"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore_Swap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "growth.json")
+	store := NewFileStore(path)
+	ctx := context.Background()
+
+	first := port.SizeSnapshot{
+		TakenAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Tables:  map[string]port.TableSize{"public.orders": {RowEstimate: 100, TotalBytes: 8192}},
+	}
+	prev, err := store.Swap(ctx, first)
+	require.NoError(t, err)
+	assert.Nil(t, prev, "no snapshot before the first call")
+
+	second := port.SizeSnapshot{
+		TakenAt: first.TakenAt.Add(time.Hour),
+		Tables:  map[string]port.TableSize{"public.orders": {RowEstimate: 150, TotalBytes: 16384}},
+	}
+	prev, err = store.Swap(ctx, second)
+	require.NoError(t, err)
+	require.NotNil(t, prev)
+	assert.True(t, first.TakenAt.Equal(prev.TakenAt))
+	assert.Equal(t, first.Tables, prev.Tables)
+
+	// A fresh store on the same path picks up where the last one left off.
+	prev, err = NewFileStore(path).Swap(ctx, first)
+	require.NoError(t, err)
+	require.NotNil(t, prev)
+	assert.Equal(t, second.Tables, prev.Tables)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are cleaned up")
+}
+
+func TestFileStore_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "growth.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0644))
+
+	_, err := NewFileStore(path).Swap(context.Background(), port.SizeSnapshot{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "decoding snapshot")
+}
@@ -3,7 +3,9 @@ package telemetry
 import (
 	"context"
 
+	"github.com/guillermoBallester/isthmus/internal/core/port"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/metric/noop"
 )
@@ -16,6 +18,11 @@ type Instruments struct {
 	QueryDuration metric.Float64Histogram
 	QueryErrors   metric.Int64Counter
 	ToolDuration  metric.Float64Histogram
+
+	// Audit summaries, attributed by tool.
+	AuditQueries metric.Int64Counter
+	AuditErrors  metric.Int64Counter
+	AuditRows    metric.Int64Counter
 }
 
 // NewInstruments creates metric instruments from the global MeterProvider.
@@ -48,11 +55,24 @@ func newInstrumentsFromMeter(meter metric.Meter) *Instruments {
 		metric.WithUnit("ms"),
 	)
 
+	auditQueries, _ := meter.Int64Counter("isthmus.audit.queries",
+		metric.WithDescription("Total number of audited queries"),
+	)
+	auditErrors, _ := meter.Int64Counter("isthmus.audit.errors",
+		metric.WithDescription("Total number of audited queries that failed"),
+	)
+	auditRows, _ := meter.Int64Counter("isthmus.audit.rows",
+		metric.WithDescription("Total number of rows returned by audited queries"),
+	)
+
 	return &Instruments{
 		QueryCount:    queryCount,
 		QueryDuration: queryDuration,
 		QueryErrors:   queryErrors,
 		ToolDuration:  toolDuration,
+		AuditQueries:  auditQueries,
+		AuditErrors:   auditErrors,
+		AuditRows:     auditRows,
 	}
 }
 
@@ -71,3 +91,14 @@ func (i *Instruments) IncrementQueryErrors(ctx context.Context) {
 func (i *Instruments) RecordToolDuration(ctx context.Context, ms float64) {
 	i.ToolDuration.Record(ctx, ms)
 }
+
+// RecordAudit adds entry to the audit counters, attributed by its tool, so
+// query volume is visible without reading the audit log.
+func (i *Instruments) RecordAudit(ctx context.Context, entry port.AuditEntry) {
+	attrs := metric.WithAttributes(attribute.String("tool", entry.Tool))
+	i.AuditQueries.Add(ctx, 1, attrs)
+	if entry.Err != nil {
+		i.AuditErrors.Add(ctx, 1, attrs)
+	}
+	i.AuditRows.Add(ctx, int64(entry.RowsReturned), attrs)
+}
@@ -0,0 +1,79 @@
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// PoolStats is a snapshot of connection pool usage. It mirrors the fields of
+// pgxpool.Stat that matter for saturation alerts, so this package does not
+// depend on the database driver.
+type PoolStats struct {
+	AcquiredConns     int32
+	IdleConns         int32
+	TotalConns        int32
+	MaxConns          int32
+	EmptyAcquireCount int64         // acquires that had to wait for a connection
+	AcquireDuration   time.Duration // cumulative time spent acquiring connections
+}
+
+// RegisterPoolMetrics registers asynchronous isthmus.pool.* instruments on
+// the global MeterProvider. stats is called once per collection cycle.
+func RegisterPoolMetrics(stats func() PoolStats) error {
+	return registerPoolMetrics(otel.Meter(meterName), stats)
+}
+
+func registerPoolMetrics(meter metric.Meter, stats func() PoolStats) error {
+	acquired, err := meter.Int64ObservableGauge("isthmus.pool.acquired_conns",
+		metric.WithDescription("Connections currently checked out of the pool"),
+	)
+	if err != nil {
+		return err
+	}
+	idle, err := meter.Int64ObservableGauge("isthmus.pool.idle_conns",
+		metric.WithDescription("Idle connections in the pool"),
+	)
+	if err != nil {
+		return err
+	}
+	total, err := meter.Int64ObservableGauge("isthmus.pool.total_conns",
+		metric.WithDescription("Total connections in the pool"),
+	)
+	if err != nil {
+		return err
+	}
+	maxConns, err := meter.Int64ObservableGauge("isthmus.pool.max_conns",
+		metric.WithDescription("Maximum size of the pool"),
+	)
+	if err != nil {
+		return err
+	}
+	waitCount, err := meter.Int64ObservableCounter("isthmus.pool.acquire_wait_count",
+		metric.WithDescription("Acquires that waited because the pool was empty"),
+	)
+	if err != nil {
+		return err
+	}
+	acquireDuration, err := meter.Float64ObservableCounter("isthmus.pool.acquire_duration",
+		metric.WithDescription("Cumulative time spent acquiring connections in milliseconds"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		s := stats()
+		o.ObserveInt64(acquired, int64(s.AcquiredConns))
+		o.ObserveInt64(idle, int64(s.IdleConns))
+		o.ObserveInt64(total, int64(s.TotalConns))
+		o.ObserveInt64(maxConns, int64(s.MaxConns))
+		o.ObserveInt64(waitCount, s.EmptyAcquireCount)
+		o.ObserveFloat64(acquireDuration, float64(s.AcquireDuration)/float64(time.Millisecond))
+		return nil
+	}, acquired, idle, total, maxConns, waitCount, acquireDuration)
+	return err
+}
@@ -14,6 +14,8 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"go.opentelemetry.io/otel/trace"
 	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 // Provider holds the OTel trace and metric providers for graceful shutdown.
@@ -22,9 +24,44 @@ type Provider struct {
 	mp *sdkmetric.MeterProvider
 }
 
+// ExporterTLS configures how the OTLP exporters connect to the collector.
+// The zero value keeps the SDK default: TLS verified against the system roots.
+type ExporterTLS struct {
+	CAFile   string // PEM CA bundle for collectors behind a private PKI
+	Insecure bool   // plaintext, e.g. for a local collector
+}
+
+// credentials returns the gRPC transport credentials for t, or nil for the
+// SDK default.
+func (t ExporterTLS) credentials() (credentials.TransportCredentials, error) {
+	switch {
+	case t.Insecure:
+		return insecure.NewCredentials(), nil
+	case t.CAFile != "":
+		creds, err := credentials.NewClientTLSFromFile(t.CAFile, "")
+		if err != nil {
+			return nil, fmt.Errorf("loading OTLP CA bundle: %w", err)
+		}
+		return creds, nil
+	default:
+		return nil, nil
+	}
+}
+
 // Init creates and registers OTel trace and metric providers with OTLP gRPC exporters.
 // The OTEL_EXPORTER_OTLP_ENDPOINT env var is read by the OTel SDK automatically.
-func Init(ctx context.Context, serviceName, version string) (*Provider, error) {
+func Init(ctx context.Context, serviceName, version string, tls ExporterTLS) (*Provider, error) {
+	creds, err := tls.credentials()
+	if err != nil {
+		return nil, err
+	}
+	var traceOpts []otlptracegrpc.Option
+	var metricOpts []otlpmetricgrpc.Option
+	if creds != nil {
+		traceOpts = append(traceOpts, otlptracegrpc.WithTLSCredentials(creds))
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithTLSCredentials(creds))
+	}
+
 	res, err := resource.New(ctx,
 		resource.WithAttributes(
 			semconv.ServiceName(serviceName),
@@ -35,7 +72,7 @@ func Init(ctx context.Context, serviceName, version string) (*Provider, error) {
 		return nil, fmt.Errorf("creating otel resource: %w", err)
 	}
 
-	traceExporter, err := otlptracegrpc.New(ctx)
+	traceExporter, err := otlptracegrpc.New(ctx, traceOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("creating trace exporter: %w", err)
 	}
@@ -45,7 +82,7 @@ func Init(ctx context.Context, serviceName, version string) (*Provider, error) {
 		sdktrace.WithResource(res),
 	)
 
-	metricExporter, err := otlpmetricgrpc.New(ctx)
+	metricExporter, err := otlpmetricgrpc.New(ctx, metricOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("creating metric exporter: %w", err)
 	}
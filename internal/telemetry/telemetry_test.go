@@ -2,8 +2,20 @@ package telemetry
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/guillermoBallester/isthmus/internal/core/port"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/attribute"
@@ -81,3 +93,128 @@ func TestMetricRecording(t *testing.T) {
 	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
 	assert.Equal(t, "test.counter", rm.ScopeMetrics[0].Metrics[0].Name)
 }
+
+func TestPoolMetrics(t *testing.T) {
+	t.Parallel()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	err := registerPoolMetrics(mp.Meter("test"), func() PoolStats {
+		return PoolStats{
+			AcquiredConns:     3,
+			IdleConns:         1,
+			TotalConns:        4,
+			MaxConns:          5,
+			EmptyAcquireCount: 7,
+			AcquireDuration:   1500 * time.Microsecond,
+		}
+	})
+	require.NoError(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	got := make(map[string]metricdata.Aggregation)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		got[m.Name] = m.Data
+	}
+	require.Len(t, got, 6)
+
+	acquired, ok := got["isthmus.pool.acquired_conns"].(metricdata.Gauge[int64])
+	require.True(t, ok, "acquired_conns should be an int64 gauge")
+	require.Len(t, acquired.DataPoints, 1)
+	assert.Equal(t, int64(3), acquired.DataPoints[0].Value)
+
+	maxConns, ok := got["isthmus.pool.max_conns"].(metricdata.Gauge[int64])
+	require.True(t, ok)
+	assert.Equal(t, int64(5), maxConns.DataPoints[0].Value)
+
+	waits, ok := got["isthmus.pool.acquire_wait_count"].(metricdata.Sum[int64])
+	require.True(t, ok)
+	assert.Equal(t, int64(7), waits.DataPoints[0].Value)
+
+	duration, ok := got["isthmus.pool.acquire_duration"].(metricdata.Sum[float64])
+	require.True(t, ok)
+	assert.InDelta(t, 1.5, duration.DataPoints[0].Value, 1e-9)
+}
+
+func TestInstruments_RecordAudit(t *testing.T) {
+	t.Parallel()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	inst := newInstrumentsFromMeter(mp.Meter("test"))
+
+	ctx := context.Background()
+	inst.RecordAudit(ctx, port.AuditEntry{Tool: "query", RowsReturned: 10})
+	inst.RecordAudit(ctx, port.AuditEntry{Tool: "query", RowsReturned: 5})
+	inst.RecordAudit(ctx, port.AuditEntry{Tool: "ping", Err: errors.New("connection refused")})
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	// byTool sums a counter's data points per tool attribute.
+	byTool := func(name string) map[string]int64 {
+		for _, m := range rm.ScopeMetrics[0].Metrics {
+			if m.Name != name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			require.True(t, ok, "%s should be an int64 sum", name)
+			out := make(map[string]int64)
+			for _, dp := range sum.DataPoints {
+				tool, _ := dp.Attributes.Value("tool")
+				out[tool.AsString()] += dp.Value
+			}
+			return out
+		}
+		t.Fatalf("metric %s not recorded", name)
+		return nil
+	}
+
+	assert.Equal(t, map[string]int64{"query": 2, "ping": 1}, byTool("isthmus.audit.queries"))
+	assert.Equal(t, map[string]int64{"ping": 1}, byTool("isthmus.audit.errors"))
+	assert.Equal(t, map[string]int64{"query": 15, "ping": 0}, byTool("isthmus.audit.rows"))
+}
+
+func TestExporterTLS_Credentials(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test collector CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	notPEM := filepath.Join(dir, "not.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	creds, err := ExporterTLS{}.credentials()
+	require.NoError(t, err)
+	assert.Nil(t, creds, "the SDK default is kept")
+
+	creds, err = ExporterTLS{CAFile: caFile}.credentials()
+	require.NoError(t, err)
+	require.NotNil(t, creds)
+	assert.Equal(t, "tls", creds.Info().SecurityProtocol)
+
+	creds, err = ExporterTLS{Insecure: true}.credentials()
+	require.NoError(t, err)
+	require.NotNil(t, creds)
+	assert.Equal(t, "insecure", creds.Info().SecurityProtocol)
+
+	for _, path := range []string{filepath.Join(dir, "missing.pem"), notPEM} {
+		_, err = ExporterTLS{CAFile: path}.credentials()
+		assert.ErrorContains(t, err, "loading OTLP CA bundle", path)
+	}
+}